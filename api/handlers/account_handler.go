@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionRequest represents the request body for requesting the deletion of the account
+type AccountDeletionRequest struct {
+	Mode     models.AccountDeletionMode `json:"mode" validate:"omitempty,oneof=delete anonymize"`
+	Password string                     `json:"password"` // Required when the user has a password
+}
+
+// AccountDeletionResponse is returned when an account deletion is requested
+type AccountDeletionResponse struct {
+	models.AccountDeletion
+	ConfirmationToken string `json:"confirmationToken"` // Confirms the deletion; shown only once
+}
+
+// ConfirmAccountDeletionRequest represents the request body for confirming an account deletion
+type ConfirmAccountDeletionRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ExportUserData handles downloading all personal data of the authenticated user
+// @Summary Export personal data
+// @Description Download a ZIP archive with everything stored about the authenticated user: account, linked identities, personal access tokens, household memberships, the categories, transactions and reconciliations they created (including deleted ones) and the audit log of their changes, as JSON and CSV files
+// @Tags users
+// @Produce application/zip
+// @Success 200 {file} file "ZIP archive of JSON and CSV files"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me/export [get]
+func (h *UserHandler) ExportUserData(c *gin.Context) {
+	userID, ok := accountUserID(c, "ExportUserData")
+	if !ok {
+		return
+	}
+
+	export, err := h.UserService.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("ExportUserData: Failed to collect personal data via service.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to export personal data.",
+		})
+		return
+	}
+
+	// The archive is built in memory so that a failure can still be reported as JSON
+	var archive bytes.Buffer
+	if err := WriteUserDataArchive(&archive, export); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": userID,
+		}).Error("ExportUserData: Failed to write archive.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to write export archive.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"audit":  true,
+		"event":  "personal_data_exported",
+		"userID": userID,
+	}).Info("ExportUserData: Personal data exported.")
+	filename := fmt.Sprintf("personal-data-%s.zip", export.ExportedAt.Format("2006-01-02"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/zip", archive.Bytes())
+}
+
+// GetAccountDeletion handles retrieving the pending deletion of the account
+// @Summary Get the account deletion status
+// @Description Retrieve the pending or scheduled deletion of the authenticated user's account
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.AccountDeletion
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "No account deletion has been requested"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me/deletion [get]
+func (h *UserHandler) GetAccountDeletion(c *gin.Context) {
+	userID, ok := accountUserID(c, "GetAccountDeletion")
+	if !ok {
+		return
+	}
+
+	deletion, err := h.UserService.GetAccountDeletion(c.Request.Context(), userID)
+	if err != nil {
+		respondAccountDeletionError(c, err, userID, "GetAccountDeletion", "Failed to retrieve account deletion.")
+		return
+	}
+	c.JSON(http.StatusOK, deletion)
+}
+
+// RequestAccountDeletion handles requesting the deletion of the account
+// @Summary Request account deletion
+// @Description Start deleting the authenticated user's account. The returned token must be confirmed within a short time; the account is then deleted after a grace period during which the deletion can be cancelled. With mode "delete" the user and all of their data are removed permanently; with "anonymize" records are kept without anything identifying the user. Households shared with others are kept and handed over to another member.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body AccountDeletionRequest true "Deletion mode and current password"
+// @Success 201 {object} AccountDeletionResponse
+// @Failure 400 {object} responses.ErrorResponse "Invalid input or wrong password"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me/deletion [post]
+func (h *UserHandler) RequestAccountDeletion(c *gin.Context) {
+	userID, ok := accountUserID(c, "RequestAccountDeletion")
+	if !ok {
+		return
+	}
+
+	var req AccountDeletionRequest
+	if !bindUserRequest(c, &req, "RequestAccountDeletion") {
+		return
+	}
+
+	deletion, token, err := h.UserService.RequestAccountDeletion(c.Request.Context(), userID, req.Mode, req.Password)
+	if err != nil {
+		respondAccountDeletionError(c, err, userID, "RequestAccountDeletion", "Failed to request account deletion.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"audit":  true,
+		"event":  "account_deletion_requested",
+		"userID": userID,
+		"mode":   deletion.Mode,
+	}).Info("RequestAccountDeletion: Account deletion requested.")
+	c.JSON(http.StatusCreated, AccountDeletionResponse{AccountDeletion: *deletion, ConfirmationToken: token})
+}
+
+// ConfirmAccountDeletion handles confirming the deletion of the account
+// @Summary Confirm account deletion
+// @Description Confirm a deletion request with its token. The account is deleted when the grace period ends, unless the deletion is cancelled first.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body ConfirmAccountDeletionRequest true "Confirmation token"
+// @Success 200 {object} models.AccountDeletion
+// @Failure 400 {object} responses.ErrorResponse "Invalid or expired token"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me/deletion/confirm [post]
+func (h *UserHandler) ConfirmAccountDeletion(c *gin.Context) {
+	userID, ok := accountUserID(c, "ConfirmAccountDeletion")
+	if !ok {
+		return
+	}
+
+	var req ConfirmAccountDeletionRequest
+	if !bindUserRequest(c, &req, "ConfirmAccountDeletion") {
+		return
+	}
+
+	deletion, err := h.UserService.ConfirmAccountDeletion(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		respondAccountDeletionError(c, err, userID, "ConfirmAccountDeletion", "Failed to confirm account deletion.")
+		return
+	}
+	c.JSON(http.StatusOK, deletion)
+}
+
+// CancelAccountDeletion handles cancelling the deletion of the account
+// @Summary Cancel account deletion
+// @Description Withdraw a pending or scheduled deletion of the authenticated user's account
+// @Tags users
+// @Success 204 "Account deletion cancelled"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "No account deletion has been requested"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me/deletion [delete]
+func (h *UserHandler) CancelAccountDeletion(c *gin.Context) {
+	userID, ok := accountUserID(c, "CancelAccountDeletion")
+	if !ok {
+		return
+	}
+
+	if err := h.UserService.CancelAccountDeletion(c.Request.Context(), userID); err != nil {
+		respondAccountDeletionError(c, err, userID, "CancelAccountDeletion", "Failed to cancel account deletion.")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// accountUserID retrieves the authenticated user's ID, responding with an error if it is missing
+func accountUserID(c *gin.Context, handlerName string) (uint, bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error(handlerName + ": UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+	}
+	return userID, exists
+}
+
+// respondAccountDeletionError logs an account deletion error and maps it to the matching HTTP response
+func respondAccountDeletionError(c *gin.Context, err error, userID uint, handlerName, internalDetails string) {
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"error":     err.Error(),
+		"errorType": appErrors.GetType(err),
+		"userID":    userID,
+	}).Warn(handlerName + ": Account deletion operation failed.")
+
+	switch appErrors.GetType(err) {
+	case appErrors.TypeValidation:
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: err.Error(),
+		})
+	case appErrors.TypeNotFound:
+		c.JSON(http.StatusNotFound, responses.ErrorResponse{
+			Error:   "Not Found",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: internalDetails,
+		})
+	}
+}
+
+// WriteUserDataArchive writes a personal data export as a ZIP archive of JSON files, plus CSV
+// files of the categories and transactions for use in spreadsheets. The export command of the
+// administration tool writes the same archive.
+func WriteUserDataArchive(w io.Writer, export *services.UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	jsonFiles := []struct {
+		name string
+		data interface{}
+	}{
+		{"user.json", export.User},
+		{"identities.json", export.Identities},
+		{"api_tokens.json", export.APITokens},
+		{"households.json", export.Households},
+		{"categories.json", export.Categories},
+		{"transactions.json", export.Transactions},
+		{"reconciliations.json", export.Reconciliations},
+		{"audit_log.json", export.AuditEntries},
+	}
+	for _, file := range jsonFiles {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("writing %s: %w", file.name, err)
+		}
+	}
+
+	categories := [][]string{{"ID", "Household ID", "Name", "Parent ID", "Created At", "Deleted At"}}
+	for _, category := range export.Categories {
+		parentID := ""
+		if category.ParentID != nil {
+			parentID = strconv.FormatUint(uint64(*category.ParentID), 10)
+		}
+		categories = append(categories, []string{
+			strconv.FormatUint(uint64(category.ID), 10),
+			strconv.FormatUint(uint64(category.HouseholdID), 10),
+			category.Name,
+			parentID,
+			category.CreatedAt.UTC().Format(time.RFC3339),
+			formatDeletedAt(category.DeletedAt),
+		})
+	}
+	if err := writeArchiveCSV(archive, "categories.csv", export.ExportedAt, categories); err != nil {
+		return err
+	}
+
+	transactions := [][]string{{"ID", "Household ID", "Description", "Amount", "Type", "Date", "Category ID", "Status", "Deleted At"}}
+	for _, t := range export.Transactions {
+		transactions = append(transactions, []string{
+			strconv.FormatUint(uint64(t.ID), 10),
+			strconv.FormatUint(uint64(t.HouseholdID), 10),
+			t.Description,
+			fmt.Sprintf("%.2f", t.Amount),
+			string(t.Type),
+			t.Date.Format("2006-01-02"),
+			strconv.FormatUint(uint64(t.CategoryID), 10),
+			string(t.Status),
+			formatDeletedAt(t.DeletedAt),
+		})
+	}
+	if err := writeArchiveCSV(archive, "transactions.csv", export.ExportedAt, transactions); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// writeArchiveCSV adds a CSV file with the given rows to a ZIP archive
+func writeArchiveCSV(archive *zip.Writer, name string, modified time.Time, rows [][]string) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(f)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// formatDeletedAt renders the deletion time of a soft-deleted record, or an empty string
+func formatDeletedAt(deletedAt gorm.DeletedAt) string {
+	if !deletedAt.Valid {
+		return ""
+	}
+	return deletedAt.Time.UTC().Format(time.RFC3339)
+}
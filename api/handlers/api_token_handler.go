@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APITokenHandler holds the service for business logic access
+type APITokenHandler struct {
+	Service services.APITokenService
+}
+
+// NewAPITokenHandler creates a new handler for personal access tokens
+func NewAPITokenHandler(service services.APITokenService) *APITokenHandler {
+	return &APITokenHandler{Service: service}
+}
+
+// CreateAPITokenRequest represents the request body for creating a personal access token
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1" example:"transactions:read"`
+	ExpiresAt *time.Time `json:"expiresAt"` // Optional; tokens without expiry stay valid until revoked
+}
+
+// CreateAPITokenResponse represents a newly created personal access token
+type CreateAPITokenResponse struct {
+	models.APIToken
+	Token string `json:"token"` // Plaintext token, shown only once
+}
+
+// CreateAPIToken handles creating a personal access token
+// @Summary Create an API token
+// @Description Create a personal access token for scripts and integrations. The token is returned only in this response. Available scopes: transactions:read, transactions:write, categories:read, categories:write, reconciliations:read, reconciliations:write. Requires a login session; API tokens cannot manage tokens.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body CreateAPITokenRequest true "Token details"
+// @Success 201 {object} CreateAPITokenResponse
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/tokens [post]
+func (h *APITokenHandler) CreateAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("CreateAPIToken: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if !bindUserRequest(c, &req, "CreateAPIToken") {
+		return
+	}
+
+	token, plaintext, err := h.Service.CreateToken(c.Request.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("CreateAPIToken: Failed to create API token via service.")
+
+		if appErrors.IsType(err, appErrors.TypeValidation) {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to create API token.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":  userID,
+		"tokenID": token.ID,
+		"scopes":  token.Scopes,
+	}).Info("CreateAPIToken: API token created successfully.")
+	c.JSON(http.StatusCreated, CreateAPITokenResponse{APIToken: *token, Token: plaintext})
+}
+
+// GetAPITokens handles listing personal access tokens
+// @Summary Get all API tokens
+// @Description Retrieve the personal access tokens of the authenticated user, including revoked ones. Token values are never returned.
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.APIToken
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/tokens [get]
+func (h *APITokenHandler) GetAPITokens(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetAPITokens: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	tokens, err := h.Service.GetTokens(c.Request.Context(), userID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetAPITokens: Failed to retrieve API tokens via service.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve API tokens.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeAPIToken handles revoking a personal access token
+// @Summary Revoke an API token
+// @Description Revoke a personal access token of the authenticated user; it stops working immediately
+// @Tags users
+// @Param id path int true "API token ID"
+// @Success 204 "API token revoked"
+// @Failure 400 {object} responses.ErrorResponse "Invalid ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Active API token not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/tokens/{id} [delete]
+func (h *APITokenHandler) RevokeAPIToken(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("RevokeAPIToken: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid API token ID.",
+		})
+		return
+	}
+
+	if err := h.Service.RevokeToken(c.Request.Context(), userID, id); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+			"tokenID":   id,
+		}).Error("RevokeAPIToken: Failed to revoke API token via service.")
+
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to revoke API token.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":  userID,
+		"tokenID": id,
+	}).Info("RevokeAPIToken: API token revoked successfully.")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditHandler holds the service for business logic access
+type AuditHandler struct {
+	Service services.AuditService
+}
+
+// NewAuditHandler creates a new handler for the audit log
+func NewAuditHandler(service services.AuditService) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+// GetAuditEntries handles listing audit log entries
+// @Summary List audit log entries
+// @Description Retrieve the audit log of the selected household together with the changes the user made to their own account, newest first
+// @Tags audit
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of entries to retrieve" default(100)
+// @Param offset query int false "Number of entries to skip" default(0)
+// @Param actorId query int false "Only changes made by this user"
+// @Param action query string false "Only entries with this action" enum(create,update,delete,restore,purge)
+// @Param entityType query string false "Only entries about this type of entity" enum(transaction,category,user,user_identity)
+// @Param entityId query int false "Only entries about the entity with this ID"
+// @Param from query string false "Only entries at or after this time (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "Only entries at or before this time (RFC 3339 or YYYY-MM-DD)"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditEntries(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetAuditEntries: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	filter, problem := parseAuditFilter(c)
+	if problem != "" {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"problem": problem,
+			"userID":  userID,
+		}).Warn("GetAuditEntries: Invalid filter parameter.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: problem,
+		})
+		return
+	}
+
+	entries, err := h.Service.GetEntries(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), filter, limit, offset)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetAuditEntries: Failed to retrieve audit entries via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve audit entries.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// VerifyAuditChain handles checking the integrity of the audit log
+// @Summary Verify the audit log
+// @Description Recompute the hash chain of the whole audit log and report the first entry that was altered, removed or inserted out of order
+// @Tags audit
+// @Produce json
+// @Success 200 {object} models.AuditVerification
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /audit/verify [get]
+func (h *AuditHandler) VerifyAuditChain(c *gin.Context) {
+	result, err := h.Service.VerifyChain(c.Request.Context())
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+		}).Error("VerifyAuditChain: Failed to verify audit log via service.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to verify audit log.",
+		})
+		return
+	}
+
+	if !result.Valid {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"audit":          true,
+			"event":          "audit_chain_broken",
+			"firstInvalidID": *result.FirstInvalidID,
+		}).Error("VerifyAuditChain: Audit log hash chain is broken.")
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// parseAuditFilter reads the audit log filters from the query string. It returns a description
+// of the first invalid parameter, if any.
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, string) {
+	var filter models.AuditFilter
+	if value := c.Query("actorId"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return filter, "Invalid actorId. Expected a positive integer."
+		}
+		actorID := uint(id)
+		filter.ActorID = &actorID
+	}
+	if value := c.Query("entityId"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return filter, "Invalid entityId. Expected a positive integer."
+		}
+		entityID := uint(id)
+		filter.EntityID = &entityID
+	}
+	if value := c.Query("action"); value != "" {
+		filter.Action = &value
+	}
+	if value := c.Query("entityType"); value != "" {
+		filter.EntityType = &value
+	}
+	if value := c.Query("from"); value != "" {
+		from, ok := parseAuditTime(value, false)
+		if !ok {
+			return filter, "Invalid from. Expected an RFC 3339 time or YYYY-MM-DD."
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, ok := parseAuditTime(value, true)
+		if !ok {
+			return filter, "Invalid to. Expected an RFC 3339 time or YYYY-MM-DD."
+		}
+		filter.To = &to
+	}
+	return filter, ""
+}
+
+// parseAuditTime parses an RFC 3339 time or a date. A date used as the end of a range stands
+// for the end of that day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, true
+}
@@ -1,215 +1,785 @@
-package handlers
-
-import (
-	"fmt"
-	"net/http"
-	"personal-finance-tracker-api/api/middleware"
-	"personal-finance-tracker-api/api/responses"
-	appErrors "personal-finance-tracker-api/internal/errors"
-	"personal-finance-tracker-api/internal/models"
-	"personal-finance-tracker-api/internal/services"
-	"strconv"
-
-	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
-	"github.com/sirupsen/logrus"
-)
-
-var categoryValidate *validator.Validate
-
-func init() {
-	categoryValidate = validator.New()
-}
-
-// CategoryHandler holds the service for business logic access
-type CategoryHandler struct {
-	Service services.CategoryService
-}
-
-// NewCategoryHandler creates a new handler for categories
-func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
-	return &CategoryHandler{Service: service}
-}
-
-// CreateCategory handles the creation of a new category
-// @Summary Create a new category
-// @Description Add a new category to the system, associated with the authenticated user
-// @Tags categories
-// @Accept json
-// @Produce json
-// @Param category body models.Category true "Category object"
-// @Success 201 {object} models.Category
-// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
-// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
-// @Failure 409 {object} responses.ErrorResponse "Conflict error (e.g., category name already exists for this user)"
-// @Failure 500 {object} responses.ErrorResponse "Internal server error"
-// @Router /categories [post]
-func (h *CategoryHandler) CreateCategory(c *gin.Context) {
-	userID, exists := middleware.GetUserIDFromContext(c)
-	if !exists {
-		logrus.Error("CreateCategory: UserID not found in context, authentication middleware error.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Authenticated user ID not found.",
-		})
-		return
-	}
-
-	var category models.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":  err.Error(),
-			"userID": userID,
-		}).Warn("CreateCategory: Invalid JSON format or data type mismatch.")
-		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-			Error:   "Bad Request",
-			Details: "Invalid JSON format or data type mismatch.",
-		})
-		return
-	}
-
-	// Set the UserID from the authenticated context
-	category.UserID = userID
-
-	// Perform validation using the 'categoryValidate' instance
-	if err := categoryValidate.Struct(category); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			var fields []responses.ValidationFieldError
-			for _, fieldErr := range validationErrors {
-				fields = append(fields, responses.ValidationFieldError{
-					Field:   fieldErr.Field(),
-					Tag:     fieldErr.Tag(),
-					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
-				})
-			}
-			logrus.WithFields(logrus.Fields{
-				"validationErrors": fields,
-				"category":         category,
-				"userID":           userID,
-			}).Warn("CreateCategory: Input validation error.")
-			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
-				Error:  "Validation Error",
-				Fields: fields,
-			})
-			return
-		}
-		logrus.WithFields(logrus.Fields{
-			"error":    err.Error(),
-			"category": category,
-			"userID":   userID,
-		}).Warn("CreateCategory: Unknown input validation error.")
-		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-			Error:   "Bad Request",
-			Details: "Validation failed: " + err.Error(),
-		})
-		return
-	}
-
-	// Capture both returned values
-	createdCategory, err := h.Service.CreateCategory(c.Request.Context(), &category)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":     err.Error(),
-			"category":  category,
-			"errorType": appErrors.GetType(err),
-			"userID":    userID,
-		}).Error("CreateCategory: Failed to create category via service.")
-
-		if appErrors.IsType(err, appErrors.TypeAlreadyExists) {
-			c.JSON(http.StatusConflict, responses.ErrorResponse{
-				Error:   "Conflict",
-				Details: err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to create category.",
-		})
-		return
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"categoryID":   createdCategory.ID,
-		"categoryName": createdCategory.Name,
-		"userID":       userID,
-	}).Info("CreateCategory: Category created successfully.")
-	c.JSON(http.StatusCreated, createdCategory)
-}
-
-// GetCategories handles listing all categories with pagination
-// @Summary Get all categories
-// @Description Retrieve a list of all transaction categories with optional pagination, filtered by authenticated user
-// @Tags categories
-// @Produce json
-// @Param limit query int false "Maximum number of categories to retrieve" default(100)
-// @Param offset query int false "Number of categories to skip" default(0)
-// @Param name query string false "Search categories by name (case-insensitive)"
-// @Success 200 {array} models.Category
-// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
-// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
-// @Failure 500 {object} responses.ErrorResponse "Internal server error"
-// @Router /categories [get]
-func (h *CategoryHandler) GetCategories(c *gin.Context) {
-	userID, exists := middleware.GetUserIDFromContext(c)
-	if !exists {
-		logrus.Error("GetCategories: UserID not found in context, authentication middleware error.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Authenticated user ID not found.",
-		})
-		return
-	}
-
-	limitStr := c.DefaultQuery("limit", "100")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		logrus.WithFields(logrus.Fields{
-			"limitStr": limitStr,
-			"error":    err,
-			"userID":   userID,
-		}).Warn("GetCategories: Invalid limit parameter, defaulting to 100.")
-		limit = 100
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		logrus.WithFields(logrus.Fields{
-			"offsetStr": offsetStr,
-			"error":     err,
-			"userID":    userID,
-		}).Warn("GetCategories: Invalid offset parameter, defaulting to 0.")
-		offset = 0
-	}
-
-	// Filtering parameters
-	var categoryName *string
-	if nameStr := c.Query("name"); nameStr != "" {
-		categoryName = &nameStr
-	}
-
-	categories, err := h.Service.GetCategories(c.Request.Context(), userID, limit, offset, categoryName)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":     err.Error(),
-			"errorType": appErrors.GetType(err),
-			"userID":    userID,
-		}).Error("GetCategories: Failed to retrieve categories via service.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to retrieve categories.",
-		})
-		return
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"count":  len(categories),
-		"limit":  limit,
-		"offset": offset,
-		"userID": userID,
-	}).Info("GetCategories: Categories retrieved successfully with pagination and user filter.")
-	c.JSON(http.StatusOK, categories)
-}
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+var categoryValidate *validator.Validate
+
+func init() {
+	categoryValidate = validator.New()
+}
+
+// CategoryHandler holds the service for business logic access
+type CategoryHandler struct {
+	Service services.CategoryService
+}
+
+// NewCategoryHandler creates a new handler for categories
+func NewCategoryHandler(service services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{Service: service}
+}
+
+// CreateCategory handles the creation of a new category
+// @Summary Create a new category
+// @Description Add a new category to the system, associated with the authenticated user
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param category body models.Category true "Category object"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict error (e.g., category name already exists for this user)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories [post]
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("CreateCategory: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": userID,
+		}).Warn("CreateCategory: Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return
+	}
+
+	// Perform validation using the 'categoryValidate' instance
+	if err := categoryValidate.Struct(category); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"category":         category,
+				"userID":           userID,
+			}).Warn("CreateCategory: Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return
+		}
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":    err.Error(),
+			"category": category,
+			"userID":   userID,
+		}).Warn("CreateCategory: Unknown input validation error.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	// Capture both returned values
+	createdCategory, err := h.Service.CreateCategory(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), &category)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"category":  category,
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("CreateCategory: Failed to create category via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeAlreadyExists) {
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeValidation) {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to create category.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"categoryID":   createdCategory.ID,
+		"categoryName": createdCategory.Name,
+		"userID":       userID,
+	}).Info("CreateCategory: Category created successfully.")
+	c.JSON(http.StatusCreated, createdCategory)
+}
+
+// GetCategories handles listing all categories with pagination
+// @Summary Get all categories
+// @Description Retrieve a list of all transaction categories with optional pagination, filtered by authenticated user
+// @Tags categories
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of categories to retrieve" default(100)
+// @Param offset query int false "Number of categories to skip" default(0)
+// @Param name query string false "Search categories by name (case-insensitive)"
+// @Success 200 {array} models.Category
+// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories [get]
+func (h *CategoryHandler) GetCategories(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetCategories: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 0 {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"limitStr": limitStr,
+			"error":    err,
+			"userID":   userID,
+		}).Warn("GetCategories: Invalid limit parameter, defaulting to 100.")
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"offsetStr": offsetStr,
+			"error":     err,
+			"userID":    userID,
+		}).Warn("GetCategories: Invalid offset parameter, defaulting to 0.")
+		offset = 0
+	}
+
+	// Filtering parameters
+	var categoryName *string
+	if nameStr := c.Query("name"); nameStr != "" {
+		categoryName = &nameStr
+	}
+
+	categories, err := h.Service.GetCategories(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), limit, offset, categoryName)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetCategories: Failed to retrieve categories via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve categories.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"count":  len(categories),
+		"limit":  limit,
+		"offset": offset,
+		"userID": userID,
+	}).Info("GetCategories: Categories retrieved successfully with pagination and user filter.")
+	c.JSON(http.StatusOK, categories)
+}
+
+// UpdateCategoryRequest represents the request body for renaming a category
+type UpdateCategoryRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// MoveCategoryRequest represents the request body for changing a category's parent
+type MoveCategoryRequest struct {
+	ParentID *uint `json:"parentId"` // null moves the category to the top level
+}
+
+// MergeCategoryRequest represents the request body for merging one category into another
+type MergeCategoryRequest struct {
+	TargetID uint `json:"targetId" validate:"required"`
+}
+
+// GetCategoryTree handles retrieving the category hierarchy
+// @Summary Get the category tree
+// @Description Retrieve all of the authenticated user's categories as nested trees in one response, optionally with per-category transaction counts and totals
+// @Tags categories
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param depth query int false "Maximum number of levels to return (1 returns only top-level categories)"
+// @Param stats query bool false "Include transaction counts and totals per category" default(false)
+// @Param startDate query string false "Only count transactions from this date (YYYY-MM-DD) in the user's time zone, inclusive" format(date)
+// @Param endDate query string false "Only count transactions up to this date (YYYY-MM-DD) in the user's time zone, inclusive" format(date)
+// @Success 200 {array} models.CategoryNode
+// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/tree [get]
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetCategoryTree: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	depth := 0
+	if depthStr := c.Query("depth"); depthStr != "" {
+		parsed, err := strconv.Atoi(depthStr)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid 'depth' parameter. Must be a positive integer.",
+			})
+			return
+		}
+		depth = parsed
+	}
+
+	withStats := false
+	if statsStr := c.Query("stats"); statsStr != "" {
+		parsed, err := strconv.ParseBool(statsStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid 'stats' parameter. Must be true or false.",
+			})
+			return
+		}
+		withStats = parsed
+	}
+
+	var startDate *time.Time
+	if sdStr := c.Query("startDate"); sdStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", sdStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid startDate format. Expected YYYY-MM-DD.",
+			})
+			return
+		}
+		startDate = &parsedDate
+	}
+
+	var endDate *time.Time
+	if edStr := c.Query("endDate"); edStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", edStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid endDate format. Expected YYYY-MM-DD.",
+			})
+			return
+		}
+		endDate = &parsedDate
+	}
+
+	tree, err := h.Service.GetCategoryTree(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), depth, withStats, startDate, endDate)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetCategoryTree: Failed to retrieve category tree via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve category tree.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"roots":  len(tree),
+		"depth":  depth,
+		"stats":  withStats,
+		"userID": userID,
+	}).Info("GetCategoryTree: Category tree retrieved successfully.")
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetCategoryTemplates handles listing the available category templates
+// @Summary List category templates
+// @Description Retrieve the predefined category hierarchies that can be applied to an account
+// @Tags categories
+// @Produce json
+// @Success 200 {array} templates.CategoryTemplate
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Router /categories/templates [get]
+func (h *CategoryHandler) GetCategoryTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Service.GetCategoryTemplates())
+}
+
+// ApplyCategoryTemplate handles adding a template's categories to the user's categories
+// @Summary Apply a category template
+// @Description Create the categories of a template for the authenticated user. Categories whose name already exists are skipped, so a template can safely be applied more than once.
+// @Tags categories
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param name path string true "Template name"
+// @Success 200 {array} models.Category "The newly created categories"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Template not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/templates/{name}/apply [post]
+func (h *CategoryHandler) ApplyCategoryTemplate(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("ApplyCategoryTemplate: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	name := c.Param("name")
+	created, err := h.Service.ApplyCategoryTemplate(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), name)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"template":  name,
+			"userID":    userID,
+		}).Error("ApplyCategoryTemplate: Failed to apply category template via service.")
+		respondCategoryError(c, err, "Failed to apply category template.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"template": name,
+		"created":  len(created),
+		"userID":   userID,
+	}).Info("ApplyCategoryTemplate: Category template applied successfully.")
+	c.JSON(http.StatusOK, created)
+}
+
+// GetCategory handles retrieving a single category
+// @Summary Get a category
+// @Description Retrieve a single category owned by the authenticated user
+// @Tags categories
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} responses.ErrorResponse "Invalid category ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 404 {object} responses.ErrorResponse "Category not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/{id} [get]
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetCategory: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid category ID.",
+		})
+		return
+	}
+
+	category, err := h.Service.GetCategory(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":      err.Error(),
+			"errorType":  appErrors.GetType(err),
+			"categoryID": id,
+			"userID":     userID,
+		}).Error("GetCategory: Failed to retrieve category via service.")
+		respondCategoryError(c, err, "Failed to retrieve category.")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory handles renaming a category
+// @Summary Rename a category
+// @Description Change the name of a category owned by the authenticated user
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Param request body UpdateCategoryRequest true "New category name"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Category not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (category name already exists)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("UpdateCategory: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid category ID.",
+		})
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if !bindCategoryRequest(c, &req, "UpdateCategory", userID) {
+		return
+	}
+
+	category, err := h.Service.UpdateCategory(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, req.Name)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":      err.Error(),
+			"errorType":  appErrors.GetType(err),
+			"categoryID": id,
+			"userID":     userID,
+		}).Error("UpdateCategory: Failed to update category via service.")
+		respondCategoryError(c, err, "Failed to update category.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"categoryID":   category.ID,
+		"categoryName": category.Name,
+		"userID":       userID,
+	}).Info("UpdateCategory: Category updated successfully.")
+	c.JSON(http.StatusOK, category)
+}
+
+// MoveCategory handles changing the parent of a category
+// @Summary Move a category
+// @Description Change the parent of a category. A null parentId moves it to the top level. Moving a category underneath one of its own descendants is rejected.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Param request body MoveCategoryRequest true "New parent category"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} responses.ErrorResponse "Invalid input or the move would create a cycle"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Category not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/{id}/parent [patch]
+func (h *CategoryHandler) MoveCategory(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("MoveCategory: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid category ID.",
+		})
+		return
+	}
+
+	var req MoveCategoryRequest
+	if !bindCategoryRequest(c, &req, "MoveCategory", userID) {
+		return
+	}
+
+	category, err := h.Service.MoveCategory(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, req.ParentID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":      err.Error(),
+			"errorType":  appErrors.GetType(err),
+			"categoryID": id,
+			"parentID":   req.ParentID,
+			"userID":     userID,
+		}).Error("MoveCategory: Failed to move category via service.")
+		respondCategoryError(c, err, "Failed to move category.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"categoryID": category.ID,
+		"parentID":   category.ParentID,
+		"userID":     userID,
+	}).Info("MoveCategory: Category moved successfully.")
+	c.JSON(http.StatusOK, category)
+}
+
+// MergeCategories handles merging one category into another
+// @Summary Merge categories
+// @Description Reassign all transactions and child categories of a category to the target category, then delete it. Categories with reconciled transactions cannot be merged.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID to merge away"
+// @Param request body MergeCategoryRequest true "Target category"
+// @Success 200 {object} models.Category "The target category"
+// @Failure 400 {object} responses.ErrorResponse "Invalid input or the merge would create a cycle"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Category not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (category has reconciled transactions)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/{id}/merge [post]
+func (h *CategoryHandler) MergeCategories(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("MergeCategories: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid category ID.",
+		})
+		return
+	}
+
+	var req MergeCategoryRequest
+	if !bindCategoryRequest(c, &req, "MergeCategories", userID) {
+		return
+	}
+
+	target, err := h.Service.MergeCategories(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, req.TargetID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"sourceID":  id,
+			"targetID":  req.TargetID,
+			"userID":    userID,
+		}).Error("MergeCategories: Failed to merge categories via service.")
+		respondCategoryError(c, err, "Failed to merge categories.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"sourceID": id,
+		"targetID": target.ID,
+		"userID":   userID,
+	}).Info("MergeCategories: Categories merged successfully.")
+	c.JSON(http.StatusOK, target)
+}
+
+// DeleteCategory handles soft deleting a category
+// @Summary Delete a category
+// @Description Soft delete a category. Categories with transactions require reassignTo, the category to move them to. Child categories move up to the deleted category's parent.
+// @Tags categories
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Param reassignTo query int false "Category to reassign existing transactions to"
+// @Success 204 "Category deleted"
+// @Failure 400 {object} responses.ErrorResponse "Invalid category ID or reassignment category"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Category not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (category still has transactions, or reconciled ones to reassign)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("DeleteCategory: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid category ID.",
+		})
+		return
+	}
+
+	var reassignTo *uint
+	if reassignStr := c.Query("reassignTo"); reassignStr != "" {
+		parsed, err := strconv.ParseUint(reassignStr, 10, 32)
+		if err != nil || parsed == 0 {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid 'reassignTo' parameter. Must be a category ID.",
+			})
+			return
+		}
+		target := uint(parsed)
+		reassignTo = &target
+	}
+
+	if err := h.Service.DeleteCategory(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, reassignTo); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":      err.Error(),
+			"errorType":  appErrors.GetType(err),
+			"categoryID": id,
+			"reassignTo": reassignTo,
+			"userID":     userID,
+		}).Error("DeleteCategory: Failed to delete category via service.")
+		respondCategoryError(c, err, "Failed to delete category.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"categoryID": id,
+		"reassignTo": reassignTo,
+		"userID":     userID,
+	}).Info("DeleteCategory: Category deleted successfully.")
+	c.Status(http.StatusNoContent)
+}
+
+// bindCategoryRequest binds and validates a JSON request body, writing the error response on failure
+func bindCategoryRequest(c *gin.Context, req interface{}, name string, userID uint) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": userID,
+		}).Warn(name + ": Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return false
+	}
+
+	if err := categoryValidate.Struct(req); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"userID":           userID,
+			}).Warn(name + ": Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// respondCategoryError maps a service error to the matching HTTP error response
+func respondCategoryError(c *gin.Context, err error, internalDetails string) {
+	switch appErrors.GetType(err) {
+	case appErrors.TypeNotFound:
+		c.JSON(http.StatusNotFound, responses.ErrorResponse{
+			Error:   "Not Found",
+			Details: err.Error(),
+		})
+	case appErrors.TypeValidation:
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: err.Error(),
+		})
+	case appErrors.TypeForbidden:
+		c.JSON(http.StatusForbidden, responses.ErrorResponse{
+			Error:   "Forbidden",
+			Details: err.Error(),
+		})
+	case appErrors.TypeAlreadyExists, appErrors.TypeConflict:
+		c.JSON(http.StatusConflict, responses.ErrorResponse{
+			Error:   "Conflict",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: internalDetails,
+		})
+	}
+}
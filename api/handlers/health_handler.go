@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/internal/buildinfo"
+	"personal-finance-tracker-api/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler answers the probes of orchestrators and load balancers
+type HealthHandler struct {
+	Readiness *health.Checker // Checks that must pass before the instance receives traffic
+}
+
+// NewHealthHandler creates a new instance of HealthHandler
+func NewHealthHandler(readiness *health.Checker) *HealthHandler {
+	return &HealthHandler{Readiness: readiness}
+}
+
+// LivenessResponse is the body of the liveness probe
+type LivenessResponse struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// GetLiveness handles the liveness probe
+// @Summary Liveness probe
+// @Description Report that the process is running and serving HTTP. Dependencies are not checked, so a failing database does not get the instance restarted.
+// @Tags health
+// @Produce json
+// @Success 200 {object} handlers.LivenessResponse "Process is alive"
+// @Router /healthz [get]
+func (h *HealthHandler) GetLiveness(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, LivenessResponse{Status: health.StatusOK})
+}
+
+// GetReadiness handles the readiness probe
+// @Summary Readiness probe
+// @Description Check the database connection, that no schema migrations are pending and that the background jobs are running and their last run succeeded. Every check is reported with its duration and, when it fails, its error.
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report "Ready to serve traffic"
+// @Failure 503 {object} health.Report "A check failed"
+// @Router /readyz [get]
+func (h *HealthHandler) GetReadiness(c *gin.Context) {
+	report := h.Readiness.Run(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
+
+// GetVersion handles retrieving the build information
+// @Summary Get build information
+// @Description Retrieve the version, commit and build time of the running server, as set by the release build
+// @Tags health
+// @Produce json
+// @Success 200 {object} buildinfo.Info "Build information"
+// @Router /version [get]
+func (h *HealthHandler) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HouseholdHandler holds the service for business logic access
+type HouseholdHandler struct {
+	Service services.HouseholdService
+}
+
+// NewHouseholdHandler creates a new handler for households, members and invitations
+func NewHouseholdHandler(service services.HouseholdService) *HouseholdHandler {
+	return &HouseholdHandler{Service: service}
+}
+
+// HouseholdRequest represents the request body for creating or renaming a household
+type HouseholdRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// UpdateMemberRoleRequest represents the request body for changing a member's role
+type UpdateMemberRoleRequest struct {
+	Role models.HouseholdRole `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// CreateInvitationRequest represents the request body for inviting someone to a household
+type CreateInvitationRequest struct {
+	Email string               `json:"email" validate:"omitempty,email,max=255"` // Optional; restricts the invitation to this address
+	Role  models.HouseholdRole `json:"role" validate:"required,oneof=owner editor viewer"`
+}
+
+// CreateInvitationResponse represents a newly created invitation
+type CreateInvitationResponse struct {
+	models.HouseholdInvitation
+	Token string `json:"token"` // Plaintext invitation token, shown only once
+}
+
+// AcceptInvitationRequest represents the request body for accepting an invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// GetHouseholds handles listing the households of the user
+// @Summary Get all households
+// @Description Retrieve the households the authenticated user is a member of, with the user's role in each
+// @Tags households
+// @Produce json
+// @Success 200 {array} models.HouseholdMember
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households [get]
+func (h *HouseholdHandler) GetHouseholds(c *gin.Context) {
+	userID, ok := householdUserID(c, "GetHouseholds")
+	if !ok {
+		return
+	}
+
+	memberships, err := h.Service.GetHouseholds(c.Request.Context(), userID)
+	if err != nil {
+		logHouseholdError(c, err, "GetHouseholds", userID, 0)
+		respondMembershipError(c, err, "Failed to retrieve households.")
+		return
+	}
+
+	c.JSON(http.StatusOK, memberships)
+}
+
+// CreateHousehold handles creating a household
+// @Summary Create a household
+// @Description Create a household owned by the authenticated user
+// @Tags households
+// @Accept json
+// @Produce json
+// @Param household body HouseholdRequest true "Household details"
+// @Success 201 {object} models.Household
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households [post]
+func (h *HouseholdHandler) CreateHousehold(c *gin.Context) {
+	userID, ok := householdUserID(c, "CreateHousehold")
+	if !ok {
+		return
+	}
+
+	var req HouseholdRequest
+	if !bindUserRequest(c, &req, "CreateHousehold") {
+		return
+	}
+
+	household, err := h.Service.CreateHousehold(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		logHouseholdError(c, err, "CreateHousehold", userID, 0)
+		respondMembershipError(c, err, "Failed to create household.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":      userID,
+		"householdID": household.ID,
+	}).Info("CreateHousehold: Household created successfully.")
+	c.JSON(http.StatusCreated, household)
+}
+
+// GetHousehold handles retrieving a household
+// @Summary Get a household
+// @Description Retrieve a household the authenticated user is a member of
+// @Tags households
+// @Produce json
+// @Param id path int true "Household ID"
+// @Success 200 {object} models.Household
+// @Failure 400 {object} responses.ErrorResponse "Invalid household ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id} [get]
+func (h *HouseholdHandler) GetHousehold(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "GetHousehold")
+	if !ok {
+		return
+	}
+
+	household, err := h.Service.GetHousehold(c.Request.Context(), userID, householdID)
+	if err != nil {
+		logHouseholdError(c, err, "GetHousehold", userID, householdID)
+		respondMembershipError(c, err, "Failed to retrieve household.")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+// UpdateHousehold handles renaming a household
+// @Summary Rename a household
+// @Description Rename a household. Requires the owner role.
+// @Tags households
+// @Accept json
+// @Produce json
+// @Param id path int true "Household ID"
+// @Param household body HouseholdRequest true "Household details"
+// @Success 200 {object} models.Household
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id} [put]
+func (h *HouseholdHandler) UpdateHousehold(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "UpdateHousehold")
+	if !ok {
+		return
+	}
+
+	var req HouseholdRequest
+	if !bindUserRequest(c, &req, "UpdateHousehold") {
+		return
+	}
+
+	household, err := h.Service.UpdateHousehold(c.Request.Context(), userID, householdID, req.Name)
+	if err != nil {
+		logHouseholdError(c, err, "UpdateHousehold", userID, householdID)
+		respondMembershipError(c, err, "Failed to update household.")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+// SetDefaultHousehold handles selecting the default household of the user
+// @Summary Set the default household
+// @Description Use this household for requests that do not send an X-Household-ID header
+// @Tags households
+// @Param id path int true "Household ID"
+// @Success 204 "Default household set"
+// @Failure 400 {object} responses.ErrorResponse "Invalid household ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/default [put]
+func (h *HouseholdHandler) SetDefaultHousehold(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "SetDefaultHousehold")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.SetDefaultHousehold(c.Request.Context(), userID, householdID); err != nil {
+		logHouseholdError(c, err, "SetDefaultHousehold", userID, householdID)
+		respondMembershipError(c, err, "Failed to set default household.")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMembers handles listing the members of a household
+// @Summary Get household members
+// @Description Retrieve the members of a household and their roles
+// @Tags households
+// @Produce json
+// @Param id path int true "Household ID"
+// @Success 200 {array} models.HouseholdMember
+// @Failure 400 {object} responses.ErrorResponse "Invalid household ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/members [get]
+func (h *HouseholdHandler) GetMembers(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "GetMembers")
+	if !ok {
+		return
+	}
+
+	members, err := h.Service.GetMembers(c.Request.Context(), userID, householdID)
+	if err != nil {
+		logHouseholdError(c, err, "GetMembers", userID, householdID)
+		respondMembershipError(c, err, "Failed to retrieve household members.")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole handles changing the role of a household member
+// @Summary Change a member's role
+// @Description Change the role of a household member to owner, editor or viewer. Requires the owner role; the last owner cannot be demoted.
+// @Tags households
+// @Accept json
+// @Param id path int true "Household ID"
+// @Param userId path int true "User ID of the member"
+// @Param request body UpdateMemberRoleRequest true "New role"
+// @Success 204 "Role changed"
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household or member not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (household would have no owner)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/members/{userId} [put]
+func (h *HouseholdHandler) UpdateMemberRole(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "UpdateMemberRole")
+	if !ok {
+		return
+	}
+	memberUserID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid user ID.",
+		})
+		return
+	}
+
+	var req UpdateMemberRoleRequest
+	if !bindUserRequest(c, &req, "UpdateMemberRole") {
+		return
+	}
+
+	if err := h.Service.UpdateMemberRole(c.Request.Context(), userID, householdID, memberUserID, req.Role); err != nil {
+		logHouseholdError(c, err, "UpdateMemberRole", userID, householdID)
+		respondMembershipError(c, err, "Failed to change member role.")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember handles removing a member from a household
+// @Summary Remove a household member
+// @Description Remove a member from a household. Owners may remove any member and every member may remove themselves to leave; the last owner cannot leave.
+// @Tags households
+// @Param id path int true "Household ID"
+// @Param userId path int true "User ID of the member"
+// @Success 204 "Member removed"
+// @Failure 400 {object} responses.ErrorResponse "Invalid ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household or member not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (household would have no owner)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/members/{userId} [delete]
+func (h *HouseholdHandler) RemoveMember(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "RemoveMember")
+	if !ok {
+		return
+	}
+	memberUserID, ok := parseIDParam(c, "userId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid user ID.",
+		})
+		return
+	}
+
+	if err := h.Service.RemoveMember(c.Request.Context(), userID, householdID, memberUserID); err != nil {
+		logHouseholdError(c, err, "RemoveMember", userID, householdID)
+		respondMembershipError(c, err, "Failed to remove household member.")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateInvitation handles inviting someone to a household
+// @Summary Invite to a household
+// @Description Create an invitation to join a household with the given role. The token is returned only in this response; when an email address is given it is also sent there and only the user with that address can accept it. Requires the owner role.
+// @Tags households
+// @Accept json
+// @Produce json
+// @Param id path int true "Household ID"
+// @Param request body CreateInvitationRequest true "Invitation details"
+// @Success 201 {object} CreateInvitationResponse
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/invitations [post]
+func (h *HouseholdHandler) CreateInvitation(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "CreateInvitation")
+	if !ok {
+		return
+	}
+
+	var req CreateInvitationRequest
+	if !bindUserRequest(c, &req, "CreateInvitation") {
+		return
+	}
+
+	invitation, token, err := h.Service.CreateInvitation(c.Request.Context(), userID, householdID, req.Email, req.Role)
+	if err != nil {
+		logHouseholdError(c, err, "CreateInvitation", userID, householdID)
+		respondMembershipError(c, err, "Failed to create invitation.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateInvitationResponse{HouseholdInvitation: *invitation, Token: token})
+}
+
+// GetInvitations handles listing the pending invitations of a household
+// @Summary Get pending invitations
+// @Description Retrieve the invitations of a household that have not been accepted, revoked or expired. Requires the owner role.
+// @Tags households
+// @Produce json
+// @Param id path int true "Household ID"
+// @Success 200 {array} models.HouseholdInvitation
+// @Failure 400 {object} responses.ErrorResponse "Invalid household ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/invitations [get]
+func (h *HouseholdHandler) GetInvitations(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "GetInvitations")
+	if !ok {
+		return
+	}
+
+	invitations, err := h.Service.GetInvitations(c.Request.Context(), userID, householdID)
+	if err != nil {
+		logHouseholdError(c, err, "GetInvitations", userID, householdID)
+		respondMembershipError(c, err, "Failed to retrieve invitations.")
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation handles withdrawing a pending invitation
+// @Summary Revoke an invitation
+// @Description Withdraw a pending invitation so it can no longer be accepted. Requires the owner role.
+// @Tags households
+// @Param id path int true "Household ID"
+// @Param invitationId path int true "Invitation ID"
+// @Success 204 "Invitation revoked"
+// @Failure 400 {object} responses.ErrorResponse "Invalid ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (not an owner, or called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Household or pending invitation not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/{id}/invitations/{invitationId} [delete]
+func (h *HouseholdHandler) RevokeInvitation(c *gin.Context) {
+	userID, householdID, ok := householdParams(c, "RevokeInvitation")
+	if !ok {
+		return
+	}
+	invitationID, ok := parseIDParam(c, "invitationId")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid invitation ID.",
+		})
+		return
+	}
+
+	if err := h.Service.RevokeInvitation(c.Request.Context(), userID, householdID, invitationID); err != nil {
+		logHouseholdError(c, err, "RevokeInvitation", userID, householdID)
+		respondMembershipError(c, err, "Failed to revoke invitation.")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation handles joining a household with an invitation token
+// @Summary Accept an invitation
+// @Description Join the household of an invitation with the invited role
+// @Tags households
+// @Accept json
+// @Produce json
+// @Param request body AcceptInvitationRequest true "Invitation token"
+// @Success 201 {object} models.HouseholdMember
+// @Failure 400 {object} responses.ErrorResponse "Invalid or expired invitation token"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (invitation sent to another email address, or called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (already a member)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /households/invitations/accept [post]
+func (h *HouseholdHandler) AcceptInvitation(c *gin.Context) {
+	userID, ok := householdUserID(c, "AcceptInvitation")
+	if !ok {
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if !bindUserRequest(c, &req, "AcceptInvitation") {
+		return
+	}
+
+	member, err := h.Service.AcceptInvitation(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		logHouseholdError(c, err, "AcceptInvitation", userID, 0)
+		respondMembershipError(c, err, "Failed to accept invitation.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// householdUserID reads the authenticated user, responding with an error when it is missing
+func householdUserID(c *gin.Context, name string) (uint, bool) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error(name + ": UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return 0, false
+	}
+	return userID, true
+}
+
+// householdParams reads the authenticated user and the household ID path parameter
+func householdParams(c *gin.Context, name string) (uint, uint, bool) {
+	userID, ok := householdUserID(c, name)
+	if !ok {
+		return 0, 0, false
+	}
+	householdID, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid household ID.",
+		})
+		return 0, 0, false
+	}
+	return userID, householdID, true
+}
+
+// logHouseholdError logs a failed household service call
+func logHouseholdError(c *gin.Context, err error, name string, userID, householdID uint) {
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"error":       err.Error(),
+		"errorType":   appErrors.GetType(err),
+		"userID":      userID,
+		"householdID": householdID,
+	}).Error(name + ": Household service call failed.")
+}
+
+// respondHouseholdError writes the response for errors of the household access checks: an
+// unknown household, a role that does not allow the action, or no household to fall back to.
+// It reports whether err was one of them.
+func respondHouseholdError(c *gin.Context, err error) bool {
+	switch appErrors.GetType(err) {
+	case appErrors.TypeNotFound:
+		c.JSON(http.StatusNotFound, responses.ErrorResponse{
+			Error:   "Not Found",
+			Details: err.Error(),
+		})
+	case appErrors.TypeForbidden:
+		c.JSON(http.StatusForbidden, responses.ErrorResponse{
+			Error:   "Forbidden",
+			Details: err.Error(),
+		})
+	case appErrors.TypeValidation:
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: err.Error(),
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// respondMembershipError maps a household service error to the matching HTTP error response
+func respondMembershipError(c *gin.Context, err error, internalDetails string) {
+	if respondHouseholdError(c, err) {
+		return
+	}
+	switch appErrors.GetType(err) {
+	case appErrors.TypeAlreadyExists, appErrors.TypeConflict:
+		c.JSON(http.StatusConflict, responses.ErrorResponse{
+			Error:   "Conflict",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: internalDetails,
+		})
+	}
+}
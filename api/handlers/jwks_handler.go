@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public JWT verification keys
+type JWKSHandler struct {
+	Keys *auth.KeySet // Signing key set
+}
+
+// NewJWKSHandler creates a new instance of JWKSHandler
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{Keys: keys}
+}
+
+// GetJWKS handles retrieving the JSON Web Key Set
+// @Summary Get JSON Web Key Set
+// @Description Retrieve the public keys that verify access tokens, including retired keys still within their grace period. Tokens name their key in the kid header. Shared-secret (HS256) keys are never published.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKSet "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
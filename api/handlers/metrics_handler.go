@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"personal-finance-tracker-api/api/responses"
+	"personal-finance-tracker-api/internal/metrics"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler exposes the Prometheus metrics
+type MetricsHandler struct {
+	Token   string // Bearer token required to scrape the metrics; empty allows anyone, so is only fit for a private listener
+	handler http.Handler
+}
+
+// NewMetricsHandler creates a new instance of MetricsHandler
+func NewMetricsHandler(token string) *MetricsHandler {
+	return &MetricsHandler{Token: token, handler: metrics.Handler()}
+}
+
+// GetMetrics handles scraping the metrics
+// @Summary Get Prometheus metrics
+// @Description Retrieve HTTP request counts and latencies by route template and status, database query durations and connection pool statistics, and counters of created transactions, imports and failed logins, in the Prometheus text format. Served on the API port only when METRICS_TOKEN is set and METRICS_ADDR is not, and then the token must be sent as a bearer token.
+// @Tags health
+// @Produce plain
+// @Param Authorization header string true "Bearer token set in METRICS_TOKEN"
+// @Success 200 {string} string "Metrics in the Prometheus exposition format"
+// @Failure 401 {object} responses.ErrorResponse "Missing or invalid metrics token"
+// @Router /metrics [get]
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.Token != "" {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{Error: "Invalid metrics token"})
+			return
+		}
+	}
+	h.handler.ServeHTTP(c.Writer, c.Request)
+}
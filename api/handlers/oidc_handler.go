@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// oidcStateCookie binds an OIDC flow to the browser that started it. Without it, a flow started
+// by an attacker could be completed in a victim's browser, logging the victim in as the attacker.
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/users/oidc"
+)
+
+// OIDCAuthorizationResponse holds the provider URL that starts an OIDC flow
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+// OIDCLogin handles starting a single sign-on login
+// @Summary Start an OIDC login
+// @Description Redirect the browser to the OpenID Connect provider. After authenticating there the user returns to /users/oidc/callback, which must be called from the same browser: the flow is bound to it by the oidc_state cookie.
+// @Tags users
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} responses.ErrorResponse "OIDC login is not enabled"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/oidc/login [get]
+func (h *UserHandler) OIDCLogin(c *gin.Context) {
+	authorization, err := h.UserService.BeginOIDCLogin(c.Request.Context(), nil)
+	if err != nil {
+		respondOIDCError(c, err, "OIDCLogin", "Failed to start OIDC login.")
+		return
+	}
+	setOIDCStateCookie(c, authorization)
+	c.Redirect(http.StatusFound, authorization.URL)
+}
+
+// OIDCCallback handles the redirect back from the identity provider
+// @Summary Complete an OIDC login
+// @Description Redeem the authorization code returned by the OpenID Connect provider. The request must carry the oidc_state cookie set when the flow was started. A login returns access and refresh tokens, or a challenge for users with two-factor authentication; users are provisioned on their first login when enabled. A flow started from /users/identities/oidc links the identity instead and returns it; it must be completed with an access token of the user who started it.
+// @Tags users
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State of the login"
+// @Success 200 {object} LoginResponse "Authentication successful with access and refresh tokens, or the linked identity"
+// @Success 202 {object} TwoFactorChallengeResponse "Identity accepted, two-factor code required"
+// @Failure 400 {object} responses.ErrorResponse "Missing parameters or error reported by the provider"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (invalid state, cookie, code or ID token, no linked account, or linking as another user)"
+// @Failure 404 {object} responses.ErrorResponse "OIDC login is not enabled"
+// @Failure 409 {object} responses.ErrorResponse "Identity already linked to another user, or its email matches an account whose email is not verified"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/oidc/callback [get]
+func (h *UserHandler) OIDCCallback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":       providerError,
+			"description": c.Query("error_description"),
+		}).Warn("OIDCCallback: Identity provider returned an error.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Identity provider returned an error: " + providerError,
+		})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Query parameters 'code' and 'state' are required.",
+		})
+		return
+	}
+
+	// The flow is single-use whatever the outcome, so its cookie is no longer needed
+	browserState, _ := c.Cookie(oidcStateCookie)
+	clearOIDCStateCookie(c)
+	if subtle.ConstantTimeCompare([]byte(browserState), []byte(state)) != 1 {
+		logrus.WithContext(c.Request.Context()).Warn("OIDCCallback: State does not match the cookie of this browser.")
+		c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
+			Error:   "Unauthorized",
+			Details: "The OIDC login was not started in this browser; start again.",
+		})
+		return
+	}
+
+	// Only links need a session, and only a login session can link identities
+	var sessionUserID *uint
+	if claims, ok := middleware.GetAccessClaimsFromContext(c); ok {
+		sessionUserID = &claims.UserID
+	}
+
+	result, err := h.UserService.CompleteOIDCLogin(c.Request.Context(), state, code, sessionUserID)
+	if err != nil {
+		respondOIDCError(c, err, "OIDCCallback", "Failed to complete OIDC login.")
+		return
+	}
+
+	if result.Linked {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"userID":     result.User.ID,
+			"identityID": result.Identity.ID,
+		}).Info("OIDCCallback: Identity linked successfully.")
+		c.JSON(http.StatusOK, result.Identity)
+		return
+	}
+
+	h.completeLogin(c, &result.LoginResult, "OIDCCallback")
+}
+
+// LinkOIDCIdentity handles starting a flow that links a provider identity to the authenticated user
+// @Summary Link an OIDC identity
+// @Description Start an OpenID Connect flow that links the provider account to the authenticated user. Send the browser to the returned URL; the flow is bound to it by the oidc_state cookie. The callback must be called from that browser with an access token of the same user and returns the linked identity.
+// @Tags users
+// @Produce json
+// @Success 200 {object} OIDCAuthorizationResponse
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "OIDC login is not enabled"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/identities/oidc [post]
+func (h *UserHandler) LinkOIDCIdentity(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("LinkOIDCIdentity: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	authorization, err := h.UserService.BeginOIDCLogin(c.Request.Context(), &userID)
+	if err != nil {
+		respondOIDCError(c, err, "LinkOIDCIdentity", "Failed to start OIDC login.")
+		return
+	}
+	setOIDCStateCookie(c, authorization)
+	c.JSON(http.StatusOK, OIDCAuthorizationResponse{AuthorizationURL: authorization.URL})
+}
+
+// GetIdentities handles listing the external identities of the authenticated user
+// @Summary Get linked identities
+// @Description Retrieve the OpenID Connect identities linked to the authenticated user
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.UserIdentity
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/identities [get]
+func (h *UserHandler) GetIdentities(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetIdentities: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	identities, err := h.UserService.GetIdentities(c.Request.Context(), userID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetIdentities: Failed to retrieve identities via service.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve identities.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkIdentity handles removing an external identity from the authenticated user
+// @Summary Unlink an identity
+// @Description Remove a linked OpenID Connect identity. Users without a password must keep at least one identity.
+// @Tags users
+// @Param id path int true "Identity ID"
+// @Success 204 "Identity unlinked"
+// @Failure 400 {object} responses.ErrorResponse "Invalid ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 404 {object} responses.ErrorResponse "Identity not found"
+// @Failure 409 {object} responses.ErrorResponse "Last identity of a user without a password"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/identities/{id} [delete]
+func (h *UserHandler) UnlinkIdentity(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("UnlinkIdentity: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid identity ID.",
+		})
+		return
+	}
+
+	if err := h.UserService.UnlinkIdentity(c.Request.Context(), userID, id); err != nil {
+		respondOIDCError(c, err, "UnlinkIdentity", "Failed to unlink identity.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":     userID,
+		"identityID": id,
+	}).Info("UnlinkIdentity: Identity unlinked successfully.")
+	c.Status(http.StatusNoContent)
+}
+
+// setOIDCStateCookie stores the state of a started flow in the browser. SameSite=Lax lets the
+// cookie accompany the top-level redirect back from the provider but not cross-site requests.
+func setOIDCStateCookie(c *gin.Context, authorization *services.OIDCAuthorization) {
+	maxAge := int(time.Until(authorization.ExpiresAt).Seconds())
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, authorization.State, maxAge, oidcStateCookiePath, "", isHTTPS(c), true)
+}
+
+// clearOIDCStateCookie removes the state cookie from the browser
+func clearOIDCStateCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", isHTTPS(c), true)
+}
+
+// isHTTPS reports whether the client reached the server over HTTPS, directly or through a proxy
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
+
+// respondOIDCError maps errors of the OIDC flows to responses
+func respondOIDCError(c *gin.Context, err error, name, internalDetails string) {
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"error":     err.Error(),
+		"errorType": appErrors.GetType(err),
+	}).Warn(name + ": OIDC request failed.")
+
+	switch {
+	case appErrors.IsType(err, appErrors.TypeUnauthorized):
+		c.JSON(http.StatusUnauthorized, responses.ErrorResponse{Error: "Unauthorized", Details: err.Error()})
+	case appErrors.IsType(err, appErrors.TypeNotFound):
+		c.JSON(http.StatusNotFound, responses.ErrorResponse{Error: "Not Found", Details: err.Error()})
+	case appErrors.IsType(err, appErrors.TypeConflict), appErrors.IsType(err, appErrors.TypeAlreadyExists):
+		c.JSON(http.StatusConflict, responses.ErrorResponse{Error: "Conflict", Details: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{Error: "Internal Server Error", Details: internalDetails})
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ChangePasswordRequest represents the request body for changing the password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+}
+
+// ForgotPasswordRequest represents the request body for requesting a password reset
+type ForgotPasswordRequest struct {
+	Identifier string `json:"identifier" validate:"required,max=255"` // Username or email address
+}
+
+// ResetPasswordRequest represents the request body for completing a password reset
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// ChangePassword handles changing the password of the authenticated user
+// @Summary Change the password
+// @Description Change the password of the authenticated user. Every existing session, including the current one, is signed out, personal access tokens are revoked and a new token pair is returned.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} LoginResponse "Password changed; new access and refresh tokens"
+// @Failure 400 {object} responses.ErrorResponse "Invalid input, wrong current password or password policy violation"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 429 {object} responses.ErrorResponse "Too many wrong passwords; retry after the Retry-After header"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/password [put]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	claims, exists := middleware.GetAccessClaimsFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("ChangePassword: Access token claims not found in context.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Access token claims not found in context.",
+		})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if !bindUserRequest(c, &req, "ChangePassword") {
+		return
+	}
+
+	user, err := h.UserService.ChangePassword(c.Request.Context(), claims.UserID, req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    claims.UserID,
+		}).Warn("ChangePassword: Failed to change password.")
+
+		if appErrors.IsType(err, appErrors.TypeValidation) {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeRateLimited) {
+			respondRateLimited(c, err)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to change password.",
+		})
+		return
+	}
+
+	// The session cut-off already rejects the presented token; it is also put on the
+	// denylist so that it stays revoked independently of the user record
+	if err := h.TokenService.Logout(c.Request.Context(), claims, "", false); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": claims.UserID,
+		}).Warn("ChangePassword: Failed to revoke the current access token.")
+	}
+
+	h.issueTokens(c, user, "ChangePassword")
+}
+
+// ForgotPassword handles requesting a password reset link
+// @Summary Request a password reset
+// @Description Send a password reset link to the email address of the user with the given username or email address. The response is the same whether or not the user exists.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Username or email address"
+// @Success 202 "Reset link sent if the user exists and has an email address"
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/password/forgot [post]
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if !bindUserRequest(c, &req, "ForgotPassword") {
+		return
+	}
+
+	if err := h.UserService.RequestPasswordReset(c.Request.Context(), req.Identifier); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+		}).Error("ForgotPassword: Failed to request password reset.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to request password reset.",
+		})
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword handles setting a new password with a reset token
+// @Summary Reset the password
+// @Description Set a new password with the token from a password reset link. Every existing session is signed out, personal access tokens are revoked and any login lockout is lifted.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 204 "Password reset"
+// @Failure 400 {object} responses.ErrorResponse "Invalid input, invalid or expired token or password policy violation"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/password/reset [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if !bindUserRequest(c, &req, "ResetPassword") {
+		return
+	}
+
+	if err := h.UserService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+		}).Warn("ResetPassword: Failed to reset password.")
+
+		if appErrors.IsType(err, appErrors.TypeValidation) {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to reset password.",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UserProfileRequest represents the request body for updating the profile of the user.
+// All fields are replaced; omitting displayName or email removes them.
+type UserProfileRequest struct {
+	DisplayName     string `json:"displayName" validate:"max=100"`
+	Email           string `json:"email" validate:"omitempty,email,max=255"`
+	Locale          string `json:"locale" validate:"required,bcp47_language_tag,max=35"`
+	Timezone        string `json:"timezone" validate:"required,timezone,max=64"`
+	BaseCurrency    string `json:"baseCurrency" validate:"required,iso4217"`
+	FirstDayOfWeek  string `json:"firstDayOfWeek" validate:"required,oneof=monday tuesday wednesday thursday friday saturday sunday"`
+	FiscalYearStart string `json:"fiscalYearStart" validate:"required,len=5"` // Month and day as MM-DD
+	CurrentPassword string `json:"currentPassword"`                           // Required to change the email address when the user has a password
+}
+
+// GetProfile handles retrieving the profile of the user
+// @Summary Get the profile
+// @Description Retrieve the profile and preferences of the authenticated user. Dates without a time, such as the date ranges of transaction lists and category statistics, are interpreted in the user's time zone.
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.User
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me [get]
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, ok := accountUserID(c, "GetProfile")
+	if !ok {
+		return
+	}
+
+	user, err := h.UserService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetProfile: Failed to retrieve profile via service.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve profile.",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile handles updating the profile of the user
+// @Summary Update the profile
+// @Description Replace the display name, email address, locale, time zone, base currency, first day of the week and fiscal year start of the authenticated user. Changing the email address requires the current password.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body UserProfileRequest true "Profile and preferences"
+// @Success 200 {object} models.User
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or wrong password"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Email address already in use"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/me [put]
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := accountUserID(c, "UpdateProfile")
+	if !ok {
+		return
+	}
+
+	var req UserProfileRequest
+	if !bindUserRequest(c, &req, "UpdateProfile") {
+		return
+	}
+
+	user, err := h.UserService.UpdateProfile(c.Request.Context(), userID, services.UserProfile{
+		DisplayName:     req.DisplayName,
+		Email:           req.Email,
+		Locale:          req.Locale,
+		Timezone:        req.Timezone,
+		BaseCurrency:    req.BaseCurrency,
+		FirstDayOfWeek:  req.FirstDayOfWeek,
+		FiscalYearStart: req.FiscalYearStart,
+		CurrentPassword: req.CurrentPassword,
+	})
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Warn("UpdateProfile: Failed to update profile via service.")
+		switch appErrors.GetType(err) {
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeAlreadyExists:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to update profile.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("UpdateProfile: Profile updated successfully.")
+	c.JSON(http.StatusOK, user)
+}
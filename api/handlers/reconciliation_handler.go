@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+// ReconciliationHandler holds the service for business logic access
+type ReconciliationHandler struct {
+	Service services.ReconciliationService
+}
+
+// NewReconciliationHandler creates a new handler for reconciliations
+func NewReconciliationHandler(service services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{Service: service}
+}
+
+// StartReconciliationRequest represents the request body for starting a reconciliation
+type StartReconciliationRequest struct {
+	StatementEndDate string   `json:"statementEndDate" validate:"required"` // YYYY-MM-DD, a calendar date in the user's time zone
+	ClosingBalance   *float64 `json:"closingBalance" validate:"required"`
+}
+
+// StartReconciliation handles opening a new reconciliation session
+// @Summary Start a reconciliation
+// @Description Open a reconciliation session for a bank statement and compute the difference between the statement closing balance and the cleared transactions up to the end of the statement end date in the user's time zone
+// @Tags reconciliations
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param request body StartReconciliationRequest true "Statement details"
+// @Success 201 {object} models.Reconciliation
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (another reconciliation is already open)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /reconciliations [post]
+func (h *ReconciliationHandler) StartReconciliation(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("StartReconciliation: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var req StartReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": userID,
+		}).Warn("StartReconciliation: Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"userID":           userID,
+			}).Warn("StartReconciliation: Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	statementEndDate, err := time.Parse("2006-01-02", req.StatementEndDate)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"statementEndDate": req.StatementEndDate,
+			"error":            err,
+			"userID":           userID,
+		}).Warn("StartReconciliation: Invalid statementEndDate format.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid statementEndDate format. Expected YYYY-MM-DD.",
+		})
+		return
+	}
+
+	reconciliation, err := h.Service.StartReconciliation(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), statementEndDate, *req.ClosingBalance)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("StartReconciliation: Failed to start reconciliation via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeConflict) {
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to start reconciliation.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"reconciliationID": reconciliation.ID,
+		"difference":       reconciliation.Difference,
+		"userID":           userID,
+	}).Info("StartReconciliation: Reconciliation started successfully.")
+	c.JSON(http.StatusCreated, reconciliation)
+}
+
+// GetReconciliations handles listing reconciliation sessions
+// @Summary Get all reconciliations
+// @Description Retrieve reconciliation sessions for the authenticated user, newest statement first
+// @Tags reconciliations
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of reconciliations to retrieve" default(100)
+// @Param offset query int false "Number of reconciliations to skip" default(0)
+// @Param status query string false "Filter by status (open, completed)" enum(open,completed)
+// @Success 200 {array} models.Reconciliation
+// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /reconciliations [get]
+func (h *ReconciliationHandler) GetReconciliations(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetReconciliations: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	var status *models.ReconciliationStatus
+	if statusStr := c.Query("status"); statusStr != "" {
+		s := models.ReconciliationStatus(statusStr)
+		if s != models.ReconciliationOpen && s != models.ReconciliationCompleted {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid 'status' parameter. Must be 'open' or 'completed'.",
+			})
+			return
+		}
+		status = &s
+	}
+
+	reconciliations, err := h.Service.GetReconciliations(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), limit, offset, status)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetReconciliations: Failed to retrieve reconciliations via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve reconciliations.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, reconciliations)
+}
+
+// GetReconciliation handles retrieving a single reconciliation session
+// @Summary Get a reconciliation
+// @Description Retrieve a reconciliation session. Open sessions report the current cleared balance and difference.
+// @Tags reconciliations
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Reconciliation ID"
+// @Success 200 {object} models.Reconciliation
+// @Failure 400 {object} responses.ErrorResponse "Invalid reconciliation ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 404 {object} responses.ErrorResponse "Reconciliation not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /reconciliations/{id} [get]
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetReconciliation: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid reconciliation ID.",
+		})
+		return
+	}
+
+	reconciliation, err := h.Service.GetReconciliation(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":            err.Error(),
+			"errorType":        appErrors.GetType(err),
+			"reconciliationID": id,
+			"userID":           userID,
+		}).Error("GetReconciliation: Failed to retrieve reconciliation via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve reconciliation.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, reconciliation)
+}
+
+// CompleteReconciliation handles completing a reconciliation session
+// @Summary Complete a reconciliation
+// @Description Mark all cleared transactions up to the statement end date as reconciled, locking them against edits and deletes. Requires a zero difference.
+// @Tags reconciliations
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Reconciliation ID"
+// @Success 200 {object} models.Reconciliation
+// @Failure 400 {object} responses.ErrorResponse "Difference is not zero"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Reconciliation not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (reconciliation already completed)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /reconciliations/{id}/complete [post]
+func (h *ReconciliationHandler) CompleteReconciliation(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("CompleteReconciliation: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid reconciliation ID.",
+		})
+		return
+	}
+
+	reconciliation, err := h.Service.CompleteReconciliation(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":            err.Error(),
+			"errorType":        appErrors.GetType(err),
+			"reconciliationID": id,
+			"userID":           userID,
+		}).Error("CompleteReconciliation: Failed to complete reconciliation via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to complete reconciliation.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"reconciliationID": reconciliation.ID,
+		"userID":           userID,
+	}).Info("CompleteReconciliation: Reconciliation completed successfully.")
+	c.JSON(http.StatusOK, reconciliation)
+}
+
+// CancelReconciliation handles discarding an open reconciliation session
+// @Summary Cancel a reconciliation
+// @Description Discard an open reconciliation session. Completed reconciliations cannot be cancelled.
+// @Tags reconciliations
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Reconciliation ID"
+// @Success 204 "Reconciliation cancelled"
+// @Failure 400 {object} responses.ErrorResponse "Invalid reconciliation ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Reconciliation not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (reconciliation already completed)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /reconciliations/{id} [delete]
+func (h *ReconciliationHandler) CancelReconciliation(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("CancelReconciliation: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid reconciliation ID.",
+		})
+		return
+	}
+
+	if err := h.Service.CancelReconciliation(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":            err.Error(),
+			"errorType":        appErrors.GetType(err),
+			"reconciliationID": id,
+			"userID":           userID,
+		}).Error("CancelReconciliation: Failed to cancel reconciliation via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to cancel reconciliation.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"reconciliationID": id,
+		"userID":           userID,
+	}).Info("CancelReconciliation: Reconciliation cancelled successfully.")
+	c.Status(http.StatusNoContent)
+}
@@ -1,356 +1,762 @@
-package handlers
-
-import (
-	"encoding/csv"
-	"fmt"
-	"net/http"
-	"personal-finance-tracker-api/api/middleware"
-	"personal-finance-tracker-api/api/responses"
-	appErrors "personal-finance-tracker-api/internal/errors"
-	"personal-finance-tracker-api/internal/models"
-	"personal-finance-tracker-api/internal/services"
-	"strconv"
-	"time"
-
-	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
-	"github.com/sirupsen/logrus"
-)
-
-var validate *validator.Validate
-
-func init() {
-	validate = validator.New()
-}
-
-// TransactionHandler holds the service for business logic access
-type TransactionHandler struct {
-	Service services.TransactionService
-}
-
-// NewTransactionHandler creates a new handler for transactions
-func NewTransactionHandler(service services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{Service: service}
-}
-
-// CreateTransaction handles the creation of a new transaction
-// @Summary Create a new transaction
-// @Description Add a new income or expense transaction
-// @Tags transactions
-// @Accept json
-// @Produce json
-// @Param transaction body models.Transaction true "Transaction object"
-// @Success 201 {object} models.Transaction
-// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
-// @Failure 409 {object} responses.ErrorResponse "Conflict error (e.g., transaction already exists)"
-// @Failure 500 {object} responses.ErrorResponse "Internal server error"
-// @Router /transactions [post]
-func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
-	userID, exists := middleware.GetUserIDFromContext(c)
-	if !exists {
-		logrus.Error("CreateTransaction: UserID not found in context, authentication middleware error.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Authenticated user ID not found.",
-		})
-		return
-	}
-
-	var transaction models.Transaction
-	if err := c.ShouldBindJSON(&transaction); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err.Error(),
-		}).Warn("CreateTransaction: Invalid JSON format or data type mismatch.")
-		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-			Error:   "Bad Request",
-			Details: "Invalid JSON format or data type mismatch.",
-		})
-		return
-	}
-
-	// Set the UserID from the authenticated context
-	transaction.UserID = userID
-
-	if err := validate.Struct(transaction); err != nil {
-		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			var fields []responses.ValidationFieldError
-			for _, fieldErr := range validationErrors {
-				fields = append(fields, responses.ValidationFieldError{
-					Field:   fieldErr.Field(),
-					Tag:     fieldErr.Tag(),
-					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
-				})
-			}
-			logrus.WithFields(logrus.Fields{
-				"validationErrors": fields,
-				"transaction":      transaction,
-				"userID":           userID,
-			}).Warn("CreateTransaction: Input validation error.")
-			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
-				Error:  "Validation Error",
-				Fields: fields,
-			})
-			return
-		}
-		logrus.WithFields(logrus.Fields{
-			"error":       err.Error(),
-			"transaction": transaction,
-			"userID":      userID,
-		}).Warn("CreateTransaction: Unknown input validation error.")
-		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-			Error:   "Bad Request",
-			Details: "Validation failed: " + err.Error(),
-		})
-		return
-	}
-
-	createdTransaction, err := h.Service.CreateTransaction(c.Request.Context(), &transaction)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":       err.Error(),
-			"transaction": transaction,
-			"errorType":   appErrors.GetType(err),
-			"userID":      userID,
-		}).Error("CreateTransaction: Failed to create transaction via service.")
-
-		if appErrors.IsType(err, appErrors.TypeConflict) {
-			c.JSON(http.StatusConflict, responses.ErrorResponse{
-				Error:   "Conflict",
-				Details: err.Error(),
-			})
-			return
-		}
-		if appErrors.IsType(err, appErrors.TypeValidation) {
-			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-				Error:   "Bad Request",
-				Details: err.Error(),
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to create transaction.",
-		})
-		return
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"transactionID": createdTransaction.ID,
-		"amount":        createdTransaction.Amount,
-		"type":          createdTransaction.Type,
-		"userID":        userID,
-	}).Info("CreateTransaction: Transaction created successfully.")
-	c.JSON(http.StatusCreated, createdTransaction)
-}
-
-// GetTransactions handles listing all transactions
-// @Summary Get all transactions
-// @Description Retrieve a list of all transactions, ordered by date
-// @Tags transactions
-// @Produce json
-// @Param limit query int false "Maximum number of transaction to retrieve" default(100)
-// @Param offset query int false "Number of transactions to skip" default(0)
-// @Param startDate query string false "Filter transactions from this date (YYYY-MM-DD)" format(date)
-// @Param endDate query string false "Filter transactions up to this date (YYYY-MM-DD)" format(date)
-// @Param type query string false "Filter by transaction type (income, expense)" enum(income,expense)
-// @Param description query string false "Search transactions by description (case-insensitive)"
-// @Success 200 {array} models.Transaction
-// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
-// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
-// @Failure 500 {object} responses.ErrorResponse "Internal server error"
-// @Router /transactions [get]
-func (h *TransactionHandler) GetTransactions(c *gin.Context) {
-	userID, exists := middleware.GetUserIDFromContext(c)
-	if !exists {
-		logrus.Error("GetTransactions: UserID not found in context, authentication middleware error.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Authenticated user ID not found.",
-		})
-		return
-	}
-
-	limitStr := c.DefaultQuery("limit", "100")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		logrus.WithFields(logrus.Fields{
-			"limitStr": limitStr,
-			"error":    err,
-			"userID":   userID,
-		}).Warn("GetTransactions: Invalid limit parameter, defaulting to 100.")
-		limit = 100
-	}
-
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		logrus.WithFields(logrus.Fields{
-			"offsetStr": offsetStr,
-			"error":     err,
-			"userID":    userID,
-		}).Warn("GetTransactions: Invalid offset parameter, defaulting to 0.")
-		offset = 0
-	}
-
-	// Filtering parameters
-	var startDate *time.Time
-	if sdStr := c.Query("startDate"); sdStr != "" {
-		parsedDate, err := time.Parse("2006-01-02", sdStr)
-		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"startDateStr": sdStr,
-				"error":        err,
-				"userID":       userID,
-			}).Warn("GetTransactions: Invalid startDate parameter format.")
-			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-				Error:   "Bad Request",
-				Details: "Invalid startDate format. Expected YYYY-MM-DD.",
-			})
-			return
-		}
-		startDate = &parsedDate
-	}
-
-	var endDate *time.Time
-	if edStr := c.Query("endDate"); edStr != "" {
-		parsedDate, err := time.Parse("2006-01-02", edStr)
-		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"endDateStr": edStr,
-				"error":      err,
-				"userID":     userID,
-			}).Warn("GetTransactions: Invalid endDate parameter format.")
-			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-				Error:   "Bad Request",
-				Details: "Invalid endDate format. Expected YYYY-MM-DD.",
-			})
-			return
-		}
-		endDate = &parsedDate
-	}
-
-	var transactionType *models.TransactionType
-	if typeStr := c.Query("type"); typeStr != "" {
-		tt := models.TransactionType(typeStr)
-		if tt != models.Income && tt != models.Expense {
-			logrus.WithFields(logrus.Fields{
-				"typeStr": typeStr,
-				"userID":  userID,
-			}).Warn("GetTransactions: Invalid transaction type parameter.")
-			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
-				Error:   "Bad Request",
-				Details: "Invalid 'type' parameter. Must be 'income' or 'expense'.",
-			})
-			return
-		}
-		transactionType = &tt
-	}
-
-	var description *string
-	if descStr := c.Query("description"); descStr != "" {
-		description = &descStr
-	}
-
-	transactions, err := h.Service.GetTransactions(c.Request.Context(), userID, limit, offset, startDate, endDate, transactionType, description)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":     err.Error(),
-			"errorType": appErrors.GetType(err),
-			"userID":    userID,
-		}).Error("GetTransactions: Failed to retrieve transactions via service.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to retrieve transactions.",
-		})
-		return
-	}
-
-	logrus.WithFields(logrus.Fields{
-		"count":  len(transactions),
-		"limit":  limit,
-		"offset": offset,
-		"userID": userID,
-	}).Info("GetTransactions: Transactions retrieved successfully with pagination and user filter.")
-	c.JSON(http.StatusOK, transactions)
-}
-
-// ExportTransactionsCSV handles exporting transactions to a CSV file
-// @Summary Export transactions to CSV
-// @Description Download a CSV file containing all transaction data
-// @Tags transactions
-// @Produce text/csv
-// @Success 200 {file} file
-// @Failure 500 {object} responses.ErrorResponse "Internal server error"
-// @Router /transactions/export/csv [get]
-func (h *TransactionHandler) ExportTransactionsCSV(c *gin.Context) {
-	userID, exists := middleware.GetUserIDFromContext(c)
-	if !exists {
-		logrus.Error("ExportTransactionsCSV: UserID not found in context, authentication middleware error.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Authenticated user ID not found.",
-		})
-		return
-	}
-
-	transactions, err := h.Service.ExportTransactionsCSV(c.Request.Context(), userID)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":     err.Error(),
-			"errorType": appErrors.GetType(err),
-			"userID":    userID,
-		}).Error("ExportTransactionsCSV: Failed to retrieve transactions for CSV export via service.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to retrieve transactions.",
-		})
-		return
-	}
-
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Disposition", "attachment; filename=transactions.csv")
-	c.Header("Content-Type", "text/csv")
-
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
-
-	header := []string{"ID", "Description", "Amount", "Type", "Date", "Category"}
-	if err := writer.Write(header); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error":  err.Error(),
-			"userID": userID,
-		}).Error("ExportTransactionsCSV: Failed to write CSV header.")
-		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-			Error:   "Internal Server Error",
-			Details: "Failed to write CSV header.",
-		})
-		return
-	}
-
-	for _, t := range transactions {
-		record := []string{
-			fmt.Sprintf("%d", t.ID),
-			t.Description,
-			fmt.Sprintf("%.2f", t.Amount),
-			string(t.Type),
-			t.Date.Format("2006-01-02"),
-			t.Category.Name,
-		}
-		if err := writer.Write(record); err != nil {
-			logrus.WithFields(logrus.Fields{
-				"error":         err.Error(),
-				"transactionID": t.ID,
-				"userID":        userID,
-			}).Error("ExportTransactionsCSV: Failed to write CSV record. Stopping export.")
-			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
-				Error:   "Internal Server Error",
-				Details: "Failed to write CSV record during export.",
-			})
-			return
-		}
-	}
-	logrus.WithFields(logrus.Fields{
-		"userID": userID,
-	}).Info("ExportTransactionsCSV: Transactions exported successfully.")
-}
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+var validate *validator.Validate
+
+func init() {
+	validate = validator.New()
+}
+
+// TransactionHandler holds the service for business logic access
+type TransactionHandler struct {
+	Service services.TransactionService
+}
+
+// NewTransactionHandler creates a new handler for transactions
+func NewTransactionHandler(service services.TransactionService) *TransactionHandler {
+	return &TransactionHandler{Service: service}
+}
+
+// CreateTransaction handles the creation of a new transaction
+// @Summary Create a new transaction
+// @Description Add a new income or expense transaction
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param transaction body models.Transaction true "Transaction object"
+// @Success 201 {object} models.Transaction
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict error (e.g., transaction already exists)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions [post]
+func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("CreateTransaction: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var transaction models.Transaction
+	if err := c.ShouldBindJSON(&transaction); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("CreateTransaction: Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return
+	}
+
+	if err := validate.Struct(transaction); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"transaction":      transaction,
+				"userID":           userID,
+			}).Warn("CreateTransaction: Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return
+		}
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":       err.Error(),
+			"transaction": transaction,
+			"userID":      userID,
+		}).Warn("CreateTransaction: Unknown input validation error.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	createdTransaction, err := h.Service.CreateTransaction(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), &transaction)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":       err.Error(),
+			"transaction": transaction,
+			"errorType":   appErrors.GetType(err),
+			"userID":      userID,
+		}).Error("CreateTransaction: Failed to create transaction via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeConflict) {
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeValidation) {
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to create transaction.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"transactionID": createdTransaction.ID,
+		"amount":        createdTransaction.Amount,
+		"type":          createdTransaction.Type,
+		"userID":        userID,
+	}).Info("CreateTransaction: Transaction created successfully.")
+	c.JSON(http.StatusCreated, createdTransaction)
+}
+
+// GetTransactions handles listing all transactions
+// @Summary Get all transactions
+// @Description Retrieve a list of all transactions, ordered by date
+// @Tags transactions
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of transaction to retrieve" default(100)
+// @Param offset query int false "Number of transactions to skip" default(0)
+// @Param startDate query string false "Filter transactions from this date (YYYY-MM-DD) in the user's time zone, inclusive" format(date)
+// @Param endDate query string false "Filter transactions up to this date (YYYY-MM-DD) in the user's time zone, inclusive" format(date)
+// @Param type query string false "Filter by transaction type (income, expense)" enum(income,expense)
+// @Param description query string false "Search transactions by description (case-insensitive)"
+// @Success 200 {array} models.Transaction
+// @Failure 400 {object} responses.ErrorResponse "Invalid query parameters"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions [get]
+func (h *TransactionHandler) GetTransactions(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetTransactions: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "100")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"limitStr": limitStr,
+			"error":    err,
+			"userID":   userID,
+		}).Warn("GetTransactions: Invalid limit parameter, defaulting to 100.")
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"offsetStr": offsetStr,
+			"error":     err,
+			"userID":    userID,
+		}).Warn("GetTransactions: Invalid offset parameter, defaulting to 0.")
+		offset = 0
+	}
+
+	// Filtering parameters
+	var startDate *time.Time
+	if sdStr := c.Query("startDate"); sdStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", sdStr)
+		if err != nil {
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"startDateStr": sdStr,
+				"error":        err,
+				"userID":       userID,
+			}).Warn("GetTransactions: Invalid startDate parameter format.")
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid startDate format. Expected YYYY-MM-DD.",
+			})
+			return
+		}
+		startDate = &parsedDate
+	}
+
+	var endDate *time.Time
+	if edStr := c.Query("endDate"); edStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", edStr)
+		if err != nil {
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"endDateStr": edStr,
+				"error":      err,
+				"userID":     userID,
+			}).Warn("GetTransactions: Invalid endDate parameter format.")
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid endDate format. Expected YYYY-MM-DD.",
+			})
+			return
+		}
+		endDate = &parsedDate
+	}
+
+	var transactionType *models.TransactionType
+	if typeStr := c.Query("type"); typeStr != "" {
+		tt := models.TransactionType(typeStr)
+		if tt != models.Income && tt != models.Expense {
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"typeStr": typeStr,
+				"userID":  userID,
+			}).Warn("GetTransactions: Invalid transaction type parameter.")
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: "Invalid 'type' parameter. Must be 'income' or 'expense'.",
+			})
+			return
+		}
+		transactionType = &tt
+	}
+
+	var description *string
+	if descStr := c.Query("description"); descStr != "" {
+		description = &descStr
+	}
+
+	transactions, err := h.Service.GetTransactions(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), limit, offset, startDate, endDate, transactionType, description)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetTransactions: Failed to retrieve transactions via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve transactions.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"count":  len(transactions),
+		"limit":  limit,
+		"offset": offset,
+		"userID": userID,
+	}).Info("GetTransactions: Transactions retrieved successfully with pagination and user filter.")
+	c.JSON(http.StatusOK, transactions)
+}
+
+// ExportTransactionsCSV handles exporting transactions to a CSV file
+// @Summary Export transactions to CSV
+// @Description Download a CSV file containing all transaction data
+// @Tags transactions
+// @Produce text/csv
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Success 200 {file} file
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions/export/csv [get]
+func (h *TransactionHandler) ExportTransactionsCSV(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("ExportTransactionsCSV: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	transactions, err := h.Service.ExportTransactionsCSV(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c))
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("ExportTransactionsCSV: Failed to retrieve transactions for CSV export via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve transactions.",
+		})
+		return
+	}
+
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", "attachment; filename=transactions.csv")
+	c.Header("Content-Type", "text/csv")
+
+	writer := csv.NewWriter(c.Writer)
+	defer writer.Flush()
+
+	header := []string{"ID", "Description", "Amount", "Type", "Date", "Category", "Status"}
+	if err := writer.Write(header); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": userID,
+		}).Error("ExportTransactionsCSV: Failed to write CSV header.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to write CSV header.",
+		})
+		return
+	}
+
+	for _, t := range transactions {
+		record := []string{
+			fmt.Sprintf("%d", t.ID),
+			t.Description,
+			fmt.Sprintf("%.2f", t.Amount),
+			string(t.Type),
+			t.Date.Format("2006-01-02"),
+			t.Category.Name,
+			string(t.Status),
+		}
+		if err := writer.Write(record); err != nil {
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"error":         err.Error(),
+				"transactionID": t.ID,
+				"userID":        userID,
+			}).Error("ExportTransactionsCSV: Failed to write CSV record. Stopping export.")
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to write CSV record during export.",
+			})
+			return
+		}
+	}
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("ExportTransactionsCSV: Transactions exported successfully.")
+}
+
+// UpdateTransactionStatusRequest represents the request body for changing a transaction's status
+type UpdateTransactionStatusRequest struct {
+	Status models.TransactionStatus `json:"status" validate:"required,oneof=pending cleared"`
+}
+
+// GetTransaction handles retrieving a single transaction
+// @Summary Get a transaction
+// @Description Retrieve a single transaction owned by the authenticated user
+// @Tags transactions
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} responses.ErrorResponse "Invalid transaction ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 404 {object} responses.ErrorResponse "Transaction not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions/{id} [get]
+func (h *TransactionHandler) GetTransaction(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetTransaction: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid transaction ID.",
+		})
+		return
+	}
+
+	transaction, err := h.Service.GetTransaction(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":         err.Error(),
+			"errorType":     appErrors.GetType(err),
+			"transactionID": id,
+			"userID":        userID,
+		}).Error("GetTransaction: Failed to retrieve transaction via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve transaction.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// UpdateTransaction handles updating an existing transaction
+// @Summary Update a transaction
+// @Description Replace the details of a transaction. Reconciled transactions are locked and cannot be edited.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Param transaction body models.Transaction true "Transaction object"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Transaction not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (transaction is reconciled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions/{id} [put]
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("UpdateTransaction: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid transaction ID.",
+		})
+		return
+	}
+
+	var transaction models.Transaction
+	if err := c.ShouldBindJSON(&transaction); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("UpdateTransaction: Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return
+	}
+
+	transaction.UserID = userID
+
+	if err := validate.Struct(transaction); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"transaction":      transaction,
+				"userID":           userID,
+			}).Warn("UpdateTransaction: Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return
+		}
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":       err.Error(),
+			"transaction": transaction,
+			"userID":      userID,
+		}).Warn("UpdateTransaction: Unknown input validation error.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	updatedTransaction, err := h.Service.UpdateTransaction(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, &transaction)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":         err.Error(),
+			"errorType":     appErrors.GetType(err),
+			"transactionID": id,
+			"userID":        userID,
+		}).Error("UpdateTransaction: Failed to update transaction via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to update transaction.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"transactionID": updatedTransaction.ID,
+		"userID":        userID,
+	}).Info("UpdateTransaction: Transaction updated successfully.")
+	c.JSON(http.StatusOK, updatedTransaction)
+}
+
+// UpdateTransactionStatus handles marking a transaction as pending or cleared
+// @Summary Update a transaction's status
+// @Description Mark a transaction as pending or cleared. Transactions become reconciled only by completing a reconciliation.
+// @Tags transactions
+// @Accept json
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Param request body UpdateTransactionStatusRequest true "New status"
+// @Success 200 {object} models.Transaction
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Transaction not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (transaction is reconciled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions/{id}/status [patch]
+func (h *TransactionHandler) UpdateTransactionStatus(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("UpdateTransactionStatus: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid transaction ID.",
+		})
+		return
+	}
+
+	var req UpdateTransactionStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn("UpdateTransactionStatus: Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+				"userID":           userID,
+			}).Warn("UpdateTransactionStatus: Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return
+	}
+
+	updatedTransaction, err := h.Service.UpdateTransactionStatus(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id, req.Status)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":         err.Error(),
+			"errorType":     appErrors.GetType(err),
+			"transactionID": id,
+			"status":        req.Status,
+			"userID":        userID,
+		}).Error("UpdateTransactionStatus: Failed to update transaction status via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to update transaction status.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"transactionID": updatedTransaction.ID,
+		"status":        updatedTransaction.Status,
+		"userID":        userID,
+	}).Info("UpdateTransactionStatus: Transaction status updated successfully.")
+	c.JSON(http.StatusOK, updatedTransaction)
+}
+
+// DeleteTransaction handles soft deleting a transaction
+// @Summary Delete a transaction
+// @Description Soft delete a transaction. Reconciled transactions are locked and cannot be deleted.
+// @Tags transactions
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Success 204 "Transaction deleted"
+// @Failure 400 {object} responses.ErrorResponse "Invalid transaction ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Transaction not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (transaction is reconciled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /transactions/{id} [delete]
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("DeleteTransaction: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid transaction ID.",
+		})
+		return
+	}
+
+	if err := h.Service.DeleteTransaction(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":         err.Error(),
+			"errorType":     appErrors.GetType(err),
+			"transactionID": id,
+			"userID":        userID,
+		}).Error("DeleteTransaction: Failed to delete transaction via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to delete transaction.",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"transactionID": id,
+		"userID":        userID,
+	}).Info("DeleteTransaction: Transaction deleted successfully.")
+	c.Status(http.StatusNoContent)
+}
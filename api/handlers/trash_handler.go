@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TrashHandler holds the service for business logic access
+type TrashHandler struct {
+	Service services.TrashService
+}
+
+// NewTrashHandler creates a new handler for soft-deleted records
+func NewTrashHandler(service services.TrashService) *TrashHandler {
+	return &TrashHandler{Service: service}
+}
+
+// GetDeletedTransactions handles listing soft-deleted transactions
+// @Summary List deleted transactions
+// @Description Retrieve the authenticated user's soft-deleted transactions, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of transactions to retrieve" default(100)
+// @Param offset query int false "Number of transactions to skip" default(0)
+// @Success 200 {array} models.Transaction
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/transactions [get]
+func (h *TrashHandler) GetDeletedTransactions(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetDeletedTransactions: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	transactions, err := h.Service.GetDeletedTransactions(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), limit, offset)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetDeletedTransactions: Failed to retrieve deleted transactions via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve deleted transactions.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// RestoreTransaction handles restoring a soft-deleted transaction
+// @Summary Restore a deleted transaction
+// @Description Restore a soft-deleted transaction. Its category must not be deleted.
+// @Tags trash
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Success 204 "Transaction restored"
+// @Failure 400 {object} responses.ErrorResponse "Invalid transaction ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Deleted transaction not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (category is deleted)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/transactions/{id}/restore [post]
+func (h *TrashHandler) RestoreTransaction(c *gin.Context) {
+	h.handleTrashAction(c, "RestoreTransaction", "transaction", "restore", h.Service.RestoreTransaction)
+}
+
+// PurgeTransaction handles permanently deleting a soft-deleted transaction
+// @Summary Permanently delete a transaction
+// @Description Permanently delete a transaction that is already in the trash
+// @Tags trash
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Transaction ID"
+// @Success 204 "Transaction purged"
+// @Failure 400 {object} responses.ErrorResponse "Invalid transaction ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Deleted transaction not found"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/transactions/{id} [delete]
+func (h *TrashHandler) PurgeTransaction(c *gin.Context) {
+	h.handleTrashAction(c, "PurgeTransaction", "transaction", "purge", h.Service.PurgeTransaction)
+}
+
+// GetDeletedCategories handles listing soft-deleted categories
+// @Summary List deleted categories
+// @Description Retrieve the authenticated user's soft-deleted categories, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param limit query int false "Maximum number of categories to retrieve" default(100)
+// @Param offset query int false "Number of categories to skip" default(0)
+// @Success 200 {array} models.Category
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/categories [get]
+func (h *TrashHandler) GetDeletedCategories(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetDeletedCategories: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	categories, err := h.Service.GetDeletedCategories(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), limit, offset)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("GetDeletedCategories: Failed to retrieve deleted categories via service.")
+
+		if respondHouseholdError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to retrieve deleted categories.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// RestoreCategory handles restoring a soft-deleted category
+// @Summary Restore a deleted category
+// @Description Restore a soft-deleted category. Its parent category must not be deleted.
+// @Tags trash
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Success 204 "Category restored"
+// @Failure 400 {object} responses.ErrorResponse "Invalid category ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Deleted category not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (parent category is deleted)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/categories/{id}/restore [post]
+func (h *TrashHandler) RestoreCategory(c *gin.Context) {
+	h.handleTrashAction(c, "RestoreCategory", "category", "restore", h.Service.RestoreCategory)
+}
+
+// PurgeCategory handles permanently deleting a soft-deleted category
+// @Summary Permanently delete a category
+// @Description Permanently delete a category that is already in the trash. Child categories are detached. Categories still referenced by transactions cannot be purged.
+// @Tags trash
+// @Param X-Household-ID header int false "Household to work on; defaults to the user's default household"
+// @Param id path int true "Category ID"
+// @Success 204 "Category purged"
+// @Failure 400 {object} responses.ErrorResponse "Invalid category ID"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (household role does not allow changes)"
+// @Failure 404 {object} responses.ErrorResponse "Deleted category not found"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (category still referenced by transactions)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /trash/categories/{id} [delete]
+func (h *TrashHandler) PurgeCategory(c *gin.Context) {
+	h.handleTrashAction(c, "PurgeCategory", "category", "purge", h.Service.PurgeCategory)
+}
+
+// handleTrashAction runs a restore or purge action for the record identified by the "id" path parameter
+func (h *TrashHandler) handleTrashAction(c *gin.Context, name, entity, verb string, action func(ctx context.Context, userID, householdID uint, id uint) error) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error(name + ": UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid " + entity + " ID.",
+		})
+		return
+	}
+
+	if err := action(c.Request.Context(), userID, middleware.GetHouseholdIDFromContext(c), id); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"id":        id,
+			"userID":    userID,
+		}).Error(name + ": Failed to " + verb + " " + entity + " via service.")
+
+		switch appErrors.GetType(err) {
+		case appErrors.TypeNotFound:
+			c.JSON(http.StatusNotFound, responses.ErrorResponse{
+				Error:   "Not Found",
+				Details: err.Error(),
+			})
+		case appErrors.TypeConflict:
+			c.JSON(http.StatusConflict, responses.ErrorResponse{
+				Error:   "Conflict",
+				Details: err.Error(),
+			})
+		case appErrors.TypeValidation:
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Bad Request",
+				Details: err.Error(),
+			})
+		case appErrors.TypeForbidden:
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+				Error:   "Internal Server Error",
+				Details: "Failed to " + verb + " " + entity + ".",
+			})
+		}
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"id":     id,
+		"userID": userID,
+	}).Info(name + ": Completed successfully.")
+	c.Status(http.StatusNoContent)
+}
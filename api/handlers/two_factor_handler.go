@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/api/responses"
+	appErrors "personal-finance-tracker-api/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CompleteTwoFactorLoginRequest represents the request body for the second login step
+type CompleteTwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	Code           string `json:"code" validate:"required"` // TOTP code or recovery code
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// RecoveryCodesResponse represents a newly issued set of recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"` // Shown only once; each code can be used once
+}
+
+// CompleteTwoFactorLogin handles the second step of a two-factor login
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from /users/login and a TOTP or recovery code for access and refresh tokens
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body CompleteTwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} LoginResponse "Authentication successful with access and refresh tokens"
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (invalid code or expired challenge)"
+// @Failure 429 {object} responses.ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/login/2fa [post]
+func (h *UserHandler) CompleteTwoFactorLogin(c *gin.Context) {
+	var req CompleteTwoFactorLoginRequest
+	if !bindUserRequest(c, &req, "CompleteTwoFactorLogin") {
+		return
+	}
+
+	user, err := h.UserService.CompleteLoginChallenge(c.Request.Context(), req.ChallengeToken, req.Code, c.ClientIP())
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+		}).Warn("CompleteTwoFactorLogin: Failed to complete login challenge.")
+
+		if appErrors.IsType(err, appErrors.TypeUnauthorized) {
+			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
+				Error:   "Unauthorized",
+				Details: err.Error(),
+			})
+			return
+		}
+		if appErrors.IsType(err, appErrors.TypeRateLimited) {
+			respondRateLimited(c, err)
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to log in.",
+		})
+		return
+	}
+
+	h.issueTokens(c, user, "CompleteTwoFactorLogin")
+}
+
+// GetTwoFactorStatus handles retrieving the two-factor authentication state
+// @Summary Get two-factor status
+// @Description Report whether two-factor authentication is enabled and how many recovery codes remain
+// @Tags users
+// @Produce json
+// @Success 200 {object} services.TwoFactorStatus
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/2fa [get]
+func (h *UserHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("GetTwoFactorStatus: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	status, err := h.UserService.GetTwoFactorStatus(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err, "GetTwoFactorStatus", userID, "Failed to retrieve two-factor status.")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// BeginTOTPEnrollment handles starting a TOTP enrolment
+// @Summary Start two-factor enrolment
+// @Description Generate a TOTP secret and its otpauth:// provisioning URI for an authenticator app. Two-factor authentication is enabled once a code is confirmed at /users/2fa/confirm.
+// @Tags users
+// @Produce json
+// @Success 200 {object} services.TOTPEnrollment
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (two-factor authentication already enabled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/2fa/enroll [post]
+func (h *UserHandler) BeginTOTPEnrollment(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("BeginTOTPEnrollment: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	enrollment, err := h.UserService.BeginTOTPEnrollment(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err, "BeginTOTPEnrollment", userID, "Failed to start two-factor enrolment.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("BeginTOTPEnrollment: Two-factor enrolment started.")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTPEnrollment handles enabling two-factor authentication
+// @Summary Confirm two-factor enrolment
+// @Description Enable two-factor authentication with a code from the authenticator app and receive one-time recovery codes
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} responses.ErrorResponse "Invalid code or no enrolment in progress"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (two-factor authentication already enabled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/2fa/confirm [post]
+func (h *UserHandler) ConfirmTOTPEnrollment(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("ConfirmTOTPEnrollment: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindUserRequest(c, &req, "ConfirmTOTPEnrollment") {
+		return
+	}
+
+	codes, err := h.UserService.ConfirmTOTPEnrollment(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "ConfirmTOTPEnrollment", userID, "Failed to enable two-factor authentication.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("ConfirmTOTPEnrollment: Two-factor authentication enabled.")
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP handles turning off two-factor authentication
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication, confirmed with a TOTP or recovery code. Remaining recovery codes are deleted.
+// @Tags users
+// @Accept json
+// @Param request body TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 204 "Two-factor authentication disabled"
+// @Failure 400 {object} responses.ErrorResponse "Invalid code"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (two-factor authentication not enabled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/2fa/disable [post]
+func (h *UserHandler) DisableTOTP(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("DisableTOTP: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindUserRequest(c, &req, "DisableTOTP") {
+		return
+	}
+
+	if err := h.UserService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		respondTwoFactorError(c, err, "DisableTOTP", userID, "Failed to disable two-factor authentication.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("DisableTOTP: Two-factor authentication disabled.")
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes handles replacing the recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes with a new set, confirmed with a TOTP or recovery code
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} responses.ErrorResponse "Invalid code"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (two-factor authentication not enabled)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/2fa/recovery-codes [post]
+func (h *UserHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("RegenerateRecoveryCodes: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if !bindUserRequest(c, &req, "RegenerateRecoveryCodes") {
+		return
+	}
+
+	codes, err := h.UserService.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err, "RegenerateRecoveryCodes", userID, "Failed to regenerate recovery codes.")
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("RegenerateRecoveryCodes: Recovery codes regenerated.")
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// respondTwoFactorError logs a two-factor management error and writes the matching HTTP response
+func respondTwoFactorError(c *gin.Context, err error, name string, userID uint, internalDetails string) {
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"error":     err.Error(),
+		"errorType": appErrors.GetType(err),
+		"userID":    userID,
+	}).Error(name + ": Two-factor operation failed.")
+
+	switch appErrors.GetType(err) {
+	case appErrors.TypeValidation:
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: err.Error(),
+		})
+	case appErrors.TypeConflict:
+		c.JSON(http.StatusConflict, responses.ErrorResponse{
+			Error:   "Conflict",
+			Details: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: internalDetails,
+		})
+	}
+}
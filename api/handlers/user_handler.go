@@ -2,16 +2,18 @@ package handlers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"personal-finance-tracker-api/api/middleware"
 	"personal-finance-tracker-api/api/responses"
-	"personal-finance-tracker-api/config"
 	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
 	"personal-finance-tracker-api/internal/services"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,20 +23,22 @@ func init() {
 	userValidate = validator.New()
 }
 
-// UserHandler holds the user service for business logic access
+// UserHandler holds the user and token services for business logic access
 type UserHandler struct {
-	UserService services.UserService // User service dependency
+	UserService  services.UserService  // User service dependency
+	TokenService services.TokenService // Token service dependency
 }
 
 // NewUserHandler creates a new instance of UserHandler
-func NewUserHandler(userService services.UserService) *UserHandler {
-	return &UserHandler{UserService: userService}
+func NewUserHandler(userService services.UserService, tokenService services.TokenService) *UserHandler {
+	return &UserHandler{UserService: userService, TokenService: tokenService}
 }
 
 // RegisterUserRequest represents the request body for user registration
 type RegisterUserRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
-	Password string `json:"password" validate:"required,min=6"` // Basic password validation
+	Password string `json:"password" validate:"required"`             // Checked against the password policy by the service
+	Email    string `json:"email" validate:"omitempty,email,max=255"` // Optional; needed for password resets
 }
 
 // LoginUserRequest represents the request body for user login
@@ -43,27 +47,49 @@ type LoginUserRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// LoginResponse represents the response body for a successful login
+// LoginResponse represents the response body for a successful login or token refresh
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token            string    `json:"token"` // Short-lived access token
+	TokenType        string    `json:"tokenType" example:"Bearer"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// TwoFactorChallengeResponse represents the response body when a login needs a second factor
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"twoFactorRequired"`
+	ChallengeToken    string    `json:"challengeToken"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// RefreshTokenRequest represents the request body for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// LogoutRequest represents the request body for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"` // Refresh token of the session to end
+	AllSessions  bool   `json:"allSessions"`  // End every session of the user
 }
 
 // RegisterUser handles new user registration
 // @Summary Register a new user
-// @Description Register a new user with a username and password
+// @Description Register a new user with a username, a password meeting the password policy and an optional email address for password resets
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param request body RegisterUserRequest true "User registration details"
 // @Success 201 {object} models.User "User registered successfully"
 // @Failure 400 {object} responses.ValidationErrorResponse "Invalid input or validation error"
-// @Failure 409 {object} responses.ErrorResponse "Conflict (username already exists)"
+// @Failure 409 {object} responses.ErrorResponse "Conflict (username or email already exists)"
 // @Failure 500 {object} responses.ErrorResponse "Internal server error"
 // @Router /users/register [post]
 func (h *UserHandler) RegisterUser(c *gin.Context) {
 	var req RegisterUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Warn("RegisterUser: Invalid JSON format or data type mismatch for registration.")
 		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
@@ -84,7 +110,7 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
 				})
 			}
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 				"validationErrors": fields,
 				"username":         req.Username,
 			}).Warn("RegisterUser: Input validation error for registration.")
@@ -94,7 +120,7 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 			})
 			return
 		}
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error":    err.Error(),
 			"username": req.Username,
 		}).Warn("RegisterUser: Unknown input validation error for registration.")
@@ -106,9 +132,9 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 	}
 
 	// Call the user service to register the user
-	user, err := h.UserService.RegisterUser(c.Request.Context(), req.Username, req.Password)
+	user, err := h.UserService.RegisterUser(c.Request.Context(), req.Username, req.Password, req.Email)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error":     err.Error(),
 			"username":  req.Username,
 			"errorType": appErrors.GetType(err),
@@ -137,7 +163,7 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"userID":   user.ID,
 		"username": user.Username,
 	}).Info("RegisterUser: User registered successfully.")
@@ -146,20 +172,22 @@ func (h *UserHandler) RegisterUser(c *gin.Context) {
 
 // LoginUser handles user login and issues a JWT token
 // @Summary Log in a user
-// @Description Authenticate a user and return an authentication token
+// @Description Authenticate a user and return an authentication token. Users with two-factor authentication instead receive a challenge token (202) to complete at /users/login/2fa.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param request body LoginUserRequest true "User login details"
-// @Success 200 {object} LoginResponse "Authentication successful with JWT token"
+// @Success 200 {object} LoginResponse "Authentication successful with access and refresh tokens"
+// @Success 202 {object} TwoFactorChallengeResponse "Password accepted, two-factor code required"
 // @Failure 400 {object} responses.ValidationErrorResponse "Invalid input"
 // @Failure 401 {object} responses.ErrorResponse "Unauthorized (invalid credentials)"
+// @Failure 429 {object} responses.ErrorResponse "Too many failed attempts; see the Retry-After header"
 // @Failure 500 {object} responses.ErrorResponse "Internal server error"
 // @Router /users/login [post]
 func (h *UserHandler) LoginUser(c *gin.Context) {
 	var req LoginUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error": err.Error(),
 		}).Warn("LoginUser: Invalid JSON format or data type mismatch for login.")
 		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
@@ -179,7 +207,7 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
 				})
 			}
-			logrus.WithFields(logrus.Fields{
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 				"validationErrors": fields,
 				"username":         req.Username,
 			}).Warn("LoginUser: Input validation error for login.")
@@ -189,7 +217,7 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 			})
 			return
 		}
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error":    err.Error(),
 			"username": req.Username,
 		}).Warn("LoginUser: Unknown input validation error for login.")
@@ -200,9 +228,9 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.UserService.AuthenticateUser(c.Request.Context(), req.Username, req.Password)
+	result, err := h.UserService.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error":     err.Error(),
 			"username":  req.Username,
 			"errorType": appErrors.GetType(err),
@@ -215,6 +243,10 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 			})
 			return
 		}
+		if appErrors.IsType(err, appErrors.TypeRateLimited) {
+			respondRateLimited(c, err)
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
 			Error:   "Internal Server Error",
@@ -223,25 +255,35 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	// Define custom claims
-	claims := jwt.MapClaims{
-		"authorized": true,
-		"userID":     user.ID,
-		"username":   user.Username,
-		"exp":        time.Now().Add(time.Hour * 24).Unix(),
+	h.completeLogin(c, result, "LoginUser")
+}
+
+// completeLogin responds to a login whose first factor was accepted: with a challenge when a
+// second factor is required, otherwise with new tokens
+func (h *UserHandler) completeLogin(c *gin.Context, result *services.LoginResult, name string) {
+	if result.TwoFactorRequired() {
+		logrus.WithContext(c.Request.Context()).Info(name + ": First factor accepted, two-factor code required.")
+		c.JSON(http.StatusAccepted, TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    result.ChallengeToken,
+			ExpiresAt:         result.ChallengeExpiresAt,
+		})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	h.issueTokens(c, result.User, name)
+}
 
-	// Sign the token with the secret
-	tokenString, err := token.SignedString([]byte(config.GetJWTSecret()))
+// issueTokens responds with a new access and refresh token for a fully authenticated user
+func (h *UserHandler) issueTokens(c *gin.Context, user *models.User, name string) {
+	// Issue a short-lived access token and a refresh token for a new session
+	pair, err := h.TokenService.IssueTokens(c.Request.Context(), user)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"error":    err.Error(),
 			"userID":   user.ID,
 			"username": user.Username,
-		}).Error("LoginUser: Failed to generate JWT token.")
+		}).Error(name + ": Failed to issue authentication tokens.")
 		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
 			Error:   "Internal Server Error",
 			Details: "Failed to generate authentication token.",
@@ -249,9 +291,210 @@ func (h *UserHandler) LoginUser(c *gin.Context) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 		"userID":   user.ID,
 		"username": user.Username,
-	}).Info("LoginUser: User logged in successfully and JWT generated.")
-	c.JSON(http.StatusOK, LoginResponse{Token: tokenString})
+	}).Info(name + ": User logged in successfully and tokens issued.")
+	c.JSON(http.StatusOK, newLoginResponse(pair))
+}
+
+// RefreshTokens handles exchanging a refresh token for a new token pair
+// @Summary Refresh authentication tokens
+// @Description Exchange a refresh token for a new access token and a new refresh token. Each refresh token can be used only once; reusing one revokes all tokens of its session.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} LoginResponse "New access and refresh tokens"
+// @Failure 400 {object} responses.ValidationErrorResponse "Invalid input"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (invalid, expired, used or revoked refresh token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/refresh [post]
+func (h *UserHandler) RefreshTokens(c *gin.Context) {
+	var req RefreshTokenRequest
+	if !bindUserRequest(c, &req, "RefreshTokens") {
+		return
+	}
+
+	pair, err := h.TokenService.RefreshTokens(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+		}).Warn("RefreshTokens: Failed to refresh tokens.")
+
+		if appErrors.IsType(err, appErrors.TypeUnauthorized) {
+			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
+				Error:   "Unauthorized",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to refresh authentication tokens.",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(pair))
+}
+
+// Logout handles revoking the current session
+// @Summary Log out
+// @Description Revoke the presented access token and the session of the given refresh token, or every session of the user when allSessions is set
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param request body LogoutRequest false "Session to end"
+// @Success 204 "Logged out"
+// @Failure 400 {object} responses.ErrorResponse "Invalid input"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized"
+// @Failure 403 {object} responses.ErrorResponse "Refresh token belongs to a different user"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/logout [post]
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims, exists := middleware.GetAccessClaimsFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("Logout: Access token claims not found in context.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Access token claims not found in context.",
+		})
+		return
+	}
+
+	// The body is optional; logging out without one only revokes the access token
+	var req LogoutRequest
+	if c.Request.ContentLength > 0 && !bindUserRequest(c, &req, "Logout") {
+		return
+	}
+
+	if err := h.TokenService.Logout(c.Request.Context(), claims, req.RefreshToken, req.AllSessions); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"userID":    claims.UserID,
+			"errorType": appErrors.GetType(err),
+		}).Error("Logout: Failed to revoke tokens.")
+
+		if appErrors.IsType(err, appErrors.TypeForbidden) {
+			c.JSON(http.StatusForbidden, responses.ErrorResponse{
+				Error:   "Forbidden",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to log out.",
+		})
+		return
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"userID":      claims.UserID,
+		"allSessions": req.AllSessions,
+	}).Info("Logout: User logged out successfully.")
+	c.Status(http.StatusNoContent)
+}
+
+// UnlockAccount handles lifting a login lockout of the authenticated user
+// @Summary Unlock the account
+// @Description Lift a lockout caused by failed login attempts against the authenticated user's username, for example from a device that is still signed in. Lockouts also expire on their own.
+// @Tags users
+// @Success 204 "Lockout lifted"
+// @Failure 401 {object} responses.ErrorResponse "Unauthorized (missing or invalid token)"
+// @Failure 403 {object} responses.ErrorResponse "Forbidden (called with an API token)"
+// @Failure 500 {object} responses.ErrorResponse "Internal server error"
+// @Router /users/unlock [post]
+func (h *UserHandler) UnlockAccount(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		logrus.WithContext(c.Request.Context()).Error("UnlockAccount: UserID not found in context, authentication middleware error.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Authenticated user ID not found.",
+		})
+		return
+	}
+
+	if err := h.UserService.UnlockAccount(c.Request.Context(), userID); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error":     err.Error(),
+			"errorType": appErrors.GetType(err),
+			"userID":    userID,
+		}).Error("UnlockAccount: Failed to unlock account.")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to unlock account.",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondRateLimited writes a 429 response with a Retry-After header in whole seconds
+func respondRateLimited(c *gin.Context, err error) {
+	if retryAfter, ok := appErrors.GetRetryAfter(err); ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	c.JSON(http.StatusTooManyRequests, responses.ErrorResponse{
+		Error:   "Too Many Requests",
+		Details: err.Error(),
+	})
+}
+
+// newLoginResponse converts an issued token pair into the response body
+func newLoginResponse(pair *services.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:            pair.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        pair.AccessExpiresAt,
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+	}
+}
+
+// bindUserRequest binds and validates a JSON request body, writing the error response on failure
+func bindUserRequest(c *gin.Context, req interface{}, name string) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Warn(name + ": Invalid JSON format or data type mismatch.")
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Invalid JSON format or data type mismatch.",
+		})
+		return false
+	}
+
+	if err := userValidate.Struct(req); err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			var fields []responses.ValidationFieldError
+			for _, fieldErr := range validationErrors {
+				fields = append(fields, responses.ValidationFieldError{
+					Field:   fieldErr.Field(),
+					Tag:     fieldErr.Tag(),
+					Message: fmt.Sprintf("Validation failed on '%s' for tag '%s'", fieldErr.Field(), fieldErr.Tag()),
+				})
+			}
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"validationErrors": fields,
+			}).Warn(name + ": Input validation error.")
+			c.JSON(http.StatusBadRequest, responses.ValidationErrorResponse{
+				Error:  "Validation Error",
+				Fields: fields,
+			})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+			Error:   "Bad Request",
+			Details: "Validation failed: " + err.Error(),
+		})
+		return false
+	}
+	return true
 }
@@ -1,22 +1,24 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"personal-finance-tracker-api/api/responses"
-	"personal-finance-tracker-api/config"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/services"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 )
 
-// AuthMiddleware is a Gin middleware to authenticate requests using JWT
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware is a Gin middleware to authenticate requests using JWT access tokens
+// or personal access tokens
+func AuthMiddleware(tokenService services.TokenService, apiTokenService services.APITokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			logrus.Warn("AuthMiddleware: Missing Authorization header")
+			logrus.WithContext(c.Request.Context()).Warn("AuthMiddleware: Missing Authorization header")
 			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
 				Error:   "Unauthorized",
 				Details: "Missing authentication token.",
@@ -29,7 +31,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
 			tokenString = tokenString[7:]
 		} else {
-			logrus.Warn("AuthMiddleware: Invalid Authorization header format")
+			logrus.WithContext(c.Request.Context()).Warn("AuthMiddleware: Invalid Authorization header format")
 			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
 				Error:   "Unauthorized",
 				Details: "Invalid token format. Expected 'Bearer [token]'.",
@@ -38,55 +40,83 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the alg is what you expect:
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				logrus.Warn("AuthMiddleware: Unexpected signing method")
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		// Personal access tokens are opaque and carry their own scopes
+		if strings.HasPrefix(tokenString, models.APITokenPrefix) {
+			apiToken, err := apiTokenService.AuthenticateToken(c.Request.Context(), tokenString)
+			if err != nil {
+				abortWithAuthError(c, err)
+				return
 			}
-			return []byte(config.GetJWTSecret()), nil
-		})
 
+			c.Set("userID", apiToken.UserID)
+			c.Set("apiToken", apiToken)
+
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"userID":  apiToken.UserID,
+				"tokenID": apiToken.ID,
+				"path":    c.Request.URL.Path,
+			}).Info("AuthMiddleware: API token validated successfully")
+
+			c.Next()
+			return
+		}
+
+		// Parse and validate the token, including the revocation denylist
+		claims, err := tokenService.ValidateAccessToken(c.Request.Context(), tokenString)
 		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"error": err.Error(),
-			}).Warn("AuthMiddleware: Invalid or expired token")
-			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
-				Error:   "Unauthorized",
-				Details: "Invalid or expired authentication token.",
-			})
-			c.Abort()
+			abortWithAuthError(c, err)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Token is valid, store claims in context for subsequent handlers
-			if userID, ok := claims["userID"].(float64); ok {
-				c.Set("userID", uint(userID))
-			}
-			if username, ok := claims["username"].(string); ok {
-				c.Set("username", username)
-			}
-			// You can store the entire claims map if needed
-			c.Set("authClaims", claims)
+		// Token is valid, store claims in context for subsequent handlers
+		c.Set("userID", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set("authClaims", claims.Raw)
+		c.Set("accessClaims", claims)
 
-			logrus.WithFields(logrus.Fields{
-				"userID":   claims["userID"],
-				"username": claims["username"],
-				"path":     c.Request.URL.Path,
-			}).Info("AuthMiddleware: Token validated successfully")
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"userID":   claims.UserID,
+			"username": claims.Username,
+			"path":     c.Request.URL.Path,
+		}).Info("AuthMiddleware: Token validated successfully")
 
+		c.Next()
+	}
+}
+
+// OptionalAuth authenticates requests that carry an Authorization header with auth and lets
+// anonymous requests through, for routes that also serve signed-in users differently
+func OptionalAuth(auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
 			c.Next()
-		} else {
-			logrus.Warn("AuthMiddleware: Invalid token claims or token not valid")
-			c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
-				Error:   "Unauthorized",
-				Details: "Invalid authentication token claims.",
-			})
-			c.Abort()
+			return
 		}
+		auth(c)
+	}
+}
+
+// abortWithAuthError responds to a failed token validation
+func abortWithAuthError(c *gin.Context, err error) {
+	if !appErrors.IsType(err, appErrors.TypeUnauthorized) {
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("AuthMiddleware: Failed to validate token")
+		c.JSON(http.StatusInternalServerError, responses.ErrorResponse{
+			Error:   "Internal Server Error",
+			Details: "Failed to validate authentication token.",
+		})
+		c.Abort()
+		return
 	}
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"error": err.Error(),
+	}).Warn("AuthMiddleware: Invalid, expired or revoked token")
+	c.JSON(http.StatusUnauthorized, responses.ErrorResponse{
+		Error:   "Unauthorized",
+		Details: "Invalid or expired authentication token.",
+	})
+	c.Abort()
 }
 
 // GetUserIDFromContext is a helper to retrieve userID from Gin context
@@ -108,3 +138,23 @@ func GetUsernameFromContext(c *gin.Context) (string, bool) {
 	}
 	return "", false
 }
+
+// GetAccessClaimsFromContext is a helper to retrieve the validated access token claims from Gin context
+func GetAccessClaimsFromContext(c *gin.Context) (*services.AccessClaims, bool) {
+	if claims, exists := c.Get("accessClaims"); exists {
+		if accessClaims, ok := claims.(*services.AccessClaims); ok {
+			return accessClaims, true
+		}
+	}
+	return nil, false
+}
+
+// GetAPITokenFromContext is a helper to retrieve the personal access token a request was authenticated with
+func GetAPITokenFromContext(c *gin.Context) (*models.APIToken, bool) {
+	if token, exists := c.Get("apiToken"); exists {
+		if apiToken, ok := token.(*models.APIToken); ok {
+			return apiToken, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/responses"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// HouseholdHeader names the household a request works on. Without it the user's default
+// household is used.
+const HouseholdHeader = "X-Household-ID"
+
+// HouseholdMiddleware is a Gin middleware that reads the household selected by the
+// X-Household-ID header. Membership is checked by the services, not here.
+func HouseholdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(HouseholdHeader)
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		id, err := strconv.ParseUint(header, 10, 32)
+		if err != nil || id == 0 {
+			logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+				"header": header,
+				"path":   c.Request.URL.Path,
+			}).Warn("HouseholdMiddleware: Invalid household header")
+			c.JSON(http.StatusBadRequest, responses.ErrorResponse{
+				Error:   "Invalid request",
+				Details: "The " + HouseholdHeader + " header must be a positive integer.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("householdID", uint(id))
+		c.Next()
+	}
+}
+
+// GetHouseholdIDFromContext is a helper to retrieve the selected household from Gin context.
+// It returns 0, meaning the user's default household, when none was selected.
+func GetHouseholdIDFromContext(c *gin.Context) uint {
+	if householdID, exists := c.Get("householdID"); exists {
+		if id, ok := householdID.(uint); ok {
+			return id
+		}
+	}
+	return 0
+}
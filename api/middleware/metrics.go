@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"personal-finance-tracker-api/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that match no route, so that arbitrary paths do not each
+// create a time series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware is a Gin middleware that counts requests and observes their duration by
+// method, route template (such as /api/v1/transactions/:id) and status code
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"personal-finance-tracker-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetadataMiddleware is a Gin middleware that stores the client IP address and user agent
+// in the request context, so the services can record them in the audit log
+func RequestMetadataMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := services.WithRequestMetadata(c.Request.Context(), c.ClientIP(), c.Request.UserAgent())
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
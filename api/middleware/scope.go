@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"personal-finance-tracker-api/api/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequireScope is a Gin middleware that rejects requests authenticated with a personal access
+// token lacking the given scope. Requests authenticated with a login session have every scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiToken, ok := GetAPITokenFromContext(c)
+		if !ok || apiToken.HasScope(scope) {
+			c.Next()
+			return
+		}
+
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"userID":  apiToken.UserID,
+			"tokenID": apiToken.ID,
+			"scope":   scope,
+			"path":    c.Request.URL.Path,
+		}).Warn("RequireScope: API token lacks required scope")
+		c.JSON(http.StatusForbidden, responses.ErrorResponse{
+			Error:   "Forbidden",
+			Details: "API token lacks the required scope '" + scope + "'.",
+		})
+		c.Abort()
+	}
+}
+
+// RequireSession is a Gin middleware that rejects requests authenticated with a personal access
+// token, for routes such as token management that need an interactive login
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiToken, ok := GetAPITokenFromContext(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"userID":  apiToken.UserID,
+			"tokenID": apiToken.ID,
+			"path":    c.Request.URL.Path,
+		}).Warn("RequireSession: API token used on a session-only route")
+		c.JSON(http.StatusForbidden, responses.ErrorResponse{
+			Error:   "Forbidden",
+			Details: "This endpoint cannot be used with an API token.",
+		})
+		c.Abort()
+	}
+}
@@ -1,79 +1,233 @@
-package api
-
-import (
-	"personal-finance-tracker-api/api/handlers"
-	"personal-finance-tracker-api/api/middleware"
-	"time"
-
-	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
-
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
-
-	_ "personal-finance-tracker-api/docs"
-)
-
-// SetupRouter configures the API routes and returns a Gin engine
-// It now accepts handler instances directly
-func SetupRouter(
-	transactionHandler *handlers.TransactionHandler,
-	categoryHandler *handlers.CategoryHandler,
-	userHandler *handlers.UserHandler,
-) *gin.Engine {
-	r := gin.Default()
-
-	// Custom Logrus Middleware
-	r.Use(func(c *gin.Context) {
-		startTime := time.Now()
-
-		c.Next()
-
-		endTime := time.Now()
-		latency := endTime.Sub(startTime)
-
-		logrus.WithFields(logrus.Fields{
-			"method":     c.Request.Method,
-			"path":       c.Request.URL.Path,
-			"status":     c.Writer.Status(),
-			"latency":    latency,
-			"ip":         c.ClientIP(),
-			"user-agent": c.Request.UserAgent(),
-		}).Info("Request completed")
-	})
-
-	// Base path for the API
-	api := r.Group("/api/v1")
-	{
-		// User routes
-		users := api.Group("/users")
-		{
-			users.POST("/register", userHandler.RegisterUser)
-			users.POST("/login", userHandler.LoginUser)
-		}
-
-		// Protected routes group: Apply AuthMiddleware to these routes
-		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware())
-
-		// Transaction routes
-		transactions := api.Group("/transactions")
-		{
-			transactions.POST("", transactionHandler.CreateTransaction)
-			transactions.GET("", transactionHandler.GetTransactions)
-			transactions.GET("/export/csv", transactionHandler.ExportTransactionsCSV)
-		}
-
-		// Category routes
-		categories := api.Group("/categories")
-		{
-			categories.POST("", categoryHandler.CreateCategory)
-			categories.GET("", categoryHandler.GetCategories)
-		}
-	}
-
-	// Swagger documentation route
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	return r
-}
+package api
+
+import (
+	"personal-finance-tracker-api/api/handlers"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	_ "personal-finance-tracker-api/docs"
+)
+
+// SetupRouter configures the API routes and returns a Gin engine
+// It now accepts handler instances directly
+func SetupRouter(
+	transactionHandler *handlers.TransactionHandler,
+	categoryHandler *handlers.CategoryHandler,
+	userHandler *handlers.UserHandler,
+	reconciliationHandler *handlers.ReconciliationHandler,
+	trashHandler *handlers.TrashHandler,
+	jwksHandler *handlers.JWKSHandler,
+	apiTokenHandler *handlers.APITokenHandler,
+	householdHandler *handlers.HouseholdHandler,
+	auditHandler *handlers.AuditHandler,
+	healthHandler *handlers.HealthHandler,
+	metricsHandler *handlers.MetricsHandler,
+	authMiddleware gin.HandlerFunc,
+) *gin.Engine {
+	r := gin.Default()
+
+	// Span of every request, which the request log and everything below find in the context
+	r.Use(middleware.TracingMiddleware())
+
+	// Custom Logrus Middleware
+	r.Use(func(c *gin.Context) {
+		startTime := time.Now()
+
+		c.Next()
+
+		endTime := time.Now()
+		latency := endTime.Sub(startTime)
+
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency":    latency,
+			"ip":         c.ClientIP(),
+			"user-agent": c.Request.UserAgent(),
+		}).Info("Request completed")
+	})
+
+	// Request counts and latencies by route template for Prometheus
+	r.Use(middleware.MetricsMiddleware())
+
+	// Client details recorded in the audit log
+	r.Use(middleware.RequestMetadataMiddleware())
+
+	// Base path for the API
+	api := r.Group("/api/v1")
+	{
+		// User routes
+		users := api.Group("/users")
+		{
+			users.POST("/register", userHandler.RegisterUser)
+			users.POST("/login", userHandler.LoginUser)
+			users.POST("/login/2fa", userHandler.CompleteTwoFactorLogin)
+			users.POST("/refresh", userHandler.RefreshTokens)
+			users.POST("/logout", authMiddleware, middleware.RequireSession(), userHandler.Logout)
+			users.POST("/unlock", authMiddleware, middleware.RequireSession(), userHandler.UnlockAccount)
+			users.PUT("/password", authMiddleware, middleware.RequireSession(), userHandler.ChangePassword)
+			users.POST("/password/forgot", userHandler.ForgotPassword)
+			users.POST("/password/reset", userHandler.ResetPassword)
+
+			// Profile, personal data export and account deletion
+			me := users.Group("/me", authMiddleware, middleware.RequireSession())
+			{
+				me.GET("", userHandler.GetProfile)
+				me.PUT("", userHandler.UpdateProfile)
+				me.GET("/export", userHandler.ExportUserData)
+				me.GET("/deletion", userHandler.GetAccountDeletion)
+				me.POST("/deletion", userHandler.RequestAccountDeletion)
+				me.POST("/deletion/confirm", userHandler.ConfirmAccountDeletion)
+				me.DELETE("/deletion", userHandler.CancelAccountDeletion)
+			}
+			users.GET("/oidc/login", userHandler.OIDCLogin)
+			users.GET("/oidc/callback", middleware.OptionalAuth(authMiddleware), userHandler.OIDCCallback)
+
+			// External identities used for single sign-on
+			identities := users.Group("/identities", authMiddleware, middleware.RequireSession())
+			{
+				identities.GET("", userHandler.GetIdentities)
+				identities.POST("/oidc", userHandler.LinkOIDCIdentity)
+				identities.DELETE("/:id", userHandler.UnlinkIdentity)
+			}
+
+			// Two-factor authentication settings
+			twoFactor := users.Group("/2fa", authMiddleware, middleware.RequireSession())
+			{
+				twoFactor.GET("", userHandler.GetTwoFactorStatus)
+				twoFactor.POST("/enroll", userHandler.BeginTOTPEnrollment)
+				twoFactor.POST("/confirm", userHandler.ConfirmTOTPEnrollment)
+				twoFactor.POST("/disable", userHandler.DisableTOTP)
+				twoFactor.POST("/recovery-codes", userHandler.RegenerateRecoveryCodes)
+			}
+
+			// Personal access tokens can only be managed from a login session
+			tokens := users.Group("/tokens", authMiddleware, middleware.RequireSession())
+			{
+				tokens.POST("", apiTokenHandler.CreateAPIToken)
+				tokens.GET("", apiTokenHandler.GetAPITokens)
+				tokens.DELETE("/:id", apiTokenHandler.RevokeAPIToken)
+			}
+		}
+
+		// Households can only be managed from a login session
+		households := api.Group("/households", authMiddleware, middleware.RequireSession())
+		{
+			households.GET("", householdHandler.GetHouseholds)
+			households.POST("", householdHandler.CreateHousehold)
+			households.POST("/invitations/accept", householdHandler.AcceptInvitation)
+			households.GET("/:id", householdHandler.GetHousehold)
+			households.PUT("/:id", householdHandler.UpdateHousehold)
+			households.PUT("/:id/default", householdHandler.SetDefaultHousehold)
+			households.GET("/:id/members", householdHandler.GetMembers)
+			households.PUT("/:id/members/:userId", householdHandler.UpdateMemberRole)
+			households.DELETE("/:id/members/:userId", householdHandler.RemoveMember)
+			households.POST("/:id/invitations", householdHandler.CreateInvitation)
+			households.GET("/:id/invitations", householdHandler.GetInvitations)
+			households.DELETE("/:id/invitations/:invitationId", householdHandler.RevokeInvitation)
+		}
+
+		// The audit log can only be read from a login session
+		audit := api.Group("/audit", authMiddleware, middleware.RequireSession(), middleware.HouseholdMiddleware())
+		{
+			audit.GET("", auditHandler.GetAuditEntries)
+			audit.GET("/verify", auditHandler.VerifyAuditChain)
+		}
+
+		// Protected routes group: Apply AuthMiddleware to these routes.
+		// Data routes work on the household selected by the X-Household-ID header.
+		protected := api.Group("/")
+		protected.Use(authMiddleware, middleware.HouseholdMiddleware())
+
+		// Scope checks only restrict requests made with personal access tokens
+		readTransactions := middleware.RequireScope(models.ScopeTransactionsRead)
+		writeTransactions := middleware.RequireScope(models.ScopeTransactionsWrite)
+		readCategories := middleware.RequireScope(models.ScopeCategoriesRead)
+		writeCategories := middleware.RequireScope(models.ScopeCategoriesWrite)
+		readReconciliations := middleware.RequireScope(models.ScopeReconciliationsRead)
+		writeReconciliations := middleware.RequireScope(models.ScopeReconciliationsWrite)
+
+		// Transaction routes
+		transactions := protected.Group("/transactions")
+		{
+			transactions.POST("", writeTransactions, transactionHandler.CreateTransaction)
+			transactions.GET("", readTransactions, transactionHandler.GetTransactions)
+			transactions.GET("/export/csv", readTransactions, transactionHandler.ExportTransactionsCSV)
+			transactions.GET("/:id", readTransactions, transactionHandler.GetTransaction)
+			transactions.PUT("/:id", writeTransactions, transactionHandler.UpdateTransaction)
+			transactions.PATCH("/:id/status", writeTransactions, transactionHandler.UpdateTransactionStatus)
+			transactions.DELETE("/:id", writeTransactions, transactionHandler.DeleteTransaction)
+		}
+
+		// Category routes
+		categories := protected.Group("/categories")
+		{
+			categories.POST("", writeCategories, categoryHandler.CreateCategory)
+			categories.GET("", readCategories, categoryHandler.GetCategories)
+			categories.GET("/tree", readCategories, categoryHandler.GetCategoryTree)
+			categories.GET("/templates", readCategories, categoryHandler.GetCategoryTemplates)
+			categories.POST("/templates/:name/apply", writeCategories, categoryHandler.ApplyCategoryTemplate)
+			categories.GET("/:id", readCategories, categoryHandler.GetCategory)
+			categories.PUT("/:id", writeCategories, categoryHandler.UpdateCategory)
+			categories.PATCH("/:id/parent", writeCategories, categoryHandler.MoveCategory)
+			categories.POST("/:id/merge", writeCategories, categoryHandler.MergeCategories)
+			categories.DELETE("/:id", writeCategories, categoryHandler.DeleteCategory)
+		}
+
+		// Reconciliation routes
+		reconciliations := protected.Group("/reconciliations")
+		{
+			reconciliations.POST("", writeReconciliations, reconciliationHandler.StartReconciliation)
+			reconciliations.GET("", readReconciliations, reconciliationHandler.GetReconciliations)
+			reconciliations.GET("/:id", readReconciliations, reconciliationHandler.GetReconciliation)
+			reconciliations.POST("/:id/complete", writeReconciliations, reconciliationHandler.CompleteReconciliation)
+			reconciliations.DELETE("/:id", writeReconciliations, reconciliationHandler.CancelReconciliation)
+		}
+
+		// Trash routes for soft-deleted records
+		trash := protected.Group("/trash")
+		{
+			trash.GET("/transactions", readTransactions, trashHandler.GetDeletedTransactions)
+			trash.POST("/transactions/:id/restore", writeTransactions, trashHandler.RestoreTransaction)
+			trash.DELETE("/transactions/:id", writeTransactions, trashHandler.PurgeTransaction)
+			trash.GET("/categories", readCategories, trashHandler.GetDeletedCategories)
+			trash.POST("/categories/:id/restore", writeCategories, trashHandler.RestoreCategory)
+			trash.DELETE("/categories/:id", writeCategories, trashHandler.PurgeCategory)
+		}
+	}
+
+	// Public keys for verifying access tokens
+	r.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	// Probes and build information for orchestrators and monitoring
+	r.GET("/healthz", healthHandler.GetLiveness)
+	r.GET("/readyz", healthHandler.GetReadiness)
+	r.GET("/version", healthHandler.GetVersion)
+	// Metrics reveal traffic and login failures, so the public port only serves them with a token;
+	// without one they are served by SetupMetricsRouter on a private address, or not at all
+	if metricsHandler != nil && metricsHandler.Token != "" {
+		r.GET("/metrics", metricsHandler.GetMetrics)
+	}
+
+	// Swagger documentation route
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	return r
+}
+
+// SetupMetricsRouter returns a Gin engine that only serves /metrics, for a listen address
+// separate from the API
+func SetupMetricsRouter(metricsHandler *handlers.MetricsHandler) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/metrics", metricsHandler.GetMetrics)
+	return r
+}
@@ -0,0 +1,529 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/api/handlers"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/internal/auth"
+	"personal-finance-tracker-api/internal/health"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/oidc"
+	"personal-finance-tracker-api/internal/oidc/oidctest"
+	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/services"
+	"personal-finance-tracker-api/internal/templates"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword satisfies the password policy of newTestRouter
+const testPassword = "correct horse battery"
+
+// testMetricsToken is the bearer token the test router requires for /metrics
+const testMetricsToken = "scrape-secret"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	logrus.SetOutput(io.Discard)
+}
+
+// newTestRouter wires the full application around an in-memory repository
+func newTestRouter(t *testing.T) (*gin.Engine, repository.Repository) {
+	t.Helper()
+	return newTestRouterWithUsers(t, services.UserServiceOptions{})
+}
+
+// newTestRouterWithUsers is newTestRouter with extra user service options, such as an OIDC provider
+func newTestRouterWithUsers(t *testing.T, userOptions services.UserServiceOptions) (*gin.Engine, repository.Repository) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+
+	categoryTemplates, err := templates.Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := auth.LoadKeySet(auth.KeySetConfig{HMACSecret: "router-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptAlgorithm, err := auth.NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userOptions.PasswordPolicy = auth.PasswordPolicy{MinLength: 12, MaxLength: 128}
+	userOptions.PasswordHasher = auth.NewPasswordHasher(bcryptAlgorithm)
+	userService := services.NewUserService(repo, categoryTemplates, nil, notify.NewLogNotifier(), userOptions)
+	tokenService := services.NewTokenService(repo, keys, 15*time.Minute, 24*time.Hour)
+	apiTokenService := services.NewAPITokenService(repo)
+
+	router := SetupRouter(
+		handlers.NewTransactionHandler(services.TraceTransactionService(services.NewTransactionService(repo))),
+		handlers.NewCategoryHandler(services.NewCategoryService(repo, categoryTemplates)),
+		handlers.NewUserHandler(userService, tokenService),
+		handlers.NewReconciliationHandler(services.NewReconciliationService(repo)),
+		handlers.NewTrashHandler(services.NewTrashService(repo)),
+		handlers.NewJWKSHandler(keys),
+		handlers.NewAPITokenHandler(apiTokenService),
+		handlers.NewHouseholdHandler(services.NewHouseholdService(repo, notify.NewLogNotifier(), services.HouseholdServiceOptions{InvitationTTL: time.Hour})),
+		handlers.NewAuditHandler(services.NewAuditService(repo)),
+		handlers.NewHealthHandler(health.NewChecker(time.Second)),
+		handlers.NewMetricsHandler(testMetricsToken),
+		middleware.AuthMiddleware(tokenService, apiTokenService),
+	)
+	return router, repo
+}
+
+// request describes a call made by the tests
+type request struct {
+	method    string
+	path      string
+	body      interface{}
+	token     string
+	household string // X-Household-ID header; empty uses the default household
+	cookies   []*http.Cookie
+}
+
+// do sends a request to router and returns the recorded response
+func do(t *testing.T, router *gin.Engine, r request) *httptest.ResponseRecorder {
+	t.Helper()
+	var body io.Reader
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(r.method, r.path, body)
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	if r.household != "" {
+		req.Header.Set(middleware.HouseholdHeader, r.household)
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// decode unmarshals a JSON response body into v
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", recorder.Body.String(), err)
+	}
+}
+
+// signUp registers and logs in a user, returning an access token and the user
+func signUp(t *testing.T, router *gin.Engine, username string) (string, models.User) {
+	t.Helper()
+	credentials := map[string]string{"username": username, "password": testPassword}
+	recorder := do(t, router, request{method: http.MethodPost, path: "/api/v1/users/register", body: credentials})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("register %s: status %d: %s", username, recorder.Code, recorder.Body)
+	}
+	var user models.User
+	decode(t, recorder, &user)
+
+	recorder = do(t, router, request{method: http.MethodPost, path: "/api/v1/users/login", body: credentials})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("login %s: status %d: %s", username, recorder.Code, recorder.Body)
+	}
+	var login handlers.LoginResponse
+	decode(t, recorder, &login)
+	return login.Token, user
+}
+
+func TestProbes(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		recorder := do(t, router, request{method: http.MethodGet, path: path})
+		var body struct{ Status string }
+		decode(t, recorder, &body)
+		if recorder.Code != http.StatusOK || body.Status != "ok" {
+			t.Errorf("%s = %d %s, want 200 ok", path, recorder.Code, recorder.Body)
+		}
+	}
+
+	recorder := do(t, router, request{method: http.MethodGet, path: "/version"})
+	var version struct{ Version, Commit, GoVersion string }
+	decode(t, recorder, &version)
+	if recorder.Code != http.StatusOK || version.Version != "dev" || version.Commit == "" || version.GoVersion == "" {
+		t.Errorf("/version = %d %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	router, _ := newTestRouter(t)
+	do(t, router, request{method: http.MethodGet, path: "/api/v1/transactions/42"})
+	do(t, router, request{method: http.MethodGet, path: "/no/such/route"})
+
+	recorder := do(t, router, request{method: http.MethodGet, path: "/metrics", token: testMetricsToken})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("/metrics = %d %s", recorder.Code, recorder.Body)
+	}
+	for _, want := range []string{
+		`pft_http_requests_total{method="GET",route="/api/v1/transactions/:id",status="401"}`,
+		`pft_http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`pft_http_request_duration_seconds_bucket{method="GET",route="/api/v1/transactions/:id",status="401",le="+Inf"}`,
+		`pft_login_failures_total{reason="rate_limited"} `,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(recorder.Body.String(), want) {
+			t.Errorf("/metrics does not contain %s", want)
+		}
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		router     *gin.Engine
+		token      string
+		wantStatus int
+	}{
+		{"public port without token", publicMetricsRouter(t, testMetricsToken), "", http.StatusUnauthorized},
+		{"public port with a wrong token", publicMetricsRouter(t, testMetricsToken), "wrong", http.StatusUnauthorized},
+		{"public port with the token", publicMetricsRouter(t, testMetricsToken), testMetricsToken, http.StatusOK},
+		// Metrics are never public: without a token the API port does not serve them at all
+		{"public port, no token configured", publicMetricsRouter(t, ""), "", http.StatusNotFound},
+		{"public port, metrics on a private address", publicMetricsRouter(t, "-"), "", http.StatusNotFound},
+		{"private address, no token configured", SetupMetricsRouter(handlers.NewMetricsHandler("")), "", http.StatusOK},
+		{"private address with a token", SetupMetricsRouter(handlers.NewMetricsHandler(testMetricsToken)), "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recorder := do(t, tt.router, request{method: http.MethodGet, path: "/metrics", token: tt.token}); recorder.Code != tt.wantStatus {
+				t.Errorf("/metrics = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// publicMetricsRouter returns the API router with metrics protected by token; "-" passes no
+// metrics handler, as when they are served on a separate address
+func publicMetricsRouter(t *testing.T, token string) *gin.Engine {
+	t.Helper()
+	var metricsHandler *handlers.MetricsHandler
+	if token != "-" {
+		metricsHandler = handlers.NewMetricsHandler(token)
+	}
+	return SetupRouter(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, metricsHandler, nil)
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	router, _ := newTestRouter(t)
+	token, _ := signUp(t, router, "alice")
+
+	const traceID, parentSpanID = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transactions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentSpanID+"-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+	server, ok := spans["GET /api/v1/transactions"]
+	if !ok {
+		t.Fatalf("no server span in the caller's trace, got %v", spans)
+	}
+	if got := server.Parent().SpanID().String(); got != parentSpanID || !server.Parent().IsRemote() {
+		t.Errorf("server span parent = %s (remote %t), want the caller's span %s", got, server.Parent().IsRemote(), parentSpanID)
+	}
+	service, ok := spans["TransactionService.GetTransactions"]
+	if !ok {
+		t.Fatalf("no service span in the caller's trace, got %v", spans)
+	}
+	if service.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("service span parent = %s, want the server span %s", service.Parent().SpanID(), server.SpanContext().SpanID())
+	}
+}
+
+func TestRegisterUser(t *testing.T) {
+	router, _ := newTestRouter(t)
+	signUp(t, router, "alice")
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{"valid", map[string]string{"username": "bob", "password": testPassword, "email": "bob@example.com"}, http.StatusCreated},
+		{"taken username", map[string]string{"username": "alice", "password": testPassword}, http.StatusConflict},
+		{"short username", map[string]string{"username": "al", "password": testPassword}, http.StatusBadRequest},
+		{"invalid email", map[string]string{"username": "carol", "password": testPassword, "email": "carol"}, http.StatusBadRequest},
+		{"weak password", map[string]string{"username": "dave", "password": "short"}, http.StatusBadRequest},
+		{"malformed body", "not an object", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := do(t, router, request{method: http.MethodPost, path: "/api/v1/users/register", body: tt.body})
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	router, _ := newTestRouter(t)
+	signUp(t, router, "alice")
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{"valid", map[string]string{"username": "alice", "password": testPassword}, http.StatusOK},
+		{"wrong password", map[string]string{"username": "alice", "password": "wrong password!"}, http.StatusUnauthorized},
+		{"unknown user", map[string]string{"username": "mallory", "password": testPassword}, http.StatusUnauthorized},
+		{"missing password", map[string]string{"username": "alice"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := do(t, router, request{method: http.MethodPost, path: "/api/v1/users/login", body: tt.body})
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var login handlers.LoginResponse
+			decode(t, recorder, &login)
+			if login.Token == "" || login.RefreshToken == "" || login.TokenType != "Bearer" {
+				t.Errorf("login response %+v lacks tokens", login)
+			}
+		})
+	}
+}
+
+func TestOIDCCallback(t *testing.T) {
+	server := oidctest.NewServer(t, "finance-tracker")
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:   server.Issuer(),
+		ClientID:    server.ClientID,
+		RedirectURL: "https://finance.example.com/api/v1/users/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, _ := newTestRouterWithUsers(t, services.UserServiceOptions{
+		OIDCProvider:      provider,
+		OIDCLoginTTL:      time.Minute,
+		OIDCAutoProvision: true,
+	})
+	bobToken, _ := signUp(t, router, "bob")
+
+	// begin starts a flow, authenticates identity at the provider and returns the callback URL
+	// and the state cookie the browser was given
+	begin := func(t *testing.T, r request, identity oidctest.Identity) (string, *http.Cookie) {
+		t.Helper()
+		recorder := do(t, router, r)
+		authURL := recorder.Header().Get("Location")
+		if recorder.Code == http.StatusOK {
+			var authorization handlers.OIDCAuthorizationResponse
+			decode(t, recorder, &authorization)
+			authURL = authorization.AuthorizationURL
+		}
+		if authURL == "" {
+			t.Fatalf("status %d without an authorization URL: %s", recorder.Code, recorder.Body)
+		}
+		var cookie *http.Cookie
+		for _, c := range recorder.Result().Cookies() {
+			if c.Name == "oidc_state" {
+				cookie = c
+			}
+		}
+		if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("state cookie = %+v, want an HttpOnly SameSite=Lax cookie", cookie)
+		}
+		state, code := server.Authorize(t, authURL, identity)
+		return "/api/v1/users/oidc/callback?" + url.Values{"state": {state}, "code": {code}}.Encode(), cookie
+	}
+	login := request{method: http.MethodGet, path: "/api/v1/users/oidc/login"}
+	link := request{method: http.MethodPost, path: "/api/v1/users/identities/oidc", token: bobToken}
+	alice := oidctest.Identity{Subject: "alice-sub", PreferredUsername: "alice"}
+
+	tests := []struct {
+		name       string
+		start      request
+		identity   oidctest.Identity
+		cookie     func(own *http.Cookie) []*http.Cookie
+		token      string
+		wantStatus int
+	}{
+		{"login without the state cookie", login, alice, func(*http.Cookie) []*http.Cookie { return nil }, "", http.StatusUnauthorized},
+		{"login with the cookie of another flow", login, alice, func(*http.Cookie) []*http.Cookie {
+			_, other := begin(t, login, alice)
+			return []*http.Cookie{other}
+		}, "", http.StatusUnauthorized},
+		{"login from the browser that started it", login, alice, func(own *http.Cookie) []*http.Cookie { return []*http.Cookie{own} }, "", http.StatusOK},
+		{"link without a session", link, oidctest.Identity{Subject: "bob-sub"}, func(own *http.Cookie) []*http.Cookie { return []*http.Cookie{own} }, "", http.StatusUnauthorized},
+		{"link by the user who started it", link, oidctest.Identity{Subject: "bob-sub"}, func(own *http.Cookie) []*http.Cookie { return []*http.Cookie{own} }, bobToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback, cookie := begin(t, tt.start, tt.identity)
+			recorder := do(t, router, request{method: http.MethodGet, path: callback, token: tt.token, cookies: tt.cookie(cookie)})
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestProtectedRoutesRejectInvalidRequests(t *testing.T) {
+	router, _ := newTestRouter(t)
+	token, _ := signUp(t, router, "alice")
+
+	tests := []struct {
+		name       string
+		request    request
+		wantStatus int
+	}{
+		{"no token", request{method: http.MethodGet, path: "/api/v1/transactions"}, http.StatusUnauthorized},
+		{"malformed token", request{method: http.MethodGet, path: "/api/v1/categories", token: "garbage"}, http.StatusUnauthorized},
+		{"profile without token", request{method: http.MethodGet, path: "/api/v1/users/me"}, http.StatusUnauthorized},
+		{"invalid household header", request{method: http.MethodGet, path: "/api/v1/transactions", token: token, household: "abc"}, http.StatusBadRequest},
+		{"household of another user", request{method: http.MethodGet, path: "/api/v1/transactions", token: token, household: "9999"}, http.StatusNotFound},
+		{"valid", request{method: http.MethodGet, path: "/api/v1/transactions", token: token}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := do(t, router, tt.request)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestTransactionLifecycle(t *testing.T) {
+	router, _ := newTestRouter(t)
+	token, _ := signUp(t, router, "alice")
+
+	recorder := do(t, router, request{method: http.MethodPost, path: "/api/v1/categories", token: token, body: map[string]string{"name": "Groceries"}})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create category: status %d: %s", recorder.Code, recorder.Body)
+	}
+	var category models.Category
+	decode(t, recorder, &category)
+
+	transaction := map[string]interface{}{
+		"description": "Weekly shop", "amount": 54.2, "type": "expense",
+		"date": "2026-04-01T10:00:00Z", "categoryId": category.ID,
+	}
+	recorder = do(t, router, request{method: http.MethodPost, path: "/api/v1/transactions", token: token, body: transaction})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create transaction: status %d: %s", recorder.Code, recorder.Body)
+	}
+	var created models.Transaction
+	decode(t, recorder, &created)
+	path := fmt.Sprintf("/api/v1/transactions/%d", created.ID)
+
+	invalid := map[string]interface{}{"amount": -5, "type": "expense", "date": "2026-04-01T10:00:00Z", "categoryId": category.ID}
+	tests := []struct {
+		name       string
+		request    request
+		wantStatus int
+		wantBody   string // Substring of the response body
+	}{
+		{"create with invalid amount", request{method: http.MethodPost, path: "/api/v1/transactions", body: invalid}, http.StatusBadRequest, "Amount"},
+		{"create in unknown category", request{method: http.MethodPost, path: "/api/v1/transactions", body: map[string]interface{}{
+			"amount": 5, "type": "expense", "date": "2026-04-01T10:00:00Z", "categoryId": 9999,
+		}}, http.StatusBadRequest, "Category with ID 9999"},
+		{"list", request{method: http.MethodGet, path: "/api/v1/transactions?type=expense"}, http.StatusOK, "Weekly shop"},
+		{"list other type", request{method: http.MethodGet, path: "/api/v1/transactions?type=income"}, http.StatusOK, "[]"},
+		{"get", request{method: http.MethodGet, path: path}, http.StatusOK, `"name":"Groceries"`},
+		{"clear", request{method: http.MethodPatch, path: path + "/status", body: map[string]string{"status": "cleared"}}, http.StatusOK, `"status":"cleared"`},
+		{"update", request{method: http.MethodPut, path: path, body: map[string]interface{}{
+			"description": "Monthly shop", "amount": 210, "type": "expense", "date": "2026-04-02T10:00:00Z", "categoryId": category.ID,
+		}}, http.StatusOK, "Monthly shop"},
+		{"export", request{method: http.MethodGet, path: "/api/v1/transactions/export/csv"}, http.StatusOK, "Monthly shop"},
+		{"delete", request{method: http.MethodDelete, path: path}, http.StatusNoContent, ""},
+		{"get deleted", request{method: http.MethodGet, path: path}, http.StatusNotFound, ""},
+		{"in the trash", request{method: http.MethodGet, path: "/api/v1/trash/transactions"}, http.StatusOK, "Monthly shop"},
+		{"restore", request{method: http.MethodPost, path: "/api/v1/trash/transactions/" + fmt.Sprint(created.ID) + "/restore"}, http.StatusNoContent, ""},
+		{"get restored", request{method: http.MethodGet, path: path}, http.StatusOK, "Monthly shop"},
+	}
+	for _, tt := range tests {
+		// The steps build on each other, so the first failure ends the test
+		tt.request.token = token
+		recorder := do(t, router, tt.request)
+		if recorder.Code != tt.wantStatus {
+			t.Fatalf("%s: status = %d, want %d: %s", tt.name, recorder.Code, tt.wantStatus, recorder.Body)
+		}
+		if !bytes.Contains(recorder.Body.Bytes(), []byte(tt.wantBody)) {
+			t.Fatalf("%s: response %s does not contain %q", tt.name, recorder.Body, tt.wantBody)
+		}
+	}
+}
+
+func TestHouseholdsAreIsolated(t *testing.T) {
+	router, _ := newTestRouter(t)
+	aliceToken, alice := signUp(t, router, "alice")
+	bobToken, _ := signUp(t, router, "bob")
+
+	recorder := do(t, router, request{method: http.MethodPost, path: "/api/v1/categories", token: aliceToken, body: map[string]string{"name": "Groceries"}})
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create category: status %d: %s", recorder.Code, recorder.Body)
+	}
+	var category models.Category
+	decode(t, recorder, &category)
+	aliceHousehold := fmt.Sprint(*alice.DefaultHouseholdID)
+
+	tests := []struct {
+		name       string
+		request    request
+		wantStatus int
+	}{
+		{"owner reads the category", request{method: http.MethodGet, path: fmt.Sprintf("/api/v1/categories/%d", category.ID), token: aliceToken}, http.StatusOK},
+		{"other user in their own household", request{method: http.MethodGet, path: fmt.Sprintf("/api/v1/categories/%d", category.ID), token: bobToken}, http.StatusNotFound},
+		{"other user selecting the household", request{method: http.MethodGet, path: "/api/v1/categories", token: bobToken, household: aliceHousehold}, http.StatusNotFound},
+		{"other user creating in the household", request{method: http.MethodPost, path: "/api/v1/categories", token: bobToken, household: aliceHousehold, body: map[string]string{"name": "Intrusion"}}, http.StatusNotFound},
+		{"other user deleting", request{method: http.MethodDelete, path: fmt.Sprintf("/api/v1/categories/%d", category.ID), token: bobToken}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := do(t, router, tt.request)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body)
+			}
+		})
+	}
+}
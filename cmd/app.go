@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/auth"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/oidc"
+	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/services"
+	"personal-finance-tracker-api/internal/templates"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// application holds the services shared by the HTTP server and the administrative commands
+type application struct {
+	cfg                   *config.Config
+	db                    *gorm.DB
+	repo                  repository.Repository
+	signingKeys           *auth.KeySet
+	loginLimiter          *services.LoginLimiter
+	userNotifier          *notify.AsyncNotifier // Sends the user service's messages in the background
+	transactionService    services.TransactionService
+	categoryService       services.CategoryService
+	userService           services.UserService
+	reconciliationService services.ReconciliationService
+	trashService          services.TrashService
+	householdService      services.HouseholdService
+	apiTokenService       services.APITokenService
+	auditService          services.AuditService
+	tokenService          services.TokenService
+}
+
+// openApplication connects to the database for an administrative command. The schema must be
+// up to date; unlike the server, commands never migrate it implicitly.
+func openApplication(cfg *config.Config) *application {
+	db := repository.InitDB(cfg.DatabaseDriver, cfg.DatabaseURL)
+	migrateOnStartup(db, false)
+	return newApplication(cfg, db, nil)
+}
+
+// newApplication creates the services on top of db. oidcProvider may be nil, which disables
+// single sign-on.
+func newApplication(cfg *config.Config, db *gorm.DB, oidcProvider services.OIDCProvider) *application {
+	app := &application{cfg: cfg, db: db}
+
+	// Create repository instance
+	app.repo = repository.NewGormRepository(db)
+
+	// Load the built-in category templates
+	categoryTemplates, err := templates.Builtin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to load category templates")
+	}
+	if _, ok := categoryTemplates.Get(cfg.DefaultCategoryTemplate); cfg.DefaultCategoryTemplate != "" && !ok {
+		logrus.WithFields(logrus.Fields{
+			"template": cfg.DefaultCategoryTemplate,
+		}).Fatal("Unknown DEFAULT_CATEGORY_TEMPLATE")
+	}
+
+	// Load the JWT signing keys
+	app.signingKeys, err = auth.LoadKeySet(auth.KeySetConfig{
+		KeyFiles:    cfg.JWTKeyFiles,
+		ActiveKeyID: cfg.JWTActiveKeyID,
+		RetiredKeys: cfg.JWTRetiredKeys,
+		GracePeriod: cfg.JWTKeyGracePeriod,
+		HMACSecret:  cfg.JWTSecret,
+
+		PreviousHMACSecret:          cfg.JWTPreviousSecret,
+		PreviousHMACSecretRetiredAt: cfg.JWTPreviousSecretRetiredAt,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to load JWT signing keys")
+	}
+	logrus.WithFields(logrus.Fields{
+		"activeKeyID": app.signingKeys.ActiveKeyID(),
+		"algorithms":  app.signingKeys.ValidMethods(),
+	}).Info("JWT signing keys loaded")
+
+	// Build the password policy, including the optional breached password list
+	passwordPolicy := auth.PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     cfg.PasswordMaxLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+	}
+	if cfg.PasswordBreachedListFile != "" {
+		passwordPolicy.Breached, err = auth.LoadBreachedPasswords(cfg.PasswordBreachedListFile)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+				"file":  cfg.PasswordBreachedListFile,
+			}).Fatal("Failed to load breached password list")
+		}
+		logrus.WithFields(logrus.Fields{
+			"hashes": passwordPolicy.Breached.Len(),
+		}).Info("Breached password list loaded")
+	}
+
+	// Hash new passwords with the configured algorithm; hashes of the other one are upgraded on login
+	argon2id, err := auth.NewArgon2id(auth.Argon2idParams{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  uint32(cfg.Argon2SaltLength),
+		KeyLength:   uint32(cfg.Argon2KeyLength),
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Invalid Argon2id parameters")
+	}
+	bcryptAlgorithm, err := auth.NewBcrypt(cfg.BcryptCost)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Invalid BCRYPT_COST")
+	}
+	var passwordHasher *auth.PasswordHasher
+	switch cfg.PasswordHashAlgorithm {
+	case "argon2id":
+		passwordHasher = auth.NewPasswordHasher(argon2id, bcryptAlgorithm)
+	case "bcrypt":
+		passwordHasher = auth.NewPasswordHasher(bcryptAlgorithm, argon2id)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"algorithm": cfg.PasswordHashAlgorithm,
+		}).Fatal("Unknown PASSWORD_HASH_ALGORITHM")
+	}
+
+	// Select how messages such as password reset links are delivered
+	var notifier notify.Notifier
+	switch cfg.Notifier {
+	case "log":
+		notifier = notify.NewLogNotifier()
+	case "smtp":
+		notifier = notify.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"notifier": cfg.Notifier,
+		}).Fatal("Unknown NOTIFIER")
+	}
+
+	// Create service instances, injecting the repository
+	app.transactionService = services.NewTransactionService(app.repo)
+	app.categoryService = services.NewCategoryService(app.repo, categoryTemplates)
+	var loginAttemptStore services.LoginAttemptStore
+	switch cfg.LoginAttemptStore {
+	case "memory":
+		loginAttemptStore = services.NewMemoryLoginAttemptStore()
+	case "database":
+		loginAttemptStore = services.NewRepositoryLoginAttemptStore(app.repo)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"store": cfg.LoginAttemptStore,
+		}).Fatal("Unknown LOGIN_ATTEMPT_STORE")
+	}
+	app.loginLimiter = services.NewLoginLimiter(loginAttemptStore, services.LoginLimiterOptions{
+		FreeAttempts:             cfg.LoginFreeAttempts,
+		BackoffBase:              cfg.LoginBackoffBase,
+		BackoffMax:               cfg.LoginBackoffMax,
+		UsernameLockoutThreshold: cfg.LoginLockoutThreshold,
+		IPLockoutThreshold:       cfg.LoginIPLockoutThreshold,
+		LockoutDuration:          cfg.LoginLockoutDuration,
+		FailureWindow:            cfg.LoginFailureWindow,
+	})
+	// Password reset links are sent in the background; waiting for the mail server would let the
+	// response time tell whether an account exists
+	app.userNotifier = notify.NewAsyncNotifier(notifier)
+	app.userService = services.NewUserService(app.repo, categoryTemplates, app.loginLimiter, app.userNotifier, services.UserServiceOptions{
+		DefaultCategoryTemplate:    cfg.DefaultCategoryTemplate,
+		TOTPIssuer:                 cfg.TOTPIssuer,
+		LoginChallengeTTL:          cfg.LoginChallengeTTL,
+		PasswordPolicy:             passwordPolicy,
+		PasswordHasher:             passwordHasher,
+		PasswordResetTTL:           cfg.PasswordResetTTL,
+		PasswordResetURL:           cfg.PasswordResetURL,
+		OIDCProvider:               oidcProvider,
+		OIDCLoginTTL:               cfg.OIDCLoginTTL,
+		OIDCAutoProvision:          cfg.OIDCAutoProvision,
+		OIDCLinkByEmail:            cfg.OIDCLinkByEmail,
+		AccountDeletionConfirmTTL:  cfg.AccountDeletionConfirmTTL,
+		AccountDeletionGracePeriod: cfg.AccountDeletionGracePeriod,
+	})
+	app.reconciliationService = services.NewReconciliationService(app.repo)
+	app.trashService = services.NewTrashService(app.repo)
+	app.householdService = services.NewHouseholdService(app.repo, notifier, services.HouseholdServiceOptions{
+		InvitationTTL: cfg.HouseholdInvitationTTL,
+		InvitationURL: cfg.HouseholdInvitationURL,
+	})
+	app.apiTokenService = services.NewAPITokenService(app.repo)
+	app.auditService = services.NewAuditService(app.repo)
+	app.tokenService = services.NewTokenService(app.repo, app.signingKeys, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+
+	// Record a span for every service call; without a configured exporter this costs little
+	app.transactionService = services.TraceTransactionService(app.transactionService)
+	app.categoryService = services.TraceCategoryService(app.categoryService)
+	app.userService = services.TraceUserService(app.userService)
+	app.reconciliationService = services.TraceReconciliationService(app.reconciliationService)
+	app.trashService = services.TraceTrashService(app.trashService)
+	app.householdService = services.TraceHouseholdService(app.householdService)
+	app.apiTokenService = services.TraceAPITokenService(app.apiTokenService)
+	app.auditService = services.TraceAuditService(app.auditService)
+	app.tokenService = services.TraceTokenService(app.tokenService)
+
+	return app
+}
+
+// newOIDCProvider discovers the OpenID Connect provider when single sign-on is configured and
+// returns nil otherwise
+func newOIDCProvider(cfg *config.Config) services.OIDCProvider {
+	if cfg.OIDCIssuerURL == "" {
+		return nil
+	}
+	discoveryCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	provider, err := oidc.NewProvider(discoveryCtx, oidc.Config{
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.OIDCRedirectURL,
+		Scopes:       cfg.OIDCScopes,
+	})
+	cancel()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error":  err,
+			"issuer": cfg.OIDCIssuerURL,
+		}).Fatal("Failed to set up OIDC provider")
+	}
+	logrus.WithFields(logrus.Fields{
+		"issuer": provider.Issuer(),
+	}).Info("OIDC login enabled")
+	return provider
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"personal-finance-tracker-api/api/handlers"
+	"personal-finance-tracker-api/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// exportCommand writes the personal data export of a user, the same ZIP archive users can
+// download from /users/me/export
+func exportCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "Write all personal data of a user to a ZIP archive of JSON and CSV files",
+		ArgsUsage: "USERNAME",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Archive to write; defaults to personal-data-USERNAME-DATE.zip"},
+		},
+		Action: func(c *cli.Context) error {
+			username, err := usernameArg(c)
+			if err != nil {
+				return err
+			}
+			app := openApplication(cfg)
+			user, err := app.userService.GetUserByUsername(c.Context, username)
+			if err != nil {
+				return err
+			}
+			export, err := app.userService.ExportUserData(c.Context, user.ID)
+			if err != nil {
+				return err
+			}
+
+			output := c.String("output")
+			if output == "" {
+				output = fmt.Sprintf("personal-data-%s-%s.zip", user.Username, export.ExportedAt.Format("2006-01-02"))
+			}
+			file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+			if err != nil {
+				return err
+			}
+			if err := handlers.WriteUserDataArchive(file, export); err != nil {
+				file.Close()
+				os.Remove(output)
+				return fmt.Errorf("writing %s: %w", output, err)
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("Exported the personal data of user %s to %s\n", user.Username, output)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/importer"
+	"personal-finance-tracker-api/internal/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+// importCommand records the transactions of a CSV or OFX file for a user
+func importCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "Import transactions from a CSV or OFX file",
+		ArgsUsage: "FILE",
+		Description: "CSV files need a header row with at least Date and Amount columns, as written by the CSV export.\n" +
+			"Transactions are assigned to the category named in their Category column, or else to --category.\n" +
+			"Either all transactions of the file are imported or, when one is invalid, none is.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "user", Usage: "Username of the user recording the transactions", Required: true},
+			&cli.UintFlag{Name: "household", Usage: "Household to import into; defaults to the user's default household"},
+			&cli.StringFlag{Name: "format", Usage: "File format, csv or ofx; guessed from the file extension by default"},
+			&cli.StringFlag{Name: "category", Usage: "Category for transactions without one in the file"},
+			&cli.BoolFlag{Name: "create-categories", Usage: "Create categories named in the file that do not exist yet"},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return cli.Exit("Usage: "+c.Command.HelpName+" [command options] FILE", 2)
+			}
+			path := c.Args().First()
+			format := strings.ToLower(c.String("format"))
+			if format == "" {
+				var err error
+				if format, err = importer.FormatFromFileName(path); err != nil {
+					return cli.Exit(err.Error()+"; use --format", 2)
+				}
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			records, err := importer.Parse(format, file)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+
+			app := openApplication(cfg)
+			user, err := app.userService.GetUserByUsername(c.Context, c.String("user"))
+			if err != nil {
+				return err
+			}
+			householdID := c.Uint("household")
+
+			categoryIDs, err := importCategories(c, app, user.ID, householdID, records)
+			if err != nil {
+				return err
+			}
+			transactions := make([]models.Transaction, len(records))
+			for i, record := range records {
+				transactions[i] = models.Transaction{
+					Description: record.Description,
+					Amount:      record.Amount,
+					Type:        record.Type,
+					Date:        record.Date,
+					Status:      record.Status,
+					CategoryID:  categoryIDs[i],
+				}
+			}
+
+			imported, err := app.transactionService.ImportTransactions(c.Context, user.ID, householdID, transactions)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Imported %d transactions for user %s\n", len(imported), user.Username)
+			return nil
+		},
+	}
+}
+
+// importCategories resolves the category of every record by name, ignoring case, and returns
+// their IDs in the same order. With --create-categories, unknown categories are created.
+func importCategories(c *cli.Context, app *application, userID, householdID uint, records []importer.Record) ([]uint, error) {
+	categories, err := app.categoryService.GetCategories(c.Context, userID, householdID, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]uint, len(categories))
+	for _, category := range categories {
+		if _, ok := byName[strings.ToLower(category.Name)]; !ok {
+			byName[strings.ToLower(category.Name)] = category.ID
+		}
+	}
+
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		name := record.Category
+		if name == "" {
+			name = c.String("category")
+		}
+		if name == "" {
+			return nil, cli.Exit(fmt.Sprintf("Line %d has no category; use --category to choose one", record.Line), 1)
+		}
+		id, ok := byName[strings.ToLower(name)]
+		if !ok {
+			if !c.Bool("create-categories") {
+				return nil, cli.Exit(fmt.Sprintf("Line %d: category %q does not exist; use --create-categories to create it", record.Line, name), 1)
+			}
+			category, err := app.categoryService.CreateCategory(c.Context, userID, householdID, &models.Category{Name: name})
+			if err != nil {
+				return nil, err
+			}
+			fmt.Printf("Created category %s\n", category.Name)
+			id = category.ID
+			byName[strings.ToLower(name)] = id
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
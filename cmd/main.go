@@ -1,70 +1,74 @@
-package main
-
-import (
-	"fmt"
-	"os"
-
-	"personal-finance-tracker-api/api"
-	"personal-finance-tracker-api/api/handlers"
-	"personal-finance-tracker-api/config"
-	"personal-finance-tracker-api/internal/repository"
-	"personal-finance-tracker-api/internal/services"
-
-	"github.com/sirupsen/logrus"
-)
-
-// @title Personal Finance Tracker API
-// @version 1.0
-// @description This is a RESTful API for a personal finance tracking application.
-// @termsOfService https://jacquesmurray.site/terms/
-
-// @contact.name Jacques Murray
-// @contact.url https://jacquesmurray.site/support
-// @contact.email support@jacquesmurray.site
-
-// @license.name MIT
-// @license.url https://opensource.org/licenses/MIT
-
-// @host localhost:8080
-// @BasePath /api/v1
-func main() {
-	// Initialize Logrus
-	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetOutput(os.Stdout)
-	logrus.SetLevel(logrus.InfoLevel)
-
-	// Load application configuration
-	cfg := config.New()
-
-	// Initialize database connection
-	db := repository.InitDB(cfg.DatabaseURL)
-
-	// Create repository instance
-	repo := repository.NewGormRepository(db)
-
-	// Create service instances, injecting the repository
-	transactionService := services.NewTransactionService(repo)
-	categoryService := services.NewCategoryService(repo)
-	userService := services.NewUserService(repo)
-
-	// Create handler instances, injecting the services
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
-	categoryHandler := handlers.NewCategoryHandler(categoryService)
-	userHandler := handlers.NewUserHandler(userService)
-
-	// Set up the router, passing all initialized handlers
-	router := api.SetupRouter(transactionHandler, categoryHandler, userHandler)
-
-	// Start the server
-	serverAddr := fmt.Sprintf(":%s", cfg.APIPort)
-	logrus.WithFields(logrus.Fields{
-		"address": serverAddr,
-		"port":    cfg.APIPort,
-	}).Info("Server starting")
-
-	if err := router.Run(serverAddr); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-		}).Fatal("Failed to start server")
-	}
-}
+package main
+
+import (
+	"fmt"
+	"os"
+	_ "time/tzdata" // User time zones must resolve on hosts without a zoneinfo database
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+)
+
+// @title Personal Finance Tracker API
+// @version 1.0
+// @description This is a RESTful API for a personal finance tracking application.
+// @termsOfService https://jacquesmurray.site/terms/
+
+// @contact.name Jacques Murray
+// @contact.url https://jacquesmurray.site/support
+// @contact.email support@jacquesmurray.site
+
+// @license.name MIT
+// @license.url https://opensource.org/licenses/MIT
+
+// @host localhost:8080
+// @BasePath /api/v1
+func main() {
+	// Initialize Logrus
+	logrus.SetFormatter(&logrus.JSONFormatter{})
+	logrus.SetOutput(os.Stdout)
+	logrus.SetLevel(logrus.InfoLevel)
+	// Entries logged with a request's context carry its trace and span IDs
+	logrus.AddHook(tracing.LogHook{})
+
+	// Keep standard output of the administrative commands free for their results
+	if len(os.Args) > 1 && os.Args[1] != "serve" {
+		logrus.SetOutput(os.Stderr)
+	}
+
+	// Load application configuration
+	cfg := config.New()
+
+	if err := newCLI(cfg).Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newCLI creates the command-line interface. Without a command the server is started, so
+// existing deployments keep working; the other commands are for administrators.
+func newCLI(cfg *config.Config) *cli.App {
+	return &cli.App{
+		Name:            "personal-finance-tracker-api",
+		Usage:           "Personal finance tracker API server and administration tool",
+		HideHelpCommand: true,
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 0 {
+				return cli.Exit(fmt.Sprintf("Unknown command %q; run with --help for a list of commands", c.Args().First()), 2)
+			}
+			return serve(cfg)
+		},
+		Commands: []*cli.Command{
+			serveCommand(cfg),
+			migrateCommand(cfg),
+			userCommand(cfg),
+			importCommand(cfg),
+			exportCommand(cfg),
+			purgeDeletedCommand(cfg),
+			seedDemoDataCommand(cfg),
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/migrations"
+	"personal-finance-tracker-api/internal/repository"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gorm.io/gorm"
+)
+
+// newMigrator creates a migrator for the embedded migrations on the database behind db
+func newMigrator(db *gorm.DB) *migrations.Migrator {
+	builtin, err := migrations.Builtin(db.Dialector.Name())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to load migrations")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to access database connection pool")
+	}
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), builtin)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to create migrator")
+	}
+	return migrator
+}
+
+// migrateOnStartup brings the schema up to date before the server starts, or with autoMigrate
+// disabled only checks that it is
+func migrateOnStartup(db *gorm.DB, autoMigrate bool) {
+	migrator := newMigrator(db)
+	ctx := context.Background()
+
+	if autoMigrate {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Fatal("Failed to migrate database schema")
+		}
+		logrus.WithFields(logrus.Fields{
+			"applied": len(applied),
+		}).Info("Database schema is up to date")
+		return
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to check database schema")
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			logrus.WithFields(logrus.Fields{
+				"version": status.Version,
+				"name":    status.Name,
+			}).Fatal("Database schema has pending migrations; run the migrate command or set AUTO_MIGRATE=true")
+		}
+	}
+}
+
+// migrateCommand applies and reverts schema migrations, e.g. before rolling out a new version
+func migrateCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:   "migrate",
+		Usage:  "Apply, revert or list database schema migrations; without a command, applies them",
+		Action: migrateUp(cfg),
+		Subcommands: []*cli.Command{
+			{
+				Name:   "up",
+				Usage:  "Apply all pending migrations",
+				Action: migrateUp(cfg),
+			},
+			{
+				Name:      "down",
+				Usage:     "Revert the last N applied migrations",
+				ArgsUsage: "[N]",
+				Action: func(c *cli.Context) error {
+					steps := 1
+					if c.NArg() > 0 {
+						parsed, err := strconv.Atoi(c.Args().First())
+						if err != nil || parsed < 1 {
+							return cli.Exit("The number of migrations to revert must be a positive integer", 2)
+						}
+						steps = parsed
+					}
+					reverted, err := openMigrator(cfg).Down(c.Context, steps)
+					if err != nil {
+						return fmt.Errorf("reverting migrations: %w", err)
+					}
+					for _, migration := range reverted {
+						fmt.Printf("Reverted %d_%s\n", migration.Version, migration.Name)
+					}
+					if len(reverted) == 0 {
+						fmt.Println("No applied migrations")
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "List migrations and whether they have been applied",
+				Action: func(c *cli.Context) error {
+					statuses, err := openMigrator(cfg).Status(c.Context)
+					if err != nil {
+						return fmt.Errorf("reading migration status: %w", err)
+					}
+					for _, status := range statuses {
+						applied := "pending"
+						if status.AppliedAt != nil {
+							applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+						}
+						fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// migrateUp applies all pending migrations
+func migrateUp(cfg *config.Config) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		applied, err := openMigrator(cfg).Up(c.Context)
+		if err != nil {
+			return fmt.Errorf("applying migrations: %w", err)
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return nil
+	}
+}
+
+// openMigrator connects to the configured database and creates a migrator for it
+func openMigrator(cfg *config.Config) *migrations.Migrator {
+	return newMigrator(repository.InitDB(cfg.DatabaseDriver, cfg.DatabaseURL))
+}
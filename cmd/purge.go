@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"personal-finance-tracker-api/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// purgeDeletedCommand permanently removes soft-deleted records, like the trash purge job of the
+// server but on demand
+func purgeDeletedCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "purge-deleted",
+		Usage: "Permanently delete transactions and categories that have been in the trash for a while",
+		Flags: []cli.Flag{
+			&cli.IntFlag{Name: "older-than-days", Value: cfg.TrashRetentionDays, Usage: "Purge records deleted at least this many days ago; 0 empties the trash", DefaultText: "TRASH_RETENTION_DAYS"},
+		},
+		Action: func(c *cli.Context) error {
+			days := c.Int("older-than-days")
+			if days < 0 {
+				return cli.Exit("--older-than-days must not be negative", 2)
+			}
+			app := openApplication(cfg)
+			result, err := app.trashService.PurgeExpired(c.Context, time.Now().AddDate(0, 0, -days))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Purged %d transactions and %d categories\n", result.Transactions, result.Categories)
+			return nil
+		},
+	}
+}
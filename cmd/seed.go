@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+// demoTemplate is the category template the demo data is written for
+const demoTemplate = "basic"
+
+// demoExpense describes a kind of expense the demo data contains a few of every month
+type demoExpense struct {
+	category     string
+	descriptions []string
+	minPerMonth  int
+	maxPerMonth  int
+	minAmount    float64
+	maxAmount    float64
+}
+
+// demoExpenses are the variable expenses of the demo data; the fixed ones are added separately
+var demoExpenses = []demoExpense{
+	{"Groceries", []string{"Supermarket", "Farmers market", "Corner shop", "Bakery"}, 4, 7, 12, 140},
+	{"Transport", []string{"Fuel", "Bus pass top-up", "Taxi", "Parking"}, 2, 5, 4, 70},
+	{"Entertainment", []string{"Cinema", "Streaming subscription", "Concert tickets", "Restaurant"}, 1, 4, 9, 95},
+	{"Health", []string{"Pharmacy", "Dentist", "Gym membership"}, 0, 2, 15, 120},
+}
+
+// seedDemoDataCommand creates a user with a few months of realistic transactions, e.g. for
+// trying out the API or a client against it
+func seedDemoDataCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "seed-demo-data",
+		Usage: "Create a demo user with categories and a few months of transactions",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{Name: "user", Value: "demo", Usage: "Username of the demo user, who must not exist yet"},
+			&cli.IntFlag{Name: "months", Value: 6, Usage: "Number of months of transactions, up to today"},
+			&cli.Uint64Flag{Name: "seed", Value: 1, Usage: "Seed of the random amounts and dates; the same seed gives the same data"},
+		}, passwordFlags...),
+		Action: func(c *cli.Context) error {
+			months := c.Int("months")
+			if months < 1 {
+				return cli.Exit("--months must be at least 1", 2)
+			}
+			password, generated, err := passwordFromFlags(c)
+			if err != nil {
+				return err
+			}
+
+			app := openApplication(cfg)
+			user, err := app.userService.RegisterUser(c.Context, c.String("user"), password, "")
+			if err != nil {
+				return err
+			}
+			if _, err := app.categoryService.ApplyCategoryTemplate(c.Context, user.ID, 0, demoTemplate); err != nil {
+				return err
+			}
+			categories, err := app.categoryService.GetCategories(c.Context, user.ID, 0, 0, 0, nil)
+			if err != nil {
+				return err
+			}
+			categoryIDs := make(map[string]uint, len(categories))
+			for _, category := range categories {
+				categoryIDs[category.Name] = category.ID
+			}
+
+			transactions := demoTransactions(rand.New(rand.NewPCG(c.Uint64("seed"), 0)), categoryIDs, months, time.Now())
+			if _, err := app.transactionService.ImportTransactions(c.Context, user.ID, 0, transactions); err != nil {
+				return err
+			}
+
+			fmt.Printf("Created demo user %s with %d categories and %d transactions\n", user.Username, len(categories), len(transactions))
+			if generated {
+				fmt.Printf("Password: %s\n", password)
+			}
+			return nil
+		},
+	}
+}
+
+// demoTransactions generates the transactions of the given number of months up to today: a
+// salary, rent, utilities and a savings transfer every month, and a random number of
+// variable expenses. Transactions older than a week are cleared.
+func demoTransactions(rng *rand.Rand, categoryIDs map[string]uint, months int, now time.Time) []models.Transaction {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	firstMonth := time.Date(today.Year(), today.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+
+	var transactions []models.Transaction
+	add := func(date time.Time, category, description string, transactionType models.TransactionType, amount float64) {
+		if date.After(today) {
+			return
+		}
+		status := models.StatusCleared
+		if today.Sub(date) < 7*24*time.Hour {
+			status = models.StatusPending
+		}
+		transactions = append(transactions, models.Transaction{
+			Description: description,
+			Amount:      math.Round(amount*100) / 100,
+			Type:        transactionType,
+			Date:        date,
+			Status:      status,
+			CategoryID:  categoryIDs[category],
+		})
+	}
+	between := func(min, max float64) float64 {
+		return min + rng.Float64()*(max-min)
+	}
+
+	for month := firstMonth; !month.After(today); month = month.AddDate(0, 1, 0) {
+		daysInMonth := month.AddDate(0, 1, -1).Day()
+		day := func(d int) time.Time { return month.AddDate(0, 0, d-1) }
+
+		add(day(1), "Salary", "Monthly salary", models.Income, 3200)
+		add(day(3), "Rent", "Rent", models.Expense, 1150)
+		add(day(12), "Utilities", "Electricity and water", models.Expense, between(80, 160))
+		add(day(15), "Savings", "Transfer to savings account", models.Expense, 300)
+		if rng.IntN(3) == 0 {
+			add(day(1+rng.IntN(daysInMonth)), "Other Income", "Sold items online", models.Income, between(20, 250))
+		}
+		for _, expense := range demoExpenses {
+			count := expense.minPerMonth + rng.IntN(expense.maxPerMonth-expense.minPerMonth+1)
+			for i := 0; i < count; i++ {
+				description := expense.descriptions[rng.IntN(len(expense.descriptions))]
+				add(day(1+rng.IntN(daysInMonth)), expense.category, description, models.Expense, between(expense.minAmount, expense.maxAmount))
+			}
+		}
+	}
+	return transactions
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"personal-finance-tracker-api/api"
+	"personal-finance-tracker-api/api/handlers"
+	"personal-finance-tracker-api/api/middleware"
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/health"
+	"personal-finance-tracker-api/internal/jobs"
+	"personal-finance-tracker-api/internal/lifecycle"
+	"personal-finance-tracker-api/internal/metrics"
+	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"gorm.io/gorm"
+)
+
+// serveCommand starts the HTTP server; it is also what runs when no command is given
+func serveCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Start the HTTP API server",
+		Action: func(c *cli.Context) error {
+			return serve(cfg)
+		},
+	}
+}
+
+// serve brings the schema up to date, starts the background jobs and serves the API until
+// SIGINT or SIGTERM, then drains in-flight requests and stops the jobs within the shutdown timeout
+func serve(cfg *config.Config) error {
+	tracerProvider := setupTracing(cfg)
+
+	// Initialize database connection and bring the schema up to date
+	db := repository.InitDB(cfg.DatabaseDriver, cfg.DatabaseURL)
+	migrateOnStartup(db, cfg.AutoMigrate)
+	registerDBMetrics(db)
+	registerDBTracing(db)
+
+	app := newApplication(cfg, db, newOIDCProvider(cfg))
+
+	// Create handler instances, injecting the services
+	transactionHandler := handlers.NewTransactionHandler(app.transactionService)
+	categoryHandler := handlers.NewCategoryHandler(app.categoryService)
+	userHandler := handlers.NewUserHandler(app.userService, app.tokenService)
+	reconciliationHandler := handlers.NewReconciliationHandler(app.reconciliationService)
+	trashHandler := handlers.NewTrashHandler(app.trashService)
+	jwksHandler := handlers.NewJWKSHandler(app.signingKeys)
+	apiTokenHandler := handlers.NewAPITokenHandler(app.apiTokenService)
+	householdHandler := handlers.NewHouseholdHandler(app.householdService)
+	auditHandler := handlers.NewAuditHandler(app.auditService)
+	readiness := newReadinessChecker(cfg, db)
+	healthHandler := handlers.NewHealthHandler(readiness)
+	metricsHandler := handlers.NewMetricsHandler(cfg.MetricsToken)
+	publicMetricsHandler := metricsHandler
+	if cfg.MetricsAddr != "" {
+		publicMetricsHandler = nil
+	}
+
+	// Set up the router, passing all initialized handlers
+	router := api.SetupRouter(
+		transactionHandler,
+		categoryHandler,
+		userHandler,
+		reconciliationHandler,
+		trashHandler,
+		jwksHandler,
+		apiTokenHandler,
+		householdHandler,
+		auditHandler,
+		healthHandler,
+		publicMetricsHandler,
+		middleware.AuthMiddleware(app.tokenService, app.apiTokenService),
+	)
+
+	// Components start in this order and stop in reverse: the database pool closes after the
+	// server has drained its requests, the messages of those requests have been sent and the jobs
+	// have finished their runs, and the tracer provider flushes the spans of all of them last
+	manager := lifecycle.NewManager()
+	manager.Register("tracer provider", tracerProvider)
+	manager.Register("database", lifecycle.Hook{OnStop: func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}})
+	registerJobs(manager, readiness, cfg, app)
+	manager.Register("user notifier", app.userNotifier)
+	var metricsErrs <-chan error // Stays nil, and never ready, without a metrics server
+	if cfg.MetricsAddr != "" {
+		metricsServer := lifecycle.NewHTTPServer(&http.Server{
+			Addr:         cfg.MetricsAddr,
+			Handler:      api.SetupMetricsRouter(metricsHandler),
+			ReadTimeout:  cfg.ServerReadTimeout,
+			WriteTimeout: cfg.ServerWriteTimeout,
+			IdleTimeout:  cfg.ServerIdleTimeout,
+		})
+		manager.Register("metrics server", metricsServer)
+		metricsErrs = metricsServer.Err()
+	} else if cfg.MetricsToken == "" {
+		logrus.Warn("Metrics are not served; set METRICS_ADDR to serve them on a private address or METRICS_TOKEN to serve them with a bearer token")
+	}
+
+	serverAddr := fmt.Sprintf(":%s", cfg.APIPort)
+	server := lifecycle.NewHTTPServer(&http.Server{
+		Addr:         serverAddr,
+		Handler:      router,
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
+	})
+	manager.Register("http server", server)
+
+	// A first SIGINT or SIGTERM starts a graceful shutdown; a second one exits immediately
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if err := manager.Start(context.Background()); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"address": serverAddr,
+		"port":    cfg.APIPort,
+	}).Info("Server started")
+
+	var serveErr error
+	select {
+	case <-signals.Done():
+		logrus.Info("Shutdown signal received, draining requests and stopping background jobs")
+	case serveErr = <-server.Err():
+		logrus.WithFields(logrus.Fields{
+			"error": serveErr.Error(),
+		}).Error("Server stopped unexpectedly, shutting down")
+	case serveErr = <-metricsErrs:
+		logrus.WithFields(logrus.Fields{
+			"error": serveErr.Error(),
+		}).Error("Metrics server stopped unexpectedly, shutting down")
+	}
+	stopSignals()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := manager.Stop(ctx); err != nil {
+		return errors.Join(serveErr, err)
+	}
+	logrus.Info("Server stopped")
+	return serveErr
+}
+
+// registerDBMetrics times every GORM operation and exports the connection pool statistics
+func registerDBMetrics(db *gorm.DB) {
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to register database metrics")
+	}
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = metrics.RegisterDBStats(sqlDB)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to register database pool metrics")
+	}
+}
+
+// setupTracing configures the exporter of the request, service and query spans
+func setupTracing(cfg *config.Config) *tracing.Provider {
+	provider, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingExporter,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to set up tracing")
+	}
+	if cfg.TracingExporter != tracing.ExporterNone {
+		logrus.WithFields(logrus.Fields{
+			"exporter":    cfg.TracingExporter,
+			"sampleRatio": cfg.TracingSampleRatio,
+		}).Info("Tracing enabled")
+	}
+	return provider
+}
+
+// registerDBTracing records a span for every GORM operation
+func registerDBTracing(db *gorm.DB) {
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Fatal("Failed to register database tracing")
+	}
+}
+
+// newReadinessChecker creates the checker behind /readyz with the checks of the database; the
+// background jobs add theirs when they are registered
+func newReadinessChecker(cfg *config.Config, db *gorm.DB) *health.Checker {
+	readiness := health.NewChecker(cfg.HealthCheckTimeout)
+	readiness.Register("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	migrator := newMigrator(db)
+	readiness.Register("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations pending, the first is %d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	})
+	return readiness
+}
+
+// job is a background job run by the lifecycle manager and checked for readiness
+type job interface {
+	lifecycle.Component
+	Health(ctx context.Context) error
+}
+
+// registerJobs registers the background jobs that are enabled by the configuration
+func registerJobs(manager *lifecycle.Manager, readiness *health.Checker, cfg *config.Config, app *application) {
+	register := func(name string, j job) {
+		manager.Register(name, j)
+		readiness.Register(name, j.Health)
+	}
+
+	if cfg.TrashRetentionDays > 0 && cfg.TrashPurgeInterval > 0 {
+		retention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+		register("trash purge job", jobs.NewTrashPurgeJob(app.trashService, retention, cfg.TrashPurgeInterval))
+	}
+	if cfg.TokenCleanupInterval > 0 {
+		register("token cleanup job", jobs.NewTokenCleanupJob(app.tokenService, cfg.TokenCleanupInterval))
+		register("login attempt cleanup job", jobs.NewLoginAttemptCleanupJob(app.loginLimiter, cfg.TokenCleanupInterval))
+	}
+	if cfg.AccountDeletionInterval > 0 {
+		register("account deletion job", jobs.NewAccountDeletionJob(app.userService, cfg.AccountDeletionInterval))
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"personal-finance-tracker-api/config"
+	"personal-finance-tracker-api/internal/models"
+
+	"github.com/urfave/cli/v2"
+)
+
+// generatedPasswordLength is the length of passwords generated when none is given
+const generatedPasswordLength = 20
+
+// passwordFlags choose where the password of user create and user reset-password comes from
+var passwordFlags = []cli.Flag{
+	&cli.StringFlag{Name: "password", Usage: "New password; visible in the process list and shell history"},
+	&cli.BoolFlag{Name: "password-stdin", Usage: "Read the new password from the first line of standard input"},
+}
+
+// userCommand manages user accounts
+func userCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "user",
+		Usage: "Manage user accounts",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Create a user with a personal household; a password is generated unless one is given",
+				ArgsUsage: "USERNAME",
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{Name: "email", Usage: "Email address, used for password resets"},
+				}, passwordFlags...),
+				Action: func(c *cli.Context) error {
+					username, err := usernameArg(c)
+					if err != nil {
+						return err
+					}
+					password, generated, err := passwordFromFlags(c)
+					if err != nil {
+						return err
+					}
+					app := openApplication(cfg)
+					user, err := app.userService.RegisterUser(c.Context, username, password, c.String("email"))
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Created user %s with ID %d\n", user.Username, user.ID)
+					if generated {
+						fmt.Printf("Password: %s\n", password)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List users",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "limit", Usage: "Maximum number of users to list; 0 lists all"},
+					&cli.IntFlag{Name: "offset", Usage: "Number of users to skip"},
+				},
+				Action: func(c *cli.Context) error {
+					app := openApplication(cfg)
+					users, err := app.userService.ListUsers(c.Context, c.Int("limit"), c.Int("offset"))
+					if err != nil {
+						return err
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tCREATED\tSTATUS")
+					for _, user := range users {
+						email := ""
+						if user.Email != nil {
+							email = *user.Email
+						}
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", user.ID, user.Username, email, user.CreatedAt.Format(time.RFC3339), userStatus(&user))
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:      "disable",
+				Usage:     "Prevent a user from signing in and sign out all their sessions",
+				ArgsUsage: "USERNAME",
+				Action: func(c *cli.Context) error {
+					return changeUser(c, cfg, func(app *application, user *models.User) error {
+						if _, err := app.userService.DisableUser(c.Context, user.ID); err != nil {
+							return err
+						}
+						fmt.Printf("Disabled user %s\n", user.Username)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "enable",
+				Usage:     "Let a disabled user sign in again",
+				ArgsUsage: "USERNAME",
+				Action: func(c *cli.Context) error {
+					return changeUser(c, cfg, func(app *application, user *models.User) error {
+						if _, err := app.userService.EnableUser(c.Context, user.ID); err != nil {
+							return err
+						}
+						fmt.Printf("Enabled user %s\n", user.Username)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "reset-password",
+				Usage:     "Set a new password, sign out all sessions and lift any login lockout; a password is generated unless one is given",
+				ArgsUsage: "USERNAME",
+				Flags:     passwordFlags,
+				Action: func(c *cli.Context) error {
+					password, generated, err := passwordFromFlags(c)
+					if err != nil {
+						return err
+					}
+					return changeUser(c, cfg, func(app *application, user *models.User) error {
+						if err := app.userService.SetPassword(c.Context, user.ID, password); err != nil {
+							return err
+						}
+						fmt.Printf("Reset the password of user %s\n", user.Username)
+						if generated {
+							fmt.Printf("Password: %s\n", password)
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// changeUser looks up the user named by the first argument and applies change to them
+func changeUser(c *cli.Context, cfg *config.Config, change func(app *application, user *models.User) error) error {
+	username, err := usernameArg(c)
+	if err != nil {
+		return err
+	}
+	app := openApplication(cfg)
+	user, err := app.userService.GetUserByUsername(c.Context, username)
+	if err != nil {
+		return err
+	}
+	return change(app, user)
+}
+
+// usernameArg returns the username given as the only argument of a command
+func usernameArg(c *cli.Context) (string, error) {
+	if c.NArg() != 1 {
+		return "", cli.Exit(fmt.Sprintf("Usage: %s [command options] %s", c.Command.HelpName, c.Command.ArgsUsage), 2)
+	}
+	return c.Args().First(), nil
+}
+
+// userStatus describes whether a user may sign in
+func userStatus(user *models.User) string {
+	if user.DisabledAt != nil {
+		return "disabled since " + user.DisabledAt.Format(time.RFC3339)
+	}
+	return "active"
+}
+
+// passwordFromFlags returns the password given by the --password or --password-stdin flag, or
+// a generated one, reporting which
+func passwordFromFlags(c *cli.Context) (string, bool, error) {
+	if c.IsSet("password") && c.Bool("password-stdin") {
+		return "", false, cli.Exit("Use either --password or --password-stdin", 2)
+	}
+	if c.IsSet("password") {
+		return c.String("password"), false, nil
+	}
+	if c.Bool("password-stdin") {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", false, fmt.Errorf("reading password from standard input: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), false, nil
+	}
+	password, err := generatePassword(generatedPasswordLength)
+	return password, true, err
+}
+
+// generatePassword returns a random password with upper and lower case letters, digits and
+// symbols, so that it meets any configurable password policy of that length
+func generatePassword(length int) (string, error) {
+	classes := []string{
+		"ABCDEFGHJKLMNPQRSTUVWXYZ",
+		"abcdefghijkmnopqrstuvwxyz",
+		"23456789",
+		"!#%+-=?@^_",
+	}
+	alphabet := strings.Join(classes, "")
+	if length < len(classes) {
+		return "", errors.New("password length is too short for every character class")
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		// Every class appears at least once; the remaining characters come from all of them
+		from := alphabet
+		if i < len(classes) {
+			from = classes[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(from))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = from[n.Int64()]
+	}
+
+	// Shuffle so that the guaranteed characters are not always at the start
+	for i := len(password) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
@@ -1,75 +1,407 @@
-package config
-
-import (
-	"fmt"
-	"os"
-
-	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
-)
-
-// Config holds all configuration for the application
-type Config struct {
-	APIPort     string
-	DatabaseURL string
-	JWTSecret   string
-}
-
-// Global variable to hold the loaded configuration
-var appConfig *Config
-
-// New loads configuration from environment variables
-func New() *Config {
-	if appConfig != nil {
-		return appConfig
-	}
-
-	if err := godotenv.Load(); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-		}).Warn("Error loading .env file. Environment variables will be used directly.")
-	}
-
-	dbUser := getEnv("DB_USER", "postgres")
-	dbPassword := getEnv("DB_PASSWORD", "password")
-	dbHost := getEnv("DB_HOST", "localhost")
-	dbPort := getEnv("DB_PORT", "5432")
-	dbName := getEnv("DB_NAME", "finance_tracker")
-	dbSSLMode := getEnv("DB_SSLMODE", "disable")
-
-	// Create the database connection string
-	databaseUrl := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		dbHost, dbUser, dbPassword, dbName, dbPort, dbSSLMode)
-
-	appConfig = &Config{
-		APIPort:     getEnv("API_PORT", "8080"),
-		DatabaseURL: databaseUrl,
-		JWTSecret:   getEnv("JWT_SECRET", "supersecretjwtkey"),
-	}
-
-	// Warn if using default JWT secret in production
-	if appConfig.JWTSecret == "supersecretjwtkey" {
-		logrus.Warn("Using default JWT_SECRET. Please set a strong, unique JWT_SECRET environment variable in production.")
-	}
-
-	return appConfig
-}
-
-// getEnv retrieves and environment variable or returns a default value
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
-	}
-	logrus.WithFields(logrus.Fields{
-		"key": key,
-	}).Info("Defaulting to fallback value for environment variable")
-	return fallback
-}
-
-// GetJWTSecret provides access to the loaded JWT secret
-func GetJWTSecret() string {
-	if appConfig == nil {
-		logrus.Fatal("Configuration not loaded. Call config.New() first.")
-	}
-	return appConfig.JWTSecret
-}
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// Config holds all configuration for the application
+type Config struct {
+	APIPort string
+
+	// HTTP server timeouts: reading a whole request, writing a response and keeping an idle
+	// keep-alive connection open. On SIGINT or SIGTERM, in-flight requests and background jobs
+	// get ShutdownTimeout to finish before the server exits.
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+	ShutdownTimeout    time.Duration
+
+	// HealthCheckTimeout limits each check of the readiness probe
+	HealthCheckTimeout time.Duration
+
+	// MetricsAddr, when set, is a separate listen address such as "127.0.0.1:9090" that serves
+	// /metrics instead of the API port. MetricsToken is the bearer token Prometheus must send to
+	// scrape /metrics; the API port only serves metrics with one, so that they are never public.
+	MetricsAddr  string
+	MetricsToken string
+
+	// Tracing exporter: "none", "otlp" (configured by the standard OTEL_EXPORTER_OTLP_*
+	// variables) or "stdout" for local testing. TracingSampleRatio is the fraction of new traces
+	// that are recorded; requests that arrive with a sampled trace context are always recorded.
+	TracingExporter    string
+	TracingServiceName string
+	TracingSampleRatio float64
+
+	// DatabaseDriver is "postgres" or "sqlite". DatabaseURL is the PostgreSQL connection string,
+	// or for SQLite the path of the database file.
+	DatabaseDriver string
+	DatabaseURL    string
+
+	JWTSecret string
+
+	// JWT_SECRET before it was last changed, and the RFC 3339 time it was changed. Tokens signed
+	// with it are accepted until JWTKeyGracePeriod after that time, so users stay signed in.
+	JWTPreviousSecret          string
+	JWTPreviousSecretRetiredAt string
+
+	// Apply pending schema migrations on startup; when disabled the server refuses to start
+	// until they have been applied with the migrate command
+	AutoMigrate bool
+
+	// Asymmetric JWT signing keys by kid. Without key files tokens are signed with JWTSecret (HS256).
+	// Tokens signed with a retired key are accepted until JWTKeyGracePeriod after its retirement.
+	JWTKeyFiles       map[string]string
+	JWTActiveKeyID    string
+	JWTRetiredKeys    map[string]string
+	JWTKeyGracePeriod time.Duration
+
+	// Access tokens are short-lived; sessions are extended with rotating refresh tokens.
+	// Expired tokens are removed every TokenCleanupInterval.
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	TokenCleanupInterval time.Duration
+
+	// Brute-force protection of the login: LoginAttemptStore is "database" (shared by all
+	// instances) or "memory". After LoginFreeAttempts failures each further failure doubles a
+	// delay starting at LoginBackoffBase up to LoginBackoffMax; reaching a lockout threshold
+	// blocks the username or IP for LoginLockoutDuration. Failures expire after LoginFailureWindow.
+	LoginAttemptStore       string
+	LoginFreeAttempts       int
+	LoginBackoffBase        time.Duration
+	LoginBackoffMax         time.Duration
+	LoginLockoutThreshold   int
+	LoginIPLockoutThreshold int
+	LoginLockoutDuration    time.Duration
+	LoginFailureWindow      time.Duration
+
+	// Two-factor authentication: issuer shown in authenticator apps and the time
+	// allowed to enter a code after the password step of a login
+	TOTPIssuer        string
+	LoginChallengeTTL time.Duration
+
+	// Password policy applied on registration, password change and reset. PasswordBreachedListFile
+	// optionally names a file of SHA-1 hashes of known breached passwords, one per line.
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequireUpper     bool
+	PasswordRequireLower     bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	PasswordBreachedListFile string
+
+	// Password hashing: PasswordHashAlgorithm ("argon2id" or "bcrypt") hashes new passwords; hashes of
+	// the other algorithm or with outdated parameters still verify and are upgraded on the next login
+	PasswordHashAlgorithm string
+	Argon2Memory          int // KiB
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	Argon2SaltLength      int
+	Argon2KeyLength       int
+	BcryptCost            int
+
+	// Password reset links are valid for PasswordResetTTL. PasswordResetURL is the link sent to
+	// users, with "{token}" replaced by the reset token; empty sends the bare token.
+	PasswordResetTTL time.Duration
+	PasswordResetURL string
+
+	// Household invitations are valid for HouseholdInvitationTTL. HouseholdInvitationURL is the link
+	// sent to invited email addresses, with "{token}" replaced by the invitation token.
+	HouseholdInvitationTTL time.Duration
+	HouseholdInvitationURL string
+
+	// Account deletion requests must be confirmed within AccountDeletionConfirmTTL and are carried
+	// out AccountDeletionGracePeriod after confirmation, checked every AccountDeletionInterval.
+	AccountDeletionConfirmTTL  time.Duration
+	AccountDeletionGracePeriod time.Duration
+	AccountDeletionInterval    time.Duration
+
+	// Notifier delivers messages such as password reset links: "log" writes them to the
+	// application log, "smtp" sends email through the SMTP server below
+	Notifier     string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// OpenID Connect single sign-on, enabled when OIDCIssuerURL is set. OIDCRedirectURL must point
+	// at /api/v1/users/oidc/callback and be registered with the provider. Unknown identities are
+	// linked to the user with the same verified email when OIDCLinkByEmail is set, and otherwise
+	// provisioned as new users when OIDCAutoProvision is set.
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        []string
+	OIDCLoginTTL      time.Duration
+	OIDCAutoProvision bool
+	OIDCLinkByEmail   bool
+
+	// Soft-deleted records older than TrashRetentionDays are purged permanently
+	// every TrashPurgeInterval. A retention of 0 disables the purge job.
+	TrashRetentionDays int
+	TrashPurgeInterval time.Duration
+
+	// Category template applied to newly registered users; empty disables seeding
+	DefaultCategoryTemplate string
+}
+
+// Global variable to hold the loaded configuration
+var appConfig *Config
+
+// New loads configuration from environment variables
+func New() *Config {
+	if appConfig != nil {
+		return appConfig
+	}
+
+	if err := godotenv.Load(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Error loading .env file. Environment variables will be used directly.")
+	}
+
+	databaseDriver := getEnv("DB_DRIVER", "postgres")
+	dbUser := getEnv("DB_USER", "postgres")
+	dbPassword := getEnv("DB_PASSWORD", "password")
+	dbHost := getEnv("DB_HOST", "localhost")
+	dbPort := getEnv("DB_PORT", "5432")
+	dbName := getEnv("DB_NAME", "finance_tracker")
+	dbSSLMode := getEnv("DB_SSLMODE", "disable")
+
+	// Create the database connection string
+	databaseUrl := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		dbHost, dbUser, dbPassword, dbName, dbPort, dbSSLMode)
+	if databaseDriver == "sqlite" {
+		databaseUrl = getEnv("DB_PATH", "finance_tracker.db")
+	}
+
+	appConfig = &Config{
+		APIPort: getEnv("API_PORT", "8080"),
+
+		ServerReadTimeout:  getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second),
+		ServerWriteTimeout: getEnvDuration("SERVER_WRITE_TIMEOUT", 60*time.Second),
+		ServerIdleTimeout:  getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute),
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		HealthCheckTimeout: getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+
+		MetricsAddr:  getEnv("METRICS_ADDR", ""),
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		TracingExporter:    getEnv("TRACING_EXPORTER", "none"),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "personal-finance-tracker-api"),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1.0),
+
+		DatabaseDriver: databaseDriver,
+		DatabaseURL:    databaseUrl,
+
+		JWTSecret:                  getEnv("JWT_SECRET", "supersecretjwtkey"),
+		JWTPreviousSecret:          getEnv("JWT_PREVIOUS_SECRET", ""),
+		JWTPreviousSecretRetiredAt: getEnv("JWT_PREVIOUS_SECRET_RETIRED_AT", ""),
+
+		AutoMigrate: getEnvBool("AUTO_MIGRATE", true),
+
+		JWTKeyFiles:       getEnvMap("JWT_KEY_FILES"),
+		JWTActiveKeyID:    getEnv("JWT_ACTIVE_KEY_ID", ""),
+		JWTRetiredKeys:    getEnvMap("JWT_RETIRED_KEYS"),
+		JWTKeyGracePeriod: getEnvDuration("JWT_KEY_GRACE_PERIOD", 24*time.Hour),
+
+		AccessTokenTTL:       getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		TokenCleanupInterval: getEnvDuration("TOKEN_CLEANUP_INTERVAL", time.Hour),
+
+		LoginAttemptStore:       getEnv("LOGIN_ATTEMPT_STORE", "database"),
+		LoginFreeAttempts:       getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+		LoginBackoffBase:        getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:         getEnvDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginLockoutThreshold:   getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginIPLockoutThreshold: getEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 50),
+		LoginLockoutDuration:    getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginFailureWindow:      getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+
+		TOTPIssuer:        getEnv("TOTP_ISSUER", "Personal Finance Tracker"),
+		LoginChallengeTTL: getEnvDuration("LOGIN_CHALLENGE_TTL", 5*time.Minute),
+
+		PasswordMinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireUpper:     getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:     getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordBreachedListFile: getEnv("PASSWORD_BREACHED_LIST_FILE", ""),
+
+		PasswordHashAlgorithm: getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2Memory:          getEnvInt("ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:      getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:     getEnvInt("ARGON2_PARALLELISM", 4),
+		Argon2SaltLength:      getEnvInt("ARGON2_SALT_LENGTH", 16),
+		Argon2KeyLength:       getEnvInt("ARGON2_KEY_LENGTH", 32),
+		BcryptCost:            getEnvInt("BCRYPT_COST", 10),
+
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", ""),
+
+		HouseholdInvitationTTL: getEnvDuration("HOUSEHOLD_INVITATION_TTL", 7*24*time.Hour),
+		HouseholdInvitationURL: getEnv("HOUSEHOLD_INVITATION_URL", ""),
+
+		AccountDeletionConfirmTTL:  getEnvDuration("ACCOUNT_DELETION_CONFIRM_TTL", 15*time.Minute),
+		AccountDeletionGracePeriod: getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour),
+		AccountDeletionInterval:    getEnvDuration("ACCOUNT_DELETION_INTERVAL", time.Hour),
+
+		Notifier:     getEnv("NOTIFIER", "log"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 25),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@localhost"),
+
+		OIDCIssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/users/oidc/callback"),
+		OIDCScopes:        strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+		OIDCLoginTTL:      getEnvDuration("OIDC_LOGIN_TTL", 10*time.Minute),
+		OIDCAutoProvision: getEnvBool("OIDC_AUTO_PROVISION", true),
+		OIDCLinkByEmail:   getEnvBool("OIDC_LINK_BY_EMAIL", false),
+
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", 24*time.Hour),
+
+		DefaultCategoryTemplate: getEnv("DEFAULT_CATEGORY_TEMPLATE", "basic"),
+	}
+
+	// Warn if using default JWT secret in production
+	if len(appConfig.JWTKeyFiles) == 0 && appConfig.JWTSecret == "supersecretjwtkey" {
+		logrus.Warn("Using default JWT_SECRET. Please set a strong, unique JWT_SECRET environment variable in production.")
+	}
+
+	return appConfig
+}
+
+// getEnv retrieves and environment variable or returns a default value
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	logrus.WithFields(logrus.Fields{
+		"key": key,
+	}).Info("Defaulting to fallback value for environment variable")
+	return fallback
+}
+
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key": key,
+		}).Info("Defaulting to fallback value for environment variable")
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"key":   key,
+			"value": value,
+		}).Warn("Invalid integer in environment variable, using fallback value")
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvFloat retrieves a floating-point environment variable or returns a default value
+func getEnvFloat(key string, fallback float64) float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key": key,
+		}).Info("Defaulting to fallback value for environment variable")
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"key":   key,
+			"value": value,
+		}).Warn("Invalid number in environment variable, using fallback value")
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvDuration retrieves a duration environment variable (e.g. "15m", "24h") or returns a default value
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key": key,
+		}).Info("Defaulting to fallback value for environment variable")
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"key":   key,
+			"value": value,
+		}).Warn("Invalid duration in environment variable, using fallback value")
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvBool retrieves a boolean environment variable (e.g. "true", "1", "false") or returns a default value
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key": key,
+		}).Info("Defaulting to fallback value for environment variable")
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"key":   key,
+			"value": value,
+		}).Warn("Invalid boolean in environment variable, using fallback value")
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvMap retrieves a comma-separated list of key=value pairs (e.g. "k1=a,k2=b")
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	value, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(value) == "" {
+		return result
+	}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || k == "" {
+			logrus.WithFields(logrus.Fields{
+				"key":  key,
+				"pair": pair,
+			}).Warn("Ignoring malformed key=value pair in environment variable")
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// GetJWTSecret provides access to the loaded JWT secret
+func GetJWTSecret() string {
+	if appConfig == nil {
+		logrus.Fatal("Configuration not loaded. Call config.New() first.")
+	}
+	return appConfig.JWTSecret
+}
@@ -0,0 +1,242 @@
+// Package auth contains the cryptographic building blocks of authentication:
+// JWT signing keys and their publication as a JSON Web Key Set.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultHMACKeyID is the key ID of the shared-secret key used when no key files are configured
+const DefaultHMACKeyID = "default"
+
+// SigningKey is a single JWT key identified by its kid
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{} // nil for verify-only keys
+	verifyKey interface{}
+	RetiredAt *time.Time // retired keys only verify, and only until the grace period ends
+}
+
+// CanSign reports whether the private part of the key is available
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeySetConfig describes where to load signing keys from
+type KeySetConfig struct {
+	KeyFiles    map[string]string // kid => path of a PEM encoded private or public key
+	ActiveKeyID string            // kid used to sign new tokens
+	RetiredKeys map[string]string // kid => RFC 3339 time the key was retired
+	GracePeriod time.Duration     // how long tokens signed with a retired key stay valid
+	HMACSecret  string            // fallback HS256 secret when no key files are configured
+
+	// Shared secret HMACSecret replaced, and the RFC 3339 time it was replaced. Tokens signed
+	// with it stay valid for the grace period, like those of a retired key.
+	PreviousHMACSecret          string
+	PreviousHMACSecretRetiredAt string
+}
+
+// KeySet holds every key that may verify tokens and the one key that signs them
+type KeySet struct {
+	keys         map[string]*SigningKey
+	active       *SigningKey
+	previousHMAC *SigningKey // retired shared secret, which shares the kid of the current one
+	gracePeriod  time.Duration
+	now          func() time.Time
+}
+
+// LoadKeySet loads the signing keys described by cfg.
+// Without key files it falls back to an HS256 key built from cfg.HMACSecret, and while it is
+// being rotated one built from cfg.PreviousHMACSecret that only verifies.
+func LoadKeySet(cfg KeySetConfig) (*KeySet, error) {
+	ks := &KeySet{
+		keys:        make(map[string]*SigningKey),
+		gracePeriod: cfg.GracePeriod,
+		now:         time.Now,
+	}
+
+	if len(cfg.KeyFiles) == 0 {
+		if cfg.HMACSecret == "" {
+			return nil, fmt.Errorf("no JWT key files configured and the JWT secret is empty")
+		}
+		key := &SigningKey{
+			ID:        DefaultHMACKeyID,
+			Method:    jwt.SigningMethodHS256,
+			signKey:   []byte(cfg.HMACSecret),
+			verifyKey: []byte(cfg.HMACSecret),
+		}
+		ks.keys[key.ID] = key
+		ks.active = key
+
+		if cfg.PreviousHMACSecret != "" {
+			retiredAt, err := time.Parse(time.RFC3339, cfg.PreviousHMACSecretRetiredAt)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retirement time for the previous JWT secret: %w", err)
+			}
+			ks.previousHMAC = &SigningKey{
+				ID:        DefaultHMACKeyID,
+				Method:    jwt.SigningMethodHS256,
+				verifyKey: []byte(cfg.PreviousHMACSecret),
+				RetiredAt: &retiredAt,
+			}
+		}
+		return ks, nil
+	}
+
+	for kid, path := range cfg.KeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key %q: %w", kid, err)
+		}
+		key, err := ParsePEMKey(kid, data)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[kid] = key
+	}
+
+	for kid, retiredAt := range cfg.RetiredKeys {
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("retired key %q is not configured", kid)
+		}
+		t, err := time.Parse(time.RFC3339, retiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retirement time for key %q: %w", kid, err)
+		}
+		key.RetiredAt = &t
+	}
+
+	active, ok := ks.keys[cfg.ActiveKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not configured", cfg.ActiveKeyID)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active key %q has no private key", cfg.ActiveKeyID)
+	}
+	if active.RetiredAt != nil {
+		return nil, fmt.Errorf("active key %q is retired", cfg.ActiveKeyID)
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+// ParsePEMKey parses a PEM encoded RSA, ECDSA (P-256) or Ed25519 key.
+// The signing algorithm is inferred from the key type; public keys yield verify-only keys.
+func ParsePEMKey(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", kid)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %q has unsupported PEM block type %q", kid, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %q: %w", kid, err)
+	}
+
+	key := &SigningKey{ID: kid}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.signKey = signer
+		parsed = signer.Public()
+	}
+
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("key %q uses an unsupported curve; only P-256 (ES256) is supported", kid)
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("key %q has unsupported type %T", kid, parsed)
+	}
+	key.verifyKey = parsed
+
+	return key, nil
+}
+
+// Sign signs claims with the active key and sets the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signKey)
+}
+
+// Keyfunc resolves the verification key of a token by its kid header.
+// Tokens without a kid are only accepted when the shared-secret fallback key is in use. Both
+// secrets have the same kid, so during a rotation either of them may verify such tokens.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultHMACKeyID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), kid)
+	}
+	if !ks.usable(key) {
+		return nil, fmt.Errorf("signing key %q was retired", kid)
+	}
+	if key == ks.active && ks.previousHMAC != nil && ks.usable(ks.previousHMAC) {
+		return jwt.VerificationKeySet{Keys: []jwt.VerificationKey{key.verifyKey.([]byte), ks.previousHMAC.verifyKey.([]byte)}}, nil
+	}
+	return key.verifyKey, nil
+}
+
+// ValidMethods lists the algorithms of all configured keys, for jwt.WithValidMethods
+func (ks *KeySet) ValidMethods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// ActiveKeyID returns the kid of the key that signs new tokens
+func (ks *KeySet) ActiveKeyID() string {
+	return ks.active.ID
+}
+
+// usable reports whether a key may still verify tokens
+func (ks *KeySet) usable(key *SigningKey) bool {
+	return key.RetiredAt == nil || ks.now().Before(key.RetiredAt.Add(ks.gracePeriod))
+}
@@ -0,0 +1,396 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testKeys generates one key of every supported type, and one of an unsupported curve
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+	p384    *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ecdsa: ecdsaKey, ed25519: ed25519Key, p384: p384Key}
+}
+
+// pemBlock encodes der as a PEM block of the given type
+func pemBlock(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+// pkcs8 encodes a private key as a "PRIVATE KEY" PEM block
+func pkcs8(t *testing.T, key crypto.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemBlock("PRIVATE KEY", der)
+}
+
+// pkix encodes a public key as a "PUBLIC KEY" PEM block
+func pkix(t *testing.T, key crypto.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pemBlock("PUBLIC KEY", der)
+}
+
+// writeKeyFile writes a PEM key to a temporary file and returns its path
+func writeKeyFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParsePEMKey(t *testing.T) {
+	keys := newTestKeys(t)
+	ecDER, err := x509.MarshalECPrivateKey(keys.ecdsa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantAlg     string
+		wantCanSign bool
+		wantErr     string
+	}{
+		{"RSA PKCS #1", pemBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(keys.rsa)), "RS256", true, ""},
+		{"RSA PKCS #8", pkcs8(t, keys.rsa), "RS256", true, ""},
+		{"EC P-256", pemBlock("EC PRIVATE KEY", ecDER), "ES256", true, ""},
+		{"EC P-256 PKCS #8", pkcs8(t, keys.ecdsa), "ES256", true, ""},
+		{"Ed25519", pkcs8(t, keys.ed25519), "EdDSA", true, ""},
+		{"RSA public key", pkix(t, &keys.rsa.PublicKey), "RS256", false, ""},
+		{"Ed25519 public key", pkix(t, keys.ed25519.Public()), "EdDSA", false, ""},
+		{"EC P-384", pkcs8(t, keys.p384), "", false, "unsupported curve"},
+		{"not PEM", []byte("not a key"), "", false, "not PEM encoded"},
+		{"certificate", pemBlock("CERTIFICATE", []byte("x")), "", false, "unsupported PEM block type"},
+		{"corrupt key", pemBlock("PRIVATE KEY", []byte("x")), "", false, "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePEMKey("k1", tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePEMKey error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePEMKey: %v", err)
+			}
+			if key.ID != "k1" || key.Method.Alg() != tt.wantAlg || key.CanSign() != tt.wantCanSign {
+				t.Errorf("key = %s %s, can sign %v; want k1 %s, can sign %v", key.ID, key.Method.Alg(), key.CanSign(), tt.wantAlg, tt.wantCanSign)
+			}
+		})
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+	rsaFile := writeKeyFile(t, pkcs8(t, keys.rsa))
+	publicFile := writeKeyFile(t, pkix(t, keys.ed25519.Public()))
+
+	tests := []struct {
+		name    string
+		cfg     KeySetConfig
+		wantKID string
+		wantErr string
+	}{
+		{"shared secret", KeySetConfig{HMACSecret: "secret"}, DefaultHMACKeyID, ""},
+		{"no keys", KeySetConfig{}, "", "JWT secret is empty"},
+		{"key files", KeySetConfig{KeyFiles: map[string]string{"rsa": rsaFile}, ActiveKeyID: "rsa", HMACSecret: "ignored"}, "rsa", ""},
+		{"missing file", KeySetConfig{KeyFiles: map[string]string{"rsa": rsaFile + ".missing"}, ActiveKeyID: "rsa"}, "", "failed to read key"},
+		{"unknown active key", KeySetConfig{KeyFiles: map[string]string{"rsa": rsaFile}, ActiveKeyID: "other"}, "", "is not configured"},
+		{"verify-only active key", KeySetConfig{KeyFiles: map[string]string{"ed": publicFile}, ActiveKeyID: "ed"}, "", "has no private key"},
+		{"retired active key", KeySetConfig{
+			KeyFiles: map[string]string{"rsa": rsaFile}, ActiveKeyID: "rsa",
+			RetiredKeys: map[string]string{"rsa": "2026-01-01T00:00:00Z"},
+		}, "", "is retired"},
+		{"unknown retired key", KeySetConfig{
+			KeyFiles: map[string]string{"rsa": rsaFile}, ActiveKeyID: "rsa",
+			RetiredKeys: map[string]string{"old": "2026-01-01T00:00:00Z"},
+		}, "", "retired key \"old\" is not configured"},
+		{"invalid retirement time", KeySetConfig{
+			KeyFiles: map[string]string{"rsa": rsaFile, "ed": publicFile}, ActiveKeyID: "rsa",
+			RetiredKeys: map[string]string{"ed": "yesterday"},
+		}, "", "invalid retirement time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := LoadKeySet(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadKeySet error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeySet: %v", err)
+			}
+			if ks.ActiveKeyID() != tt.wantKID {
+				t.Errorf("active key = %s, want %s", ks.ActiveKeyID(), tt.wantKID)
+			}
+		})
+	}
+}
+
+// parseToken verifies a token with ks the way the token service does
+func parseToken(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	return err
+}
+
+func TestKeySetKeyRotation(t *testing.T) {
+	keys := newTestKeys(t)
+	oldFile := writeKeyFile(t, pkcs8(t, keys.rsa))
+	newFile := writeKeyFile(t, pkcs8(t, keys.ecdsa))
+	retiredAt := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// Before the rotation the old key signs
+	before, err := LoadKeySet(KeySetConfig{KeyFiles: map[string]string{"old": oldFile}, ActiveKeyID: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := LoadKeySet(KeySetConfig{
+		KeyFiles:    map[string]string{"old": oldFile, "new": newFile},
+		ActiveKeyID: "new",
+		RetiredKeys: map[string]string{"old": retiredAt.Format(time.RFC3339)},
+		GracePeriod: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(newToken, ".")
+	if parsed, _, err := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{}); err != nil ||
+		parsed.Header["kid"] != "new" || parsed.Header["alg"] != "ES256" {
+		t.Fatalf("header of a new token %s = %v, %v; want kid new and alg ES256", header, parsed.Header, err)
+	}
+	if methods := after.ValidMethods(); !reflect.DeepEqual(methods, []string{"ES256", "RS256"}) {
+		t.Errorf("ValidMethods = %v, want ES256 and RS256", methods)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		token    string
+		wantErr  bool
+		wantKIDs []string // Keys published in the JWKS
+	}{
+		{"new token", retiredAt, newToken, false, []string{"new", "old"}},
+		{"old token within the grace period", retiredAt.Add(59 * time.Minute), oldToken, false, []string{"new", "old"}},
+		{"old token after the grace period", retiredAt.Add(time.Hour), oldToken, true, []string{"new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after.now = func() time.Time { return tt.now }
+			if err := parseToken(after, tt.token); (err != nil) != tt.wantErr {
+				t.Errorf("parse error = %v, want error %v", err, tt.wantErr)
+			}
+			var kids []string
+			for _, jwk := range after.JWKS().Keys {
+				kids = append(kids, jwk.KeyID)
+			}
+			if !reflect.DeepEqual(kids, tt.wantKIDs) {
+				t.Errorf("JWKS keys = %v, want %v", kids, tt.wantKIDs)
+			}
+		})
+	}
+}
+
+func TestKeySetSharedSecretRotation(t *testing.T) {
+	retiredAt := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	before, err := LoadKeySet(KeySetConfig{HMACSecret: "old secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := LoadKeySet(KeySetConfig{
+		HMACSecret:                  "new secret",
+		PreviousHMACSecret:          "old secret",
+		PreviousHMACSecretRetiredAt: retiredAt.Format(time.RFC3339),
+		GracePeriod:                 time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(jwt.MapClaims{"sub": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parseToken(before, newToken); err == nil {
+		t.Error("token signed with the new secret verified by the old one")
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		token   string
+		wantErr bool
+	}{
+		{"new token", retiredAt.Add(2 * time.Hour), newToken, false},
+		{"old token within the grace period", retiredAt.Add(59 * time.Minute), oldToken, false},
+		{"old token after the grace period", retiredAt.Add(time.Hour), oldToken, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after.now = func() time.Time { return tt.now }
+			if err := parseToken(after, tt.token); (err != nil) != tt.wantErr {
+				t.Errorf("parse error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err = LoadKeySet(KeySetConfig{HMACSecret: "new secret", PreviousHMACSecret: "old secret"})
+	if err == nil {
+		t.Error("previous secret without a retirement time accepted")
+	}
+	if keys := after.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("JWKS publishes %d shared secrets", len(keys))
+	}
+}
+
+func TestKeySetKeyfunc(t *testing.T) {
+	keys := newTestKeys(t)
+	fileKeys, err := LoadKeySet(KeySetConfig{KeyFiles: map[string]string{"rsa": writeKeyFile(t, pkcs8(t, keys.rsa))}, ActiveKeyID: "rsa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedSecret, err := LoadKeySet(KeySetConfig{HMACSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sign creates a token with the given header values, signed by key
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "1"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		ks      *KeySet
+		token   string
+		wantErr bool
+	}{
+		{"kid and alg of the key", fileKeys, sign(jwt.SigningMethodRS256, "rsa", keys.rsa), false},
+		{"unknown kid", fileKeys, sign(jwt.SigningMethodRS256, "other", keys.rsa), true},
+		{"alg other than the key's", fileKeys, sign(jwt.SigningMethodRS512, "rsa", keys.rsa), true},
+		{"HMAC with the public key as secret", fileKeys, sign(jwt.SigningMethodHS256, "rsa", x509.MarshalPKCS1PublicKey(&keys.rsa.PublicKey)), true},
+		{"no kid with key files", fileKeys, sign(jwt.SigningMethodRS256, "", keys.rsa), true},
+		{"no kid with the shared secret", sharedSecret, sign(jwt.SigningMethodHS256, "", []byte("secret")), false},
+		{"wrong shared secret", sharedSecret, sign(jwt.SigningMethodHS256, DefaultHMACKeyID, []byte("guess")), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := parseToken(tt.ks, tt.token); (err != nil) != tt.wantErr {
+				t.Errorf("parse error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := newTestKeys(t)
+	ks, err := LoadKeySet(KeySetConfig{
+		KeyFiles: map[string]string{
+			"rsa":     writeKeyFile(t, pkcs8(t, keys.rsa)),
+			"ec":      writeKeyFile(t, pkcs8(t, keys.ecdsa)),
+			"ed25519": writeKeyFile(t, pkix(t, keys.ed25519.Public())),
+		},
+		ActiveKeyID: "rsa",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]crypto.PublicKey{
+		"rsa":     &keys.rsa.PublicKey,
+		"ec":      &keys.ecdsa.PublicKey,
+		"ed25519": keys.ed25519.Public(),
+	}
+
+	set := ks.JWKS()
+	if len(set.Keys) != len(want) {
+		t.Fatalf("JWKS has %d keys, want %d", len(set.Keys), len(want))
+	}
+	for _, jwk := range set.Keys {
+		public, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("key %s: %v", jwk.KeyID, err)
+		}
+		if !public.(interface{ Equal(crypto.PublicKey) bool }).Equal(want[jwk.KeyID]) {
+			t.Errorf("key %s does not round-trip through the JWKS", jwk.KeyID)
+		}
+	}
+
+	// The shared secret is never published
+	sharedSecret, err := LoadKeySet(KeySetConfig{HMACSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := sharedSecret.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("JWKS of the shared secret = %+v, want no keys", keys)
+	}
+}
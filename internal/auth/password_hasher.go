@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownPasswordHash is returned when a stored hash was produced by no configured algorithm
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordAlgorithm hashes passwords with one algorithm and parameter set
+type PasswordAlgorithm interface {
+	// Name identifies the algorithm in configuration and logs
+	Name() string
+	// Hash returns the encoded hash of password, including algorithm, parameters and salt
+	Hash(password string) (string, error)
+	// Recognizes reports whether encoded was produced by this algorithm
+	Recognizes(encoded string) bool
+	// Verify reports whether password matches encoded
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded was produced with different parameters than the current ones
+	NeedsRehash(encoded string) bool
+}
+
+// PasswordHasher hashes new passwords with a preferred algorithm and verifies hashes of any
+// configured algorithm, so stored hashes can be upgraded as users log in
+type PasswordHasher struct {
+	preferred  PasswordAlgorithm
+	algorithms []PasswordAlgorithm
+	dummyOnce  sync.Once
+	dummy      string // Hash of a random password, see VerifyDummy
+}
+
+// NewPasswordHasher creates a hasher that hashes with preferred and also accepts hashes of legacy
+func NewPasswordHasher(preferred PasswordAlgorithm, legacy ...PasswordAlgorithm) *PasswordHasher {
+	return &PasswordHasher{
+		preferred:  preferred,
+		algorithms: append([]PasswordAlgorithm{preferred}, legacy...),
+	}
+}
+
+// Algorithm returns the name of the algorithm new hashes are created with
+func (h *PasswordHasher) Algorithm() string {
+	return h.preferred.Name()
+}
+
+// Hash hashes password with the preferred algorithm
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+// Verify checks password against encoded. When it matches, needsRehash reports whether encoded
+// should be replaced by a hash from Hash because its algorithm or parameters are outdated.
+func (h *PasswordHasher) Verify(password, encoded string) (match, needsRehash bool, err error) {
+	for _, algorithm := range h.algorithms {
+		if !algorithm.Recognizes(encoded) {
+			continue
+		}
+		match, err := algorithm.Verify(password, encoded)
+		if err != nil || !match {
+			return false, false, err
+		}
+		return true, algorithm != h.preferred || algorithm.NeedsRehash(encoded), nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+// VerifyDummy verifies password against a hash no password matches, taking as long as Verify
+// does for a hash of the preferred algorithm. It is called when there is no hash to check, e.g.
+// for an unknown username, so that response times do not reveal which users exist.
+func (h *PasswordHasher) VerifyDummy(password string) {
+	h.dummyOnce.Do(func() {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err == nil {
+			h.dummy, _ = h.preferred.Hash(base64.RawStdEncoding.EncodeToString(secret))
+		}
+	})
+	if h.dummy != "" {
+		h.preferred.Verify(password, h.dummy)
+	}
+}
+
+// Argon2idParams configures Argon2id (RFC 9106)
+type Argon2idParams struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams are the second recommended option of RFC 9106 for memory-constrained environments
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2id hashes passwords with Argon2id, encoded in the PHC string format
+// "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>"
+type Argon2id struct {
+	params Argon2idParams
+}
+
+// NewArgon2id creates an Argon2id algorithm, rejecting parameters too weak to be useful
+func NewArgon2id(params Argon2idParams) (*Argon2id, error) {
+	switch {
+	case params.Memory < 8*uint32(params.Parallelism) || params.Memory < 1024:
+		return nil, fmt.Errorf("argon2id memory must be at least 1024 KiB and 8 KiB per lane")
+	case params.Iterations < 1:
+		return nil, fmt.Errorf("argon2id iterations must be at least 1")
+	case params.Parallelism < 1:
+		return nil, fmt.Errorf("argon2id parallelism must be at least 1")
+	case params.SaltLength < 8:
+		return nil, fmt.Errorf("argon2id salt length must be at least 8 bytes")
+	case params.KeyLength < 16:
+		return nil, fmt.Errorf("argon2id key length must be at least 16 bytes")
+	}
+	return &Argon2id{params: params}, nil
+}
+
+// Name returns "argon2id"
+func (a *Argon2id) Name() string {
+	return "argon2id"
+}
+
+// Hash hashes password with a new random salt
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		a.params.Memory, a.params.Iterations, a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Recognizes reports whether encoded is an Argon2id hash
+func (a *Argon2id) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Verify recomputes the hash with the parameters and salt stored in encoded
+func (a *Argon2id) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+// NeedsRehash reports whether encoded uses other parameters than the configured ones
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != a.params
+}
+
+// decodeArgon2id parses a PHC-formatted Argon2id hash
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash: %w", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// Bcrypt hashes passwords with bcrypt at a fixed cost
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt creates a bcrypt algorithm with the given cost
+func NewBcrypt(cost int) (*Bcrypt, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &Bcrypt{cost: cost}, nil
+}
+
+// Name returns "bcrypt"
+func (b *Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+// Hash hashes password with the configured cost
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Recognizes reports whether encoded is a bcrypt hash ($2a$, $2b$ or $2y$)
+func (b *Bcrypt) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Verify compares password with encoded
+func (b *Bcrypt) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash reports whether encoded uses another cost than the configured one
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.cost
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams are cheap parameters that keep the tests fast
+var testArgon2idParams = Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// mustHash hashes password with algorithm or fails the test
+func mustHash(t *testing.T, algorithm PasswordAlgorithm, password string) string {
+	t.Helper()
+	hash, err := algorithm.Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestNewArgon2id(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Argon2idParams)
+		wantErr string
+	}{
+		{"valid", func(*Argon2idParams) {}, ""},
+		{"too little memory", func(p *Argon2idParams) { p.Memory = 512 }, "memory"},
+		{"too little memory per lane", func(p *Argon2idParams) { p.Memory, p.Parallelism = 1024, 255 }, "memory"},
+		{"no iterations", func(p *Argon2idParams) { p.Iterations = 0 }, "iterations"},
+		{"no parallelism", func(p *Argon2idParams) { p.Parallelism = 0 }, "parallelism"},
+		{"short salt", func(p *Argon2idParams) { p.SaltLength = 4 }, "salt"},
+		{"short key", func(p *Argon2idParams) { p.KeyLength = 8 }, "key length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := testArgon2idParams
+			tt.modify(&params)
+			_, err := NewArgon2id(params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewArgon2id: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewArgon2id error = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2id(t *testing.T) {
+	algorithm, err := NewArgon2id(testArgon2idParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := mustHash(t, algorithm, "correct horse")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("hash %q is not in the PHC format with the configured parameters", hash)
+	}
+	if hash == mustHash(t, algorithm, "correct horse") {
+		t.Error("two hashes of the same password are equal; the salt is not random")
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		encoded   string
+		wantMatch bool
+		wantErr   bool
+	}{
+		{"correct password", "correct horse", hash, true, false},
+		{"wrong password", "battery staple", hash, false, false},
+		{"malformed hash", "correct horse", "$argon2id$v=19$m=1024", false, true},
+		{"other version", "correct horse", strings.Replace(hash, "v=19", "v=16", 1), false, true},
+		{"invalid salt", "correct horse", strings.Replace(hash, "p=1$", "p=1$!", 1), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := algorithm.Verify(tt.password, tt.encoded)
+			if match != tt.wantMatch || (err != nil) != tt.wantErr {
+				t.Errorf("Verify = %v, %v; want %v and error %v", match, err, tt.wantMatch, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	current, err := NewArgon2id(testArgon2idParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := mustHash(t, current, "correct horse")
+
+	tests := []struct {
+		name   string
+		modify func(*Argon2idParams)
+		want   bool
+	}{
+		{"same parameters", func(*Argon2idParams) {}, false},
+		{"more memory", func(p *Argon2idParams) { p.Memory = 2048 }, true},
+		{"more iterations", func(p *Argon2idParams) { p.Iterations = 2 }, true},
+		{"more parallelism", func(p *Argon2idParams) { p.Parallelism = 2 }, true},
+		{"longer salt", func(p *Argon2idParams) { p.SaltLength = 32 }, true},
+		{"longer key", func(p *Argon2idParams) { p.KeyLength = 64 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := testArgon2idParams
+			tt.modify(&params)
+			upgraded, err := NewArgon2id(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := upgraded.NeedsRehash(hash); got != tt.want {
+				t.Errorf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+			// Hashes stay verifiable whatever the configured parameters
+			if match, err := upgraded.Verify("correct horse", hash); !match || err != nil {
+				t.Errorf("Verify with changed parameters = %v, %v", match, err)
+			}
+		})
+	}
+	if !current.NeedsRehash("$argon2id$garbage") {
+		t.Error("a malformed hash does not need a rehash")
+	}
+}
+
+func TestBcrypt(t *testing.T) {
+	if _, err := NewBcrypt(bcrypt.MaxCost + 1); err == nil {
+		t.Error("NewBcrypt accepted a cost above the maximum")
+	}
+	algorithm, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := mustHash(t, algorithm, "correct horse")
+	if !algorithm.Recognizes(hash) || algorithm.Recognizes("$argon2id$v=19$") {
+		t.Error("Recognizes does not tell bcrypt from argon2id hashes")
+	}
+	if match, err := algorithm.Verify("correct horse", hash); !match || err != nil {
+		t.Errorf("Verify of the correct password = %v, %v", match, err)
+	}
+	if match, err := algorithm.Verify("battery staple", hash); match || err != nil {
+		t.Errorf("Verify of a wrong password = %v, %v; want no match and no error", match, err)
+	}
+	if algorithm.NeedsRehash(hash) {
+		t.Error("hash with the configured cost needs a rehash")
+	}
+	higher, _ := NewBcrypt(bcrypt.MinCost + 1)
+	if !higher.NeedsRehash(hash) {
+		t.Error("hash with a lower cost does not need a rehash")
+	}
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	bcryptAlgorithm, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	argon2id, err := NewArgon2id(testArgon2idParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	strongerArgon2id, err := NewArgon2id(stronger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptHash := mustHash(t, bcryptAlgorithm, "correct horse")
+	argon2idHash := mustHash(t, argon2id, "correct horse")
+
+	tests := []struct {
+		name            string
+		hasher          *PasswordHasher
+		password        string
+		encoded         string
+		wantMatch       bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{"preferred algorithm", NewPasswordHasher(argon2id, bcryptAlgorithm), "correct horse", argon2idHash, true, false, nil},
+		{"legacy algorithm", NewPasswordHasher(argon2id, bcryptAlgorithm), "correct horse", bcryptHash, true, true, nil},
+		{"legacy algorithm, wrong password", NewPasswordHasher(argon2id, bcryptAlgorithm), "battery staple", bcryptHash, false, false, nil},
+		{"outdated parameters", NewPasswordHasher(strongerArgon2id), "correct horse", argon2idHash, true, true, nil},
+		{"algorithm not configured", NewPasswordHasher(argon2id), "correct horse", bcryptHash, false, false, ErrUnknownPasswordHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, needsRehash, err := tt.hasher.Verify(tt.password, tt.encoded)
+			if match != tt.wantMatch || needsRehash != tt.wantNeedsRehash || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify = %v, %v, %v; want %v, %v, %v", match, needsRehash, err, tt.wantMatch, tt.wantNeedsRehash, tt.wantErr)
+			}
+		})
+	}
+
+	// A rehash produces a hash of the preferred algorithm that needs no further upgrade
+	hasher := NewPasswordHasher(argon2id, bcryptAlgorithm)
+	rehashed, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, needsRehash, err := hasher.Verify("correct horse", rehashed); !match || needsRehash || err != nil {
+		t.Errorf("Verify of the rehashed password = %v, %v, %v; want a match without rehash", match, needsRehash, err)
+	}
+	if hasher.Algorithm() != "argon2id" {
+		t.Errorf("Algorithm = %s, want argon2id", hasher.Algorithm())
+	}
+}
+
+// countingPasswordAlgorithm counts the passwords verified by the algorithm it wraps
+type countingPasswordAlgorithm struct {
+	PasswordAlgorithm
+	verified int
+}
+
+func (a *countingPasswordAlgorithm) Verify(password, encoded string) (bool, error) {
+	a.verified++
+	return a.PasswordAlgorithm.Verify(password, encoded)
+}
+
+func TestPasswordHasherVerifyDummy(t *testing.T) {
+	bcryptAlgorithm, err := NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := &countingPasswordAlgorithm{PasswordAlgorithm: bcryptAlgorithm}
+	hasher := NewPasswordHasher(algorithm)
+	for i := 0; i < 2; i++ {
+		hasher.VerifyDummy("correct horse")
+	}
+	if algorithm.verified != 2 {
+		t.Errorf("%d passwords verified, want 2", algorithm.verified)
+	}
+	if !algorithm.Recognizes(hasher.dummy) {
+		t.Errorf("dummy hash %q is not a hash of the preferred algorithm", hasher.dummy)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy describes the requirements new passwords must meet
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Breached      *BreachedPasswords // Optional list of known-compromised passwords
+}
+
+// Validate returns a description of every requirement password fails, or nil if it is acceptable.
+// Passwords equal to the username are always rejected.
+func (p *PasswordPolicy) Validate(password, username string) []string {
+	var problems []string
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "must contain an upper-case letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "must contain a lower-case letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		problems = append(problems, "must not be the same as the username")
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		problems = append(problems, "appears in a list of breached passwords")
+	}
+
+	return problems
+}
+
+// BreachedPasswords is a set of SHA-1 hashes of known-compromised passwords
+type BreachedPasswords struct {
+	hashes map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a list of hex SHA-1 password hashes, one per line. Lines in the
+// Have I Been Pwned format "HASH:COUNT" are accepted; blank lines and lines starting with # are skipped.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	list := &BreachedPasswords{hashes: make(map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		list.hashes[strings.ToUpper(hash)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return list, nil
+}
+
+// Contains reports whether password is on the list
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	_, found := b.hashes[strings.ToUpper(hex.EncodeToString(sum[:]))]
+	return found
+}
+
+// Len returns the number of hashes on the list
+func (b *BreachedPasswords) Len() int {
+	return len(b.hashes)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	breached := writeBreachedList(t, "# comment\n\n"+
+		// SHA-1 of "password1", in the Have I Been Pwned format
+		"E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2413945\n")
+	list, err := LoadBreachedPasswords(breached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		username string
+		want     []string
+	}{
+		{"acceptable", PasswordPolicy{MinLength: 8}, "long enough", "alice", nil},
+		{"too short", PasswordPolicy{MinLength: 8}, "short", "alice", []string{"must be at least 8 characters long"}},
+		{"length counted in characters", PasswordPolicy{MinLength: 4, MaxLength: 4}, "äöüß", "alice", nil},
+		{"too long", PasswordPolicy{MaxLength: 8}, "far too long", "alice", []string{"must be at most 8 characters long"}},
+		{"no maximum", PasswordPolicy{}, strings.Repeat("a", 1000), "alice", nil},
+		{"all classes present", PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, "Abc1 ", "alice", nil},
+		{"all classes missing", PasswordPolicy{RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}, "", "alice", []string{
+			"must contain an upper-case letter",
+			"must contain a lower-case letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+		{"non-ASCII classes", PasswordPolicy{RequireUpper: true, RequireLower: true, RequireSymbol: true}, "Éé€", "alice", nil},
+		{"same as the username", PasswordPolicy{}, "ALICE", "alice", []string{"must not be the same as the username"}},
+		{"without username", PasswordPolicy{}, "", "", nil},
+		{"breached", PasswordPolicy{Breached: list}, "password1", "alice", []string{"appears in a list of breached passwords"}},
+		{"not breached", PasswordPolicy{Breached: list}, "password2", "alice", nil},
+		{"several problems", PasswordPolicy{MinLength: 12, RequireDigit: true}, "alice", "alice", []string{
+			"must be at least 12 characters long",
+			"must contain a digit",
+			"must not be the same as the username",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Validate(tt.password, tt.username); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantLen int
+		wantErr string
+	}{
+		{"hashes and counts", "# header\n" +
+			"e38ad214943daad1d64c102faec29de4afe9da3d\n" +
+			"\n" +
+			"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n", 2, ""},
+		{"empty", "", 0, ""},
+		{"invalid hash", "not-a-hash\n", 0, "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := LoadBreachedPasswords(writeBreachedList(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadBreachedPasswords error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadBreachedPasswords: %v", err)
+			}
+			if list.Len() != tt.wantLen {
+				t.Errorf("Len = %d, want %d", list.Len(), tt.wantLen)
+			}
+		})
+	}
+
+	if _, err := LoadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachedPasswords accepted a missing file")
+	}
+}
+
+// writeBreachedList writes a breached password list to a temporary file and returns its path
+func writeBreachedList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
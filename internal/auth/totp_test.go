@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 secret of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The last six digits of the RFC 6238 appendix B values for SHA-1
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, TOTPCounter(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Errorf("code = %s, want %s", code, tt.want)
+			}
+		})
+	}
+
+	// Lower case and padded secrets decode to the same key
+	if code, err := TOTPCode(strings.ToLower(rfc6238Secret)+"====", 1); err != nil || code != "287082" {
+		t.Errorf("code for a lower-case padded secret = %s, %v; want 287082", code, err)
+	}
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode accepted an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPCounter(now)
+	codeAt := func(counter int64) string {
+		code, err := TOTPCode(rfc6238Secret, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		wantCounter int64
+		wantOK      bool
+	}{
+		{"current step", codeAt(current), current, true},
+		{"with spaces", codeAt(current)[:3] + " " + codeAt(current)[3:], current, true},
+		{"previous step", codeAt(current - 1), current - 1, true},
+		{"next step", codeAt(current + 1), current + 1, true},
+		{"two steps ago", codeAt(current - 2), 0, false},
+		{"two steps ahead", codeAt(current + 2), 0, false},
+		{"too short", codeAt(current)[:5], 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK || counter != tt.wantCounter {
+				t.Errorf("ValidateTOTP = %d, %v; want %d, %v", counter, ok, tt.wantCounter, tt.wantOK)
+			}
+		})
+	}
+
+	// A code keeps validating while its step is in the window, always as the same step, which
+	// is what lets callers reject a replay by remembering the last accepted step
+	code := codeAt(current)
+	for _, at := range []time.Time{now, now.Add(TOTPPeriod), now.Add(-TOTPPeriod)} {
+		if counter, ok := ValidateTOTP(rfc6238Secret, code, at); !ok || counter != current {
+			t.Errorf("ValidateTOTP at %v = %d, %v; want %d, true", at, counter, ok, current)
+		}
+	}
+	if _, ok := ValidateTOTP(rfc6238Secret, code, now.Add(2*TOTPPeriod)); ok {
+		t.Error("code accepted two steps after it was current")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret %q has %d characters, want 32 for 160 bits", secret, len(secret))
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("generated secret is unusable: %v", err)
+	}
+	if other, _ := GenerateTOTPSecret(); other == secret {
+		t.Error("two generated secrets are equal")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(TOTPProvisioningURI("Finance Tracker", "alice", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Finance Tracker:alice" {
+		t.Errorf("URI %s does not name the totp type and the issuer:account label", uri)
+	}
+	query := uri.Query()
+	for param, want := range map[string]string{
+		"secret": rfc6238Secret, "issuer": "Finance Tracker", "algorithm": "SHA1", "digits": "6", "period": "30",
+	} {
+		if got := query.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 11 || code[5] != '-' || strings.ToLower(code) != code {
+		t.Errorf("recovery code %q is not formatted as xxxxx-xxxxx", code)
+	}
+
+	tests := []struct {
+		typed string
+		want  string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{"abcde fghij", "abcdefghij"},
+		{" abcdefghij ", "abcdefghij"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.typed); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.typed, got, tt.want)
+		}
+	}
+}
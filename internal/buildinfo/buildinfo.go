@@ -0,0 +1,52 @@
+// Package buildinfo describes the build of the running binary. Release builds set the version,
+// commit and build time with linker flags:
+//
+//	go build -ldflags "-X personal-finance-tracker-api/internal/buildinfo.Version=1.2.3 \
+//		-X personal-finance-tracker-api/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X personal-finance-tracker-api/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set by the linker; see the package documentation
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info is the build of the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build of the running binary. Without linker flags, the commit comes from the
+// version control information Go records in binaries built from a checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok && info.Commit == "" {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
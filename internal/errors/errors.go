@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // CustomError is an interface for custom application errors
 type CustomError interface {
@@ -20,6 +23,7 @@ const (
 	TypeUnauthorized  ErrorType = "UNAUTHORIZED"
 	TypeForbidden     ErrorType = "FORBIDDEN"
 	TypeConflict      ErrorType = "CONFLICT"
+	TypeRateLimited   ErrorType = "RATE_LIMITED"
 )
 
 // appError is the concrete implementation of CustomError
@@ -80,6 +84,25 @@ func NewConflictError(msg string, err error) CustomError {
 	return New(TypeConflict, msg, err)
 }
 
+// rateLimitError is a CustomError that tells the client when it may retry
+type rateLimitError struct {
+	appError
+	retryAfter time.Duration
+}
+
+func NewRateLimitedError(msg string, retryAfter time.Duration, err error) CustomError {
+	base := New(TypeRateLimited, msg, err).(*appError)
+	return &rateLimitError{appError: *base, retryAfter: retryAfter}
+}
+
+// GetRetryAfter retrieves how long a client should wait before retrying, if the error says so
+func GetRetryAfter(err error) (time.Duration, bool) {
+	if rlErr, ok := err.(*rateLimitError); ok {
+		return rlErr.retryAfter, true
+	}
+	return 0, false
+}
+
 // IsType checks if a given error is of a specific CustomError type
 func IsType(err error, t ErrorType) bool {
 	if customErr, ok := err.(CustomError); ok {
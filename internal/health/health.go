@@ -0,0 +1,115 @@
+// Package health runs the checks that decide whether the application is ready to serve traffic
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Statuses of a report and of its checks
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc reports a problem with a dependency of the application as an error
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one check. Reports are served without authentication, so the
+// error of a failed check is only logged, never included.
+type CheckResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// Report is the outcome of all checks; it is ok only when every check is
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// namedCheck is a registered check
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs registered checks concurrently, each limited to the same timeout
+type Checker struct {
+	mu      sync.RWMutex
+	checks  []namedCheck
+	timeout time.Duration
+}
+
+// NewChecker creates a checker without checks
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check, reported under name in the order of registration
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check and reports their results
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// run runs a check within the timeout. A check that does not return in time is reported as
+// failed without waiting for it.
+func (c *Checker) run(ctx context.Context, check namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check.check(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:       check.name,
+		Status:     StatusOK,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"check": check.name,
+			"error": err.Error(),
+		}).Warn("Health: Check failed")
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCheckerRun(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	t.Cleanup(func() { logrus.SetOutput(os.Stderr) })
+
+	checker := NewChecker(20 * time.Millisecond)
+	checker.Register("database", func(ctx context.Context) error { return nil })
+	checker.Register("migrations", func(ctx context.Context) error { return errors.New("2 migrations pending") })
+	checker.Register("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	report := checker.Run(context.Background())
+	if report.Status != StatusUnavailable {
+		t.Errorf("status = %s, want %s", report.Status, StatusUnavailable)
+	}
+	var got []string
+	for _, check := range report.Checks {
+		got = append(got, check.Name+" "+check.Status)
+	}
+	want := []string{"database ok", "migrations unavailable", "stuck unavailable"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checks = %q, want %q", got, want)
+	}
+
+	// Errors may name hosts and ports, so they are logged but not reported
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"2 migrations pending", "context deadline exceeded"} {
+		if strings.Contains(string(body), message) {
+			t.Errorf("report %s contains the error %q", body, message)
+		}
+		if !strings.Contains(logs.String(), message) {
+			t.Errorf("error %q not logged", message)
+		}
+	}
+	if report.Checks[2].DurationMs < 20 || report.Checks[2].DurationMs > 500 {
+		t.Errorf("duration of the stuck check = %vms, want about the timeout", report.Checks[2].DurationMs)
+	}
+}
+
+func TestCheckerRunWithoutChecks(t *testing.T) {
+	if report := NewChecker(time.Second).Run(context.Background()); report.Status != StatusOK || len(report.Checks) != 0 {
+		t.Errorf("report = %+v, want ok without checks", report)
+	}
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"personal-finance-tracker-api/internal/models"
+)
+
+// csvDateLayouts are the date formats accepted in CSV files, tried in order
+var csvDateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// ParseCSV reads transactions from a CSV file with a header row, such as the one produced by
+// the CSV export. Columns are matched by name, ignoring case; Date and Amount are required and
+// Description, Type, Category and Status are optional. Without a Type column, negative amounts
+// are expenses and positive ones income.
+func ParseCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the CSV file is empty")
+		}
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\ufeff") // Byte order mark written by spreadsheet programs
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the CSV header has no %s column", required)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		record := Record{
+			Line:        line,
+			Description: field(row, "description"),
+			Category:    field(row, "category"),
+			Status:      models.TransactionStatus(strings.ToLower(field(row, "status"))),
+		}
+
+		record.Date, err = parseCSVDate(field(row, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(field(row, "amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, field(row, "amount"))
+		}
+		switch transactionType := models.TransactionType(strings.ToLower(field(row, "type"))); transactionType {
+		case "":
+			if err := signedRecord(&record, amount); err != nil {
+				return nil, err
+			}
+		case models.Income, models.Expense:
+			if amount == 0 {
+				return nil, fmt.Errorf("line %d: amount must not be zero", line)
+			}
+			if amount < 0 {
+				amount = -amount
+			}
+			record.Amount, record.Type = amount, transactionType
+		default:
+			return nil, fmt.Errorf("line %d: invalid type %q, expected income or expense", line, field(row, "type"))
+		}
+
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseCSVDate parses a date in one of csvDateLayouts and returns midnight UTC of that day
+func parseCSVDate(value string) (time.Time, error) {
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+}
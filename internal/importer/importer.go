@@ -0,0 +1,67 @@
+// Package importer reads transactions from files exported by banks and spreadsheets
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"personal-finance-tracker-api/internal/models"
+)
+
+// Supported file formats
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+)
+
+// Record is a transaction read from a file, before it is assigned to a category
+type Record struct {
+	Line        int // Line of the file the record starts on, for error messages
+	Description string
+	Amount      float64 // Always positive; Type gives the direction
+	Type        models.TransactionType
+	Date        time.Time // Midnight UTC of the booking day
+	Category    string    // Category name, when the file has one
+	Status      models.TransactionStatus
+}
+
+// FormatFromFileName guesses the format of a file from its extension. QFX files are OFX.
+func FormatFromFileName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".ofx", ".qfx":
+		return FormatOFX, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of %s from its extension; expected .csv, .ofx or .qfx", name)
+	}
+}
+
+// Parse reads every transaction from r in the given format
+func Parse(format string, r io.Reader) ([]Record, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatOFX:
+		return ParseOFX(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// signedRecord fills in the amount and type of a record from a signed amount, where negative
+// amounts are expenses
+func signedRecord(record *Record, amount float64) error {
+	switch {
+	case amount < 0:
+		record.Amount, record.Type = -amount, models.Expense
+	case amount > 0:
+		record.Amount, record.Type = amount, models.Income
+	default:
+		return fmt.Errorf("line %d: amount must not be zero", record.Line)
+	}
+	return nil
+}
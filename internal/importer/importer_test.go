@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/models"
+)
+
+// formatRecords renders records compactly for comparison
+func formatRecords(records []Record) string {
+	var lines []string
+	for _, r := range records {
+		lines = append(lines, fmt.Sprintf("%d %s %s %.2f %q %q %s", r.Line, r.Date.Format("2006-01-02"), r.Type, r.Amount, r.Description, r.Category, r.Status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "export format",
+			input: "ID,Description,Amount,Type,Date,Category,Status\n" +
+				"1,Weekly shop,42.50,expense,2026-03-01,Groceries,cleared\n" +
+				"2,\"Salary, March\",3200.00,income,2026-03-02,Salary,pending\n",
+			want: "2 2026-03-01 expense 42.50 \"Weekly shop\" \"Groceries\" cleared\n" +
+				"3 2026-03-02 income 3200.00 \"Salary, March\" \"Salary\" pending",
+		},
+		{
+			name:  "signed amounts without a type column",
+			input: "\ufeffdate, amount ,description\n2026-03-01,-3.5,Coffee\n2026-03-02T10:00:00+02:00,12,Refund\n",
+			want: "2 2026-03-01 expense 3.50 \"Coffee\" \"\" \n" +
+				"3 2026-03-02 income 12.00 \"Refund\" \"\" ",
+		},
+		{
+			name:  "negative amount with a type",
+			input: "Date,Amount,Type\n2026-03-01,-7,expense\n",
+			want:  "2 2026-03-01 expense 7.00 \"\" \"\" ",
+		},
+		{name: "empty file", input: "", wantErr: "the CSV file is empty"},
+		{name: "missing amount column", input: "Date,Description\n", wantErr: "no amount column"},
+		{name: "invalid date", input: "Date,Amount\n01/03/2026,5\n", wantErr: "line 2: invalid date"},
+		{name: "invalid amount", input: "Date,Amount\n2026-03-01,five\n", wantErr: "line 2: invalid amount"},
+		{name: "zero amount", input: "Date,Amount\n2026-03-01,0\n", wantErr: "line 2: amount must not be zero"},
+		{name: "invalid type", input: "Date,Amount,Type\n2026-03-01,5,transfer\n", wantErr: "line 2: invalid type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseCSV(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatRecords(records); got != tt.want {
+				t.Errorf("records:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "SGML",
+			input: "OFXHEADER:100\nDATA:OFXSGML\n\n<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>\n" +
+				"<STMTTRN>\n<TRNTYPE>DEBIT\n<DTPOSTED>20260310120000[-5:EST]\n<TRNAMT>-42.10\n<FITID>1\n<NAME>Gas &amp; Co\n</STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260311<TRNAMT>100,50<FITID>2<MEMO>Transfer</STMTTRN>\n" +
+				"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\n",
+			want: "5 2026-03-10 expense 42.10 \"Gas & Co\" \"\" cleared\n" +
+				"12 2026-03-11 income 100.50 \"Transfer\" \"\" cleared",
+		},
+		{
+			name: "XML",
+			input: "<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\" VERSION=\"220\"?>\n<OFX><BANKTRANLIST>\n" +
+				"<STMTTRN><TRNTYPE>POS</TRNTYPE><DTPOSTED>20260312</DTPOSTED><TRNAMT>-9.99</TRNAMT><NAME>Bookshop</NAME><MEMO>Card 1234</MEMO></STMTTRN>\n" +
+				"</BANKTRANLIST></OFX>\n",
+			want: "4 2026-03-12 expense 9.99 \"Bookshop\" \"\" cleared",
+		},
+		{name: "no transactions", input: "<OFX></OFX>", want: ""},
+		{name: "invalid date", input: "<STMTTRN><DTPOSTED>2026<TRNAMT>1</STMTTRN>", wantErr: "line 1: missing or invalid DTPOSTED"},
+		{name: "invalid amount", input: "<STMTTRN><DTPOSTED>20260312<TRNAMT>x</STMTTRN>", wantErr: "line 1: invalid TRNAMT"},
+		{name: "unterminated transaction", input: "<STMTTRN><DTPOSTED>20260312<TRNAMT>1", wantErr: "ends inside a transaction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := ParseOFX(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := formatRecords(records); got != tt.want {
+				t.Errorf("records:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromFileName(t *testing.T) {
+	for name, want := range map[string]string{"statement.CSV": FormatCSV, "bank.ofx": FormatOFX, "bank.qfx": FormatOFX, "notes.txt": ""} {
+		got, err := FormatFromFileName(name)
+		if got != want || (want == "") != (err != nil) {
+			t.Errorf("FormatFromFileName(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestParseRecordsAreDaysInUTC(t *testing.T) {
+	records, err := Parse(FormatCSV, strings.NewReader("Date,Amount\n2026-03-02T23:30:00-08:00,1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !records[0].Date.Equal(want) || records[0].Type != models.Income {
+		t.Errorf("record = %+v, want income on %s", records[0], want)
+	}
+}
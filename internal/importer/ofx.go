@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"personal-finance-tracker-api/internal/models"
+)
+
+// ParseOFX reads the statement transactions (STMTTRN) of an OFX or QFX file. Both the SGML
+// syntax of OFX 1.x, where elements have no closing tags, and the XML syntax of OFX 2.x are
+// read. Amounts are signed as in the file, the description is the payee name or else the
+// memo, and transactions are cleared since the bank has booked them.
+func ParseOFX(r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+
+	var records []Record
+	var fields map[string]string // Elements of the transaction being read
+	line, recordLine := 1, 0
+	for pos := 0; ; {
+		start := strings.IndexByte(content[pos:], '<')
+		if start < 0 {
+			break
+		}
+		start += pos
+		end := strings.IndexByte(content[start:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated tag", line+strings.Count(content[pos:start], "\n"))
+		}
+		end += start
+		line += strings.Count(content[pos:start], "\n")
+
+		// An element's value runs up to the next tag, closing or not
+		valueEnd := strings.IndexByte(content[end+1:], '<')
+		if valueEnd < 0 {
+			valueEnd = len(content)
+		} else {
+			valueEnd += end + 1
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[start+1 : end]))
+		value := html.UnescapeString(strings.TrimSpace(content[end+1 : valueEnd]))
+		pos = end + 1
+
+		switch {
+		case tag == "STMTTRN":
+			fields = make(map[string]string)
+			recordLine = line
+		case tag == "/STMTTRN":
+			if fields == nil {
+				return nil, fmt.Errorf("line %d: </STMTTRN> without <STMTTRN>", line)
+			}
+			record, err := ofxRecord(fields, recordLine)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+			fields = nil
+		case fields != nil && !strings.HasPrefix(tag, "/"):
+			fields[tag] = value
+		}
+	}
+	if fields != nil {
+		return nil, errors.New("the OFX file ends inside a transaction")
+	}
+	return records, nil
+}
+
+// ofxRecord converts the elements of a STMTTRN aggregate into a record
+func ofxRecord(fields map[string]string, line int) (Record, error) {
+	record := Record{Line: line, Status: models.StatusCleared}
+
+	// Dates are YYYYMMDD, optionally followed by a time and a time zone that do not change the day
+	posted := fields["DTPOSTED"]
+	if len(posted) < 8 {
+		return record, fmt.Errorf("line %d: missing or invalid DTPOSTED %q", line, posted)
+	}
+	date, err := time.Parse("20060102", posted[:8])
+	if err != nil {
+		return record, fmt.Errorf("line %d: invalid DTPOSTED %q", line, posted)
+	}
+	record.Date = date
+
+	// Some banks write amounts with a decimal comma
+	amount, err := strconv.ParseFloat(strings.Replace(fields["TRNAMT"], ",", ".", 1), 64)
+	if err != nil {
+		return record, fmt.Errorf("line %d: invalid TRNAMT %q", line, fields["TRNAMT"])
+	}
+	if err := signedRecord(&record, amount); err != nil {
+		return record, err
+	}
+
+	record.Description = fields["NAME"]
+	if record.Description == "" {
+		record.Description = fields["MEMO"]
+	}
+	return record, nil
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AccountDeletionJob periodically carries out confirmed account deletions whose grace period has passed
+type AccountDeletionJob struct {
+	service  services.UserService
+	interval time.Duration
+	loop     periodic
+}
+
+// NewAccountDeletionJob creates a new job for scheduled account deletions
+func NewAccountDeletionJob(service services.UserService, interval time.Duration) *AccountDeletionJob {
+	return &AccountDeletionJob{service: service, interval: interval}
+}
+
+// Start runs the deletions immediately and then on every interval until the job is stopped
+func (j *AccountDeletionJob) Start(ctx context.Context) error {
+	j.loop.start(j.interval, j.RunOnce)
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"interval": j.interval.String(),
+	}).Info("AccountDeletionJob: Started")
+	return nil
+}
+
+// Stop ends the schedule, waiting for deletions in progress to finish or ctx to be done
+func (j *AccountDeletionJob) Stop(ctx context.Context) error {
+	return j.loop.halt(ctx)
+}
+
+// Health reports an error when the job is not running or its last run failed
+func (j *AccountDeletionJob) Health(ctx context.Context) error {
+	return j.loop.health()
+}
+
+// RunOnce deletes every account that is due for deletion
+func (j *AccountDeletionJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.service.DeleteDueAccounts(ctx, time.Now())
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("AccountDeletionJob: Failed to delete due accounts")
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"deleted": deleted,
+	}).Info("AccountDeletionJob: Deleted due accounts")
+	return nil
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoginAttemptCleanupJob periodically removes failed login records that no longer affect any login
+type LoginAttemptCleanupJob struct {
+	limiter  *services.LoginLimiter
+	interval time.Duration
+	loop     periodic
+}
+
+// NewLoginAttemptCleanupJob creates a new cleanup job for failed login records
+func NewLoginAttemptCleanupJob(limiter *services.LoginLimiter, interval time.Duration) *LoginAttemptCleanupJob {
+	return &LoginAttemptCleanupJob{limiter: limiter, interval: interval}
+}
+
+// Start runs the cleanup immediately and then on every interval until the job is stopped
+func (j *LoginAttemptCleanupJob) Start(ctx context.Context) error {
+	j.loop.start(j.interval, j.RunOnce)
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"interval": j.interval.String(),
+	}).Info("LoginAttemptCleanupJob: Started")
+	return nil
+}
+
+// Stop ends the schedule, waiting for a cleanup in progress to finish or ctx to be done
+func (j *LoginAttemptCleanupJob) Stop(ctx context.Context) error {
+	return j.loop.halt(ctx)
+}
+
+// Health reports an error when the job is not running or its last run failed
+func (j *LoginAttemptCleanupJob) Health(ctx context.Context) error {
+	return j.loop.health()
+}
+
+// RunOnce removes all stale failed login records
+func (j *LoginAttemptCleanupJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.limiter.PurgeStale(ctx)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("LoginAttemptCleanupJob: Failed to remove stale login attempts")
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"deleted": deleted,
+	}).Info("LoginAttemptCleanupJob: Removed stale login attempts")
+	return nil
+}
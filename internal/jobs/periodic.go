@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// periodic runs a function immediately and then on every interval, in its own goroutine, until
+// it is stopped. The runs get a context of their own, so that stopping can let a run in progress
+// finish and only cancel it when the shutdown deadline passes.
+type periodic struct {
+	stop   chan struct{}
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	lastRun time.Time // When the last completed run started
+	lastErr error     // Error of the last completed run
+}
+
+// start begins running run on every interval
+func (p *periodic) start(interval time.Duration, run func(ctx context.Context) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	p.cancel = cancel
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			started := time.Now()
+			err := run(ctx)
+			p.mu.Lock()
+			p.lastRun, p.lastErr = started, err
+			p.mu.Unlock()
+
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// halt stops further runs and waits for a run in progress to finish. When ctx is done first,
+// the run is cancelled and ctx's error returned once it has returned.
+func (p *periodic) halt(ctx context.Context) error {
+	if p.stop == nil {
+		return nil
+	}
+	close(p.stop)
+
+	select {
+	case <-p.done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// health reports an error when the schedule is not running or its last run failed. A failed
+// run stays reported until the next run succeeds.
+func (p *periodic) health() error {
+	if p.stop == nil {
+		return errors.New("not started")
+	}
+	select {
+	case <-p.done:
+		return errors.New("stopped")
+	default:
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.lastErr != nil {
+		return fmt.Errorf("last run at %s failed: %w", p.lastRun.UTC().Format(time.RFC3339), p.lastErr)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeriodicHaltWaitsForRunInProgress(t *testing.T) {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	finished := false
+
+	var p periodic
+	p.start(time.Hour, func(ctx context.Context) error {
+		close(running)
+		<-release
+		finished = true
+		return nil
+	})
+	<-running
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if err := p.halt(context.Background()); err != nil {
+		t.Fatalf("halt: %v", err)
+	}
+	if !finished {
+		t.Error("halt returned before the run finished")
+	}
+}
+
+func TestPeriodicHaltCancelsRunAtDeadline(t *testing.T) {
+	running := make(chan struct{})
+	cancelled := false
+
+	var p periodic
+	p.start(time.Hour, func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		cancelled = true
+		return ctx.Err()
+	})
+	<-running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.halt(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("halt error = %v, want deadline exceeded", err)
+	}
+	if !cancelled {
+		t.Error("halt returned before the run was cancelled")
+	}
+}
+
+func TestPeriodicHaltWithoutStart(t *testing.T) {
+	var p periodic
+	if err := p.halt(context.Background()); err != nil {
+		t.Errorf("halt: %v", err)
+	}
+}
+
+func TestPeriodicHealth(t *testing.T) {
+	var p periodic
+	if err := p.health(); err == nil || err.Error() != "not started" {
+		t.Errorf("health before start = %v", err)
+	}
+
+	// Every run announces itself and returns the next result; a run has been recorded once
+	// the following one has started
+	started := make(chan struct{})
+	results := make(chan error)
+	p.start(time.Millisecond, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case err := <-results:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	<-started
+	results <- errors.New("database is down")
+	<-started
+	if err := p.health(); err == nil || !strings.Contains(err.Error(), "failed: database is down") {
+		t.Errorf("health after a failed run = %v", err)
+	}
+	results <- nil
+	<-started
+	if err := p.health(); err != nil {
+		t.Errorf("health after a successful run = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.halt(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("halt error = %v, want deadline exceeded", err)
+	}
+	if err := p.health(); err == nil || err.Error() != "stopped" {
+		t.Errorf("health after halt = %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TokenCleanupJob periodically removes expired refresh tokens and access token denylist entries
+type TokenCleanupJob struct {
+	service  services.TokenService
+	interval time.Duration
+	loop     periodic
+}
+
+// NewTokenCleanupJob creates a new cleanup job for expired tokens
+func NewTokenCleanupJob(service services.TokenService, interval time.Duration) *TokenCleanupJob {
+	return &TokenCleanupJob{service: service, interval: interval}
+}
+
+// Start runs the cleanup immediately and then on every interval until the job is stopped
+func (j *TokenCleanupJob) Start(ctx context.Context) error {
+	j.loop.start(j.interval, j.RunOnce)
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"interval": j.interval.String(),
+	}).Info("TokenCleanupJob: Started")
+	return nil
+}
+
+// Stop ends the schedule, waiting for a cleanup in progress to finish or ctx to be done
+func (j *TokenCleanupJob) Stop(ctx context.Context) error {
+	return j.loop.halt(ctx)
+}
+
+// Health reports an error when the job is not running or its last run failed
+func (j *TokenCleanupJob) Health(ctx context.Context) error {
+	return j.loop.health()
+}
+
+// RunOnce removes all tokens that have already expired
+func (j *TokenCleanupJob) RunOnce(ctx context.Context) error {
+	deleted, err := j.service.PurgeExpired(ctx, time.Now())
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("TokenCleanupJob: Failed to remove expired tokens")
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"deleted": deleted,
+	}).Info("TokenCleanupJob: Removed expired tokens")
+	return nil
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/services"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TrashPurgeJob periodically purges soft-deleted records older than the retention period
+type TrashPurgeJob struct {
+	service   services.TrashService
+	retention time.Duration
+	interval  time.Duration
+	loop      periodic
+}
+
+// NewTrashPurgeJob creates a new retention job for soft-deleted records
+func NewTrashPurgeJob(service services.TrashService, retention, interval time.Duration) *TrashPurgeJob {
+	return &TrashPurgeJob{service: service, retention: retention, interval: interval}
+}
+
+// Start runs the purge immediately and then on every interval until the job is stopped
+func (j *TrashPurgeJob) Start(ctx context.Context) error {
+	j.loop.start(j.interval, j.RunOnce)
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"retention": j.retention.String(),
+		"interval":  j.interval.String(),
+	}).Info("TrashPurgeJob: Started")
+	return nil
+}
+
+// Stop ends the schedule, waiting for a purge in progress to finish or ctx to be done
+func (j *TrashPurgeJob) Stop(ctx context.Context) error {
+	return j.loop.halt(ctx)
+}
+
+// Health reports an error when the job is not running or its last run failed
+func (j *TrashPurgeJob) Health(ctx context.Context) error {
+	return j.loop.health()
+}
+
+// RunOnce purges all records that were soft-deleted before the retention cutoff
+func (j *TrashPurgeJob) RunOnce(ctx context.Context) error {
+	cutoff := time.Now().Add(-j.retention)
+
+	result, err := j.service.PurgeExpired(ctx, cutoff)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"cutoff": cutoff,
+		}).Error("TrashPurgeJob: Failed to purge expired records")
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"cutoff":       cutoff,
+		"transactions": result.Transactions,
+		"categories":   result.Categories,
+	}).Info("TrashPurgeJob: Purged expired records")
+	return nil
+}
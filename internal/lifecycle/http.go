@@ -0,0 +1,49 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// HTTPServer runs an http.Server as a component. Start returns once the server listens, so that
+// an address in use fails startup; errors while serving are reported on Err.
+type HTTPServer struct {
+	server *http.Server
+	errs   chan error
+}
+
+// NewHTTPServer creates a component for the server
+func NewHTTPServer(server *http.Server) *HTTPServer {
+	return &HTTPServer{server: server, errs: make(chan error, 1)}
+}
+
+// Start listens on the server's address and serves connections in the background
+func (s *HTTPServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
+		}
+	}()
+	return nil
+}
+
+// Stop stops accepting connections and waits for in-flight requests to complete. Connections
+// still open when ctx is done are closed.
+func (s *HTTPServer) Stop(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.server.Close()
+	}
+	return err
+}
+
+// Err receives an error when the server stops serving other than through Stop
+func (s *HTTPServer) Err() <-chan error {
+	return s.errs
+}
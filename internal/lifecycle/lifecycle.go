@@ -0,0 +1,125 @@
+// Package lifecycle starts and stops the long-running parts of the application, such as the
+// HTTP server and background jobs, in a defined order
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Component is a part of the application that runs from startup to shutdown. Start must not
+// block beyond setting the component up; work continues in the background until Stop. Stop
+// should return once the work has finished, or when ctx is done.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Hook adapts a pair of functions to a Component; either may be nil
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Start calls OnStart
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+// Stop calls OnStop
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+// registered is a component with the name it is logged under
+type registered struct {
+	name      string
+	component Component
+}
+
+// Manager starts components in the order they were registered and stops them in reverse, so a
+// component can rely on everything registered before it, such as the database, while it runs
+// and while it stops
+type Manager struct {
+	mu         sync.Mutex
+	components []registered
+	started    int // Number of components, from the first, that have been started
+}
+
+// NewManager creates a manager without components
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Register adds a component to be started after those registered so far. Components cannot be
+// registered once the manager has started.
+func (m *Manager) Register(name string, component Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started > 0 {
+		panic(fmt.Sprintf("lifecycle: component %s registered after start", name))
+	}
+	m.components = append(m.components, registered{name: name, component: component})
+}
+
+// Start starts every component in order. When one fails, the components already started are
+// stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.started < len(m.components) {
+		c := m.components[m.started]
+		if err := c.component.Start(ctx); err != nil {
+			startErr := fmt.Errorf("starting %s: %w", c.name, err)
+			if stopErr := m.stopStarted(ctx); stopErr != nil {
+				return errors.Join(startErr, stopErr)
+			}
+			return startErr
+		}
+		m.started++
+		logrus.WithFields(logrus.Fields{
+			"component": c.name,
+		}).Info("Lifecycle: Component started")
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order. Every component is asked to stop even
+// when an earlier one fails or ctx is done; the errors are returned together.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopStarted(ctx)
+}
+
+// stopStarted stops the started components in reverse order; m.mu must be held
+func (m *Manager) stopStarted(ctx context.Context) error {
+	var errs []error
+	for m.started > 0 {
+		m.started--
+		c := m.components[m.started]
+		if err := c.component.Stop(ctx); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"component": c.name,
+				"error":     err.Error(),
+			}).Error("Lifecycle: Component failed to stop cleanly")
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.name, err))
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"component": c.name,
+		}).Info("Lifecycle: Component stopped")
+	}
+	return errors.Join(errs...)
+}
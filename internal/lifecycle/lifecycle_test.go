@@ -0,0 +1,143 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder returns components that append their start and stop to a shared log
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr, stopErr error) Component {
+	return Hook{
+		OnStart: func(ctx context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestManagerStartsInOrderAndStopsInReverse(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Register("db", r.component("db", nil, nil))
+	m.Register("job", r.component("job", nil, nil))
+	m.Register("server", r.component("server", nil, nil))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	want := []string{"start db", "start job", "start server", "stop server", "stop job", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v", r.events, want)
+	}
+
+	// Stopping again has nothing left to stop
+	if err := m.Stop(context.Background()); err != nil || len(r.events) != len(want) {
+		t.Errorf("second Stop = %v with events %v", err, r.events)
+	}
+}
+
+func TestManagerStartFailureStopsStartedComponents(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Register("db", r.component("db", nil, nil))
+	m.Register("job", r.component("job", nil, errors.New("job stuck")))
+	m.Register("server", r.component("server", errors.New("address in use"), nil))
+	m.Register("never", r.component("never", nil, nil))
+
+	err := m.Start(context.Background())
+	if err == nil || err.Error() != "starting server: address in use\nstopping job: job stuck" {
+		t.Fatalf("Start error = %v", err)
+	}
+	want := []string{"start db", "start job", "start server", "stop job", "stop db"}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("events = %v, want %v", r.events, want)
+	}
+}
+
+func TestManagerStopContinuesAfterFailure(t *testing.T) {
+	r := &recorder{}
+	m := NewManager()
+	m.Register("db", r.component("db", nil, errors.New("close failed")))
+	m.Register("server", r.component("server", nil, context.DeadlineExceeded))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.Stop(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || err.Error() != "stopping server: context deadline exceeded\nstopping db: close failed" {
+		t.Errorf("Stop error = %v", err)
+	}
+}
+
+func TestHTTPServerDrainsRequestsOnStop(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		fmt.Fprint(w, "done")
+	})
+
+	// Find a free port for the server to listen on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := NewHTTPServer(&http.Server{Addr: addr, Handler: handler})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := NewHTTPServer(&http.Server{Addr: addr}).Start(context.Background()); err == nil {
+		t.Error("starting a second server on the same address succeeded")
+	}
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if got := <-body; got != "done" {
+		t.Errorf("response = %q, want done", got)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// gormStartKey holds the start time of an operation in the GORM statement
+const gormStartKey = "metrics:start"
+
+// GormPlugin records the duration of every GORM operation in DBQueryDuration
+type GormPlugin struct{}
+
+// Name identifies the plugin to GORM
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize registers the timing callbacks around each GORM operation that runs SQL
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", startTimer),
+		cb.Create().After("gorm:create").Register("metrics:after_create", observeDuration("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", startTimer),
+		cb.Query().After("gorm:query").Register("metrics:after_query", observeDuration("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", startTimer),
+		cb.Update().After("gorm:update").Register("metrics:after_update", observeDuration("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", startTimer),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", observeDuration("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", startTimer),
+		cb.Row().After("gorm:row").Register("metrics:after_row", observeDuration("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", startTimer),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", observeDuration("raw")),
+	)
+}
+
+// startTimer records when an operation starts
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+// observeDuration returns a callback that records the duration of an operation by its table.
+// Raw SQL has no table and is recorded as "unknown".
+func observeDuration(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
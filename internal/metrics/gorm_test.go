@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"testing"
+
+	"personal-finance-tracker-api/internal/repository"
+)
+
+// observedQueries returns the number of durations observed in DBQueryDuration, keyed by
+// operation and table
+func observedQueries(t *testing.T) map[string]uint64 {
+	t.Helper()
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != "pft_db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			observed[labels["operation"]+" "+labels["table"]] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return observed
+}
+
+func TestGormPluginObservesQueries(t *testing.T) {
+	db, err := repository.OpenDB(repository.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	if err := db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var widgets []widget
+	if err := db.Find(&widgets).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Find(&widgets).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	observed := observedQueries(t)
+	for key, want := range map[string]uint64{"raw unknown": 1, "create widgets": 1, "query widgets": 2} {
+		if observed[key] != want {
+			t.Errorf("durations observed for %s = %d, want %d (all: %v)", key, observed[key], want, observed)
+		}
+	}
+}
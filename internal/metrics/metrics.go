@@ -0,0 +1,111 @@
+// Package metrics defines the Prometheus metrics of the application and the registry that
+// /metrics exposes. The metrics are package variables, so that any layer can record to them
+// without having them passed in.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of the application's metrics
+const namespace = "pft"
+
+// Reasons of a failed login, as recorded by LoginFailures
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials"
+	LoginFailureInvalidCode        = "invalid_second_factor"
+	LoginFailureRateLimited        = "rate_limited"
+)
+
+// Results of an import, as recorded by ImportsProcessed
+const (
+	ImportSucceeded = "success"
+	ImportFailed    = "failure"
+)
+
+// Registry holds every metric exposed by the application, including the Go runtime and process
+// metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts handled requests by method, route template and status code
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes the time taken to handle requests
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// DBQueryDuration observes the time taken by GORM operations
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time taken by database queries, by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// TransactionsCreated counts transactions recorded one at a time or by import
+	TransactionsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_created_total",
+		Help:      "Transactions created, including imported ones.",
+	})
+
+	// ImportsProcessed counts transaction imports by result
+	ImportsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "imports_processed_total",
+		Help:      "Transaction imports processed, by result.",
+	}, []string{"result"})
+
+	// LoginFailures counts rejected logins by reason
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Rejected logins, by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		TransactionsCreated,
+		ImportsProcessed,
+		LoginFailures,
+	)
+
+	// Export the labelled counters at zero before their first event, so rates can be computed
+	for _, result := range []string{ImportSucceeded, ImportFailed} {
+		ImportsProcessed.WithLabelValues(result)
+	}
+	for _, reason := range []string{LoginFailureInvalidCredentials, LoginFailureInvalidCode, LoginFailureRateLimited} {
+		LoginFailures.WithLabelValues(reason)
+	}
+}
+
+// RegisterDBStats exports the statistics of a database connection pool, such as open, in-use
+// and idle connections and the time spent waiting for one
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "main"))
+}
+
+// Handler serves the metrics of Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
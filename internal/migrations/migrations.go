@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var builtinMigrations embed.FS
+
+// Dialects with built-in migrations, named like the GORM dialector of the database
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// fileNamePattern matches migration scripts named <version>_<name>.<up|down>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change to the database schema with the script that reverts it
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // Hex SHA-256 of Up; a changed script no longer matches the applied version
+}
+
+// Builtin loads the migrations embedded in the binary for a dialect. Both dialects have the
+// same versions, written in their own SQL.
+func Builtin(dialect string) ([]Migration, error) {
+	if dialect != DialectPostgres && dialect != DialectSQLite {
+		return nil, fmt.Errorf("no migrations for database dialect %q", dialect)
+	}
+	sub, err := fs.Sub(builtinMigrations, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load reads every migration at the root of the given file system, ordered by version. Each
+// version needs both an up and a down script, and versions must be unique. Version 0 upgrades
+// databases of releases before versioned migrations and runs before the initial schema.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, file := range files {
+		parts := fileNamePattern.FindStringSubmatch(file)
+		if parts == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected <version>_<name>.<up|down>.sql", file)
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s", file)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			migration.Up = string(data)
+			sum := sha256.Sum256(data)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
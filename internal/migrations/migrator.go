@@ -0,0 +1,295 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migrationLockID identifies the PostgreSQL advisory lock held while migrating, so that only
+// one of several replicas starting at the same time changes the schema
+const migrationLockID int64 = 7_341_905_213
+
+// dialectQueries holds the statements of the migrator that differ between dialects
+type dialectQueries struct {
+	lock          string // Empty when the database needs no lock
+	unlock        string
+	createHistory string // Creates schema_migrations, which records the applied migrations
+	historyExists string // Reports whether schema_migrations has been created
+	insertHistory string
+	deleteHistory string
+}
+
+var queries = map[string]dialectQueries{
+	DialectPostgres: {
+		lock:   fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockID),
+		unlock: fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockID),
+		createHistory: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL
+)`,
+		historyExists: "SELECT to_regclass('schema_migrations') IS NOT NULL",
+		insertHistory: "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+		deleteHistory: "DELETE FROM schema_migrations WHERE version = $1",
+	},
+	// SQLite databases are files of a single process, and writers are serialised by SQLite itself
+	DialectSQLite: {
+		createHistory: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at DATETIME NOT NULL
+)`,
+		historyExists: "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')",
+		insertHistory: "INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+		deleteHistory: "DELETE FROM schema_migrations WHERE version = ?",
+	},
+}
+
+// Status describes a known migration and whether it has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts migrations on a PostgreSQL or SQLite database
+type Migrator struct {
+	db         *sql.DB
+	queries    dialectQueries
+	migrations []Migration
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// NewMigrator creates a Migrator for the given migrations of a dialect, which must be ordered
+// by version
+func NewMigrator(db *sql.DB, dialect string, migrations []Migration) (*Migrator, error) {
+	q, ok := queries[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database dialect %q", dialect)
+	}
+	return &Migrator{db: db, queries: q, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the ones it applied. Each migration
+// runs in its own transaction together with its entry in schema_migrations.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		history, err := m.verifiedHistory(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones it reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		history, err := m.verifiedHistory(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := history[migration.Version]; !ok {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied, if it was
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		history, err := m.verifiedHistory(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if row, ok := history[migration.Version]; ok {
+				status.AppliedAt = &row.AppliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending lists the migrations that have not been applied yet. Unlike Status it neither takes
+// the migration lock nor creates the history table, so it is cheap enough for readiness probes;
+// on a database without the table every migration is pending.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer conn.Close()
+
+	var exists bool
+	if err := conn.QueryRowContext(ctx, m.queries.historyExists).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	history := map[int64]appliedMigration{}
+	if exists {
+		if history, err = m.verifiedHistory(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := history[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// withLock runs fn on a connection holding the migration lock. Other migrators wait until the
+// lock is released, after which they find the migrations already applied.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.queries.lock != "" {
+		if _, err := conn.ExecContext(ctx, m.queries.lock); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			// The lock is also released when the session ends, so a failure here is harmless
+			if _, err := conn.ExecContext(context.Background(), m.queries.unlock); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+				}).Warn("Failed to release migration lock")
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.queries.createHistory); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return fn(conn)
+}
+
+// verifiedHistory loads the applied migrations, keyed by version. It fails when an applied
+// migration has been changed since, or is unknown because the database is newer than the binary.
+func (m *Migrator) verifiedHistory(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	history := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		history[row.Version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	for version, row := range history {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("database has migration %d_%s applied, which this version does not know; it is newer than the application", version, row.Name)
+		}
+		if migration.Checksum != row.Checksum {
+			return nil, fmt.Errorf("migration %d_%s has been modified after it was applied (checksum %s, expected %s)", version, migration.Name, migration.Checksum, row.Checksum)
+		}
+	}
+	return history, nil
+}
+
+// apply runs the up script of a migration and records it
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.queries.insertHistory, migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	logrus.WithFields(logrus.Fields{
+		"version": migration.Version,
+		"name":    migration.Name,
+	}).Info("Migration applied")
+	return nil
+}
+
+// revert runs the down script of a migration and removes its record
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.queries.deleteHistory, migration.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	logrus.WithFields(logrus.Fields{
+		"version": migration.Version,
+		"name":    migration.Name,
+	}).Info("Migration reverted")
+	return nil
+}
+
+// inTransaction runs fn in a transaction on conn, committing when it succeeds
+func inTransaction(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,298 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/glebarez/go-sqlite"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	logrus.SetOutput(io.Discard)
+}
+
+// openSQLite opens an empty SQLite database that is closed when the test ends
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(DialectSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testMigrations returns two migrations creating and extending a table
+func testMigrations(t *testing.T) []Migration {
+	t.Helper()
+	migrations, err := Load(fstest.MapFS{
+		"0001_create_notes.up.sql":     {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY)")},
+		"0001_create_notes.down.sql":   {Data: []byte("DROP TABLE notes")},
+		"0002_note_text.up.sql":        {Data: []byte("ALTER TABLE notes ADD COLUMN text TEXT")},
+		"0002_note_text.down.sql":      {Data: []byte("ALTER TABLE notes DROP COLUMN text")},
+		"README.md":                    {Data: []byte("not a migration")},
+		"nested/0003_ignored.up.sql":   {Data: []byte("SELECT 1")},
+		"nested/0003_ignored.down.sql": {Data: []byte("SELECT 1")},
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return migrations
+}
+
+// versions returns the versions of migrations
+func versions(migrations []Migration) []int64 {
+	var result []int64
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+// assertVersions fails the test unless migrations have exactly the versions want
+func assertVersions(t *testing.T, name string, migrations []Migration, want ...int64) {
+	t.Helper()
+	got := versions(migrations)
+	if len(got) != len(want) {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{"ordered by version", fstest.MapFS{
+			"0010_b.up.sql": {Data: []byte("b")}, "0010_b.down.sql": {Data: []byte("b")},
+			"0002_a.up.sql": {Data: []byte("a")}, "0002_a.down.sql": {Data: []byte("a")},
+		}, ""},
+		{"invalid file name", fstest.MapFS{"0001-a.up.sql": {Data: []byte("a")}}, "invalid migration file name"},
+		{"missing down script", fstest.MapFS{"0001_a.up.sql": {Data: []byte("a")}}, "needs both an up and a down script"},
+		{"version used twice", fstest.MapFS{
+			"0001_a.up.sql": {Data: []byte("a")}, "0001_a.down.sql": {Data: []byte("a")},
+			"0001_b.up.sql": {Data: []byte("b")}, "0001_b.down.sql": {Data: []byte("b")},
+		}, "is used by both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			assertVersions(t, "versions", migrations, 2, 10)
+			if migrations[0].Name != "a" || migrations[0].Checksum == migrations[1].Checksum {
+				t.Errorf("migrations = %+v, want a with its own checksum first", migrations)
+			}
+		})
+	}
+}
+
+func TestBuiltinDialectsInStep(t *testing.T) {
+	postgres, err := Builtin(DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := Builtin(DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations, sqlite %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+			t.Errorf("migration %d is %d_%s for postgres and %d_%s for sqlite", i,
+				postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+		}
+	}
+	if _, err := Builtin("mysql"); err == nil {
+		t.Error("Builtin accepted an unsupported dialect")
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrator, err := NewMigrator(db, DialectSQLite, testMigrations(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new database has no history table yet, which readiness probes must not trip over
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending on a new database: %v", err)
+	}
+	assertVersions(t, "pending", pending, 1, 2)
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	assertVersions(t, "applied", applied, 1, 2)
+	if _, err := db.Exec("INSERT INTO notes (text) VALUES ('hello')"); err != nil {
+		t.Fatalf("schema not migrated: %v", err)
+	}
+	if applied, err = migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up = %v, %v; want nothing applied", versions(applied), err)
+	}
+	pending, err = migrator.Pending(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Fatalf("Pending after Up = %v, %v; want none", versions(pending), err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	assertVersions(t, "reverted", reverted, 2)
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("statuses = %+v, want 1 applied and 2 pending", statuses)
+	}
+
+	if reverted, err = migrator.Down(ctx, 5); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	assertVersions(t, "reverted", reverted, 1)
+	if _, err := db.Exec("SELECT 1 FROM notes"); err == nil {
+		t.Error("notes still exists after reverting every migration")
+	}
+}
+
+func TestMigratorStatusOnNewDatabase(t *testing.T) {
+	migrator, err := NewMigrator(openSQLite(t), DialectSQLite, testMigrations(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].AppliedAt != nil || statuses[1].AppliedAt != nil {
+		t.Fatalf("statuses = %+v, want both pending", statuses)
+	}
+}
+
+func TestMigratorVerifiesHistory(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	original := testMigrations(t)
+	migrator, err := NewMigrator(db, DialectSQLite, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	modified := append([]Migration(nil), original...)
+	modified[1].Up = "ALTER TABLE notes ADD COLUMN body TEXT"
+	modified[1].Checksum = "changed"
+
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    string
+	}{
+		{"applied migration modified", modified, "has been modified"},
+		{"applied migration unknown", original[:1], "newer than the application"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrator, err := NewMigrator(db, DialectSQLite, tt.migrations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := migrator.Up(ctx); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Up error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if _, err := migrator.Pending(ctx); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Pending error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigratorRollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	migrations := testMigrations(t)
+	migrations[1].Up = "ALTER TABLE notes ADD COLUMN text TEXT; ALTER TABLE missing ADD COLUMN x TEXT"
+	migrator, err := NewMigrator(db, DialectSQLite, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_note_text") {
+		t.Fatalf("Up error = %v, want the failure of 2_note_text", err)
+	}
+	assertVersions(t, "applied", applied, 1)
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "pending", pending, 2)
+	if _, err := db.Exec("SELECT text FROM notes"); err == nil {
+		t.Error("the failed migration was partly applied")
+	}
+}
+
+func TestBuiltinSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	builtin, err := Builtin(DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(db, DialectSQLite, builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := migrator.Down(ctx, len(builtin)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+
+	var tables []string
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	if len(tables) != 1 || tables[0] != "schema_migrations" {
+		t.Errorf("tables after reverting everything = %v, want only schema_migrations", tables)
+	}
+}
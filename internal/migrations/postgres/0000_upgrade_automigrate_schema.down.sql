@@ -0,0 +1,3 @@
+-- The upgrade only brings data and columns in line with 0001_initial_schema, whose down script
+-- removes them; there is nothing to revert separately.
+SELECT 1;
@@ -0,0 +1,97 @@
+-- Upgrades databases set up by releases before versioned migrations, whose schema GORM's
+-- AutoMigrate maintained, to the point where 0001_initial_schema can adopt them: it adds the
+-- columns later releases introduced, moves data created before households existed into a
+-- personal household per user and replaces the per-user unique category names with per-household
+-- ones. It does nothing on a new database or one that has migrations applied.
+DO $$
+DECLARE
+    legacy_user RECORD;
+    household BIGINT;
+    households_existed BOOLEAN;
+BEGIN
+    IF EXISTS (SELECT 1 FROM schema_migrations) OR to_regclass('users') IS NULL THEN
+        RETURN;
+    END IF;
+    households_existed := to_regclass('households') IS NOT NULL;
+
+    CREATE TABLE IF NOT EXISTS households (
+        id BIGSERIAL PRIMARY KEY,
+        name VARCHAR(100) NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+    CREATE TABLE IF NOT EXISTS household_members (
+        id BIGSERIAL PRIMARY KEY,
+        household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+        user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    );
+    CREATE TABLE IF NOT EXISTS reconciliations (
+        id BIGSERIAL PRIMARY KEY,
+        statement_end_date TIMESTAMPTZ NOT NULL,
+        closing_balance NUMERIC(12, 2) NOT NULL,
+        cleared_balance NUMERIC(12, 2) NOT NULL DEFAULT 0,
+        difference NUMERIC(12, 2) NOT NULL DEFAULT 0,
+        status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed')),
+        completed_at TIMESTAMPTZ,
+        household_id BIGINT REFERENCES households(id) ON DELETE CASCADE,
+        user_id BIGINT NOT NULL REFERENCES users(id),
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        deleted_at TIMESTAMPTZ
+    );
+
+    -- Columns of users added since the first release
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100);
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'en-US';
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS base_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS first_day_of_week VARCHAR(9) NOT NULL DEFAULT 'monday';
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS fiscal_year_start VARCHAR(5) NOT NULL DEFAULT '01-01';
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS default_household_id BIGINT REFERENCES households(id) ON DELETE SET NULL;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+    -- Columns of the financial records added since the first release
+    ALTER TABLE IF EXISTS categories ADD COLUMN IF NOT EXISTS household_id BIGINT REFERENCES households(id) ON DELETE CASCADE;
+    ALTER TABLE IF EXISTS transactions ADD COLUMN IF NOT EXISTS household_id BIGINT REFERENCES households(id) ON DELETE CASCADE;
+    ALTER TABLE IF EXISTS transactions ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'pending';
+    ALTER TABLE IF EXISTS transactions ADD COLUMN IF NOT EXISTS reconciliation_id BIGINT REFERENCES reconciliations(id) ON DELETE SET NULL;
+
+    -- Every user gets a personal household owning the records they created. Releases with
+    -- households already did this when they introduced them; a user without a default household
+    -- there has left their last one, and keeps having none.
+    IF NOT households_existed THEN
+        FOR legacy_user IN SELECT id, username FROM users WHERE default_household_id IS NULL ORDER BY id LOOP
+            INSERT INTO households (name, created_at, updated_at) VALUES (legacy_user.username, NOW(), NOW())
+                RETURNING id INTO household;
+            INSERT INTO household_members (household_id, user_id, role, created_at)
+                VALUES (household, legacy_user.id, 'owner', NOW());
+            UPDATE users SET default_household_id = household WHERE id = legacy_user.id;
+        END LOOP;
+    END IF;
+    IF to_regclass('categories') IS NOT NULL THEN
+        UPDATE categories SET household_id = (SELECT default_household_id FROM users WHERE users.id = categories.user_id)
+            WHERE household_id IS NULL;
+        ALTER TABLE categories ALTER COLUMN household_id SET NOT NULL;
+    END IF;
+    IF to_regclass('transactions') IS NOT NULL THEN
+        UPDATE transactions SET household_id = (SELECT default_household_id FROM users WHERE users.id = transactions.user_id)
+            WHERE household_id IS NULL;
+        ALTER TABLE transactions ALTER COLUMN household_id SET NOT NULL;
+    END IF;
+    UPDATE reconciliations SET household_id = (SELECT default_household_id FROM users WHERE users.id = reconciliations.user_id)
+        WHERE household_id IS NULL;
+    ALTER TABLE reconciliations ALTER COLUMN household_id SET NOT NULL;
+
+    -- Category names were unique globally, then per user; they are now unique per household,
+    -- which 0001_initial_schema indexes
+    DROP INDEX IF EXISTS idx_categories_user_name;
+    ALTER TABLE IF EXISTS categories DROP CONSTRAINT IF EXISTS uni_categories_name;
+    ALTER TABLE IF EXISTS categories DROP CONSTRAINT IF EXISTS categories_name_key;
+END;
+$$;
@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION reject_audit_entry_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE audit_entries DROP COLUMN erased_at;
+ALTER TABLE audit_entries DROP COLUMN content_hash;
@@ -0,0 +1,25 @@
+-- Lets the personal data of audit entries be erased when its subject deletes their account.
+-- New entries hash that data separately, so erasing it leaves the hash chain intact.
+ALTER TABLE audit_entries ADD COLUMN content_hash VARCHAR(64);
+ALTER TABLE audit_entries ADD COLUMN erased_at TIMESTAMPTZ;
+
+-- Rejects changes to audit entries once they have been written, except erasing the personal
+-- data of an entry once
+CREATE OR REPLACE FUNCTION reject_audit_entry_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.erased_at IS NULL AND NEW.erased_at IS NOT NULL
+        AND NEW.id = OLD.id
+        AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+        AND NEW.household_id IS NOT DISTINCT FROM OLD.household_id
+        AND NEW.action = OLD.action
+        AND NEW.entity_type = OLD.entity_type
+        AND NEW.entity_id = OLD.entity_id
+        AND NEW.content_hash IS NOT DISTINCT FROM OLD.content_hash
+        AND NEW.prev_hash = OLD.prev_hash
+        AND NEW.hash = OLD.hash
+        AND NEW.created_at = OLD.created_at THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1 @@
+DROP INDEX idx_reconciliations_open_household;
@@ -0,0 +1,3 @@
+-- SQLite databases are only supported since versioned migrations, so there are no databases of
+-- earlier releases to upgrade. The version exists to keep both dialects in step.
+SELECT 1;
@@ -0,0 +1,19 @@
+-- Removes the whole schema, including all data
+DROP TABLE IF EXISTS audit_entries;
+DROP TABLE IF EXISTS account_deletions;
+DROP TABLE IF EXISTS oidc_auth_requests;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS api_tokens;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS reconciliations;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS household_invitations;
+DROP TABLE IF EXISTS household_members;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS households;
@@ -0,0 +1,270 @@
+-- Creates the schema as of the introduction of versioned migrations, in SQLite's dialect
+
+-- Households own categories, transactions and reconciliations
+CREATE TABLE households (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Users with their authentication state and preferences
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(100) NOT NULL UNIQUE,
+    email VARCHAR(255),
+    password_hash TEXT NOT NULL,
+    display_name VARCHAR(100),
+    locale VARCHAR(35) NOT NULL DEFAULT 'en-US',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    base_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    first_day_of_week VARCHAR(9) NOT NULL DEFAULT 'monday',
+    fiscal_year_start VARCHAR(5) NOT NULL DEFAULT '01-01',
+    default_household_id INTEGER REFERENCES households(id) ON DELETE SET NULL,
+    sessions_revoked_at DATETIME,
+    totp_secret VARCHAR(64),
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_counter INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_users_email ON users(email);
+
+-- Roles of users in households
+CREATE TABLE household_members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_household_members_household_user ON household_members(household_id, user_id);
+CREATE INDEX idx_household_members_user_id ON household_members(user_id);
+
+-- Hashed single-use invitations to join a household
+CREATE TABLE household_invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    token_hash VARCHAR(64) NOT NULL,
+    invited_by_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    accepted_by_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_household_invitations_household_id ON household_invitations(household_id);
+CREATE UNIQUE INDEX idx_household_invitations_token_hash ON household_invitations(token_hash);
+
+-- Expense and income categories, nested through parent_id. Names are unique per household
+-- among categories that are not in the trash.
+CREATE TABLE categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    parent_id INTEGER REFERENCES categories(id) ON DELETE SET NULL,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX idx_categories_household_name ON categories(name, household_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_categories_deleted_at ON categories(deleted_at);
+
+-- Bank statement reconciliation sessions
+CREATE TABLE reconciliations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    statement_end_date DATETIME NOT NULL,
+    closing_balance NUMERIC(12, 2) NOT NULL,
+    cleared_balance NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    difference NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed')),
+    completed_at DATETIME,
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX idx_reconciliations_household_id ON reconciliations(household_id);
+CREATE INDEX idx_reconciliations_deleted_at ON reconciliations(deleted_at);
+
+-- Income and expense records
+CREATE TABLE transactions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    description TEXT,
+    amount NUMERIC(10, 2) NOT NULL,
+    type VARCHAR(7) NOT NULL CHECK (type IN ('income', 'expense')),
+    date DATETIME NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'cleared', 'reconciled')),
+    reconciliation_id INTEGER REFERENCES reconciliations(id) ON DELETE SET NULL,
+    category_id INTEGER NOT NULL REFERENCES categories(id),
+    household_id INTEGER NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+CREATE INDEX idx_transactions_household_id ON transactions(household_id);
+CREATE INDEX idx_transactions_deleted_at ON transactions(deleted_at);
+
+-- Hashed, rotating refresh tokens
+CREATE TABLE refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+
+-- Denylist of access tokens revoked before they expire
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+-- Hashed personal access tokens
+CREATE TABLE api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+CREATE UNIQUE INDEX idx_api_tokens_token_hash ON api_tokens(token_hash);
+
+-- Hashed one-time two-factor recovery codes
+CREATE TABLE recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id);
+
+-- Second step of two-factor logins
+CREATE TABLE login_challenges (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_login_challenges_user_id ON login_challenges(user_id);
+CREATE UNIQUE INDEX idx_login_challenges_token_hash ON login_challenges(token_hash);
+
+-- Failed logins per username and per client IP
+CREATE TABLE login_attempts (
+    attempt_key VARCHAR(200) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at DATETIME NOT NULL,
+    locked_until DATETIME
+);
+CREATE INDEX idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+
+-- Hashed single-use password reset tokens
+CREATE TABLE password_reset_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+CREATE UNIQUE INDEX idx_password_reset_tokens_token_hash ON password_reset_tokens(token_hash);
+
+-- Links between users and OpenID Connect provider accounts
+CREATE TABLE user_identities (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    last_login_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_user_identities_issuer_subject ON user_identities(issuer, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+
+-- Pending OpenID Connect logins
+CREATE TABLE oidc_auth_requests (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    state_hash VARCHAR(64) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    link_user_id INTEGER,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_oidc_auth_requests_state_hash ON oidc_auth_requests(state_hash);
+CREATE INDEX idx_oidc_auth_requests_expires_at ON oidc_auth_requests(expires_at);
+
+-- Account deletion requests awaiting confirmation or the end of their grace period
+CREATE TABLE account_deletions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mode VARCHAR(10) NOT NULL CHECK (mode IN ('delete', 'anonymize')),
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    confirmed_at DATETIME,
+    scheduled_for DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_account_deletions_user_id ON account_deletions(user_id);
+CREATE UNIQUE INDEX idx_account_deletions_token_hash ON account_deletions(token_hash);
+CREATE INDEX idx_account_deletions_scheduled_for ON account_deletions(scheduled_for);
+
+-- Append-only log of changes. Each entry holds the hash of the previous one; actor and
+-- household are kept without foreign keys so that entries outlive the rows they refer to.
+CREATE TABLE audit_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER,
+    household_id INTEGER,
+    action VARCHAR(30) NOT NULL,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    changes TEXT,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(255),
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX idx_audit_entries_actor_id ON audit_entries(actor_id);
+CREATE INDEX idx_audit_entries_household_id ON audit_entries(household_id);
+CREATE INDEX idx_audit_entries_entity ON audit_entries(entity_type, entity_id);
+CREATE INDEX idx_audit_entries_created_at ON audit_entries(created_at);
+CREATE UNIQUE INDEX idx_audit_entries_hash ON audit_entries(hash);
+
+-- Rejects changes to audit entries once they have been written
+CREATE TRIGGER audit_entries_no_update
+    BEFORE UPDATE ON audit_entries
+BEGIN
+    SELECT RAISE(ABORT, 'audit_entries is append-only');
+END;
+CREATE TRIGGER audit_entries_no_delete
+    BEFORE DELETE ON audit_entries
+BEGIN
+    SELECT RAISE(ABORT, 'audit_entries is append-only');
+END;
@@ -0,0 +1,2 @@
+-- Lets administrators disable accounts without deleting them
+ALTER TABLE users ADD COLUMN disabled_at DATETIME;
@@ -0,0 +1,3 @@
+-- Records that a user proved control of their email address; OIDC logins are only linked by
+-- email to verified addresses
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
@@ -0,0 +1,9 @@
+DROP TRIGGER audit_entries_no_update;
+CREATE TRIGGER audit_entries_no_update
+    BEFORE UPDATE ON audit_entries
+BEGIN
+    SELECT RAISE(ABORT, 'audit_entries is append-only');
+END;
+
+ALTER TABLE audit_entries DROP COLUMN erased_at;
+ALTER TABLE audit_entries DROP COLUMN content_hash;
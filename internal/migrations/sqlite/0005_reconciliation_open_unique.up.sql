@@ -0,0 +1,7 @@
+-- Only one reconciliation session may be open per household. Sessions opened twice by concurrent
+-- requests before this index existed are cancelled, keeping the oldest.
+UPDATE reconciliations SET deleted_at = CURRENT_TIMESTAMP
+WHERE status = 'open' AND deleted_at IS NULL AND id NOT IN (
+    SELECT MIN(id) FROM reconciliations WHERE status = 'open' AND deleted_at IS NULL GROUP BY household_id
+);
+CREATE UNIQUE INDEX idx_reconciliations_open_household ON reconciliations(household_id) WHERE status = 'open' AND deleted_at IS NULL;
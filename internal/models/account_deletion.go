@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AccountDeletionMode defines what happens to a user's data when their account is deleted
+type AccountDeletionMode string
+
+const (
+	// DeletionModeDelete removes the user and all of their data permanently
+	DeletionModeDelete AccountDeletionMode = "delete"
+	// DeletionModeAnonymize keeps the user's records but removes everything identifying them
+	DeletionModeAnonymize AccountDeletionMode = "anonymize"
+)
+
+// AccountDeletion is a user's request to delete their account. It takes effect once confirmed
+// with its single-use token, and is carried out when the grace period has passed unless the
+// user cancels it first. Only a hash of the confirmation token is stored.
+type AccountDeletion struct {
+	ID           uint                `gorm:"primaryKey" json:"id"`
+	UserID       uint                `gorm:"not null;uniqueIndex" json:"userId"`
+	User         User                `gorm:"foreignKey:UserID" json:"-"`
+	Mode         AccountDeletionMode `gorm:"type:varchar(10);not null" json:"mode"`
+	TokenHash    string              `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time           `gorm:"not null" json:"expiresAt"` // Deadline for confirming the request
+	ConfirmedAt  *time.Time          `json:"confirmedAt,omitempty"`
+	ScheduledFor *time.Time          `gorm:"index" json:"scheduledFor,omitempty"` // When the account will be deleted
+	CreatedAt    time.Time           `json:"createdAt"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// APITokenPrefix marks personal access tokens so they can be told apart from JWTs
+const APITokenPrefix = "pft_"
+
+// Scopes that can be granted to a personal access token
+const (
+	ScopeTransactionsRead     = "transactions:read"
+	ScopeTransactionsWrite    = "transactions:write"
+	ScopeCategoriesRead       = "categories:read"
+	ScopeCategoriesWrite      = "categories:write"
+	ScopeReconciliationsRead  = "reconciliations:read"
+	ScopeReconciliationsWrite = "reconciliations:write"
+)
+
+// AllScopes lists every scope a personal access token may hold
+var AllScopes = []string{
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeCategoriesRead,
+	ScopeCategoriesWrite,
+	ScopeReconciliationsRead,
+	ScopeReconciliationsWrite,
+}
+
+// IsValidScope reports whether scope is a known scope
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIToken is a user-managed personal access token for scripts and integrations.
+// Only a hash of the token is stored; the token itself is shown once on creation.
+type APIToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"` // Leading characters of the token, for identification
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// HasScope reports whether the token was granted scope
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
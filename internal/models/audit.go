@@ -0,0 +1,212 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore" // Taken out of the trash
+	AuditActionPurge   = "purge"   // Removed from the trash for good
+)
+
+// Entity types recorded in the audit log
+const (
+	AuditEntityTransaction  = "transaction"
+	AuditEntityCategory     = "category"
+	AuditEntityUser         = "user"
+	AuditEntityUserIdentity = "user_identity"
+)
+
+// AuditChange is the value of a field before and after a change. A side is absent when the
+// field had no value, e.g. Before for created entities and After for deleted ones.
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// AuditChanges holds the changed fields of an entity as JSON. The bytes are stored and hashed
+// exactly as they were produced, so reading them back cannot alter the hash of an entry.
+type AuditChanges []byte
+
+// Value implements driver.Valuer, storing the changes as text
+func (c AuditChanges) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return string(c), nil
+}
+
+// Scan implements sql.Scanner
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+	case string:
+		*c = AuditChanges(v)
+	case []byte:
+		*c = append(AuditChanges(nil), v...)
+	default:
+		return fmt.Errorf("cannot scan %T into AuditChanges", value)
+	}
+	return nil
+}
+
+// MarshalJSON embeds the changes in API responses as a JSON object
+func (c AuditChanges) MarshalJSON() ([]byte, error) {
+	if len(c) == 0 {
+		return []byte("null"), nil
+	}
+	return c, nil
+}
+
+// AuditEntry is an append-only record of a change to an entity. Every entry stores the hash of
+// the entry before it, so removing or editing an entry breaks the chain from that point on. The
+// personal data of an entry (its changes, IP address and user agent) enters the hash through
+// ContentHash, so that it can be erased once when its subject deletes their account.
+type AuditEntry struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	ActorID     *uint        `gorm:"index" json:"actorId,omitempty"` // User who made the change; nil for system changes
+	HouseholdID *uint        `gorm:"index" json:"householdId,omitempty"`
+	Action      string       `gorm:"size:30;not null" json:"action"`
+	EntityType  string       `gorm:"size:30;not null;index:idx_audit_entries_entity" json:"entityType"`
+	EntityID    uint         `gorm:"not null;index:idx_audit_entries_entity" json:"entityId"`
+	Changes     AuditChanges `gorm:"type:text" json:"changes" swaggertype:"object"`
+	IPAddress   string       `gorm:"size:45" json:"ipAddress,omitempty"`
+	UserAgent   string       `gorm:"size:255" json:"userAgent,omitempty"`
+	ContentHash string       `gorm:"size:64" json:"contentHash,omitempty"` // Empty for entries written before personal data could be erased
+	ErasedAt    *time.Time   `json:"erasedAt,omitempty"`
+	PrevHash    string       `gorm:"size:64;not null" json:"prevHash"`
+	Hash        string       `gorm:"size:64;not null;uniqueIndex" json:"hash"`
+	CreatedAt   time.Time    `gorm:"not null;index" json:"createdAt"`
+}
+
+// auditHashInput lists the fields covered by the hash of an audit entry in a fixed order
+type auditHashInput struct {
+	PrevHash    string `json:"prevHash"`
+	ActorID     *uint  `json:"actorId"`
+	HouseholdID *uint  `json:"householdId"`
+	Action      string `json:"action"`
+	EntityType  string `json:"entityType"`
+	EntityID    uint   `json:"entityId"`
+	ContentHash string `json:"contentHash"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// auditContentHashInput lists the personal data of an audit entry in a fixed order
+type auditContentHashInput struct {
+	Changes   json.RawMessage `json:"changes"`
+	IPAddress string          `json:"ipAddress"`
+	UserAgent string          `json:"userAgent"`
+}
+
+// legacyAuditHashInput lists the fields covered by the hash of entries without a content hash
+type legacyAuditHashInput struct {
+	PrevHash    string          `json:"prevHash"`
+	ActorID     *uint           `json:"actorId"`
+	HouseholdID *uint           `json:"householdId"`
+	Action      string          `json:"action"`
+	EntityType  string          `json:"entityType"`
+	EntityID    uint            `json:"entityId"`
+	Changes     json.RawMessage `json:"changes"`
+	IPAddress   string          `json:"ipAddress"`
+	UserAgent   string          `json:"userAgent"`
+	CreatedAt   string          `json:"createdAt"`
+}
+
+// ComputeContentHash returns the hex SHA-256 hash of the entry's personal data
+func (e *AuditEntry) ComputeContentHash() string {
+	payload, _ := json.Marshal(auditContentHashInput{
+		Changes:   e.rawChanges(),
+		IPAddress: e.IPAddress,
+		UserAgent: e.UserAgent,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// ComputeHash returns the hex SHA-256 hash of the entry, its content hash and the previous hash.
+// Entries without a content hash cover their personal data directly. CreatedAt must already be
+// truncated to microseconds, the precision the database keeps.
+func (e *AuditEntry) ComputeHash() string {
+	createdAt := e.CreatedAt.UTC().Format(time.RFC3339Nano)
+	if e.ContentHash != "" {
+		payload, _ := json.Marshal(auditHashInput{
+			PrevHash:    e.PrevHash,
+			ActorID:     e.ActorID,
+			HouseholdID: e.HouseholdID,
+			Action:      e.Action,
+			EntityType:  e.EntityType,
+			EntityID:    e.EntityID,
+			ContentHash: e.ContentHash,
+			CreatedAt:   createdAt,
+		})
+		sum := sha256.Sum256(payload)
+		return hex.EncodeToString(sum[:])
+	}
+
+	payload, _ := json.Marshal(legacyAuditHashInput{
+		PrevHash:    e.PrevHash,
+		ActorID:     e.ActorID,
+		HouseholdID: e.HouseholdID,
+		Action:      e.Action,
+		EntityType:  e.EntityType,
+		EntityID:    e.EntityID,
+		Changes:     e.rawChanges(),
+		IPAddress:   e.IPAddress,
+		UserAgent:   e.UserAgent,
+		CreatedAt:   createdAt,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Intact reports whether the entry matches its hash and, unless its personal data has been
+// erased, whether that data matches its content hash. Erased entries without a content hash
+// cannot be checked; only their place in the chain can.
+func (e *AuditEntry) Intact() bool {
+	if e.ContentHash == "" && e.ErasedAt != nil {
+		return true
+	}
+	if e.ComputeHash() != e.Hash {
+		return false
+	}
+	return e.ContentHash == "" || e.ErasedAt != nil || e.ComputeContentHash() == e.ContentHash
+}
+
+// rawChanges returns the changes as JSON, null when there are none
+func (e *AuditEntry) rawChanges() json.RawMessage {
+	if len(e.Changes) == 0 {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(e.Changes)
+}
+
+// AuditVerification is the result of checking the hash chain of the audit log
+type AuditVerification struct {
+	Valid          bool  `json:"valid"`
+	EntriesChecked int64 `json:"entriesChecked"`
+	FirstInvalidID *uint `json:"firstInvalidId,omitempty"` // First entry whose hash or link does not match
+}
+
+// AuditFilter narrows down the audit entries returned by a query. Entries are always limited to
+// those of VisibleHousehold and the account entries of VisibleUser, i.e. entries without a
+// household that the user made; the remaining fields are optional.
+type AuditFilter struct {
+	VisibleHousehold uint
+	VisibleUser      uint
+	ActorID          *uint
+	Action           *string
+	EntityType       *string
+	EntityID         *uint
+	From             *time.Time
+	To               *time.Time
+}
@@ -1,13 +1,15 @@
-package models
-
-import "gorm.io/gorm"
-
-// Category represents a classification for a transaction
-type Category struct {
-	gorm.Model
-	Name     string    `gorm:"size:100;not null;unique" json:"name" validate:"required,min=2,max=100"`
-	ParentID *uint     `json:"parentId,omitempty"`
-	Parent   *Category `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
-	UserID   uint      `json:"userId"`
-	User     User      `gorm:"foreignKey:UserID" json:"user"`
-}
+package models
+
+import "gorm.io/gorm"
+
+// Category represents a classification for a transaction
+type Category struct {
+	gorm.Model
+	Name        string     `gorm:"size:100;not null;uniqueIndex:idx_categories_household_name,where:deleted_at IS NULL" json:"name" validate:"required,min=2,max=100"`
+	ParentID    *uint      `json:"parentId,omitempty"`
+	Parent      *Category  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	HouseholdID uint       `gorm:"uniqueIndex:idx_categories_household_name,where:deleted_at IS NULL" json:"householdId"`
+	Household   *Household `gorm:"foreignKey:HouseholdID" json:"-"`
+	UserID      uint       `json:"userId"` // The member who created the category
+	User        User       `gorm:"foreignKey:UserID" json:"user" validate:"-"`
+}
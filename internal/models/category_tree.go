@@ -0,0 +1,30 @@
+package models
+
+// CategoryTreeRow is a flattened category produced by the recursive category tree query
+type CategoryTreeRow struct {
+	ID               uint
+	Name             string
+	ParentID         *uint
+	Depth            int
+	TransactionCount int64
+	IncomeTotal      float64
+	ExpenseTotal     float64
+}
+
+// CategoryStats holds transaction aggregates for a category
+type CategoryStats struct {
+	TransactionCount int64   `json:"transactionCount"`
+	IncomeTotal      float64 `json:"incomeTotal"`
+	ExpenseTotal     float64 `json:"expenseTotal"`
+}
+
+// CategoryNode is a category with its nested children, as returned by the category tree endpoint
+type CategoryNode struct {
+	ID         uint            `json:"id"`
+	Name       string          `json:"name"`
+	ParentID   *uint           `json:"parentId,omitempty"`
+	Depth      int             `json:"depth"`
+	Stats      *CategoryStats  `json:"stats,omitempty"`      // Transactions assigned directly to this category
+	TotalStats *CategoryStats  `json:"totalStats,omitempty"` // Transactions of this category and all its descendants
+	Children   []*CategoryNode `json:"children"`
+}
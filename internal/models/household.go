@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// HouseholdRole defines what a member may do within a household
+type HouseholdRole string
+
+const (
+	RoleOwner  HouseholdRole = "owner"  // Manages members, invitations and the household itself
+	RoleEditor HouseholdRole = "editor" // Creates and changes categories, transactions and reconciliations
+	RoleViewer HouseholdRole = "viewer" // Reads household data only
+)
+
+// householdRoleRanks orders the roles, each including the permissions of the lower ones
+var householdRoleRanks = map[HouseholdRole]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// IsValid reports whether r is a known role
+func (r HouseholdRole) IsValid() bool {
+	_, ok := householdRoleRanks[r]
+	return ok
+}
+
+// Includes reports whether r grants at least the permissions of required
+func (r HouseholdRole) Includes(required HouseholdRole) bool {
+	return r.IsValid() && householdRoleRanks[r] >= householdRoleRanks[required]
+}
+
+// Household is a shared workspace owning categories, transactions and reconciliations
+type Household struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name" validate:"required,min=2,max=100"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// HouseholdMember grants a user a role in a household
+type HouseholdMember struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	HouseholdID uint          `gorm:"not null;uniqueIndex:idx_household_members_household_user" json:"householdId"`
+	Household   *Household    `gorm:"foreignKey:HouseholdID" json:"household,omitempty"`
+	UserID      uint          `gorm:"not null;uniqueIndex:idx_household_members_household_user;index" json:"userId"`
+	User        *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Role        HouseholdRole `gorm:"type:varchar(10);not null" json:"role"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
+
+// HouseholdInvitation is a single-use invitation to join a household. Only a hash of the
+// token is stored; when Email is set only the user with that address may accept it.
+type HouseholdInvitation struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	HouseholdID  uint          `gorm:"not null;index" json:"householdId"`
+	Household    *Household    `gorm:"foreignKey:HouseholdID" json:"household,omitempty"`
+	Email        string        `gorm:"size:255" json:"email,omitempty"`
+	Role         HouseholdRole `gorm:"type:varchar(10);not null" json:"role"`
+	TokenHash    string        `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	InvitedByID  uint          `gorm:"not null" json:"invitedById"`
+	ExpiresAt    time.Time     `gorm:"not null" json:"expiresAt"`
+	AcceptedAt   *time.Time    `json:"acceptedAt,omitempty"`
+	AcceptedByID *uint         `json:"acceptedById,omitempty"`
+	RevokedAt    *time.Time    `json:"revokedAt,omitempty"`
+	CreatedAt    time.Time     `json:"createdAt"`
+}
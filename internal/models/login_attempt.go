@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempt tracks recent failed logins for one username or client IP
+type LoginAttempt struct {
+	Key           string     `gorm:"column:attempt_key;primaryKey;size:200" json:"key"` // "user:<username>" or "ip:<address>"
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"not null;index" json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
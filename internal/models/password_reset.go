@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use token sent to a user to set a new password.
+// Only a hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReconciliationStatus defines the state of a reconciliation session
+type ReconciliationStatus string
+
+const (
+	ReconciliationOpen      ReconciliationStatus = "open"
+	ReconciliationCompleted ReconciliationStatus = "completed"
+)
+
+// Reconciliation represents a session matching cleared transactions against a bank statement
+type Reconciliation struct {
+	gorm.Model
+	StatementEndDate time.Time            `gorm:"not null" json:"statementEndDate" validate:"required"`
+	ClosingBalance   float64              `gorm:"type:numeric(12,2);not null" json:"closingBalance"`
+	ClearedBalance   float64              `gorm:"type:numeric(12,2);not null;default:0" json:"clearedBalance"`
+	Difference       float64              `gorm:"type:numeric(12,2);not null;default:0" json:"difference"`
+	Status           ReconciliationStatus `gorm:"type:varchar(10);not null;default:open" json:"status"`
+	CompletedAt      *time.Time           `json:"completedAt,omitempty"`
+	HouseholdID      uint                 `gorm:"index" json:"householdId"`
+	Household        *Household           `gorm:"foreignKey:HouseholdID" json:"-"`
+	UserID           uint                 `json:"userId"` // The member who started the reconciliation
+	User             User                 `gorm:"foreignKey:UserID" json:"-"`
+}
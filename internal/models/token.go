@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RefreshToken is a single-use credential for obtaining a new access token.
+// Every refresh rotates it within the same family; only a hash of the token is stored.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	FamilyID  string     `gorm:"size:64;not null;index" json:"familyId"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// RevokedToken is a denylist entry for an access token revoked before it expired.
+// Entries can be removed once the token itself would have expired.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	UserID    uint      `gorm:"not null" json:"userId"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
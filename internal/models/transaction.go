@@ -1,28 +1,49 @@
-package models
-
-import (
-	"time"
-
-	"gorm.io/gorm"
-)
-
-// TransactionType defines the type of transaction: 'income' or 'expense'
-type TransactionType string
-
-const (
-	Income  TransactionType = "income"
-	Expense TransactionType = "expense"
-)
-
-// Transaction represents an income or expense record
-type Transaction struct {
-	gorm.Model
-	Description string          `gorm:"type:text" json:"description,omitempty"`
-	Amount      float64         `gorm:"type:numeric(10,2);not null" json:"amount" validate:"required,gt=0"`
-	Type        TransactionType `gorm:"type:varchar(7);not null" json:"type" validate:"required,oneof=income expense"`
-	Date        time.Time       `gorm:"not null" json:"date" validate:"required"`
-	CategoryID  uint            `json:"categoryId" validate:"required"`
-	Category    Category        `gorm:"foreignKey:CategoryID" json:"category"`
-	UserID      uint            `json:"userId"`
-	User        User            `gorm:"foreignKey:UserID" json:"user"`
-}
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TransactionType defines the type of transaction: 'income' or 'expense'
+type TransactionType string
+
+const (
+	Income  TransactionType = "income"
+	Expense TransactionType = "expense"
+)
+
+// TransactionStatus defines the bank reconciliation state of a transaction
+type TransactionStatus string
+
+const (
+	StatusPending    TransactionStatus = "pending"
+	StatusCleared    TransactionStatus = "cleared"
+	StatusReconciled TransactionStatus = "reconciled"
+)
+
+// Transaction represents an income or expense record
+type Transaction struct {
+	gorm.Model
+	Description      string            `gorm:"type:text" json:"description,omitempty"`
+	Amount           float64           `gorm:"type:numeric(10,2);not null" json:"amount" validate:"required,gt=0"`
+	Type             TransactionType   `gorm:"type:varchar(7);not null" json:"type" validate:"required,oneof=income expense"`
+	Date             time.Time         `gorm:"not null" json:"date" validate:"required"`
+	Status           TransactionStatus `gorm:"type:varchar(10);not null;default:pending" json:"status" validate:"omitempty,oneof=pending cleared reconciled"`
+	ReconciliationID *uint             `json:"reconciliationId,omitempty"`
+	CategoryID       uint              `json:"categoryId" validate:"required"`
+	Category         Category          `gorm:"foreignKey:CategoryID" json:"category" validate:"-"`
+	HouseholdID      uint              `gorm:"index" json:"householdId"`
+	Household        *Household        `gorm:"foreignKey:HouseholdID" json:"-"`
+	UserID           uint              `json:"userId"` // The member who recorded the transaction
+	User             User              `gorm:"foreignKey:UserID" json:"user" validate:"-"`
+}
+
+// SignedAmount returns the amount as it affects a balance: positive for income, negative for expenses
+func (t *Transaction) SignedAmount() float64 {
+	if t.Type == Expense {
+		return -t.Amount
+	}
+	return t.Amount
+}
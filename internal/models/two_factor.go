@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the authenticator is unavailable.
+// Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// LoginChallenge is the short-lived second step of a login for users with two-factor authentication.
+// It is issued after the password check and exchanged for tokens together with a TOTP or recovery code.
+type LoginChallenge struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expiresAt"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
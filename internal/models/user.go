@@ -6,7 +6,47 @@ import "time"
 type User struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	Username     string    `gorm:"size:100;not null;unique" json:"username" validate:"required,min=3,max=50"`
+	Email        *string   `gorm:"size:255;uniqueIndex" json:"email,omitempty" validate:"omitempty,email,max=255"`
 	PasswordHash string    `gorm:"type:text;not null" json:"-"`
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
+
+	// Profile and preferences. Dates given without a time, such as report ranges, are days in Timezone.
+	DisplayName     string `gorm:"size:100" json:"displayName,omitempty"`
+	Locale          string `gorm:"size:35;not null;default:en-US" json:"locale"`         // BCP 47 language tag
+	Timezone        string `gorm:"size:64;not null;default:UTC" json:"timezone"`         // IANA time zone name
+	BaseCurrency    string `gorm:"size:3;not null;default:USD" json:"baseCurrency"`      // ISO 4217 currency code
+	FirstDayOfWeek  string `gorm:"size:9;not null;default:monday" json:"firstDayOfWeek"` // Lower-case English weekday name
+	FiscalYearStart string `gorm:"size:5;not null;default:01-01" json:"fiscalYearStart"` // Month and day as MM-DD
+
+	// Household used when a request does not name one; set to the personal household on registration
+	DefaultHouseholdID *uint `json:"defaultHouseholdId,omitempty"`
+
+	// Access tokens issued before this time are rejected, e.g. after a password change
+	SessionsRevokedAt *time.Time `json:"-"`
+
+	// Set once the user has proved control of Email, by completing a password reset sent to it or
+	// signing up through an identity provider that verified it; cleared when the email changes
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+
+	// Set while an administrator has disabled the account; disabled users cannot sign in
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+
+	// Two-factor authentication. The secret is set on enrolment and only takes
+	// effect once a code has been confirmed, which sets TOTPEnabled.
+	TOTPSecret      string `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled     bool   `gorm:"column:totp_enabled;not null;default:false" json:"totpEnabled"`
+	TOTPLastCounter int64  `gorm:"column:totp_last_counter;not null;default:0" json:"-"` // Last accepted time step, prevents code replay
+}
+
+// Location returns the user's time zone, or UTC when it is not set or unknown
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
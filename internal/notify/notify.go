@@ -0,0 +1,140 @@
+// Package notify delivers messages such as password reset links to users.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a plain-text notification to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes messages to the application log instead of delivering them.
+// It is meant for development; message bodies may contain secrets such as reset links.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a notifier that logs messages
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Send logs the message
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	logrus.WithFields(logrus.Fields{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	}).Info("LogNotifier: Notification")
+	return nil
+}
+
+// SMTPNotifier delivers messages by e-mail. STARTTLS is used when the server offers it;
+// credentials are only sent over TLS or to a server on localhost, such as a local SMTP stand-in.
+type SMTPNotifier struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier creates a notifier for the given SMTP server. An empty username disables authentication.
+func NewSMTPNotifier(host string, port int, username, password, from string) *SMTPNotifier {
+	n := &SMTPNotifier{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: from,
+	}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n
+}
+
+// Send delivers the message
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value in message")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to send mail via %s: %w", n.addr, err)
+	}
+	return nil
+}
+
+// AsyncNotifier hands messages to another notifier in the background, so that the time a request
+// takes does not depend on the mail server, e.g. revealing whether a password reset was sent.
+// Failures are logged. It is a lifecycle component whose Stop waits for messages still being sent.
+type AsyncNotifier struct {
+	next    Notifier
+	pending sync.WaitGroup
+}
+
+// NewAsyncNotifier creates a notifier that delivers through next in the background
+func NewAsyncNotifier(next Notifier) *AsyncNotifier {
+	return &AsyncNotifier{next: next}
+}
+
+// Send starts delivering the message and returns without waiting for it
+func (n *AsyncNotifier) Send(ctx context.Context, msg Message) error {
+	// The request, and with it ctx, usually ends before the message is sent
+	ctx = context.WithoutCancel(ctx)
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		if err := n.next.Send(ctx, msg); err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":   err.Error(),
+				"subject": msg.Subject,
+			}).Error("AsyncNotifier: Failed to send message")
+		}
+	}()
+	return nil
+}
+
+// Start does nothing; messages are sent as they arrive
+func (n *AsyncNotifier) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop waits until the messages handed to Send have been delivered, or until ctx is done
+func (n *AsyncNotifier) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	logrus.SetOutput(io.Discard)
+}
+
+// smtpServer is a local SMTP stand-in that accepts every message and keeps what it received
+type smtpServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	auth     []string // Decoded AUTH PLAIN credentials
+	mail     []receivedMail
+}
+
+// receivedMail is a message as the SMTP stand-in received it
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// newSMTPServer starts an SMTP stand-in on a free local port that stops when the test ends
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// port returns the port the stand-in listens on
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// received returns the messages received so far
+func (s *smtpServer) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mail...)
+}
+
+// serve speaks just enough SMTP for net/smtp.SendMail
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var mail receivedMail
+	reply("220 localhost SMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			s.mu.Lock()
+			s.auth = append(s.auth, string(credentials))
+			s.mu.Unlock()
+			reply("235 Authenticated")
+		case "MAIL":
+			mail = receivedMail{from: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+			reply("250 OK")
+		case "RCPT":
+			mail.to = append(mail.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mail.data = data.String()
+			s.mu.Lock()
+			s.mail = append(s.mail, mail)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		msg      Message
+		wantErr  bool
+		wantAuth string
+	}{
+		{"without authentication", "", Message{To: "alice@example.com", Subject: "Hello", Body: "Line one\nLine two"}, false, ""},
+		{"with authentication", "mailer", Message{To: "alice@example.com", Subject: "Hello", Body: "Hi"}, false, "\x00mailer\x00secret"},
+		{"header injection in the subject", "", Message{To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com"}, true, ""},
+		{"header injection in the recipient", "", Message{To: "alice@example.com\nBcc: mallory@example.com", Subject: "Hi"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t)
+			notifier := NewSMTPNotifier("127.0.0.1", server.port(), tt.username, "secret", "noreply@example.com")
+
+			err := notifier.Send(context.Background(), tt.msg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Send accepted an invalid message")
+				}
+				if mail := server.received(); len(mail) != 0 {
+					t.Errorf("server received %d messages, want none", len(mail))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			mail := server.received()
+			if len(mail) != 1 {
+				t.Fatalf("server received %d messages, want 1", len(mail))
+			}
+			if mail[0].from != "noreply@example.com" || len(mail[0].to) != 1 || mail[0].to[0] != tt.msg.To {
+				t.Errorf("envelope = %s to %v, want noreply@example.com to %s", mail[0].from, mail[0].to, tt.msg.To)
+			}
+			for _, want := range []string{
+				"From: noreply@example.com\r\n",
+				"To: " + tt.msg.To + "\r\n",
+				"Subject: " + tt.msg.Subject + "\r\n",
+				"\r\n\r\n" + strings.ReplaceAll(tt.msg.Body, "\n", "\r\n"),
+			} {
+				if !strings.Contains(mail[0].data, want) {
+					t.Errorf("message %q does not contain %q", mail[0].data, want)
+				}
+			}
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if tt.wantAuth == "" && len(server.auth) != 0 || tt.wantAuth != "" && (len(server.auth) != 1 || server.auth[0] != tt.wantAuth) {
+				t.Errorf("credentials = %q, want %q", server.auth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestSMTPNotifierUnreachableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	notifier := NewSMTPNotifier("127.0.0.1", port, "", "", "noreply@example.com")
+	err = notifier.Send(context.Background(), Message{To: "alice@example.com", Subject: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), strconv.Itoa(port)) {
+		t.Errorf("Send error = %v, want one naming the server", err)
+	}
+}
+
+// blockingNotifier holds every message until release is closed
+type blockingNotifier struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []Message
+	err     error
+}
+
+func (n *blockingNotifier) Send(ctx context.Context, msg Message) error {
+	<-n.release
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return n.err
+}
+
+func TestAsyncNotifier(t *testing.T) {
+	for _, sendErr := range []error{nil, errors.New("mail server down")} {
+		next := &blockingNotifier{release: make(chan struct{}), err: sendErr}
+		notifier := NewAsyncNotifier(next)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Send returns, and succeeds, before the message is delivered, even after the caller's
+		// context has ended
+		if err := notifier.Send(ctx, Message{To: "alice@example.com", Subject: "Hi"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		cancel()
+
+		stopCtx, stopCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := notifier.Stop(stopCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Stop with a message in flight = %v, want the deadline", err)
+		}
+		stopCancel()
+
+		close(next.release)
+		if err := notifier.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+		next.mu.Lock()
+		if len(next.sent) != 1 {
+			t.Errorf("delivered %d messages, want 1", len(next.sent))
+		}
+		next.mu.Unlock()
+	}
+}
@@ -0,0 +1,319 @@
+// Package oidc implements the relying-party side of OpenID Connect: provider discovery,
+// the authorization code flow with PKCE and ID token validation.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval limits how often an unknown kid triggers a refetch of the provider's keys
+const jwksRefreshInterval = time.Minute
+
+// Config describes the OIDC client registered with the identity provider
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string   // Empty for public clients, which rely on PKCE alone
+	RedirectURL  string   // Callback URL registered with the provider
+	Scopes       []string // "openid" is always requested
+	HTTPClient   *http.Client
+}
+
+// Discovery is the subset of the provider metadata (OpenID Connect Discovery 1.0) the flow needs
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Claims are the ID token claims used to identify and provision users
+type Claims struct {
+	Issuer            string `json:"iss"`
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+}
+
+// Provider is a discovered OpenID Connect provider
+type Provider struct {
+	config    Config
+	discovery Discovery
+	client    *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	keysFetched time.Time
+	now         func() time.Time
+}
+
+// NewProvider fetches the discovery document of cfg.IssuerURL and checks it belongs to that issuer
+func NewProvider(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC issuer URL, client ID and redirect URL are required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	p := &Provider{config: cfg, client: client, now: time.Now}
+	wellKnown := strings.TrimSuffix(cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &p.discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if p.discovery.Issuer != cfg.IssuerURL {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", p.discovery.Issuer, cfg.IssuerURL)
+	}
+	if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing required endpoints")
+	}
+	return p, nil
+}
+
+// Issuer returns the issuer identifier of the provider
+func (p *Provider) Issuer() string {
+	return p.discovery.Issuer
+}
+
+// AuthCodeURL returns the URL the user is sent to for authentication
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	scopes := []string{"openid"}
+	for _, scope := range p.config.Scopes {
+		if scope != "openid" && scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request rejected (status %d): %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token response contains no ID token")
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken validates the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	methods := p.discovery.SigningAlgorithms
+	if len(methods) == 0 {
+		methods = []string{"RS256"} // The only algorithm every provider must support
+	}
+
+	token, err := jwt.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods(withoutNone(methods)),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid ID token claims")
+	}
+	// With several audiences the token must name this client as its authorized party
+	if audiences, _ := mapClaims.GetAudience(); len(audiences) > 1 {
+		if azp, _ := mapClaims["azp"].(string); azp != p.config.ClientID {
+			return nil, fmt.Errorf("ID token is not authorized for this client")
+		}
+	}
+
+	raw, err := json.Marshal(mapClaims)
+	if err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("ID token has no subject")
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("ID token nonce does not match")
+	}
+	return &claims, nil
+}
+
+// key returns the provider key with the given kid, refetching the key set when the kid is unknown
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set auth.JWKSet
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue // Keys of unsupported types cannot sign tokens we accept
+		}
+		keys[jwk.KeyID] = key
+	}
+	p.keys = keys
+	p.keysFetched = p.now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a cached key; tokens without a kid match when the provider has a single key
+func (p *Provider) lookupKey(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// getJSON fetches url and decodes the JSON response into v
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// NewPKCEVerifier returns a random PKCE code verifier (RFC 7636)
+func NewPKCEVerifier() (string, error) {
+	return randomString(32)
+}
+
+// PKCEChallenge derives the S256 code challenge of verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewNonce returns a random value binding an ID token to one authorization request
+func NewNonce() (string, error) {
+	return randomString(24)
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// withoutNone drops "none", which must never be accepted, and HMAC algorithms, which the
+// provider's published key set cannot verify
+func withoutNone(methods []string) []string {
+	var result []string
+	for _, method := range methods {
+		if method != "none" && !strings.HasPrefix(method, "HS") {
+			result = append(result, method)
+		}
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+// Package oidctest provides an OpenID Connect provider for tests. It serves discovery, the token
+// endpoint and its key set over httptest, and checks the client's PKCE verifier and redirect URL
+// like a real provider would. Users "authenticate" by calling Authorize with the URL the client
+// would send the browser to.
+package oidctest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyID identifies the provider's only signing key
+const keyID = "oidctest"
+
+// Identity is the user who authenticates at the provider
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Nonce             string // Overrides the nonce of the authorization request when set
+}
+
+// grant is an issued authorization code waiting to be redeemed
+type grant struct {
+	identity      Identity
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// Server is a running test provider
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	signingKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// NewServer starts a provider for the client clientID; it is closed when the test ends
+func NewServer(t *testing.T, clientID string) *Server {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{ClientID: clientID, signingKey: private, publicKey: public, grants: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("POST /token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Issuer returns the issuer identifier, which is also the discovery base URL
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize authenticates identity for the authorization request in authURL and returns the
+// state and code the provider redirects the browser back with
+func (s *Server) Authorize(t *testing.T, authURL string, identity Identity) (state, code string) {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != s.ClientID || query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	code = base64.RawURLEncoding.EncodeToString(randomBytes(t, 16))
+	s.mu.Lock()
+	s.grants[code] = grant{
+		identity:      identity,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	s.mu.Unlock()
+	return query.Get("state"), code
+}
+
+// discovery serves the provider metadata
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"EdDSA"},
+	})
+}
+
+// jwks serves the public signing key
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, auth.JWKSet{Keys: []auth.JWK{{
+		KeyType:   "OKP",
+		KeyID:     keyID,
+		Use:       "sig",
+		Algorithm: "EdDSA",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(s.publicKey),
+	}}})
+}
+
+// token redeems an authorization code for an ID token, once, after checking the PKCE verifier
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != s.ClientID {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, found := s.grants[code]
+	delete(s.grants, code)
+	s.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || g.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := g.nonce
+	if g.identity.Nonce != "" {
+		nonce = g.identity.Nonce
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss":                s.URL,
+		"sub":                g.identity.Subject,
+		"aud":                s.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"email":              g.identity.Email,
+		"email_verified":     g.identity.EmailVerified,
+		"preferred_username": g.identity.PreferredUsername,
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.signingKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": idToken})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// randomBytes returns n random bytes
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
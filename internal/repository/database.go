@@ -1,30 +1,152 @@
-package repository
-
-import (
-	"personal-finance-tracker-api/internal/models"
-
-	"github.com/sirupsen/logrus"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-)
-
-// InitDB initializes the database connection and performs auto-migration
-func InitDB(url string) *gorm.DB {
-	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-		}).Fatal("Failed to connect to database")
-	}
-
-	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.Category{}, &models.Transaction{})
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"error": err,
-		}).Fatal("Failed to migrate database schema")
-	}
-
-	logrus.Info("Database connection successful and schema migrated")
-	return db
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlitePragmas are applied to every SQLite connection: foreign keys are off by default, and
+// writers wait for each other instead of failing while the database is locked
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// InitDB initializes the database connection. The schema is managed by the versioned
+// migrations in internal/migrations.
+func InitDB(driver, dsn string) *gorm.DB {
+	db, err := OpenDB(driver, dsn)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error":  err,
+			"driver": driver,
+		}).Fatal("Failed to connect to database")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"driver": driver,
+	}).Info("Database connection successful")
+	return db
+}
+
+// OpenDB connects to a database. For PostgreSQL dsn is a connection string, for SQLite the
+// path of the database file, or ":memory:" for a private in-memory database.
+func OpenDB(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case DriverPostgres:
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case DriverSQLite:
+		return openSQLite(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q, expected %q or %q", driver, DriverPostgres, DriverSQLite)
+	}
+}
+
+// openSQLite opens a SQLite database through a single connection, which serialises writes and
+// keeps an in-memory database alive for as long as the pool
+func openSQLite(path string) (*gorm.DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	sqlDB, err := sql.Open(DriverSQLite, path+separator+sqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: utcConnPool{sqlDB}}, &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// utcConnPool passes times to SQLite in UTC. SQLite stores times as text, which only compares
+// in chronological order when every value has the same offset.
+type utcConnPool struct {
+	gorm.ConnPool
+}
+
+func (p utcConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, query, utcArgs(args)...)
+}
+
+func (p utcConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, query, utcArgs(args)...)
+}
+
+func (p utcConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, query, utcArgs(args)...)
+}
+
+// BeginTx starts a transaction whose statements are converted as well
+func (p utcConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	beginner, ok := p.ConnPool.(gorm.TxBeginner)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{utcConnPool{tx}, tx}, nil
+}
+
+// GetDBConn returns the underlying *sql.DB, e.g. for gorm.DB.DB
+func (p utcConnPool) GetDBConn() (*sql.DB, error) {
+	if sqlDB, ok := p.ConnPool.(*sql.DB); ok {
+		return sqlDB, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// utcTx is a transaction of a utcConnPool
+type utcTx struct {
+	utcConnPool
+	tx *sql.Tx
+}
+
+func (t *utcTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *utcTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// utcArgs returns the arguments of a statement with times converted to UTC
+func utcArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			converted[i] = value.UTC()
+		case *time.Time:
+			if value != nil {
+				converted[i] = value.UTC()
+			} else {
+				converted[i] = arg
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+	return converted
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/migrations"
+	"personal-finance-tracker-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// legacyTables is the schema AutoMigrate gave the first release, before households existed
+const legacyTables = `
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(100) NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE TABLE categories (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    name VARCHAR(100) NOT NULL,
+    parent_id BIGINT CONSTRAINT fk_categories_parent REFERENCES categories(id),
+    user_id BIGINT CONSTRAINT fk_categories_user REFERENCES users(id)
+);
+CREATE INDEX idx_categories_deleted_at ON categories(deleted_at);
+CREATE TABLE transactions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    description TEXT,
+    amount NUMERIC(10,2) NOT NULL,
+    type VARCHAR(7) NOT NULL,
+    date TIMESTAMPTZ NOT NULL,
+    category_id BIGINT CONSTRAINT fk_transactions_category REFERENCES categories(id),
+    user_id BIGINT CONSTRAINT fk_transactions_user REFERENCES users(id)
+);
+CREATE INDEX idx_transactions_deleted_at ON transactions(deleted_at);
+INSERT INTO users (username, password_hash, created_at, updated_at) VALUES
+    ('alice', 'hash', NOW(), NOW()),
+    ('bob', 'hash', NOW(), NOW());
+`
+
+// householdTables adds the households of releases that created them with AutoMigrate. Alice
+// has a household; bob has left his last one.
+const householdTables = `
+CREATE TABLE households (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE household_members (
+    id BIGSERIAL PRIMARY KEY,
+    household_id BIGINT NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+ALTER TABLE users ADD COLUMN default_household_id BIGINT REFERENCES households(id) ON DELETE SET NULL;
+ALTER TABLE categories ADD COLUMN household_id BIGINT REFERENCES households(id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD COLUMN household_id BIGINT REFERENCES households(id) ON DELETE CASCADE;
+INSERT INTO households (name) VALUES ('alice');
+INSERT INTO household_members (household_id, user_id, role) SELECT 1, id, 'owner' FROM users WHERE username = 'alice';
+UPDATE users SET default_household_id = 1 WHERE username = 'alice';
+`
+
+// upgradeLegacyDatabase creates a database with the schema and data of an earlier release and
+// applies the built-in migrations to it
+func upgradeLegacyDatabase(t *testing.T, dsn, schema string) *gorm.DB {
+	t.Helper()
+	db, err := OpenDB(DriverPostgres, postgresTestSchema(t, dsn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.Exec(schema).Error; err != nil {
+		t.Fatalf("create legacy schema: %v", err)
+	}
+
+	builtin, err := migrations.Builtin(migrations.DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(sqlDB, migrations.DialectPostgres, builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate legacy database: %v", err)
+	}
+	if pending, err := migrator.Pending(context.Background()); err != nil || len(pending) != 0 {
+		t.Fatalf("Pending = %v, %v; want none", pending, err)
+	}
+	return db
+}
+
+func TestMigrationsUpgradeLegacyDatabase(t *testing.T) {
+	dsn := os.Getenv(testDatabaseURLEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	tests := []struct {
+		name string
+		// names creates the uniqueness of category names of the release and alice's and bob's
+		// categories, which that uniqueness allows
+		names string
+	}{
+		{"names unique globally", `
+ALTER TABLE categories ADD CONSTRAINT uni_categories_name UNIQUE (name);
+INSERT INTO categories (name, user_id, created_at, updated_at) VALUES ('Groceries', 1, NOW(), NOW()), ('Salary', 2, NOW(), NOW());
+`},
+		{"names unique per user", `
+CREATE UNIQUE INDEX idx_categories_user_name ON categories(name, user_id) WHERE deleted_at IS NULL;
+INSERT INTO categories (name, user_id, created_at, updated_at) VALUES ('Groceries', 1, NOW(), NOW()), ('Groceries', 2, NOW(), NOW());
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := upgradeLegacyDatabase(t, dsn, legacyTables+tt.names+`
+INSERT INTO transactions (description, amount, type, date, category_id, user_id, created_at, updated_at)
+    SELECT 'legacy', 10, 'expense', NOW(), id, user_id, NOW(), NOW() FROM categories;
+`)
+
+			// Each user owns a personal household with the records they created
+			repo := NewGormRepository(db)
+			for _, username := range []string{"alice", "bob"} {
+				user, err := repo.GetUserByUsername(ctx, username)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if user.DefaultHouseholdID == nil {
+					t.Fatalf("%s has no default household", username)
+				}
+				householdID := *user.DefaultHouseholdID
+				if _, err := repo.GetHouseholdMember(ctx, householdID, user.ID); err != nil {
+					t.Errorf("%s is not a member of their household: %v", username, err)
+				}
+				categories, err := repo.GetCategories(ctx, householdID, 10, 0, nil)
+				if err != nil || len(categories) != 1 || categories[0].UserID != user.ID {
+					t.Errorf("categories of %s's household = %+v, %v; want their own one", username, categories, err)
+				}
+				transactions, err := repo.GetTransactions(ctx, householdID, 10, 0, nil, nil, nil, nil)
+				if err != nil || len(transactions) != 1 || transactions[0].Status != models.StatusPending {
+					t.Errorf("transactions of %s's household = %+v, %v; want their own pending one", username, transactions, err)
+				}
+
+				// Category names are now unique per household
+				duplicate := &models.Category{Name: "Groceries", HouseholdID: householdID, UserID: user.ID}
+				err = repo.CreateCategory(ctx, duplicate)
+				if hasGroceries := len(categories) == 1 && categories[0].Name == "Groceries"; hasGroceries {
+					if appErrors.GetType(err) != appErrors.TypeAlreadyExists {
+						t.Errorf("CreateCategory of a taken name in %s's household: %v, want an already exists error", username, err)
+					}
+				} else if err != nil {
+					t.Errorf("CreateCategory of a name taken in another household: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsUpgradeKeepsUsersWithoutHousehold(t *testing.T) {
+	dsn := os.Getenv(testDatabaseURLEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+	ctx := context.Background()
+	db := upgradeLegacyDatabase(t, dsn, legacyTables+householdTables)
+
+	repo := NewGormRepository(db)
+	alice, err := repo.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.DefaultHouseholdID == nil || *alice.DefaultHouseholdID != 1 {
+		t.Errorf("alice's default household = %v, want her own household 1", alice.DefaultHouseholdID)
+	}
+	bob, err := repo.GetUserByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.DefaultHouseholdID != nil {
+		t.Errorf("bob, who left his last household, got household %d", *bob.DefaultHouseholdID)
+	}
+	var households int64
+	if err := db.Table("households").Count(&households).Error; err != nil {
+		t.Fatal(err)
+	}
+	if households != 1 {
+		t.Errorf("%d households after the upgrade, want 1", households)
+	}
+}
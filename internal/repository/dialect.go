@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns, using backslash as the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// isUniqueViolation reports whether err was caused by a unique constraint, in any dialect
+func (r *GormRepository) isUniqueViolation(err error) bool {
+	return errors.Is(r.translateError(err), gorm.ErrDuplicatedKey)
+}
+
+// isForeignKeyViolation reports whether err was caused by a foreign key constraint, in any dialect
+func (r *GormRepository) isForeignKeyViolation(err error) bool {
+	return errors.Is(r.translateError(err), gorm.ErrForeignKeyViolated)
+}
+
+// translateError maps a driver error to GORM's dialect-neutral errors where possible. The
+// original error is kept for callers since its message names the violated constraint.
+func (r *GormRepository) translateError(err error) error {
+	if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+	return err
+}
+
+// isPostgres reports whether the repository works on PostgreSQL
+func (r *GormRepository) isPostgres() bool {
+	return r.db.Dialector.Name() == DriverPostgres
+}
+
+// containsIgnoringCase returns a condition matching rows whose column contains term, ignoring
+// case, and its argument. It is written to work the same in PostgreSQL and SQLite.
+func containsIgnoringCase(column, term string) (string, string) {
+	return "LOWER(" + column + `) LIKE ? ESCAPE '\'`, "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+}
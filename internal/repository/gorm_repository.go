@@ -1,189 +1,1949 @@
-package repository
-
-import (
-	"context"
-	"fmt" // Import fmt for error messages
-	appErrors "personal-finance-tracker-api/internal/errors"
-	"personal-finance-tracker-api/internal/models"
-	"time"
-
-	"github.com/lib/pq" // Import for PostgreSQL specific error handling
-	"gorm.io/gorm"
-)
-
-// Repository defines the interface for database operations
-type Repository interface {
-	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
-	GetTransactions(ctx context.Context, userID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error)
-	DeleteTransaction(ctx context.Context, userID uint, id uint) error
-	CreateCategory(ctx context.Context, category *models.Category) error
-	GetCategories(ctx context.Context, userID uint, limit, offset int, name *string) ([]models.Category, error)
-	DeleteCategory(ctx context.Context, userID uint, id uint) error
-	CreateUser(ctx context.Context, user *models.User) error
-	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
-
-	Transaction(txFunc func(txRepo Repository) error) error
-}
-
-// GormRepository is an implementation of Repository using GORM
-type GormRepository struct {
-	db *gorm.DB
-}
-
-// NewGormRepository creates a new GORM repository
-func NewGormRepository(db *gorm.DB) Repository {
-	return &GormRepository{db: db}
-}
-
-// CreateTransaction adds a new transaction to the database
-func (r *GormRepository) CreateTransaction(ctx context.Context, t *models.Transaction) error {
-	result := r.db.WithContext(ctx).Create(t)
-	if result.Error != nil {
-		if pqErr, ok := result.Error.(*pq.Error); ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return appErrors.NewConflictError("Transaction already exists with given details", result.Error)
-			}
-			if pqErr.Code.Name() == "foreign_key_violation" {
-				return appErrors.NewValidationError("Invalid category ID or User ID for transaction", result.Error)
-			}
-		}
-		return appErrors.NewInternalError("Failed to create transaction due to database error", result.Error)
-	}
-	return nil
-}
-
-// GetTransactions retrieves all transactions from the database with pagination
-func (r *GormRepository) GetTransactions(ctx context.Context, userID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
-	var transactions []models.Transaction
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID).Preload("Category").Order("date desc")
-
-	// Apply date range filters
-	if startDate != nil {
-		query = query.Where("date >= ?", *startDate)
-	}
-	if endDate != nil {
-		query = query.Where("date <= ?", *endDate)
-	}
-
-	// Apply transaction type filter
-	if transactionType != nil && *transactionType != "" {
-		query = query.Where("type = ?", *transactionType)
-	}
-
-	// Apply description filter
-	if description != nil && *description != "" {
-		query = query.Where("description ILIKE ?", "%"+*description+"%")
-	}
-
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-	if offset > 0 {
-		query = query.Offset(offset)
-	}
-
-	err := query.Find(&transactions).Error
-	if err != nil {
-		return nil, appErrors.NewInternalError("Failed to retrieve transactions from database", err)
-	}
-	return transactions, nil
-}
-
-// DeleteTransaction soft deletes a transaction for a specific user.
-func (r *GormRepository) DeleteTransaction(ctx context.Context, userID uint, id uint) error {
-	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Transaction{}, id)
-	if result.Error != nil {
-		return appErrors.NewInternalError(fmt.Sprintf("Failed to soft delete transaction with ID %d", id), result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not owned by user", id), nil)
-	}
-	return nil
-}
-
-// CreateCategory adds a new category to the database
-func (r *GormRepository) CreateCategory(ctx context.Context, c *models.Category) error {
-	result := r.db.WithContext(ctx).Create(c)
-	if result.Error != nil {
-		if pqErr, ok := result.Error.(*pq.Error); ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return appErrors.NewAlreadyExistsError(fmt.Sprintf("Category with name '%s' already exists", c.Name), result.Error)
-			}
-			if pqErr.Code.Name() == "foreign_key_violation" {
-				return appErrors.NewValidationError("Invalid User ID for category", result.Error)
-			}
-		}
-		return appErrors.NewInternalError("Failed to create category due to database error", result.Error)
-	}
-	return nil
-}
-
-// GetCategories retrieves all categories, preloading their parent category
-func (r *GormRepository) GetCategories(ctx context.Context, userID uint, limit, offset int, name *string) ([]models.Category, error) {
-	var categories []models.Category
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID).Preload("Parent")
-
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
-	if offset > 0 {
-		query = query.Offset(offset)
-	}
-
-	if name != nil && *name != "" {
-		query = query.Where("name ILIKE ?", "%"+*name+"%")
-	}
-
-	err := query.Find(&categories).Error
-	if err != nil {
-		return nil, appErrors.NewInternalError("Failed to retrieve categories from database", err)
-	}
-	return categories, nil
-}
-
-// DeleteCategory soft deletes a category for a specific user.
-func (r *GormRepository) DeleteCategory(ctx context.Context, userID uint, id uint) error {
-	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Category{}, id)
-	if result.Error != nil {
-		return appErrors.NewInternalError(fmt.Sprintf("Failed to soft delete category with ID %d", id), result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not owned by user", id), nil)
-	}
-	return nil
-}
-
-// CreateUser adds a new user to the database
-func (r *GormRepository) CreateUser(ctx context.Context, u *models.User) error {
-	result := r.db.WithContext(ctx).Create(u)
-	if result.Error != nil {
-		if pqErr, ok := result.Error.(*pq.Error); ok {
-			if pqErr.Code.Name() == "unique_violation" {
-				return appErrors.NewAlreadyExistsError(fmt.Sprintf("User with username '%s' already exists", u.Username), result.Error)
-			}
-		}
-		return appErrors.NewInternalError("Failed to create user due to database error", result.Error)
-	}
-	return nil
-}
-
-// GetUserByUsername retrieves a user by their username
-func (r *GormRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	var user models.User
-	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, appErrors.NewNotFoundError(fmt.Sprintf("User '%s' not found", username), err)
-		}
-		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve user '%s' due to database error", username), err)
-	}
-	return &user, nil
-}
-
-// Transaction executes a function within a database transaction.
-func (r *GormRepository) Transaction(txFunc func(txRepo Repository) error) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		txGormRepo := NewGormRepository(tx)
-		return txFunc(txGormRepo)
-	})
-}
+package repository
+
+import (
+	"context"
+	"fmt" // Import fmt for error messages
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the interface for database operations
+type Repository interface {
+	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactions(ctx context.Context, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error)
+	GetTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error)
+	UpdateTransaction(ctx context.Context, transaction *models.Transaction) error
+	DeleteTransaction(ctx context.Context, householdID uint, id uint) error
+	GetClearedBalance(ctx context.Context, householdID uint, before time.Time) (float64, error)
+	MarkTransactionsReconciled(ctx context.Context, householdID uint, reconciliationID uint, before time.Time) (int64, error)
+	CreateReconciliation(ctx context.Context, reconciliation *models.Reconciliation) error
+	GetReconciliations(ctx context.Context, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error)
+	GetReconciliationByID(ctx context.Context, householdID uint, id uint) (*models.Reconciliation, error)
+	UpdateReconciliation(ctx context.Context, reconciliation *models.Reconciliation) error
+	DeleteReconciliation(ctx context.Context, householdID uint, id uint) error
+	CreateCategory(ctx context.Context, category *models.Category) error
+	GetCategories(ctx context.Context, householdID uint, limit, offset int, name *string) ([]models.Category, error)
+	GetCategoryTree(ctx context.Context, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]models.CategoryTreeRow, error)
+	GetCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error)
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, householdID uint, id uint) error
+	CountTransactionsByCategory(ctx context.Context, householdID uint, categoryID uint) (int64, error)
+	ReassignTransactions(ctx context.Context, householdID uint, fromCategoryID, toCategoryID uint) (int64, error)
+	ReparentCategories(ctx context.Context, householdID uint, fromParentID uint, toParentID *uint) (int64, error)
+	GetDeletedTransactions(ctx context.Context, householdID uint, limit, offset int) ([]models.Transaction, error)
+	GetDeletedTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error)
+	RestoreTransaction(ctx context.Context, householdID uint, id uint) error
+	PurgeTransaction(ctx context.Context, householdID uint, id uint) error
+	PurgeDeletedTransactions(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetDeletedCategories(ctx context.Context, householdID uint, limit, offset int) ([]models.Category, error)
+	GetDeletedCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error)
+	RestoreCategory(ctx context.Context, householdID uint, id uint) error
+	PurgeCategory(ctx context.Context, householdID uint, id uint) error
+	PurgeDeletedCategories(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+	UpdateUserProfile(ctx context.Context, user *models.User) error
+	UpdateUserPassword(ctx context.Context, userID uint, passwordHash string) error
+	ReplacePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error
+	SetUserDisabled(ctx context.Context, userID uint, disabledAt *time.Time) error
+	SetUserEmailVerified(ctx context.Context, userID uint, email string, verifiedAt time.Time) (bool, error)
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) (int64, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uint) (int64, error)
+	RevokeAccessToken(ctx context.Context, token *models.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error)
+	CreateAPIToken(ctx context.Context, token *models.APIToken) error
+	GetAPITokens(ctx context.Context, userID uint) ([]models.APIToken, error)
+	GetAPITokenByID(ctx context.Context, userID, id uint) (*models.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	UpdateAPITokenLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+	RevokeAPIToken(ctx context.Context, userID, id uint) error
+	RevokeUserAPITokens(ctx context.Context, userID uint) (int64, error)
+	UpdateUserTOTP(ctx context.Context, userID uint, secret string, enabled bool, lastCounter int64) error
+	AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	CreateLoginChallenge(ctx context.Context, challenge *models.LoginChallenge) error
+	GetLoginChallengeByHash(ctx context.Context, tokenHash string) (*models.LoginChallenge, error)
+	RecordLoginChallengeAttempt(ctx context.Context, id uint) error
+	MarkLoginChallengeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error)
+	SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+	InvalidatePasswordResetTokens(ctx context.Context, userID uint) error
+
+	// OpenID Connect identity methods
+	CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetUserIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+	GetUserIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+	RecordUserIdentityLogin(ctx context.Context, id uint, email string, at time.Time) error
+	DeleteUserIdentity(ctx context.Context, userID, id uint) error
+	CountUserIdentities(ctx context.Context, userID uint) (int64, error)
+	CreateOIDCAuthRequest(ctx context.Context, req *models.OIDCAuthRequest) error
+	ConsumeOIDCAuthRequest(ctx context.Context, stateHash string) (*models.OIDCAuthRequest, error)
+
+	// Household methods
+	CreateHousehold(ctx context.Context, household *models.Household) error
+	GetHousehold(ctx context.Context, id uint) (*models.Household, error)
+	UpdateHousehold(ctx context.Context, household *models.Household) error
+	GetUserHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error)
+	GetHouseholdMember(ctx context.Context, householdID, userID uint) (*models.HouseholdMember, error)
+	GetHouseholdMembers(ctx context.Context, householdID uint) ([]models.HouseholdMember, error)
+	AddHouseholdMember(ctx context.Context, member *models.HouseholdMember) error
+	UpdateHouseholdMemberRole(ctx context.Context, householdID, userID uint, role models.HouseholdRole) error
+	RemoveHouseholdMember(ctx context.Context, householdID, userID uint) error
+	CountHouseholdOwners(ctx context.Context, householdID uint) (int64, error)
+	SetUserDefaultHousehold(ctx context.Context, userID uint, householdID *uint) error
+	CreateHouseholdInvitation(ctx context.Context, invitation *models.HouseholdInvitation) error
+	GetHouseholdInvitations(ctx context.Context, householdID uint, now time.Time) ([]models.HouseholdInvitation, error)
+	GetHouseholdInvitationByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvitation, error)
+	MarkHouseholdInvitationAccepted(ctx context.Context, id, userID uint, acceptedAt time.Time) (bool, error)
+	RevokeHouseholdInvitation(ctx context.Context, householdID, id uint, revokedAt time.Time) error
+
+	// Audit log methods. Entries can only be appended, never changed or removed, except that
+	// their personal data can be erased once.
+	AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error)
+	GetAuditEntriesAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEntry, error)
+	GetPersonalAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error)
+	EraseAuditEntryData(ctx context.Context, entry *models.AuditEntry) error
+
+	// Personal data export and account deletion methods. Records are read and removed
+	// including soft-deleted ones.
+	GetUserCategories(ctx context.Context, userID uint) ([]models.Category, error)
+	GetUserTransactions(ctx context.Context, userID uint) ([]models.Transaction, error)
+	GetUserReconciliations(ctx context.Context, userID uint) ([]models.Reconciliation, error)
+	GetUserAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error)
+	CreateAccountDeletion(ctx context.Context, deletion *models.AccountDeletion) error
+	GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error)
+	ConfirmAccountDeletion(ctx context.Context, userID uint, tokenHash string, confirmedAt, scheduledFor time.Time) (bool, error)
+	DeleteAccountDeletion(ctx context.Context, userID uint) error
+	GetDueAccountDeletions(ctx context.Context, now time.Time) ([]models.AccountDeletion, error)
+	PurgeHousehold(ctx context.Context, householdID uint) error
+	ReassignHouseholdRecords(ctx context.Context, householdID, fromUserID, toUserID uint) error
+	AnonymizeHouseholdRecords(ctx context.Context, householdID uint, name string) error
+	DeleteUserCredentials(ctx context.Context, userID uint) error
+	AnonymizeUser(ctx context.Context, userID uint, username string, at time.Time) error
+	DeleteUser(ctx context.Context, userID uint) error
+
+	Transaction(txFunc func(txRepo Repository) error) error
+}
+
+// GormRepository is an implementation of Repository using GORM
+type GormRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRepository creates a new GORM repository
+func NewGormRepository(db *gorm.DB) Repository {
+	return &GormRepository{db: db}
+}
+
+// CreateTransaction adds a new transaction to the database
+func (r *GormRepository) CreateTransaction(ctx context.Context, t *models.Transaction) error {
+	result := r.db.WithContext(ctx).Create(t)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewConflictError("Transaction already exists with given details", result.Error)
+		}
+		if r.isForeignKeyViolation(result.Error) {
+			return appErrors.NewValidationError("Invalid category ID or household ID for transaction", result.Error)
+		}
+		return appErrors.NewInternalError("Failed to create transaction due to database error", result.Error)
+	}
+	return nil
+}
+
+// GetTransactions retrieves all transactions from the database with pagination
+func (r *GormRepository) GetTransactions(ctx context.Context, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	query := r.db.WithContext(ctx).Where("household_id = ?", householdID).Preload("Category").Order("date desc")
+
+	// Apply date range filters
+	if startDate != nil {
+		query = query.Where("date >= ?", *startDate)
+	}
+	if endDate != nil {
+		query = query.Where("date <= ?", *endDate)
+	}
+
+	// Apply transaction type filter
+	if transactionType != nil && *transactionType != "" {
+		query = query.Where("type = ?", *transactionType)
+	}
+
+	// Apply description filter
+	if description != nil && *description != "" {
+		query = query.Where(containsIgnoringCase("description", *description))
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Find(&transactions).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve transactions from database", err)
+	}
+	return transactions, nil
+}
+
+// GetTransactionByID retrieves a single transaction in a household
+func (r *GormRepository) GetTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := r.db.WithContext(ctx).Where("household_id = ?", householdID).Preload("Category").First(&transaction, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve transaction with ID %d", id), err)
+	}
+	return &transaction, nil
+}
+
+// UpdateTransaction saves the editable fields of an existing transaction
+func (r *GormRepository) UpdateTransaction(ctx context.Context, t *models.Transaction) error {
+	result := r.db.WithContext(ctx).Model(t).Where("household_id = ?", t.HouseholdID).
+		Select("description", "amount", "type", "date", "status", "reconciliation_id", "category_id").
+		Updates(t)
+	if result.Error != nil {
+		if r.isForeignKeyViolation(result.Error) {
+			return appErrors.NewValidationError("Invalid category ID for transaction", result.Error)
+		}
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update transaction with ID %d", t.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", t.ID), nil)
+	}
+	return nil
+}
+
+// DeleteTransaction soft deletes a transaction in a household.
+func (r *GormRepository) DeleteTransaction(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Where("household_id = ?", householdID).Delete(&models.Transaction{}, id)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to soft delete transaction with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// GetClearedBalance sums cleared and reconciled transactions dated before the given time.
+// Income counts towards the balance and expenses against it.
+func (r *GormRepository) GetClearedBalance(ctx context.Context, householdID uint, before time.Time) (float64, error) {
+	var balance float64
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN -amount ELSE amount END), 0)", models.Expense).
+		Where("household_id = ? AND status IN ? AND date < ?", householdID, []models.TransactionStatus{models.StatusCleared, models.StatusReconciled}, before).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError("Failed to calculate cleared balance", err)
+	}
+	return balance, nil
+}
+
+// MarkTransactionsReconciled moves cleared transactions dated before the given time into a reconciliation
+func (r *GormRepository) MarkTransactionsReconciled(ctx context.Context, householdID uint, reconciliationID uint, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("household_id = ? AND status = ? AND date < ?", householdID, models.StatusCleared, before).
+		Updates(map[string]interface{}{
+			"status":            models.StatusReconciled,
+			"reconciliation_id": reconciliationID,
+		})
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError("Failed to mark transactions as reconciled", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// CreateReconciliation adds a new reconciliation session to the database.
+// A household can only have one open session; a second one is a conflict.
+func (r *GormRepository) CreateReconciliation(ctx context.Context, rec *models.Reconciliation) error {
+	if err := r.db.WithContext(ctx).Create(rec).Error; err != nil {
+		if r.isUniqueViolation(err) {
+			return openReconciliationConflict(rec.HouseholdID)
+		}
+		return appErrors.NewInternalError("Failed to create reconciliation due to database error", err)
+	}
+	return nil
+}
+
+// openReconciliationConflict reports that a household already has an open reconciliation session
+func openReconciliationConflict(householdID uint) error {
+	return appErrors.NewConflictError(fmt.Sprintf("Household with ID %d already has an open reconciliation", householdID), nil)
+}
+
+// GetReconciliations retrieves reconciliation sessions in a household, newest statement first
+func (r *GormRepository) GetReconciliations(ctx context.Context, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error) {
+	var reconciliations []models.Reconciliation
+	query := r.db.WithContext(ctx).Where("household_id = ?", householdID).Order("statement_end_date desc")
+
+	if status != nil && *status != "" {
+		query = query.Where("status = ?", *status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&reconciliations).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve reconciliations from database", err)
+	}
+	return reconciliations, nil
+}
+
+// GetReconciliationByID retrieves a single reconciliation session in a household
+func (r *GormRepository) GetReconciliationByID(ctx context.Context, householdID uint, id uint) (*models.Reconciliation, error) {
+	var reconciliation models.Reconciliation
+	err := r.db.WithContext(ctx).Where("household_id = ?", householdID).First(&reconciliation, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve reconciliation with ID %d", id), err)
+	}
+	return &reconciliation, nil
+}
+
+// UpdateReconciliation saves the computed balances and status of a reconciliation session
+func (r *GormRepository) UpdateReconciliation(ctx context.Context, rec *models.Reconciliation) error {
+	result := r.db.WithContext(ctx).Model(rec).Where("household_id = ?", rec.HouseholdID).
+		Select("cleared_balance", "difference", "status", "completed_at").
+		Updates(rec)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update reconciliation with ID %d", rec.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", rec.ID), nil)
+	}
+	return nil
+}
+
+// DeleteReconciliation soft deletes a reconciliation session in a household.
+func (r *GormRepository) DeleteReconciliation(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Where("household_id = ?", householdID).Delete(&models.Reconciliation{}, id)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to soft delete reconciliation with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// CreateCategory adds a new category to the database
+func (r *GormRepository) CreateCategory(ctx context.Context, c *models.Category) error {
+	result := r.db.WithContext(ctx).Create(c)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewAlreadyExistsError(fmt.Sprintf("Category with name '%s' already exists", c.Name), result.Error)
+		}
+		if r.isForeignKeyViolation(result.Error) {
+			return appErrors.NewValidationError("Invalid household ID for category", result.Error)
+		}
+		return appErrors.NewInternalError("Failed to create category due to database error", result.Error)
+	}
+	return nil
+}
+
+// GetCategories retrieves all categories, preloading their parent category
+func (r *GormRepository) GetCategories(ctx context.Context, householdID uint, limit, offset int, name *string) ([]models.Category, error) {
+	var categories []models.Category
+	query := r.db.WithContext(ctx).Where("household_id = ?", householdID).Preload("Parent")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if name != nil && *name != "" {
+		query = query.Where(containsIgnoringCase("name", *name))
+	}
+
+	err := query.Find(&categories).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve categories from database", err)
+	}
+	return categories, nil
+}
+
+// GetCategoryTree retrieves the household's category forest with a recursive CTE, ordered by depth and name.
+// Categories whose parent is missing or deleted are treated as roots. Levels deeper than maxDepth
+// (0 being the roots) are omitted. With withStats, each row carries the count and income and expense
+// totals of the transactions assigned directly to it, optionally limited to a date range.
+func (r *GormRepository) GetCategoryTree(ctx context.Context, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]models.CategoryTreeRow, error) {
+	args := map[string]interface{}{
+		"householdID": householdID,
+		"maxDepth":    maxDepth,
+		"income":      models.Income,
+		"expense":     models.Expense,
+	}
+
+	tree := `WITH RECURSIVE tree AS (
+		SELECT c.id, c.name, c.parent_id, 0 AS depth
+		FROM categories c
+		WHERE c.household_id = @householdID AND c.deleted_at IS NULL
+			AND (c.parent_id IS NULL OR c.parent_id NOT IN (
+				SELECT p.id FROM categories p WHERE p.household_id = @householdID AND p.deleted_at IS NULL
+			))
+		UNION ALL
+		SELECT c.id, c.name, c.parent_id, tree.depth + 1
+		FROM categories c
+		JOIN tree ON c.parent_id = tree.id
+		WHERE c.household_id = @householdID AND c.deleted_at IS NULL AND tree.depth < @maxDepth
+	)`
+
+	var query string
+	if withStats {
+		joinConditions := "t.category_id = tree.id AND t.household_id = @householdID AND t.deleted_at IS NULL"
+		if startDate != nil {
+			joinConditions += " AND t.date >= @startDate"
+			args["startDate"] = *startDate
+		}
+		if endDate != nil {
+			joinConditions += " AND t.date <= @endDate"
+			args["endDate"] = *endDate
+		}
+		query = tree + `
+		SELECT tree.id, tree.name, tree.parent_id, tree.depth,
+			COUNT(t.id) AS transaction_count,
+			COALESCE(SUM(CASE WHEN t.type = @income THEN t.amount END), 0) AS income_total,
+			COALESCE(SUM(CASE WHEN t.type = @expense THEN t.amount END), 0) AS expense_total
+		FROM tree
+		LEFT JOIN transactions t ON ` + joinConditions + `
+		GROUP BY tree.id, tree.name, tree.parent_id, tree.depth
+		ORDER BY tree.depth, tree.name`
+	} else {
+		query = tree + `
+		SELECT tree.id, tree.name, tree.parent_id, tree.depth
+		FROM tree
+		ORDER BY tree.depth, tree.name`
+	}
+
+	var rows []models.CategoryTreeRow
+	if err := r.db.WithContext(ctx).Raw(query, args).Scan(&rows).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve category tree from database", err)
+	}
+	return rows, nil
+}
+
+// GetCategoryByID retrieves a single category in a household, preloading its parent
+func (r *GormRepository) GetCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).Where("household_id = ?", householdID).Preload("Parent").First(&category, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve category with ID %d", id), err)
+	}
+	return &category, nil
+}
+
+// UpdateCategory saves the name and parent of an existing category
+func (r *GormRepository) UpdateCategory(ctx context.Context, c *models.Category) error {
+	result := r.db.WithContext(ctx).Model(c).Where("household_id = ?", c.HouseholdID).
+		Select("name", "parent_id").
+		Updates(c)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewAlreadyExistsError(fmt.Sprintf("Category with name '%s' already exists", c.Name), result.Error)
+		}
+		if r.isForeignKeyViolation(result.Error) {
+			return appErrors.NewValidationError("Invalid parent category ID", result.Error)
+		}
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update category with ID %d", c.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", c.ID), nil)
+	}
+	return nil
+}
+
+// DeleteCategory soft deletes a category in a household.
+func (r *GormRepository) DeleteCategory(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Where("household_id = ?", householdID).Delete(&models.Category{}, id)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to soft delete category with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// GetDeletedTransactions retrieves soft-deleted transactions in a household, most recently deleted first
+func (r *GormRepository) GetDeletedTransactions(ctx context.Context, householdID uint, limit, offset int) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	query := r.db.WithContext(ctx).Unscoped().
+		Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+		Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("deleted_at desc")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&transactions).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve deleted transactions from database", err)
+	}
+	return transactions, nil
+}
+
+// GetDeletedTransactionByID retrieves a single soft-deleted transaction in a household
+func (r *GormRepository) GetDeletedTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error) {
+	var transaction models.Transaction
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+		Preload("Category", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&transaction, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve deleted transaction with ID %d", id), err)
+	}
+	return &transaction, nil
+}
+
+// RestoreTransaction clears the soft delete marker of a transaction in a household
+func (r *GormRepository) RestoreTransaction(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Transaction{}).
+		Where("id = ? AND household_id = ? AND deleted_at IS NOT NULL", id, householdID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to restore transaction with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// PurgeTransaction permanently deletes a soft-deleted transaction in a household
+func (r *GormRepository) PurgeTransaction(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+		Delete(&models.Transaction{}, id)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to purge transaction with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// PurgeDeletedTransactions permanently deletes all transactions soft-deleted before the given time
+func (r *GormRepository) PurgeDeletedTransactions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Delete(&models.Transaction{})
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError("Failed to purge deleted transactions", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetDeletedCategories retrieves soft-deleted categories in a household, most recently deleted first
+func (r *GormRepository) GetDeletedCategories(ctx context.Context, householdID uint, limit, offset int) ([]models.Category, error) {
+	var categories []models.Category
+	query := r.db.WithContext(ctx).Unscoped().
+		Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+		Preload("Parent", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("deleted_at desc")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&categories).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve deleted categories from database", err)
+	}
+	return categories, nil
+}
+
+// GetDeletedCategoryByID retrieves a single soft-deleted category in a household
+func (r *GormRepository) GetDeletedCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+		Preload("Parent", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&category, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve deleted category with ID %d", id), err)
+	}
+	return &category, nil
+}
+
+// RestoreCategory clears the soft delete marker of a category in a household
+func (r *GormRepository) RestoreCategory(ctx context.Context, householdID uint, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Category{}).
+		Where("id = ? AND household_id = ? AND deleted_at IS NOT NULL", id, householdID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewConflictError(fmt.Sprintf("Another category with the name of category %d already exists", id), result.Error)
+		}
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to restore category with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), nil)
+	}
+	return nil
+}
+
+// PurgeCategory permanently deletes a soft-deleted category in a household.
+// Child categories are detached; categories still referenced by transactions cannot be purged.
+func (r *GormRepository) PurgeCategory(ctx context.Context, householdID uint, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Category{}).
+			Where("parent_id = ? AND household_id = ?", id, householdID).
+			Update("parent_id", nil).Error; err != nil {
+			return appErrors.NewInternalError(fmt.Sprintf("Failed to detach child categories of category with ID %d", id), err)
+		}
+
+		result := tx.Unscoped().
+			Where("household_id = ? AND deleted_at IS NOT NULL", householdID).
+			Delete(&models.Category{}, id)
+		if result.Error != nil {
+			if r.isForeignKeyViolation(result.Error) {
+				return appErrors.NewConflictError(fmt.Sprintf("Category with ID %d is still referenced by transactions", id), result.Error)
+			}
+			return appErrors.NewInternalError(fmt.Sprintf("Failed to purge category with ID %d", id), result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), nil)
+		}
+		return nil
+	})
+}
+
+// PurgeDeletedCategories permanently deletes all categories soft-deleted before the given time
+// that are no longer referenced by any transaction, detaching their child categories.
+func (r *GormRepository) PurgeDeletedCategories(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		referenced := tx.Unscoped().Model(&models.Transaction{}).Select("category_id").Where("category_id IS NOT NULL")
+		expired := tx.Unscoped().Model(&models.Category{}).Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND id NOT IN (?)", deletedBefore, referenced)
+
+		if err := tx.Unscoped().Model(&models.Category{}).
+			Where("parent_id IN (?)", expired).
+			Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND id NOT IN (?)", deletedBefore, referenced).
+			Delete(&models.Category{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, appErrors.NewInternalError("Failed to purge deleted categories", err)
+	}
+	return purged, nil
+}
+
+// CountTransactionsByCategory counts the active transactions assigned to a category
+func (r *GormRepository) CountTransactionsByCategory(ctx context.Context, householdID uint, categoryID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("household_id = ? AND category_id = ?", householdID, categoryID).
+		Count(&count).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to count transactions for category with ID %d", categoryID), err)
+	}
+	return count, nil
+}
+
+// ReassignTransactions moves all transactions of a category to another category. Soft-deleted ones
+// move too, so that they can still be restored once their category is deleted. Reconciled
+// transactions are locked, so a category that has any is refused with a conflict error.
+func (r *GormRepository) ReassignTransactions(ctx context.Context, householdID uint, fromCategoryID, toCategoryID uint) (int64, error) {
+	var reconciled int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Transaction{}).
+		Where("household_id = ? AND category_id = ? AND status = ?", householdID, fromCategoryID, models.StatusReconciled).
+		Count(&reconciled).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to check transactions of category with ID %d", fromCategoryID), err)
+	}
+	if reconciled > 0 {
+		return 0, reconciledTransactionsConflict(fromCategoryID, reconciled)
+	}
+
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Transaction{}).
+		Where("household_id = ? AND category_id = ? AND status <> ?", householdID, fromCategoryID, models.StatusReconciled).
+		Update("category_id", toCategoryID)
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to reassign transactions from category with ID %d", fromCategoryID), result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// reconciledTransactionsConflict reports that a category's transactions cannot be moved because
+// some of them are locked by a reconciliation
+func reconciledTransactionsConflict(categoryID uint, reconciled int64) error {
+	return appErrors.NewConflictError(fmt.Sprintf("Category with ID %d has %d reconciled transactions, which cannot be moved to another category", categoryID, reconciled), nil)
+}
+
+// ReparentCategories moves all child categories, including soft-deleted ones, under a new parent.
+// A nil parent turns the children into top-level categories.
+func (r *GormRepository) ReparentCategories(ctx context.Context, householdID uint, fromParentID uint, toParentID *uint) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Category{}).
+		Where("household_id = ? AND parent_id = ?", householdID, fromParentID).
+		Update("parent_id", toParentID)
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to move child categories of category with ID %d", fromParentID), result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// CreateUser adds a new user to the database
+func (r *GormRepository) CreateUser(ctx context.Context, u *models.User) error {
+	result := r.db.WithContext(ctx).Create(u)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			// PostgreSQL names the violated index and SQLite the column, both mention the email
+			if strings.Contains(result.Error.Error(), "email") {
+				return appErrors.NewAlreadyExistsError("A user with this email address already exists", result.Error)
+			}
+			return appErrors.NewAlreadyExistsError(fmt.Sprintf("User with username '%s' already exists", u.Username), result.Error)
+		}
+		return appErrors.NewInternalError("Failed to create user due to database error", result.Error)
+	}
+	return nil
+}
+
+// GetUserByUsername retrieves a user by their username
+func (r *GormRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("User '%s' not found", username), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve user '%s' due to database error", username), err)
+	}
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *GormRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve user with ID %d due to database error", id), err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by their email address
+func (r *GormRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("User with this email address not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve user by email due to database error", err)
+	}
+	return &user, nil
+}
+
+// GetUsers retrieves a page of all users, ordered by ID
+func (r *GormRepository) GetUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	var users []models.User
+	query := r.db.WithContext(ctx).Order("id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve users due to database error", err)
+	}
+	return users, nil
+}
+
+// UpdateUserProfile saves the profile and preference fields of a user
+func (r *GormRepository) UpdateUserProfile(ctx context.Context, u *models.User) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
+		"display_name":      u.DisplayName,
+		"email":             u.Email,
+		"email_verified_at": u.EmailVerifiedAt,
+		"locale":            u.Locale,
+		"timezone":          u.Timezone,
+		"base_currency":     u.BaseCurrency,
+		"first_day_of_week": u.FirstDayOfWeek,
+		"fiscal_year_start": u.FiscalYearStart,
+	})
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewAlreadyExistsError("A user with this email address already exists", result.Error)
+		}
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update profile of user %d", u.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", u.ID), nil)
+	}
+	return nil
+}
+
+// UpdateUserPassword replaces the password hash of a user
+func (r *GormRepository) UpdateUserPassword(ctx context.Context, userID uint, passwordHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("password_hash", passwordHash)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update password of user %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// ReplacePasswordHash swaps the password hash of a user for a rehash of the same password.
+// It reports false when the hash was changed concurrently, e.g. by a password change.
+func (r *GormRepository) ReplacePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", userID, oldHash).
+		Update("password_hash", newHash)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to update password hash of user %d", userID), result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeUserSessions invalidates all access tokens of a user issued before revokedAt
+func (r *GormRepository) RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("sessions_revoked_at", revokedAt).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to revoke sessions of user %d", userID), err)
+	}
+	return nil
+}
+
+// SetUserEmailVerified marks the email address of a user as verified, provided it is still email.
+// It reports whether the address was marked.
+func (r *GormRepository) SetUserEmailVerified(ctx context.Context, userID uint, email string, verifiedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ? AND email = ?", userID, email).Update("email_verified_at", verifiedAt)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to mark email of user %d as verified", userID), result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetUserDisabled disables a user as of disabledAt, or enables them again when it is nil
+func (r *GormRepository) SetUserDisabled(ctx context.Context, userID uint, disabledAt *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update disabled state of user %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *GormRepository) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(t).Error; err != nil {
+		return appErrors.NewInternalError("Failed to store refresh token due to database error", err)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by the hash of its value
+func (r *GormRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("Refresh token not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve refresh token due to database error", err)
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marks an unused, unrevoked refresh token as used.
+// It reports false when the token had already been used or revoked, e.g. by a concurrent refresh.
+func (r *GormRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError("Failed to mark refresh token as used", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token descended from the same login
+func (r *GormRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError("Failed to revoke refresh token family", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user
+func (r *GormRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to revoke refresh tokens of user %d", userID), result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RevokeAccessToken adds an access token to the denylist. Revoking a token twice is not an error.
+func (r *GormRepository) RevokeAccessToken(ctx context.Context, t *models.RevokedToken) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(t).Error
+	if err != nil {
+		return appErrors.NewInternalError("Failed to revoke access token due to database error", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist
+func (r *GormRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, appErrors.NewInternalError("Failed to check access token denylist", err)
+	}
+	return count > 0, nil
+}
+
+// DeleteExpiredTokens removes refresh tokens, denylist entries, login challenges, password
+// reset tokens and pending OIDC logins that expired before the given time
+func (r *GormRepository) DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at < ?", expiredBefore).Delete(&models.RefreshToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+
+		result = tx.Where("expires_at < ?", expiredBefore).Delete(&models.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+
+		result = tx.Where("expires_at < ?", expiredBefore).Delete(&models.LoginChallenge{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+
+		result = tx.Where("expires_at < ?", expiredBefore).Delete(&models.PasswordResetToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+
+		result = tx.Where("expires_at < ?", expiredBefore).Delete(&models.OIDCAuthRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, appErrors.NewInternalError("Failed to delete expired tokens", err)
+	}
+	return deleted, nil
+}
+
+// CreateAPIToken stores a new personal access token
+func (r *GormRepository) CreateAPIToken(ctx context.Context, t *models.APIToken) error {
+	if err := r.db.WithContext(ctx).Create(t).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create API token due to database error", err)
+	}
+	return nil
+}
+
+// GetAPITokens retrieves all personal access tokens of a user, newest first
+func (r *GormRepository) GetAPITokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve API tokens for user %d", userID), err)
+	}
+	return tokens, nil
+}
+
+// GetAPITokenByID retrieves a personal access token by ID for a specific user
+func (r *GormRepository) GetAPITokenByID(ctx context.Context, userID, id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&token, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("API token with ID %d not found for user %d", id, userID), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve API token with ID %d due to database error", id), err)
+	}
+	return &token, nil
+}
+
+// GetAPITokenByHash retrieves a personal access token by the hash of its value
+func (r *GormRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("API token not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve API token due to database error", err)
+	}
+	return &token, nil
+}
+
+// UpdateAPITokenLastUsed records when a personal access token was last used
+func (r *GormRepository) UpdateAPITokenLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update last use of API token %d", id), err)
+	}
+	return nil
+}
+
+// RevokeAPIToken revokes a personal access token of a user
+func (r *GormRepository) RevokeAPIToken(ctx context.Context, userID, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to revoke API token with ID %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Active API token with ID %d not found for user %d", id, userID), nil)
+	}
+	return nil
+}
+
+// RevokeUserAPITokens revokes every personal access token of a user
+func (r *GormRepository) RevokeUserAPITokens(ctx context.Context, userID uint) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to revoke API tokens of user %d", userID), result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// UpdateUserTOTP sets the two-factor authentication state of a user
+func (r *GormRepository) UpdateUserTOTP(ctx context.Context, userID uint, secret string, enabled bool, lastCounter int64) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_enabled":      enabled,
+		"totp_last_counter": lastCounter,
+	})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update two-factor settings of user %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// AdvanceTOTPCounter records the time step of an accepted TOTP code.
+// It reports false when the same or a later time step was already used, i.e. the code is a replay.
+func (r *GormRepository) AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to record TOTP use of user %d", userID), result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user with the given ones
+func (r *GormRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to replace recovery codes of user %d", userID), err)
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code; it reports false when no such code exists
+func (r *GormRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to use recovery code of user %d", userID), result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes a user has left
+func (r *GormRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to count recovery codes of user %d", userID), err)
+	}
+	return count, nil
+}
+
+// CreateLoginChallenge stores a new two-factor login challenge
+func (r *GormRepository) CreateLoginChallenge(ctx context.Context, challenge *models.LoginChallenge) error {
+	if err := r.db.WithContext(ctx).Create(challenge).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create login challenge due to database error", err)
+	}
+	return nil
+}
+
+// GetLoginChallengeByHash retrieves a login challenge by the hash of its token
+func (r *GormRepository) GetLoginChallengeByHash(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("Login challenge not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve login challenge due to database error", err)
+	}
+	return &challenge, nil
+}
+
+// RecordLoginChallengeAttempt counts a failed code submission against a login challenge
+func (r *GormRepository) RecordLoginChallengeAttempt(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Model(&models.LoginChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to record attempt on login challenge %d", id), err)
+	}
+	return nil
+}
+
+// MarkLoginChallengeUsed completes a login challenge; it reports false when it was already used
+func (r *GormRepository) MarkLoginChallengeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to complete login challenge %d", id), result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetLoginAttempt retrieves the failed login record for a username or IP key
+func (r *GormRepository) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.WithContext(ctx).Where("attempt_key = ?", key).First(&attempt).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("No failed logins recorded for '%s'", key), err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve login attempts due to database error", err)
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailure atomically counts a failed login. Counting restarts at one when the
+// previous failure happened before resetBefore.
+func (r *GormRepository) RecordLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+		VALUES (@key, 1, @at)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < @resetBefore THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = @at
+		RETURNING *`,
+		map[string]interface{}{"key": key, "at": at, "resetBefore": resetBefore},
+	).Scan(&attempt).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to record failed login due to database error", err)
+	}
+	return &attempt, nil
+}
+
+// SetLoginLockedUntil blocks logins for a username or IP key until the given time
+func (r *GormRepository) SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.LoginAttempt{}).Where("attempt_key = ?", key).
+		Update("locked_until", until).Error
+	if err != nil {
+		return appErrors.NewInternalError("Failed to lock login due to database error", err)
+	}
+	return nil
+}
+
+// DeleteLoginAttempt forgets all failed logins of a username or IP key
+func (r *GormRepository) DeleteLoginAttempt(ctx context.Context, key string) error {
+	err := r.db.WithContext(ctx).Where("attempt_key = ?", key).Delete(&models.LoginAttempt{}).Error
+	if err != nil {
+		return appErrors.NewInternalError("Failed to reset failed logins due to database error", err)
+	}
+	return nil
+}
+
+// DeleteStaleLoginAttempts removes records whose last failure and lock both ended before the given time
+func (r *GormRepository) DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&models.LoginAttempt{})
+	if result.Error != nil {
+		return 0, appErrors.NewInternalError("Failed to delete stale login attempts", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// CreatePasswordResetToken stores a new password reset token
+func (r *GormRepository) CreatePasswordResetToken(ctx context.Context, t *models.PasswordResetToken) error {
+	if err := r.db.WithContext(ctx).Create(t).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create password reset token due to database error", err)
+	}
+	return nil
+}
+
+// GetPasswordResetTokenByHash retrieves a password reset token by the hash of its value
+func (r *GormRepository) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("Password reset token not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve password reset token due to database error", err)
+	}
+	return &token, nil
+}
+
+// MarkPasswordResetTokenUsed consumes a password reset token; it reports false when it was already used
+func (r *GormRepository) MarkPasswordResetTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to use password reset token %d", id), result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidatePasswordResetTokens marks all outstanding password reset tokens of a user as used
+func (r *GormRepository) InvalidatePasswordResetTokens(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to invalidate password reset tokens of user %d", userID), err)
+	}
+	return nil
+}
+
+// CreateUserIdentity links an external identity to a user
+func (r *GormRepository) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	result := r.db.WithContext(ctx).Create(identity)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewAlreadyExistsError("This identity is already linked to a user", result.Error)
+		}
+		return appErrors.NewInternalError("Failed to link identity due to database error", result.Error)
+	}
+	return nil
+}
+
+// GetUserIdentity retrieves the identity with the given issuer and subject
+func (r *GormRepository) GetUserIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("Identity not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve identity due to database error", err)
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities retrieves all identities linked to a user
+func (r *GormRepository) GetUserIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve identities of user %d", userID), err)
+	}
+	return identities, nil
+}
+
+// RecordUserIdentityLogin stores the time of a login with an identity and the email the provider reported
+func (r *GormRepository) RecordUserIdentityLogin(ctx context.Context, id uint, email string, at time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"email": email, "last_login_at": at}).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to record login of identity %d", id), err)
+	}
+	return nil
+}
+
+// DeleteUserIdentity unlinks an identity from a user
+func (r *GormRepository) DeleteUserIdentity(ctx context.Context, userID, id uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentity{})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to unlink identity %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Identity with ID %d not found", id), nil)
+	}
+	return nil
+}
+
+// CountUserIdentities counts the identities linked to a user
+func (r *GormRepository) CountUserIdentities(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to count identities of user %d", userID), err)
+	}
+	return count, nil
+}
+
+// CreateOIDCAuthRequest stores a pending OIDC login
+func (r *GormRepository) CreateOIDCAuthRequest(ctx context.Context, req *models.OIDCAuthRequest) error {
+	if err := r.db.WithContext(ctx).Create(req).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create OIDC login request due to database error", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCAuthRequest retrieves and deletes the pending OIDC login with the given state hash,
+// so each one can complete only once
+func (r *GormRepository) ConsumeOIDCAuthRequest(ctx context.Context, stateHash string) (*models.OIDCAuthRequest, error) {
+	var req models.OIDCAuthRequest
+	err := r.db.WithContext(ctx).Where("state_hash = ?", stateHash).First(&req).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("OIDC login request not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve OIDC login request due to database error", err)
+	}
+
+	result := r.db.WithContext(ctx).Where("id = ?", req.ID).Delete(&models.OIDCAuthRequest{})
+	if result.Error != nil {
+		return nil, appErrors.NewInternalError("Failed to consume OIDC login request due to database error", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, appErrors.NewNotFoundError("OIDC login request not found", nil)
+	}
+	return &req, nil
+}
+
+// CreateHousehold adds a new household
+func (r *GormRepository) CreateHousehold(ctx context.Context, h *models.Household) error {
+	if err := r.db.WithContext(ctx).Create(h).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create household due to database error", err)
+	}
+	return nil
+}
+
+// GetHousehold retrieves a household by its ID
+func (r *GormRepository) GetHousehold(ctx context.Context, id uint) (*models.Household, error) {
+	var household models.Household
+	err := r.db.WithContext(ctx).First(&household, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("Household with ID %d not found", id), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve household with ID %d due to database error", id), err)
+	}
+	return &household, nil
+}
+
+// UpdateHousehold updates the name of an existing household
+func (r *GormRepository) UpdateHousehold(ctx context.Context, h *models.Household) error {
+	result := r.db.WithContext(ctx).Model(h).Update("name", h.Name)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update household with ID %d due to database error", h.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Household with ID %d not found", h.ID), nil)
+	}
+	return nil
+}
+
+// GetUserHouseholds retrieves the memberships of a user with their households
+func (r *GormRepository) GetUserHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error) {
+	var members []models.HouseholdMember
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Preload("Household").Order("household_id ASC").Find(&members).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve households of user %d", userID), err)
+	}
+	return members, nil
+}
+
+// GetHouseholdMember retrieves the membership of a user in a household
+func (r *GormRepository) GetHouseholdMember(ctx context.Context, householdID, userID uint) (*models.HouseholdMember, error) {
+	var member models.HouseholdMember
+	err := r.db.WithContext(ctx).Where("household_id = ? AND user_id = ?", householdID, userID).First(&member).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve membership of user %d in household %d", userID, householdID), err)
+	}
+	return &member, nil
+}
+
+// GetHouseholdMembers retrieves all members of a household with their users
+func (r *GormRepository) GetHouseholdMembers(ctx context.Context, householdID uint) ([]models.HouseholdMember, error) {
+	var members []models.HouseholdMember
+	err := r.db.WithContext(ctx).Where("household_id = ?", householdID).Preload("User").Order("created_at ASC").Find(&members).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve members of household %d", householdID), err)
+	}
+	return members, nil
+}
+
+// AddHouseholdMember adds a user to a household
+func (r *GormRepository) AddHouseholdMember(ctx context.Context, m *models.HouseholdMember) error {
+	result := r.db.WithContext(ctx).Create(m)
+	if result.Error != nil {
+		if r.isUniqueViolation(result.Error) {
+			return appErrors.NewAlreadyExistsError("User is already a member of this household", result.Error)
+		}
+		return appErrors.NewInternalError("Failed to add household member due to database error", result.Error)
+	}
+	return nil
+}
+
+// UpdateHouseholdMemberRole changes the role of a household member
+func (r *GormRepository) UpdateHouseholdMemberRole(ctx context.Context, householdID, userID uint, role models.HouseholdRole) error {
+	result := r.db.WithContext(ctx).Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND user_id = ?", householdID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to update role of user %d in household %d", userID, householdID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), nil)
+	}
+	return nil
+}
+
+// RemoveHouseholdMember removes a user from a household
+func (r *GormRepository) RemoveHouseholdMember(ctx context.Context, householdID, userID uint) error {
+	result := r.db.WithContext(ctx).Where("household_id = ? AND user_id = ?", householdID, userID).Delete(&models.HouseholdMember{})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to remove user %d from household %d", userID, householdID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), nil)
+	}
+	return nil
+}
+
+// CountHouseholdOwners counts the members of a household with the owner role
+func (r *GormRepository) CountHouseholdOwners(ctx context.Context, householdID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.HouseholdMember{}).
+		Where("household_id = ? AND role = ?", householdID, models.RoleOwner).
+		Count(&count).Error
+	if err != nil {
+		return 0, appErrors.NewInternalError(fmt.Sprintf("Failed to count owners of household %d", householdID), err)
+	}
+	return count, nil
+}
+
+// SetUserDefaultHousehold sets the household used when a request of the user names none
+func (r *GormRepository) SetUserDefaultHousehold(ctx context.Context, userID uint, householdID *uint) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("default_household_id", householdID).Error
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to set default household of user %d", userID), err)
+	}
+	return nil
+}
+
+// CreateHouseholdInvitation stores a new household invitation
+func (r *GormRepository) CreateHouseholdInvitation(ctx context.Context, inv *models.HouseholdInvitation) error {
+	if err := r.db.WithContext(ctx).Create(inv).Error; err != nil {
+		return appErrors.NewInternalError("Failed to create household invitation due to database error", err)
+	}
+	return nil
+}
+
+// GetHouseholdInvitations retrieves the pending invitations of a household
+func (r *GormRepository) GetHouseholdInvitations(ctx context.Context, householdID uint, now time.Time) ([]models.HouseholdInvitation, error) {
+	var invitations []models.HouseholdInvitation
+	err := r.db.WithContext(ctx).
+		Where("household_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", householdID, now).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve invitations of household %d", householdID), err)
+	}
+	return invitations, nil
+}
+
+// GetHouseholdInvitationByHash retrieves an invitation with its household by the hash of its token
+func (r *GormRepository) GetHouseholdInvitationByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvitation, error) {
+	var invitation models.HouseholdInvitation
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Preload("Household").First(&invitation).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("Household invitation not found", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to retrieve household invitation due to database error", err)
+	}
+	return &invitation, nil
+}
+
+// MarkHouseholdInvitationAccepted marks an invitation as accepted by a user. It reports false if
+// the invitation was already accepted or revoked, so each invitation can be used only once.
+func (r *GormRepository) MarkHouseholdInvitationAccepted(ctx context.Context, id, userID uint, acceptedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.HouseholdInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"accepted_at": acceptedAt, "accepted_by_id": userID})
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to accept household invitation %d", id), result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeHouseholdInvitation withdraws a pending invitation of a household
+func (r *GormRepository) RevokeHouseholdInvitation(ctx context.Context, householdID, id uint, revokedAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.HouseholdInvitation{}).
+		Where("id = ? AND household_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id, householdID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to revoke household invitation %d", id), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Pending invitation with ID %d not found in household", id), nil)
+	}
+	return nil
+}
+
+// auditChainLockID identifies the advisory lock that serialises appends to the audit log
+const auditChainLockID = 7_341_905_212
+
+// AppendAuditEntry links an entry to the latest one in the audit log, computes its hash and
+// stores it. On PostgreSQL appends are serialised with a transaction-level advisory lock, so
+// concurrent requests cannot both extend the chain from the same entry; when called within a
+// transaction the lock is held until that transaction ends. SQLite serialises writers itself.
+func (r *GormRepository) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if r.isPostgres() {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockID).Error; err != nil {
+				return err
+			}
+		}
+
+		var last models.AuditEntry
+		err := tx.Select("hash").Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+		entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+		entry.PrevHash = last.Hash
+		entry.ContentHash = entry.ComputeContentHash()
+		entry.Hash = entry.ComputeHash()
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return appErrors.NewInternalError("Failed to append audit log entry due to database error", err)
+	}
+	return nil
+}
+
+// GetAuditEntries retrieves audit entries matching a filter, newest first
+func (r *GormRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	query := r.db.WithContext(ctx).
+		Where("household_id = ? OR (household_id IS NULL AND actor_id = ?)", filter.VisibleHousehold, filter.VisibleUser).
+		Order("id DESC")
+
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != nil && *filter.Action != "" {
+		query = query.Where("action = ?", *filter.Action)
+	}
+	if filter.EntityType != nil && *filter.EntityType != "" {
+		query = query.Where("entity_type = ?", *filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at <= ?", *filter.To)
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve audit log entries from database", err)
+	}
+	return entries, nil
+}
+
+// GetAuditEntriesAfter retrieves up to limit entries following the entry afterID, oldest first,
+// for walking the whole hash chain in batches
+func (r *GormRepository) GetAuditEntriesAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve audit log entries from database", err)
+	}
+	return entries, nil
+}
+
+// GetPersonalAuditEntries retrieves the audit entries of changes a user made and of changes to
+// their account by others, oldest first
+func (r *GormRepository) GetPersonalAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.db.WithContext(ctx).
+		Where("actor_id = ? OR (entity_type = ? AND entity_id = ?)", userID, models.AuditEntityUser, userID).
+		Order("id ASC").Find(&entries).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve audit entries of user %d", userID), err)
+	}
+	return entries, nil
+}
+
+// EraseAuditEntryData stores the erased personal data of an audit entry: its changes, IP address
+// and user agent, together with ErasedAt. Entries can only be erased once.
+func (r *GormRepository) EraseAuditEntryData(ctx context.Context, entry *models.AuditEntry) error {
+	result := r.db.WithContext(ctx).Model(&models.AuditEntry{}).
+		Where("id = ? AND erased_at IS NULL", entry.ID).
+		Updates(map[string]interface{}{
+			"changes":    entry.Changes,
+			"ip_address": entry.IPAddress,
+			"user_agent": entry.UserAgent,
+			"erased_at":  entry.ErasedAt,
+		})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to erase audit entry %d", entry.ID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Audit entry with ID %d not found or already erased", entry.ID), nil)
+	}
+	return nil
+}
+
+// GetUserCategories retrieves every category a user created in any household, including deleted ones
+func (r *GormRepository) GetUserCategories(ctx context.Context, userID uint) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Order("id ASC").Find(&categories).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve categories of user %d", userID), err)
+	}
+	return categories, nil
+}
+
+// GetUserTransactions retrieves every transaction a user recorded in any household, including deleted ones
+func (r *GormRepository) GetUserTransactions(ctx context.Context, userID uint) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	err := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Order("date ASC, id ASC").Find(&transactions).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve transactions of user %d", userID), err)
+	}
+	return transactions, nil
+}
+
+// GetUserReconciliations retrieves every reconciliation a user started in any household, including deleted ones
+func (r *GormRepository) GetUserReconciliations(ctx context.Context, userID uint) ([]models.Reconciliation, error) {
+	var reconciliations []models.Reconciliation
+	err := r.db.WithContext(ctx).Unscoped().Where("user_id = ?", userID).Order("id ASC").Find(&reconciliations).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve reconciliations of user %d", userID), err)
+	}
+	return reconciliations, nil
+}
+
+// GetUserAuditEntries retrieves the audit entries of changes a user made, oldest first
+func (r *GormRepository) GetUserAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := r.db.WithContext(ctx).Where("actor_id = ?", userID).Order("id ASC").Find(&entries).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve audit entries of user %d", userID), err)
+	}
+	return entries, nil
+}
+
+// CreateAccountDeletion stores a new account deletion request, replacing any earlier request of the user
+func (r *GormRepository) CreateAccountDeletion(ctx context.Context, d *models.AccountDeletion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", d.UserID).Delete(&models.AccountDeletion{}).Error; err != nil {
+			return appErrors.NewInternalError(fmt.Sprintf("Failed to replace account deletion request of user %d", d.UserID), err)
+		}
+		if err := tx.Create(d).Error; err != nil {
+			return appErrors.NewInternalError("Failed to create account deletion request due to database error", err)
+		}
+		return nil
+	})
+}
+
+// GetAccountDeletion retrieves the account deletion request of a user
+func (r *GormRepository) GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&deletion).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, appErrors.NewNotFoundError("No account deletion has been requested", err)
+		}
+		return nil, appErrors.NewInternalError(fmt.Sprintf("Failed to retrieve account deletion request of user %d", userID), err)
+	}
+	return &deletion, nil
+}
+
+// ConfirmAccountDeletion confirms the pending deletion request of a user matching tokenHash and
+// schedules it. It reports false if no unconfirmed, unexpired request matches.
+func (r *GormRepository) ConfirmAccountDeletion(ctx context.Context, userID uint, tokenHash string, confirmedAt, scheduledFor time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.AccountDeletion{}).
+		Where("user_id = ? AND token_hash = ? AND confirmed_at IS NULL AND expires_at > ?", userID, tokenHash, confirmedAt).
+		Updates(map[string]interface{}{"confirmed_at": confirmedAt, "scheduled_for": scheduledFor})
+	if result.Error != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Failed to confirm account deletion of user %d", userID), result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteAccountDeletion removes the account deletion request of a user, cancelling it
+func (r *GormRepository) DeleteAccountDeletion(ctx context.Context, userID uint) error {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.AccountDeletion{})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to cancel account deletion of user %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError("No account deletion has been requested", nil)
+	}
+	return nil
+}
+
+// GetDueAccountDeletions retrieves the confirmed account deletions whose grace period has passed
+func (r *GormRepository) GetDueAccountDeletions(ctx context.Context, now time.Time) ([]models.AccountDeletion, error) {
+	var deletions []models.AccountDeletion
+	err := r.db.WithContext(ctx).
+		Where("confirmed_at IS NOT NULL AND scheduled_for <= ?", now).
+		Order("scheduled_for ASC").
+		Find(&deletions).Error
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to retrieve due account deletions", err)
+	}
+	return deletions, nil
+}
+
+// PurgeHousehold permanently deletes a household with its members, invitations and all of its
+// records, including soft-deleted ones
+func (r *GormRepository) PurgeHousehold(ctx context.Context, householdID uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Transactions reference reconciliations and categories, and categories their parents
+		if err := tx.Unscoped().Where("household_id = ?", householdID).Delete(&models.Transaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("household_id = ?", householdID).Delete(&models.Reconciliation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Category{}).Where("household_id = ?", householdID).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("household_id = ?", householdID).Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("household_id = ?", householdID).Delete(&models.HouseholdInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("household_id = ?", householdID).Delete(&models.HouseholdMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("default_household_id = ?", householdID).Update("default_household_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Household{}, householdID).Error
+	})
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to purge household with ID %d", householdID), err)
+	}
+	return nil
+}
+
+// ReassignHouseholdRecords attributes the records a user created in a household to another
+// member, including soft-deleted ones
+func (r *GormRepository) ReassignHouseholdRecords(ctx context.Context, householdID, fromUserID, toUserID uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Transaction{}, &models.Reconciliation{}, &models.Category{}} {
+			err := tx.Unscoped().Model(model).
+				Where("household_id = ? AND user_id = ?", householdID, fromUserID).
+				Update("user_id", toUserID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to reassign records of user %d in household %d", fromUserID, householdID), err)
+	}
+	return nil
+}
+
+// AnonymizeHouseholdRecords renames a household and clears the free-text descriptions of all of
+// its transactions, including soft-deleted ones
+func (r *GormRepository) AnonymizeHouseholdRecords(ctx context.Context, householdID uint, name string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Transaction{}).Where("household_id = ?", householdID).Update("description", "").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Household{}).Where("id = ?", householdID).Update("name", name).Error
+	})
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to anonymise household with ID %d", householdID), err)
+	}
+	return nil
+}
+
+// DeleteUserCredentials removes everything a user could sign in with or that was issued to them:
+// tokens, recovery codes, login challenges, password resets, linked identities, pending logins,
+// sent invitations and the account deletion request
+func (r *GormRepository) DeleteUserCredentials(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.RefreshToken{},
+			&models.RevokedToken{},
+			&models.APIToken{},
+			&models.RecoveryCode{},
+			&models.LoginChallenge{},
+			&models.PasswordResetToken{},
+			&models.UserIdentity{},
+			&models.AccountDeletion{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("link_user_id = ?", userID).Delete(&models.OIDCAuthRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("invited_by_id = ?", userID).Delete(&models.HouseholdInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.HouseholdInvitation{}).Where("accepted_by_id = ?", userID).Update("accepted_by_id", nil).Error
+	})
+	if err != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to delete credentials of user %d", userID), err)
+	}
+	return nil
+}
+
+// AnonymizeUser replaces the username of a user, clears their email address, password and
+// two-factor settings, and signs out all of their sessions
+func (r *GormRepository) AnonymizeUser(ctx context.Context, userID uint, username string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"username":            username,
+		"email":               nil,
+		"password_hash":       "",
+		"totp_secret":         "",
+		"totp_enabled":        false,
+		"totp_last_counter":   0,
+		"sessions_revoked_at": at,
+	})
+	if result.Error != nil {
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to anonymise user with ID %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// DeleteUser permanently deletes a user. Their records must have been removed or reassigned first.
+func (r *GormRepository) DeleteUser(ctx context.Context, userID uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.User{}, userID)
+	if result.Error != nil {
+		if r.isForeignKeyViolation(result.Error) {
+			return appErrors.NewConflictError(fmt.Sprintf("User with ID %d still owns records", userID), result.Error)
+		}
+		return appErrors.NewInternalError(fmt.Sprintf("Failed to delete user with ID %d", userID), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// Transaction executes a function within a database transaction.
+func (r *GormRepository) Transaction(txFunc func(txRepo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txGormRepo := NewGormRepository(tx)
+		return txFunc(txGormRepo)
+	})
+}
@@ -0,0 +1,499 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/migrations"
+	"personal-finance-tracker-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDatabaseURLEnv names the PostgreSQL connection string the suite also runs against. Each
+// test gets its own schema, which is dropped afterwards.
+const testDatabaseURLEnv = "TEST_DATABASE_URL"
+
+// memoryBackend names the in-memory repository in the subtests of forEachBackend
+const memoryBackend = "memory"
+
+// forEachBackend runs a test against a freshly migrated database of every available backend and
+// against the in-memory repository, for which db is nil
+func forEachBackend(t *testing.T, test func(t *testing.T, repo Repository, db *gorm.DB)) {
+	t.Run(memoryBackend, func(t *testing.T) {
+		test(t, NewMemoryRepository(), nil)
+	})
+	t.Run(DriverSQLite, func(t *testing.T) {
+		db := openMigrated(t, DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+		test(t, NewGormRepository(db), db)
+	})
+	t.Run(DriverPostgres, func(t *testing.T) {
+		dsn := os.Getenv(testDatabaseURLEnv)
+		if dsn == "" {
+			t.Skipf("%s is not set", testDatabaseURLEnv)
+		}
+		db := openMigrated(t, DriverPostgres, postgresTestSchema(t, dsn))
+		test(t, NewGormRepository(db), db)
+	})
+}
+
+// postgresTestSchema creates an empty schema and returns dsn with it as the search path
+func postgresTestSchema(t *testing.T, dsn string) string {
+	t.Helper()
+	admin, err := OpenDB(DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("connect to PostgreSQL: %v", err)
+	}
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "repository_test_" + hex.EncodeToString(suffix)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		return dsn + separator + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+// openMigrated opens a database and applies the built-in migrations of its dialect
+func openMigrated(t *testing.T, driver, dsn string) *gorm.DB {
+	t.Helper()
+	db, err := OpenDB(driver, dsn)
+	if err != nil {
+		t.Fatalf("open %s database: %v", driver, err)
+	}
+	db.Logger = logger.Discard
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	builtin, err := migrations.Builtin(db.Dialector.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(sqlDB, db.Dialector.Name(), builtin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate %s database: %v", driver, err)
+	}
+	return db
+}
+
+// seedHousehold creates a user with a household and a category in it
+func seedHousehold(t *testing.T, repo Repository, username string) (*models.User, *models.Household, *models.Category) {
+	t.Helper()
+	ctx := context.Background()
+	user := &models.User{Username: username, PasswordHash: "hash"}
+	if err := repo.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	household := &models.Household{Name: username + "'s household"}
+	if err := repo.CreateHousehold(ctx, household); err != nil {
+		t.Fatalf("CreateHousehold: %v", err)
+	}
+	member := &models.HouseholdMember{HouseholdID: household.ID, UserID: user.ID, Role: models.RoleOwner}
+	if err := repo.AddHouseholdMember(ctx, member); err != nil {
+		t.Fatalf("AddHouseholdMember: %v", err)
+	}
+	category := &models.Category{Name: "Groceries", HouseholdID: household.ID, UserID: user.ID}
+	if err := repo.CreateCategory(ctx, category); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	return user, household, category
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestCreateUserRejectsDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		if err := repo.CreateUser(ctx, &models.User{Username: "alice", Email: stringPtr("alice@example.com"), PasswordHash: "hash"}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+
+		tests := []struct {
+			name        string
+			user        models.User
+			wantMessage string
+		}{
+			{"username", models.User{Username: "alice", Email: stringPtr("other@example.com"), PasswordHash: "hash"}, "username"},
+			{"email", models.User{Username: "bob", Email: stringPtr("alice@example.com"), PasswordHash: "hash"}, "email"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := repo.CreateUser(ctx, &tt.user)
+				if got := appErrors.GetType(err); got != appErrors.TypeAlreadyExists {
+					t.Fatalf("error type = %q (%v), want %q", got, err, appErrors.TypeAlreadyExists)
+				}
+				if !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Errorf("error %q does not mention the %s", err, tt.wantMessage)
+				}
+			})
+		}
+	})
+}
+
+func TestCreateCategoryNameIsUniqueAmongActiveCategories(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, category := seedHousehold(t, repo, "alice")
+
+		duplicate := &models.Category{Name: category.Name, HouseholdID: household.ID, UserID: user.ID}
+		if err := repo.CreateCategory(ctx, duplicate); appErrors.GetType(err) != appErrors.TypeAlreadyExists {
+			t.Fatalf("CreateCategory with a taken name: %v, want an already exists error", err)
+		}
+
+		if err := repo.DeleteCategory(ctx, household.ID, category.ID); err != nil {
+			t.Fatalf("DeleteCategory: %v", err)
+		}
+		if err := repo.CreateCategory(ctx, duplicate); err != nil {
+			t.Fatalf("CreateCategory with the name of a deleted category: %v", err)
+		}
+	})
+}
+
+func TestCreateTransactionRejectsUnknownCategory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		user, household, _ := seedHousehold(t, repo, "alice")
+		transaction := &models.Transaction{
+			Amount: 10, Type: models.Expense, Date: time.Now(), Status: models.StatusPending,
+			CategoryID: 9999, HouseholdID: household.ID, UserID: user.ID,
+		}
+		err := repo.CreateTransaction(context.Background(), transaction)
+		if got := appErrors.GetType(err); got != appErrors.TypeValidation {
+			t.Fatalf("error type = %q (%v), want %q", got, err, appErrors.TypeValidation)
+		}
+	})
+}
+
+func TestReassignTransactionsRefusesReconciledTransactions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, groceries := seedHousehold(t, repo, "alice")
+		rent := &models.Category{Name: "Rent", HouseholdID: household.ID, UserID: user.ID}
+		if err := repo.CreateCategory(ctx, rent); err != nil {
+			t.Fatalf("CreateCategory: %v", err)
+		}
+		create := func(status models.TransactionStatus) *models.Transaction {
+			transaction := &models.Transaction{
+				Amount: 10, Type: models.Expense, Date: time.Now(), Status: status,
+				CategoryID: groceries.ID, HouseholdID: household.ID, UserID: user.ID,
+			}
+			if err := repo.CreateTransaction(ctx, transaction); err != nil {
+				t.Fatalf("CreateTransaction: %v", err)
+			}
+			return transaction
+		}
+		create(models.StatusPending)
+		deleted := create(models.StatusCleared)
+		if err := repo.DeleteTransaction(ctx, household.ID, deleted.ID); err != nil {
+			t.Fatalf("DeleteTransaction: %v", err)
+		}
+
+		moved, err := repo.ReassignTransactions(ctx, household.ID, groceries.ID, rent.ID)
+		if err != nil {
+			t.Fatalf("ReassignTransactions: %v", err)
+		}
+		if moved != 2 {
+			t.Errorf("moved %d transactions, want both the active and the soft-deleted one", moved)
+		}
+
+		create(models.StatusReconciled)
+		_, err = repo.ReassignTransactions(ctx, household.ID, groceries.ID, rent.ID)
+		if got := appErrors.GetType(err); got != appErrors.TypeConflict {
+			t.Fatalf("error type = %q (%v), want %q", got, err, appErrors.TypeConflict)
+		}
+		if count, _ := repo.CountTransactionsByCategory(ctx, household.ID, groceries.ID); count != 1 {
+			t.Errorf("%d transactions left in the category, want the reconciled one", count)
+		}
+	})
+}
+
+func TestCreateReconciliationAllowsOneOpenSessionPerHousehold(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, _ := seedHousehold(t, repo, "alice")
+		open := func() *models.Reconciliation {
+			return &models.Reconciliation{
+				StatementEndDate: time.Now(), Status: models.ReconciliationOpen, HouseholdID: household.ID, UserID: user.ID,
+			}
+		}
+
+		first := open()
+		if err := repo.CreateReconciliation(ctx, first); err != nil {
+			t.Fatalf("CreateReconciliation: %v", err)
+		}
+		err := repo.CreateReconciliation(ctx, open())
+		if got := appErrors.GetType(err); got != appErrors.TypeConflict {
+			t.Fatalf("error type = %q (%v), want %q", got, err, appErrors.TypeConflict)
+		}
+
+		// Cancelled sessions keep their open status, but no longer count
+		if err := repo.DeleteReconciliation(ctx, household.ID, first.ID); err != nil {
+			t.Fatalf("DeleteReconciliation: %v", err)
+		}
+		if err := repo.CreateReconciliation(ctx, open()); err != nil {
+			t.Errorf("CreateReconciliation after cancelling the open session: %v", err)
+		}
+	})
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, category := seedHousehold(t, repo, "alice")
+
+		// Stored with different offsets; comparisons must still be chronological
+		auckland := time.FixedZone("NZST", 12*60*60)
+		dates := []time.Time{
+			time.Date(2026, 3, 1, 8, 0, 0, 0, auckland), // 2026-02-28T20:00Z
+			time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 31, 23, 0, 0, 0, time.FixedZone("EST", -5*60*60)), // 2026-04-01T04:00Z
+		}
+		descriptions := []string{"Weekly SHOP", "100% juice", "shop_online"}
+		for i, date := range dates {
+			transaction := &models.Transaction{
+				Description: descriptions[i], Amount: float64(i + 1), Type: models.Expense, Date: date,
+				Status: models.StatusPending, CategoryID: category.ID, HouseholdID: household.ID, UserID: user.ID,
+			}
+			if err := repo.CreateTransaction(ctx, transaction); err != nil {
+				t.Fatalf("CreateTransaction: %v", err)
+			}
+		}
+
+		march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		endOfMarch := time.Date(2026, 3, 31, 23, 59, 59, 0, time.UTC)
+		tests := []struct {
+			name        string
+			start, end  *time.Time
+			description *string
+			want        []string
+		}{
+			{"all", nil, nil, nil, []string{"shop_online", "100% juice", "Weekly SHOP"}},
+			{"date range", &march, &endOfMarch, nil, []string{"100% juice"}},
+			{"description ignores case", nil, nil, stringPtr("shop"), []string{"shop_online", "Weekly SHOP"}},
+			{"percent is literal", nil, nil, stringPtr("0%"), []string{"100% juice"}},
+			{"underscore is literal", nil, nil, stringPtr("p_o"), []string{"shop_online"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				transactions, err := repo.GetTransactions(ctx, household.ID, 0, 0, tt.start, tt.end, nil, tt.description)
+				if err != nil {
+					t.Fatalf("GetTransactions: %v", err)
+				}
+				var got []string
+				for _, transaction := range transactions {
+					got = append(got, transaction.Description)
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("descriptions = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestGetCategoryTreeWithStats(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, groceries := seedHousehold(t, repo, "alice")
+		produce := &models.Category{Name: "Produce", ParentID: &groceries.ID, HouseholdID: household.ID, UserID: user.ID}
+		if err := repo.CreateCategory(ctx, produce); err != nil {
+			t.Fatalf("CreateCategory: %v", err)
+		}
+
+		date := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+		for _, transaction := range []*models.Transaction{
+			{Amount: 12.5, Type: models.Expense, CategoryID: produce.ID},
+			{Amount: 7.5, Type: models.Expense, CategoryID: produce.ID},
+			{Amount: 3, Type: models.Income, CategoryID: groceries.ID},
+		} {
+			transaction.Date, transaction.Status = date, models.StatusCleared
+			transaction.HouseholdID, transaction.UserID = household.ID, user.ID
+			if err := repo.CreateTransaction(ctx, transaction); err != nil {
+				t.Fatalf("CreateTransaction: %v", err)
+			}
+		}
+
+		rows, err := repo.GetCategoryTree(ctx, household.ID, 10, true, nil, nil)
+		if err != nil {
+			t.Fatalf("GetCategoryTree: %v", err)
+		}
+		want := []models.CategoryTreeRow{
+			{ID: groceries.ID, Name: "Groceries", Depth: 0, TransactionCount: 1, IncomeTotal: 3},
+			{ID: produce.ID, Name: "Produce", ParentID: &groceries.ID, Depth: 1, TransactionCount: 2, ExpenseTotal: 20},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("got %d rows, want %d", len(rows), len(want))
+		}
+		for i, row := range rows {
+			w := want[i]
+			if row.ID != w.ID || row.Depth != w.Depth || row.TransactionCount != w.TransactionCount ||
+				row.IncomeTotal != w.IncomeTotal || row.ExpenseTotal != w.ExpenseTotal ||
+				(row.ParentID == nil) != (w.ParentID == nil) {
+				t.Errorf("row %d = %+v, want %+v", i, row, w)
+			}
+		}
+
+		after := date.Add(time.Hour)
+		rows, err = repo.GetCategoryTree(ctx, household.ID, 10, true, &after, nil)
+		if err != nil {
+			t.Fatalf("GetCategoryTree: %v", err)
+		}
+		for _, row := range rows {
+			if row.TransactionCount != 0 {
+				t.Errorf("category %s counts %d transactions outside the date range", row.Name, row.TransactionCount)
+			}
+		}
+	})
+}
+
+func TestAuditEntriesFormAChainAndCannotBeChanged(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		user, household, _ := seedHousehold(t, repo, "alice")
+
+		for i := 1; i <= 3; i++ {
+			entry := &models.AuditEntry{
+				ActorID: &user.ID, HouseholdID: &household.ID, Action: models.AuditActionCreate,
+				EntityType: models.AuditEntityTransaction, EntityID: uint(i),
+				Changes:   models.AuditChanges(`{"after":{"amount":1}}`),
+				CreatedAt: time.Date(2026, 1, 1, 12, 0, i, 123456789, time.FixedZone("CET", 60*60)),
+			}
+			if err := repo.AppendAuditEntry(ctx, entry); err != nil {
+				t.Fatalf("AppendAuditEntry: %v", err)
+			}
+		}
+
+		entries, err := repo.GetAuditEntriesAfter(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetAuditEntriesAfter: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("got %d entries, want 3", len(entries))
+		}
+		prevHash := ""
+		for _, entry := range entries {
+			if entry.PrevHash != prevHash {
+				t.Errorf("entry %d links to %q, want %q", entry.ID, entry.PrevHash, prevHash)
+			}
+			if got := entry.ComputeHash(); got != entry.Hash {
+				t.Errorf("entry %d read back with hash %s, stored %s", entry.ID, got, entry.Hash)
+			}
+			prevHash = entry.Hash
+		}
+
+		// Personal data can be erased once, leaving the entry verifiable
+		erased := entries[0]
+		erased.Changes = models.AuditChanges(`{"after":{"amount":"[redacted]"}}`)
+		erased.IPAddress = ""
+		erased.ErasedAt = timePtr(time.Now())
+		if err := repo.EraseAuditEntryData(ctx, &erased); err != nil {
+			t.Fatalf("EraseAuditEntryData: %v", err)
+		}
+		if err := repo.EraseAuditEntryData(ctx, &erased); appErrors.GetType(err) != appErrors.TypeNotFound {
+			t.Errorf("erasing an entry twice: %v, want a not found error", err)
+		}
+		reread, err := repo.GetAuditEntriesAfter(ctx, 0, 1)
+		if err != nil {
+			t.Fatalf("GetAuditEntriesAfter: %v", err)
+		}
+		if string(reread[0].Changes) != string(erased.Changes) || reread[0].ErasedAt == nil || !reread[0].Intact() {
+			t.Errorf("erased entry read back as %+v", reread[0])
+		}
+
+		// The database refuses other changes to the log; the in-memory repository offers no way to make them
+		if db == nil {
+			return
+		}
+		if err := db.Exec("UPDATE audit_entries SET action = ? WHERE id = ?", models.AuditActionDelete, entries[1].ID).Error; err == nil {
+			t.Error("updating an audit entry succeeded")
+		}
+		if err := db.Exec("UPDATE audit_entries SET action = ?, erased_at = ? WHERE id = ?", models.AuditActionDelete, time.Now(), entries[1].ID).Error; err == nil {
+			t.Error("changing an audit entry while erasing it succeeded")
+		}
+		if err := db.Exec("UPDATE audit_entries SET changes = NULL WHERE id = ?", entries[0].ID).Error; err == nil {
+			t.Error("updating an erased audit entry succeeded")
+		}
+		if err := db.Exec("DELETE FROM audit_entries WHERE id = ?", entries[0].ID).Error; err == nil {
+			t.Error("deleting an audit entry succeeded")
+		}
+	})
+}
+
+func TestRecordLoginFailure(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		start := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+		tests := []struct {
+			name         string
+			at           time.Time
+			wantFailures int
+		}{
+			{"first failure", start, 1},
+			{"within the window", start.Add(time.Minute), 2},
+			{"after the window", start.Add(2 * time.Hour), 1},
+		}
+		for _, tt := range tests {
+			attempt, err := repo.RecordLoginFailure(ctx, "user:alice", tt.at, tt.at.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("%s: RecordLoginFailure: %v", tt.name, err)
+			}
+			if attempt.Failures != tt.wantFailures {
+				t.Errorf("%s: failures = %d, want %d", tt.name, attempt.Failures, tt.wantFailures)
+			}
+			if !attempt.LastFailureAt.Equal(tt.at) {
+				t.Errorf("%s: last failure at %v, want %v", tt.name, attempt.LastFailureAt, tt.at)
+			}
+		}
+	})
+}
+
+func TestTransactionRollsBackOnError(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo Repository, db *gorm.DB) {
+		ctx := context.Background()
+		errAbort := errors.New("abort")
+		err := repo.Transaction(func(txRepo Repository) error {
+			if err := txRepo.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("Transaction returned %v, want %v", err, errAbort)
+		}
+		if _, err := repo.GetUserByUsername(ctx, "alice"); appErrors.GetType(err) != appErrors.TypeNotFound {
+			t.Errorf("user created in a rolled back transaction: %v", err)
+		}
+	})
+}
@@ -0,0 +1,2235 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// MemoryRepository is an implementation of Repository that keeps all records in memory, for
+// tests and demos. It enforces the unique and foreign key constraints the services rely on and
+// reports errors like GormRepository. Calls are serialised by a mutex; a transaction holds it
+// until it ends, and its changes are discarded when it returns an error.
+type MemoryRepository struct {
+	state *memoryState
+	inTx  bool // The mutex is already held by the enclosing transaction
+}
+
+// memoryState is shared by a repository and the transaction repositories derived from it
+type memoryState struct {
+	mu   sync.Mutex
+	data *memoryData
+}
+
+// memoryData holds the tables. Rows are stored by value without their associations and copied
+// in and out, so callers never share them.
+type memoryData struct {
+	sequences map[string]uint
+
+	households      map[uint]models.Household
+	users           map[uint]models.User
+	members         map[uint]models.HouseholdMember
+	invitations     map[uint]models.HouseholdInvitation
+	categories      map[uint]models.Category
+	reconciliations map[uint]models.Reconciliation
+	transactions    map[uint]models.Transaction
+	refreshTokens   map[uint]models.RefreshToken
+	revokedTokens   map[string]models.RevokedToken
+	apiTokens       map[uint]models.APIToken
+	recoveryCodes   map[uint]models.RecoveryCode
+	loginChallenges map[uint]models.LoginChallenge
+	loginAttempts   map[string]models.LoginAttempt
+	passwordResets  map[uint]models.PasswordResetToken
+	identities      map[uint]models.UserIdentity
+	oidcRequests    map[uint]models.OIDCAuthRequest
+	deletions       map[uint]models.AccountDeletion
+	auditEntries    []models.AuditEntry // Ordered by ID, which is the position plus one
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() Repository {
+	return &MemoryRepository{state: &memoryState{data: &memoryData{
+		sequences:       make(map[string]uint),
+		households:      make(map[uint]models.Household),
+		users:           make(map[uint]models.User),
+		members:         make(map[uint]models.HouseholdMember),
+		invitations:     make(map[uint]models.HouseholdInvitation),
+		categories:      make(map[uint]models.Category),
+		reconciliations: make(map[uint]models.Reconciliation),
+		transactions:    make(map[uint]models.Transaction),
+		refreshTokens:   make(map[uint]models.RefreshToken),
+		revokedTokens:   make(map[string]models.RevokedToken),
+		apiTokens:       make(map[uint]models.APIToken),
+		recoveryCodes:   make(map[uint]models.RecoveryCode),
+		loginChallenges: make(map[uint]models.LoginChallenge),
+		loginAttempts:   make(map[string]models.LoginAttempt),
+		passwordResets:  make(map[uint]models.PasswordResetToken),
+		identities:      make(map[uint]models.UserIdentity),
+		oidcRequests:    make(map[uint]models.OIDCAuthRequest),
+		deletions:       make(map[uint]models.AccountDeletion),
+	}}}
+}
+
+// clone copies the tables, which is enough to restore them since rows are replaced, never changed in place
+func (d *memoryData) clone() *memoryData {
+	return &memoryData{
+		sequences:       maps.Clone(d.sequences),
+		households:      maps.Clone(d.households),
+		users:           maps.Clone(d.users),
+		members:         maps.Clone(d.members),
+		invitations:     maps.Clone(d.invitations),
+		categories:      maps.Clone(d.categories),
+		reconciliations: maps.Clone(d.reconciliations),
+		transactions:    maps.Clone(d.transactions),
+		refreshTokens:   maps.Clone(d.refreshTokens),
+		revokedTokens:   maps.Clone(d.revokedTokens),
+		apiTokens:       maps.Clone(d.apiTokens),
+		recoveryCodes:   maps.Clone(d.recoveryCodes),
+		loginChallenges: maps.Clone(d.loginChallenges),
+		loginAttempts:   maps.Clone(d.loginAttempts),
+		passwordResets:  maps.Clone(d.passwordResets),
+		identities:      maps.Clone(d.identities),
+		oidcRequests:    maps.Clone(d.oidcRequests),
+		deletions:       maps.Clone(d.deletions),
+		auditEntries:    slices.Clone(d.auditEntries),
+	}
+}
+
+// nextID returns the next primary key of a table
+func (d *memoryData) nextID(table string) uint {
+	d.sequences[table]++
+	return d.sequences[table]
+}
+
+// lock acquires the repository for a call and returns the function releasing it
+func (r *MemoryRepository) lock() (*memoryData, func()) {
+	if r.inTx {
+		return r.state.data, func() {}
+	}
+	r.state.mu.Lock()
+	return r.state.data, r.state.mu.Unlock
+}
+
+// atomically runs fn and discards its changes when it fails, like a statement in a database transaction
+func (r *MemoryRepository) atomically(fn func(d *memoryData) error) error {
+	d, unlock := r.lock()
+	defer unlock()
+	snapshot := d.clone()
+	if err := fn(d); err != nil {
+		r.state.data = snapshot
+		return err
+	}
+	return nil
+}
+
+// sortedRows returns the rows of a table matching keep, ordered by ID. Like a query, it returns
+// an empty slice rather than nil when nothing matches.
+func sortedRows[T any](rows map[uint]T, keep func(row *T) bool) []T {
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	result := []T{}
+	for _, id := range ids {
+		row := rows[id]
+		if keep(&row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// paginate applies a limit and offset like a query would, where zero means none
+func paginate[T any](rows []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(rows) {
+			return rows[:0]
+		}
+		rows = rows[offset:]
+	}
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// deletedAt returns a soft delete marker for the current time
+func deletedAt() gorm.DeletedAt {
+	return gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+// timePtr returns a pointer to a copy of t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// containsFold reports whether s contains substr, ignoring case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// withCategory preloads the category of a transaction, including deleted ones when unscoped
+func (d *memoryData) withCategory(t models.Transaction, unscoped bool) models.Transaction {
+	t.Category = models.Category{}
+	if category, ok := d.categories[t.CategoryID]; ok && (unscoped || !category.DeletedAt.Valid) {
+		t.Category = category
+	}
+	return t
+}
+
+// withParent preloads the parent of a category, including deleted ones when unscoped
+func (d *memoryData) withParent(c models.Category, unscoped bool) models.Category {
+	c.Parent = nil
+	if c.ParentID != nil {
+		if parent, ok := d.categories[*c.ParentID]; ok && (unscoped || !parent.DeletedAt.Valid) {
+			c.Parent = &parent
+		}
+	}
+	return c
+}
+
+// CreateTransaction adds a new transaction
+func (r *MemoryRepository) CreateTransaction(ctx context.Context, t *models.Transaction) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	_, categoryExists := d.categories[t.CategoryID]
+	_, householdExists := d.households[t.HouseholdID]
+	_, userExists := d.users[t.UserID]
+	if !categoryExists || !householdExists || !userExists {
+		return appErrors.NewValidationError("Invalid category ID or household ID for transaction", gorm.ErrForeignKeyViolated)
+	}
+
+	now := time.Now()
+	t.ID = d.nextID("transactions")
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = now
+	}
+	if t.Status == "" {
+		t.Status = models.StatusPending
+	}
+	row := *t
+	row.Category, row.Household, row.User = models.Category{}, nil, models.User{}
+	d.transactions[t.ID] = row
+	return nil
+}
+
+// GetTransactions retrieves the transactions of a household, newest first
+func (r *MemoryRepository) GetTransactions(ctx context.Context, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	transactions := sortedRows(d.transactions, func(t *models.Transaction) bool {
+		return t.HouseholdID == householdID && !t.DeletedAt.Valid &&
+			(startDate == nil || !t.Date.Before(*startDate)) &&
+			(endDate == nil || !t.Date.After(*endDate)) &&
+			(transactionType == nil || *transactionType == "" || t.Type == *transactionType) &&
+			(description == nil || *description == "" || containsFold(t.Description, *description))
+	})
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.After(transactions[j].Date) })
+
+	transactions = paginate(transactions, limit, offset)
+	for i := range transactions {
+		transactions[i] = d.withCategory(transactions[i], false)
+	}
+	return transactions, nil
+}
+
+// GetTransactionByID retrieves a single transaction in a household
+func (r *MemoryRepository) GetTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.transactions[id]
+	if !ok || t.HouseholdID != householdID || t.DeletedAt.Valid {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", id), gorm.ErrRecordNotFound)
+	}
+	t = d.withCategory(t, false)
+	return &t, nil
+}
+
+// UpdateTransaction saves the editable fields of an existing transaction
+func (r *MemoryRepository) UpdateTransaction(ctx context.Context, t *models.Transaction) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	row, ok := d.transactions[t.ID]
+	if !ok || row.HouseholdID != t.HouseholdID || row.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", t.ID), nil)
+	}
+	if _, ok := d.categories[t.CategoryID]; !ok {
+		return appErrors.NewValidationError("Invalid category ID for transaction", gorm.ErrForeignKeyViolated)
+	}
+
+	row.Description, row.Amount, row.Type, row.Date = t.Description, t.Amount, t.Type, t.Date
+	row.Status, row.ReconciliationID, row.CategoryID = t.Status, t.ReconciliationID, t.CategoryID
+	row.UpdatedAt = time.Now()
+	t.UpdatedAt = row.UpdatedAt
+	d.transactions[t.ID] = row
+	return nil
+}
+
+// DeleteTransaction soft deletes a transaction in a household
+func (r *MemoryRepository) DeleteTransaction(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.transactions[id]
+	if !ok || t.HouseholdID != householdID || t.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Transaction with ID %d not found or not found in household", id), nil)
+	}
+	t.DeletedAt = deletedAt()
+	d.transactions[id] = t
+	return nil
+}
+
+// GetClearedBalance sums cleared and reconciled transactions dated before the given time
+func (r *MemoryRepository) GetClearedBalance(ctx context.Context, householdID uint, before time.Time) (float64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var balance float64
+	for _, t := range d.transactions {
+		if t.HouseholdID == householdID && !t.DeletedAt.Valid && t.Date.Before(before) &&
+			(t.Status == models.StatusCleared || t.Status == models.StatusReconciled) {
+			balance += t.SignedAmount()
+		}
+	}
+	return balance, nil
+}
+
+// MarkTransactionsReconciled moves cleared transactions dated before the given time into a reconciliation
+func (r *MemoryRepository) MarkTransactionsReconciled(ctx context.Context, householdID uint, reconciliationID uint, before time.Time) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var marked int64
+	now := time.Now()
+	for id, t := range d.transactions {
+		if t.HouseholdID == householdID && !t.DeletedAt.Valid && t.Status == models.StatusCleared && t.Date.Before(before) {
+			t.Status = models.StatusReconciled
+			t.ReconciliationID = &reconciliationID
+			t.UpdatedAt = now
+			d.transactions[id] = t
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+// CreateReconciliation adds a new reconciliation session, unless the household already has an open one
+func (r *MemoryRepository) CreateReconciliation(ctx context.Context, rec *models.Reconciliation) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	_, householdExists := d.households[rec.HouseholdID]
+	_, userExists := d.users[rec.UserID]
+	if !householdExists || !userExists {
+		return appErrors.NewInternalError("Failed to create reconciliation due to database error", gorm.ErrForeignKeyViolated)
+	}
+	if rec.Status == models.ReconciliationOpen {
+		for _, existing := range d.reconciliations {
+			if existing.HouseholdID == rec.HouseholdID && existing.Status == models.ReconciliationOpen && !existing.DeletedAt.Valid {
+				return openReconciliationConflict(rec.HouseholdID)
+			}
+		}
+	}
+
+	now := time.Now()
+	rec.ID = d.nextID("reconciliations")
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = now
+	}
+	if rec.UpdatedAt.IsZero() {
+		rec.UpdatedAt = now
+	}
+	if rec.Status == "" {
+		rec.Status = models.ReconciliationOpen
+	}
+	row := *rec
+	row.Household, row.User = nil, models.User{}
+	d.reconciliations[rec.ID] = row
+	return nil
+}
+
+// GetReconciliations retrieves reconciliation sessions in a household, newest statement first
+func (r *MemoryRepository) GetReconciliations(ctx context.Context, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	reconciliations := sortedRows(d.reconciliations, func(rec *models.Reconciliation) bool {
+		return rec.HouseholdID == householdID && !rec.DeletedAt.Valid &&
+			(status == nil || *status == "" || rec.Status == *status)
+	})
+	sort.SliceStable(reconciliations, func(i, j int) bool {
+		return reconciliations[i].StatementEndDate.After(reconciliations[j].StatementEndDate)
+	})
+	return paginate(reconciliations, limit, offset), nil
+}
+
+// GetReconciliationByID retrieves a single reconciliation session in a household
+func (r *MemoryRepository) GetReconciliationByID(ctx context.Context, householdID uint, id uint) (*models.Reconciliation, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	rec, ok := d.reconciliations[id]
+	if !ok || rec.HouseholdID != householdID || rec.DeletedAt.Valid {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", id), gorm.ErrRecordNotFound)
+	}
+	return &rec, nil
+}
+
+// UpdateReconciliation saves the computed balances and status of a reconciliation session
+func (r *MemoryRepository) UpdateReconciliation(ctx context.Context, rec *models.Reconciliation) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	row, ok := d.reconciliations[rec.ID]
+	if !ok || row.HouseholdID != rec.HouseholdID || row.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", rec.ID), nil)
+	}
+	row.ClearedBalance, row.Difference, row.Status, row.CompletedAt = rec.ClearedBalance, rec.Difference, rec.Status, rec.CompletedAt
+	row.UpdatedAt = time.Now()
+	rec.UpdatedAt = row.UpdatedAt
+	d.reconciliations[rec.ID] = row
+	return nil
+}
+
+// DeleteReconciliation soft deletes a reconciliation session in a household
+func (r *MemoryRepository) DeleteReconciliation(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	rec, ok := d.reconciliations[id]
+	if !ok || rec.HouseholdID != householdID || rec.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Reconciliation with ID %d not found or not found in household", id), nil)
+	}
+	rec.DeletedAt = deletedAt()
+	d.reconciliations[id] = rec
+	return nil
+}
+
+// categoryNameTaken reports whether another active category of the household has the name
+func (d *memoryData) categoryNameTaken(householdID uint, name string, exceptID uint) bool {
+	for id, c := range d.categories {
+		if id != exceptID && c.HouseholdID == householdID && c.Name == name && !c.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCategory adds a new category
+func (r *MemoryRepository) CreateCategory(ctx context.Context, c *models.Category) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if d.categoryNameTaken(c.HouseholdID, c.Name, 0) {
+		return appErrors.NewAlreadyExistsError(fmt.Sprintf("Category with name '%s' already exists", c.Name), gorm.ErrDuplicatedKey)
+	}
+	_, householdExists := d.households[c.HouseholdID]
+	_, userExists := d.users[c.UserID]
+	parentExists := true
+	if c.ParentID != nil {
+		_, parentExists = d.categories[*c.ParentID]
+	}
+	if !householdExists || !userExists || !parentExists {
+		return appErrors.NewValidationError("Invalid household ID for category", gorm.ErrForeignKeyViolated)
+	}
+
+	now := time.Now()
+	c.ID = d.nextID("categories")
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	if c.UpdatedAt.IsZero() {
+		c.UpdatedAt = now
+	}
+	row := *c
+	row.Parent, row.Household, row.User = nil, nil, models.User{}
+	d.categories[c.ID] = row
+	return nil
+}
+
+// GetCategories retrieves the categories of a household, preloading their parent category
+func (r *MemoryRepository) GetCategories(ctx context.Context, householdID uint, limit, offset int, name *string) ([]models.Category, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	categories := sortedRows(d.categories, func(c *models.Category) bool {
+		return c.HouseholdID == householdID && !c.DeletedAt.Valid &&
+			(name == nil || *name == "" || containsFold(c.Name, *name))
+	})
+	categories = paginate(categories, limit, offset)
+	for i := range categories {
+		categories[i] = d.withParent(categories[i], false)
+	}
+	return categories, nil
+}
+
+// GetCategoryTree retrieves the household's category forest, ordered by depth and name. It
+// follows the recursive query of GormRepository: categories whose parent is missing or deleted
+// are roots, and levels deeper than maxDepth are omitted.
+func (r *MemoryRepository) GetCategoryTree(ctx context.Context, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]models.CategoryTreeRow, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	active := sortedRows(d.categories, func(c *models.Category) bool {
+		return c.HouseholdID == householdID && !c.DeletedAt.Valid
+	})
+	activeIDs := make(map[uint]bool, len(active))
+	for _, c := range active {
+		activeIDs[c.ID] = true
+	}
+
+	rows := []models.CategoryTreeRow{}
+	var level []models.CategoryTreeRow
+	for _, c := range active {
+		if c.ParentID == nil || !activeIDs[*c.ParentID] {
+			level = append(level, models.CategoryTreeRow{ID: c.ID, Name: c.Name, ParentID: c.ParentID})
+		}
+	}
+	for depth := 0; len(level) > 0; depth++ {
+		rows = append(rows, level...)
+		if depth >= maxDepth {
+			break
+		}
+		var next []models.CategoryTreeRow
+		for _, parent := range level {
+			for _, c := range active {
+				if c.ParentID != nil && *c.ParentID == parent.ID {
+					next = append(next, models.CategoryTreeRow{ID: c.ID, Name: c.Name, ParentID: c.ParentID, Depth: depth + 1})
+				}
+			}
+		}
+		level = next
+	}
+
+	if withStats {
+		index := make(map[uint]int, len(rows))
+		for i, row := range rows {
+			index[row.ID] = i
+		}
+		for _, t := range d.transactions {
+			i, ok := index[t.CategoryID]
+			if !ok || t.HouseholdID != householdID || t.DeletedAt.Valid ||
+				(startDate != nil && t.Date.Before(*startDate)) || (endDate != nil && t.Date.After(*endDate)) {
+				continue
+			}
+			rows[i].TransactionCount++
+			switch t.Type {
+			case models.Income:
+				rows[i].IncomeTotal += t.Amount
+			case models.Expense:
+				rows[i].ExpenseTotal += t.Amount
+			}
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Depth != rows[j].Depth {
+			return rows[i].Depth < rows[j].Depth
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+// GetCategoryByID retrieves a single category in a household, preloading its parent
+func (r *MemoryRepository) GetCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	c, ok := d.categories[id]
+	if !ok || c.HouseholdID != householdID || c.DeletedAt.Valid {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", id), gorm.ErrRecordNotFound)
+	}
+	c = d.withParent(c, false)
+	return &c, nil
+}
+
+// UpdateCategory saves the name and parent of an existing category
+func (r *MemoryRepository) UpdateCategory(ctx context.Context, c *models.Category) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	row, ok := d.categories[c.ID]
+	if !ok || row.HouseholdID != c.HouseholdID || row.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", c.ID), nil)
+	}
+	if d.categoryNameTaken(row.HouseholdID, c.Name, c.ID) {
+		return appErrors.NewAlreadyExistsError(fmt.Sprintf("Category with name '%s' already exists", c.Name), gorm.ErrDuplicatedKey)
+	}
+	if c.ParentID != nil {
+		if _, ok := d.categories[*c.ParentID]; !ok {
+			return appErrors.NewValidationError("Invalid parent category ID", gorm.ErrForeignKeyViolated)
+		}
+	}
+
+	row.Name, row.ParentID = c.Name, c.ParentID
+	row.UpdatedAt = time.Now()
+	c.UpdatedAt = row.UpdatedAt
+	d.categories[c.ID] = row
+	return nil
+}
+
+// DeleteCategory soft deletes a category in a household
+func (r *MemoryRepository) DeleteCategory(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	c, ok := d.categories[id]
+	if !ok || c.HouseholdID != householdID || c.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Category with ID %d not found or not found in household", id), nil)
+	}
+	c.DeletedAt = deletedAt()
+	d.categories[id] = c
+	return nil
+}
+
+// GetDeletedTransactions retrieves soft-deleted transactions in a household, most recently deleted first
+func (r *MemoryRepository) GetDeletedTransactions(ctx context.Context, householdID uint, limit, offset int) ([]models.Transaction, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	transactions := sortedRows(d.transactions, func(t *models.Transaction) bool {
+		return t.HouseholdID == householdID && t.DeletedAt.Valid
+	})
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].DeletedAt.Time.After(transactions[j].DeletedAt.Time)
+	})
+	transactions = paginate(transactions, limit, offset)
+	for i := range transactions {
+		transactions[i] = d.withCategory(transactions[i], true)
+	}
+	return transactions, nil
+}
+
+// GetDeletedTransactionByID retrieves a single soft-deleted transaction in a household
+func (r *MemoryRepository) GetDeletedTransactionByID(ctx context.Context, householdID uint, id uint) (*models.Transaction, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.transactions[id]
+	if !ok || t.HouseholdID != householdID || !t.DeletedAt.Valid {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), gorm.ErrRecordNotFound)
+	}
+	t = d.withCategory(t, true)
+	return &t, nil
+}
+
+// RestoreTransaction clears the soft delete marker of a transaction in a household
+func (r *MemoryRepository) RestoreTransaction(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.transactions[id]
+	if !ok || t.HouseholdID != householdID || !t.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), nil)
+	}
+	t.DeletedAt = gorm.DeletedAt{}
+	t.UpdatedAt = time.Now()
+	d.transactions[id] = t
+	return nil
+}
+
+// PurgeTransaction permanently deletes a soft-deleted transaction in a household
+func (r *MemoryRepository) PurgeTransaction(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.transactions[id]
+	if !ok || t.HouseholdID != householdID || !t.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted transaction with ID %d not found or not found in household", id), nil)
+	}
+	delete(d.transactions, id)
+	return nil
+}
+
+// PurgeDeletedTransactions permanently deletes all transactions soft-deleted before the given time
+func (r *MemoryRepository) PurgeDeletedTransactions(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var purged int64
+	for id, t := range d.transactions {
+		if t.DeletedAt.Valid && t.DeletedAt.Time.Before(deletedBefore) {
+			delete(d.transactions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// GetDeletedCategories retrieves soft-deleted categories in a household, most recently deleted first
+func (r *MemoryRepository) GetDeletedCategories(ctx context.Context, householdID uint, limit, offset int) ([]models.Category, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	categories := sortedRows(d.categories, func(c *models.Category) bool {
+		return c.HouseholdID == householdID && c.DeletedAt.Valid
+	})
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].DeletedAt.Time.After(categories[j].DeletedAt.Time)
+	})
+	categories = paginate(categories, limit, offset)
+	for i := range categories {
+		categories[i] = d.withParent(categories[i], true)
+	}
+	return categories, nil
+}
+
+// GetDeletedCategoryByID retrieves a single soft-deleted category in a household
+func (r *MemoryRepository) GetDeletedCategoryByID(ctx context.Context, householdID uint, id uint) (*models.Category, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	c, ok := d.categories[id]
+	if !ok || c.HouseholdID != householdID || !c.DeletedAt.Valid {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), gorm.ErrRecordNotFound)
+	}
+	c = d.withParent(c, true)
+	return &c, nil
+}
+
+// RestoreCategory clears the soft delete marker of a category in a household
+func (r *MemoryRepository) RestoreCategory(ctx context.Context, householdID uint, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	c, ok := d.categories[id]
+	if !ok || c.HouseholdID != householdID || !c.DeletedAt.Valid {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), nil)
+	}
+	if d.categoryNameTaken(householdID, c.Name, id) {
+		return appErrors.NewConflictError(fmt.Sprintf("Another category with the name of category %d already exists", id), gorm.ErrDuplicatedKey)
+	}
+	c.DeletedAt = gorm.DeletedAt{}
+	c.UpdatedAt = time.Now()
+	d.categories[id] = c
+	return nil
+}
+
+// categoryReferenced reports whether any transaction, including deleted ones, uses a category
+func (d *memoryData) categoryReferenced(id uint) bool {
+	for _, t := range d.transactions {
+		if t.CategoryID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// detachChildren turns the children of a category into top-level categories
+func (d *memoryData) detachChildren(parentID uint, householdID *uint) {
+	for id, c := range d.categories {
+		if c.ParentID != nil && *c.ParentID == parentID && (householdID == nil || c.HouseholdID == *householdID) {
+			c.ParentID = nil
+			c.UpdatedAt = time.Now()
+			d.categories[id] = c
+		}
+	}
+}
+
+// PurgeCategory permanently deletes a soft-deleted category in a household.
+// Child categories are detached; categories still referenced by transactions cannot be purged.
+func (r *MemoryRepository) PurgeCategory(ctx context.Context, householdID uint, id uint) error {
+	return r.atomically(func(d *memoryData) error {
+		d.detachChildren(id, &householdID)
+
+		c, ok := d.categories[id]
+		if !ok || c.HouseholdID != householdID || !c.DeletedAt.Valid {
+			return appErrors.NewNotFoundError(fmt.Sprintf("Deleted category with ID %d not found or not found in household", id), nil)
+		}
+		if d.categoryReferenced(id) {
+			return appErrors.NewConflictError(fmt.Sprintf("Category with ID %d is still referenced by transactions", id), gorm.ErrForeignKeyViolated)
+		}
+		delete(d.categories, id)
+		return nil
+	})
+}
+
+// PurgeDeletedCategories permanently deletes all categories soft-deleted before the given time
+// that are no longer referenced by any transaction, detaching their child categories
+func (r *MemoryRepository) PurgeDeletedCategories(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var expired []uint
+	for id, c := range d.categories {
+		if c.DeletedAt.Valid && c.DeletedAt.Time.Before(deletedBefore) && !d.categoryReferenced(id) {
+			expired = append(expired, id)
+		}
+	}
+	for _, id := range expired {
+		d.detachChildren(id, nil)
+	}
+	for _, id := range expired {
+		delete(d.categories, id)
+	}
+	return int64(len(expired)), nil
+}
+
+// CountTransactionsByCategory counts the active transactions assigned to a category
+func (r *MemoryRepository) CountTransactionsByCategory(ctx context.Context, householdID uint, categoryID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var count int64
+	for _, t := range d.transactions {
+		if t.HouseholdID == householdID && t.CategoryID == categoryID && !t.DeletedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ReassignTransactions moves all transactions of a category, including soft-deleted ones, to another
+// category, unless any of them is reconciled
+func (r *MemoryRepository) ReassignTransactions(ctx context.Context, householdID uint, fromCategoryID, toCategoryID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var reconciled int64
+	for _, t := range d.transactions {
+		if t.HouseholdID == householdID && t.CategoryID == fromCategoryID && t.Status == models.StatusReconciled {
+			reconciled++
+		}
+	}
+	if reconciled > 0 {
+		return 0, reconciledTransactionsConflict(fromCategoryID, reconciled)
+	}
+
+	var moved int64
+	now := time.Now()
+	for id, t := range d.transactions {
+		if t.HouseholdID == householdID && t.CategoryID == fromCategoryID {
+			t.CategoryID = toCategoryID
+			t.UpdatedAt = now
+			d.transactions[id] = t
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// ReparentCategories moves all child categories, including soft-deleted ones, under a new parent
+func (r *MemoryRepository) ReparentCategories(ctx context.Context, householdID uint, fromParentID uint, toParentID *uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var moved int64
+	now := time.Now()
+	for id, c := range d.categories {
+		if c.HouseholdID == householdID && c.ParentID != nil && *c.ParentID == fromParentID {
+			c.ParentID = toParentID
+			c.UpdatedAt = now
+			d.categories[id] = c
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// findUser returns the ID of the first user matching match, or zero
+func (d *memoryData) findUser(match func(u *models.User) bool) uint {
+	for id, u := range d.users {
+		if match(&u) {
+			return id
+		}
+	}
+	return 0
+}
+
+// emailTaken reports whether another user has the email address
+func (d *memoryData) emailTaken(email *string, exceptID uint) bool {
+	if email == nil {
+		return false
+	}
+	return d.findUser(func(u *models.User) bool {
+		return u.ID != exceptID && u.Email != nil && *u.Email == *email
+	}) != 0
+}
+
+// CreateUser adds a new user
+func (r *MemoryRepository) CreateUser(ctx context.Context, u *models.User) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if d.findUser(func(other *models.User) bool { return other.Username == u.Username }) != 0 {
+		return appErrors.NewAlreadyExistsError(fmt.Sprintf("User with username '%s' already exists", u.Username), gorm.ErrDuplicatedKey)
+	}
+	if d.emailTaken(u.Email, 0) {
+		return appErrors.NewAlreadyExistsError("A user with this email address already exists", gorm.ErrDuplicatedKey)
+	}
+
+	now := time.Now()
+	u.ID = d.nextID("users")
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = now
+	}
+	if u.UpdatedAt.IsZero() {
+		u.UpdatedAt = now
+	}
+	for field, value := range map[*string]string{
+		&u.Locale:          "en-US",
+		&u.Timezone:        "UTC",
+		&u.BaseCurrency:    "USD",
+		&u.FirstDayOfWeek:  "monday",
+		&u.FiscalYearStart: "01-01",
+	} {
+		if *field == "" {
+			*field = value
+		}
+	}
+	d.users[u.ID] = *u
+	return nil
+}
+
+// GetUserByUsername retrieves a user by their username
+func (r *MemoryRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	id := d.findUser(func(u *models.User) bool { return u.Username == username })
+	if id == 0 {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("User '%s' not found", username), gorm.ErrRecordNotFound)
+	}
+	u := d.users[id]
+	return &u, nil
+}
+
+// GetUserByID retrieves a user by their ID
+func (r *MemoryRepository) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	u, ok := d.users[id]
+	if !ok {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", id), gorm.ErrRecordNotFound)
+	}
+	return &u, nil
+}
+
+// GetUserByEmail retrieves a user by their email address
+func (r *MemoryRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	id := d.findUser(func(u *models.User) bool { return u.Email != nil && *u.Email == email })
+	if id == 0 {
+		return nil, appErrors.NewNotFoundError("User with this email address not found", gorm.ErrRecordNotFound)
+	}
+	u := d.users[id]
+	return &u, nil
+}
+
+// GetUsers retrieves a page of all users, ordered by ID
+func (r *MemoryRepository) GetUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	users := sortedRows(d.users, func(u *models.User) bool { return true })
+	return paginate(users, limit, offset), nil
+}
+
+// updateUser applies change to a stored user, reporting whether the user exists
+func (d *memoryData) updateUser(id uint, change func(u *models.User)) bool {
+	u, ok := d.users[id]
+	if !ok {
+		return false
+	}
+	change(&u)
+	u.UpdatedAt = time.Now()
+	d.users[id] = u
+	return true
+}
+
+// UpdateUserProfile saves the profile and preference fields of a user
+func (r *MemoryRepository) UpdateUserProfile(ctx context.Context, u *models.User) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.users[u.ID]; ok && d.emailTaken(u.Email, u.ID) {
+		return appErrors.NewAlreadyExistsError("A user with this email address already exists", gorm.ErrDuplicatedKey)
+	}
+	found := d.updateUser(u.ID, func(row *models.User) {
+		row.DisplayName, row.Email, row.EmailVerifiedAt, row.Locale, row.Timezone = u.DisplayName, u.Email, u.EmailVerifiedAt, u.Locale, u.Timezone
+		row.BaseCurrency, row.FirstDayOfWeek, row.FiscalYearStart = u.BaseCurrency, u.FirstDayOfWeek, u.FiscalYearStart
+	})
+	if !found {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", u.ID), nil)
+	}
+	return nil
+}
+
+// UpdateUserPassword replaces the password hash of a user
+func (r *MemoryRepository) UpdateUserPassword(ctx context.Context, userID uint, passwordHash string) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if !d.updateUser(userID, func(u *models.User) { u.PasswordHash = passwordHash }) {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// ReplacePasswordHash swaps the password hash of a user for a rehash of the same password.
+// It reports false when the hash was changed concurrently.
+func (r *MemoryRepository) ReplacePasswordHash(ctx context.Context, userID uint, oldHash, newHash string) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if u, ok := d.users[userID]; !ok || u.PasswordHash != oldHash {
+		return false, nil
+	}
+	d.updateUser(userID, func(u *models.User) { u.PasswordHash = newHash })
+	return true, nil
+}
+
+// RevokeUserSessions invalidates all access tokens of a user issued before revokedAt
+func (r *MemoryRepository) RevokeUserSessions(ctx context.Context, userID uint, revokedAt time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	d.updateUser(userID, func(u *models.User) { u.SessionsRevokedAt = timePtr(revokedAt) })
+	return nil
+}
+
+// SetUserEmailVerified marks the email address of a user as verified, provided it is still email.
+// It reports whether the address was marked.
+func (r *MemoryRepository) SetUserEmailVerified(ctx context.Context, userID uint, email string, verifiedAt time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	marked := false
+	d.updateUser(userID, func(u *models.User) {
+		if u.Email != nil && *u.Email == email {
+			u.EmailVerifiedAt = timePtr(verifiedAt)
+			marked = true
+		}
+	})
+	return marked, nil
+}
+
+// SetUserDisabled disables a user as of disabledAt, or enables them again when it is nil
+func (r *MemoryRepository) SetUserDisabled(ctx context.Context, userID uint, disabledAt *time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if disabledAt != nil {
+		disabledAt = timePtr(*disabledAt)
+	}
+	if !d.updateUser(userID, func(u *models.User) { u.DisabledAt = disabledAt }) {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *MemoryRepository) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.users[t.UserID]; !ok {
+		return appErrors.NewInternalError("Failed to store refresh token due to database error", gorm.ErrForeignKeyViolated)
+	}
+	t.ID = d.nextID("refresh_tokens")
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	row := *t
+	row.User = models.User{}
+	d.refreshTokens[t.ID] = row
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by the hash of its value
+func (r *MemoryRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	tokens := sortedRows(d.refreshTokens, func(t *models.RefreshToken) bool { return t.TokenHash == tokenHash })
+	if len(tokens) == 0 {
+		return nil, appErrors.NewNotFoundError("Refresh token not found", gorm.ErrRecordNotFound)
+	}
+	return &tokens[0], nil
+}
+
+// MarkRefreshTokenUsed marks an unused, unrevoked refresh token as used.
+// It reports false when the token had already been used or revoked.
+func (r *MemoryRepository) MarkRefreshTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.refreshTokens[id]
+	if !ok || t.UsedAt != nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = timePtr(usedAt)
+	d.refreshTokens[id] = t
+	return true, nil
+}
+
+// revokeRefreshTokens revokes the unrevoked refresh tokens matching match
+func (d *memoryData) revokeRefreshTokens(match func(t *models.RefreshToken) bool) int64 {
+	var revoked int64
+	now := time.Now()
+	for id, t := range d.refreshTokens {
+		if t.RevokedAt == nil && match(&t) {
+			t.RevokedAt = timePtr(now)
+			d.refreshTokens[id] = t
+			revoked++
+		}
+	}
+	return revoked
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token descended from the same login
+func (r *MemoryRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return d.revokeRefreshTokens(func(t *models.RefreshToken) bool { return t.FamilyID == familyID }), nil
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user
+func (r *MemoryRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return d.revokeRefreshTokens(func(t *models.RefreshToken) bool { return t.UserID == userID }), nil
+}
+
+// RevokeAccessToken adds an access token to the denylist. Revoking a token twice is not an error.
+func (r *MemoryRepository) RevokeAccessToken(ctx context.Context, t *models.RevokedToken) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.revokedTokens[t.JTI]; ok {
+		return nil
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	d.revokedTokens[t.JTI] = *t
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token is on the denylist
+func (r *MemoryRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	_, ok := d.revokedTokens[jti]
+	return ok, nil
+}
+
+// deleteRows removes the rows of a table matching match and counts them
+func deleteRows[K comparable, T any](rows map[K]T, match func(row *T) bool) int64 {
+	var deleted int64
+	for key, row := range rows {
+		if match(&row) {
+			delete(rows, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// DeleteExpiredTokens removes refresh tokens, denylist entries, login challenges, password
+// reset tokens and pending OIDC logins that expired before the given time
+func (r *MemoryRepository) DeleteExpiredTokens(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	deleted := deleteRows(d.refreshTokens, func(t *models.RefreshToken) bool { return t.ExpiresAt.Before(expiredBefore) })
+	deleted += deleteRows(d.revokedTokens, func(t *models.RevokedToken) bool { return t.ExpiresAt.Before(expiredBefore) })
+	deleted += deleteRows(d.loginChallenges, func(c *models.LoginChallenge) bool { return c.ExpiresAt.Before(expiredBefore) })
+	deleted += deleteRows(d.passwordResets, func(t *models.PasswordResetToken) bool { return t.ExpiresAt.Before(expiredBefore) })
+	deleted += deleteRows(d.oidcRequests, func(req *models.OIDCAuthRequest) bool { return req.ExpiresAt.Before(expiredBefore) })
+	return deleted, nil
+}
+
+// CreateAPIToken stores a new personal access token
+func (r *MemoryRepository) CreateAPIToken(ctx context.Context, t *models.APIToken) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.users[t.UserID]; !ok {
+		return appErrors.NewInternalError("Failed to create API token due to database error", gorm.ErrForeignKeyViolated)
+	}
+	t.ID = d.nextID("api_tokens")
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	row := *t
+	row.User = models.User{}
+	row.Scopes = slices.Clone(t.Scopes)
+	d.apiTokens[t.ID] = row
+	return nil
+}
+
+// apiToken returns a copy of a stored token that does not share its scopes
+func apiToken(t models.APIToken) models.APIToken {
+	t.Scopes = slices.Clone(t.Scopes)
+	return t
+}
+
+// GetAPITokens retrieves all personal access tokens of a user, newest first
+func (r *MemoryRepository) GetAPITokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	tokens := sortedRows(d.apiTokens, func(t *models.APIToken) bool { return t.UserID == userID })
+	slices.Reverse(tokens)
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	for i := range tokens {
+		tokens[i] = apiToken(tokens[i])
+	}
+	return tokens, nil
+}
+
+// GetAPITokenByID retrieves a personal access token by ID for a specific user
+func (r *MemoryRepository) GetAPITokenByID(ctx context.Context, userID, id uint) (*models.APIToken, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.apiTokens[id]
+	if !ok || t.UserID != userID {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("API token with ID %d not found for user %d", id, userID), gorm.ErrRecordNotFound)
+	}
+	t = apiToken(t)
+	return &t, nil
+}
+
+// GetAPITokenByHash retrieves a personal access token by the hash of its value
+func (r *MemoryRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	tokens := sortedRows(d.apiTokens, func(t *models.APIToken) bool { return t.TokenHash == tokenHash })
+	if len(tokens) == 0 {
+		return nil, appErrors.NewNotFoundError("API token not found", gorm.ErrRecordNotFound)
+	}
+	t := apiToken(tokens[0])
+	return &t, nil
+}
+
+// UpdateAPITokenLastUsed records when a personal access token was last used
+func (r *MemoryRepository) UpdateAPITokenLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if t, ok := d.apiTokens[id]; ok {
+		t.LastUsedAt = timePtr(usedAt)
+		d.apiTokens[id] = t
+	}
+	return nil
+}
+
+// RevokeAPIToken revokes a personal access token of a user
+func (r *MemoryRepository) RevokeAPIToken(ctx context.Context, userID, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.apiTokens[id]
+	if !ok || t.UserID != userID || t.RevokedAt != nil {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Active API token with ID %d not found for user %d", id, userID), nil)
+	}
+	t.RevokedAt = timePtr(time.Now())
+	d.apiTokens[id] = t
+	return nil
+}
+
+// RevokeUserAPITokens revokes every personal access token of a user
+func (r *MemoryRepository) RevokeUserAPITokens(ctx context.Context, userID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	var revoked int64
+	now := time.Now()
+	for id, t := range d.apiTokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = timePtr(now)
+			d.apiTokens[id] = t
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// UpdateUserTOTP sets the two-factor authentication state of a user
+func (r *MemoryRepository) UpdateUserTOTP(ctx context.Context, userID uint, secret string, enabled bool, lastCounter int64) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	found := d.updateUser(userID, func(u *models.User) {
+		u.TOTPSecret, u.TOTPEnabled, u.TOTPLastCounter = secret, enabled, lastCounter
+	})
+	if !found {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// AdvanceTOTPCounter records the time step of an accepted TOTP code.
+// It reports false when the same or a later time step was already used.
+func (r *MemoryRepository) AdvanceTOTPCounter(ctx context.Context, userID uint, counter int64) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if u, ok := d.users[userID]; !ok || u.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	d.updateUser(userID, func(u *models.User) { u.TOTPLastCounter = counter })
+	return true, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user with the given ones
+func (r *MemoryRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	deleteRows(d.recoveryCodes, func(c *models.RecoveryCode) bool { return c.UserID == userID })
+	now := time.Now()
+	for i := range codes {
+		codes[i].ID = d.nextID("recovery_codes")
+		if codes[i].CreatedAt.IsZero() {
+			codes[i].CreatedAt = now
+		}
+		row := codes[i]
+		row.User = models.User{}
+		d.recoveryCodes[row.ID] = row
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code; it reports false when no such code exists
+func (r *MemoryRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	used := false
+	for id, c := range d.recoveryCodes {
+		if c.UserID == userID && c.CodeHash == codeHash && c.UsedAt == nil {
+			c.UsedAt = timePtr(time.Now())
+			d.recoveryCodes[id] = c
+			used = true
+		}
+	}
+	return used, nil
+}
+
+// CountUnusedRecoveryCodes counts the recovery codes a user has left
+func (r *MemoryRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return int64(len(sortedRows(d.recoveryCodes, func(c *models.RecoveryCode) bool {
+		return c.UserID == userID && c.UsedAt == nil
+	}))), nil
+}
+
+// CreateLoginChallenge stores a new two-factor login challenge
+func (r *MemoryRepository) CreateLoginChallenge(ctx context.Context, challenge *models.LoginChallenge) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	challenge.ID = d.nextID("login_challenges")
+	if challenge.CreatedAt.IsZero() {
+		challenge.CreatedAt = time.Now()
+	}
+	row := *challenge
+	row.User = models.User{}
+	d.loginChallenges[challenge.ID] = row
+	return nil
+}
+
+// GetLoginChallengeByHash retrieves a login challenge by the hash of its token
+func (r *MemoryRepository) GetLoginChallengeByHash(ctx context.Context, tokenHash string) (*models.LoginChallenge, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	challenges := sortedRows(d.loginChallenges, func(c *models.LoginChallenge) bool { return c.TokenHash == tokenHash })
+	if len(challenges) == 0 {
+		return nil, appErrors.NewNotFoundError("Login challenge not found", gorm.ErrRecordNotFound)
+	}
+	return &challenges[0], nil
+}
+
+// RecordLoginChallengeAttempt counts a failed code submission against a login challenge
+func (r *MemoryRepository) RecordLoginChallengeAttempt(ctx context.Context, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if c, ok := d.loginChallenges[id]; ok {
+		c.Attempts++
+		d.loginChallenges[id] = c
+	}
+	return nil
+}
+
+// MarkLoginChallengeUsed completes a login challenge; it reports false when it was already used
+func (r *MemoryRepository) MarkLoginChallengeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	c, ok := d.loginChallenges[id]
+	if !ok || c.UsedAt != nil {
+		return false, nil
+	}
+	c.UsedAt = timePtr(usedAt)
+	d.loginChallenges[id] = c
+	return true, nil
+}
+
+// GetLoginAttempt retrieves the failed login record for a username or IP key
+func (r *MemoryRepository) GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	attempt, ok := d.loginAttempts[key]
+	if !ok {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("No failed logins recorded for '%s'", key), gorm.ErrRecordNotFound)
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailure counts a failed login. Counting restarts at one when the previous failure
+// happened before resetBefore.
+func (r *MemoryRepository) RecordLoginFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	attempt, ok := d.loginAttempts[key]
+	switch {
+	case !ok:
+		attempt = models.LoginAttempt{Key: key, Failures: 1}
+	case attempt.LastFailureAt.Before(resetBefore):
+		attempt.Failures = 1
+	default:
+		attempt.Failures++
+	}
+	attempt.LastFailureAt = at
+	d.loginAttempts[key] = attempt
+	return &attempt, nil
+}
+
+// SetLoginLockedUntil blocks logins for a username or IP key until the given time
+func (r *MemoryRepository) SetLoginLockedUntil(ctx context.Context, key string, until time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if attempt, ok := d.loginAttempts[key]; ok {
+		attempt.LockedUntil = timePtr(until)
+		d.loginAttempts[key] = attempt
+	}
+	return nil
+}
+
+// DeleteLoginAttempt forgets all failed logins of a username or IP key
+func (r *MemoryRepository) DeleteLoginAttempt(ctx context.Context, key string) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	delete(d.loginAttempts, key)
+	return nil
+}
+
+// DeleteStaleLoginAttempts removes records whose last failure and lock both ended before the given time
+func (r *MemoryRepository) DeleteStaleLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return deleteRows(d.loginAttempts, func(a *models.LoginAttempt) bool {
+		return a.LastFailureAt.Before(before) && (a.LockedUntil == nil || a.LockedUntil.Before(before))
+	}), nil
+}
+
+// CreatePasswordResetToken stores a new password reset token
+func (r *MemoryRepository) CreatePasswordResetToken(ctx context.Context, t *models.PasswordResetToken) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t.ID = d.nextID("password_reset_tokens")
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	row := *t
+	row.User = models.User{}
+	d.passwordResets[t.ID] = row
+	return nil
+}
+
+// GetPasswordResetTokenByHash retrieves a password reset token by the hash of its value
+func (r *MemoryRepository) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	tokens := sortedRows(d.passwordResets, func(t *models.PasswordResetToken) bool { return t.TokenHash == tokenHash })
+	if len(tokens) == 0 {
+		return nil, appErrors.NewNotFoundError("Password reset token not found", gorm.ErrRecordNotFound)
+	}
+	return &tokens[0], nil
+}
+
+// MarkPasswordResetTokenUsed consumes a password reset token; it reports false when it was already used
+func (r *MemoryRepository) MarkPasswordResetTokenUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	t, ok := d.passwordResets[id]
+	if !ok || t.UsedAt != nil {
+		return false, nil
+	}
+	t.UsedAt = timePtr(usedAt)
+	d.passwordResets[id] = t
+	return true, nil
+}
+
+// InvalidatePasswordResetTokens marks all outstanding password reset tokens of a user as used
+func (r *MemoryRepository) InvalidatePasswordResetTokens(ctx context.Context, userID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now()
+	for id, t := range d.passwordResets {
+		if t.UserID == userID && t.UsedAt == nil {
+			t.UsedAt = timePtr(now)
+			d.passwordResets[id] = t
+		}
+	}
+	return nil
+}
+
+// CreateUserIdentity links an external identity to a user
+func (r *MemoryRepository) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	for _, other := range d.identities {
+		if other.Issuer == identity.Issuer && other.Subject == identity.Subject {
+			return appErrors.NewAlreadyExistsError("This identity is already linked to a user", gorm.ErrDuplicatedKey)
+		}
+	}
+	identity.ID = d.nextID("user_identities")
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	row := *identity
+	row.User = models.User{}
+	d.identities[identity.ID] = row
+	return nil
+}
+
+// GetUserIdentity retrieves the identity with the given issuer and subject
+func (r *MemoryRepository) GetUserIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	identities := sortedRows(d.identities, func(i *models.UserIdentity) bool {
+		return i.Issuer == issuer && i.Subject == subject
+	})
+	if len(identities) == 0 {
+		return nil, appErrors.NewNotFoundError("Identity not found", gorm.ErrRecordNotFound)
+	}
+	return &identities[0], nil
+}
+
+// GetUserIdentities retrieves all identities linked to a user
+func (r *MemoryRepository) GetUserIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	identities := sortedRows(d.identities, func(i *models.UserIdentity) bool { return i.UserID == userID })
+	sort.SliceStable(identities, func(i, j int) bool { return identities[i].CreatedAt.Before(identities[j].CreatedAt) })
+	return identities, nil
+}
+
+// RecordUserIdentityLogin stores the time of a login with an identity and the email the provider reported
+func (r *MemoryRepository) RecordUserIdentityLogin(ctx context.Context, id uint, email string, at time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if identity, ok := d.identities[id]; ok {
+		identity.Email = email
+		identity.LastLoginAt = timePtr(at)
+		d.identities[id] = identity
+	}
+	return nil
+}
+
+// DeleteUserIdentity unlinks an identity from a user
+func (r *MemoryRepository) DeleteUserIdentity(ctx context.Context, userID, id uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	identity, ok := d.identities[id]
+	if !ok || identity.UserID != userID {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Identity with ID %d not found", id), nil)
+	}
+	delete(d.identities, id)
+	return nil
+}
+
+// CountUserIdentities counts the identities linked to a user
+func (r *MemoryRepository) CountUserIdentities(ctx context.Context, userID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return int64(len(sortedRows(d.identities, func(i *models.UserIdentity) bool { return i.UserID == userID }))), nil
+}
+
+// CreateOIDCAuthRequest stores a pending OIDC login
+func (r *MemoryRepository) CreateOIDCAuthRequest(ctx context.Context, req *models.OIDCAuthRequest) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	req.ID = d.nextID("oidc_auth_requests")
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	d.oidcRequests[req.ID] = *req
+	return nil
+}
+
+// ConsumeOIDCAuthRequest retrieves and deletes the pending OIDC login with the given state hash
+func (r *MemoryRepository) ConsumeOIDCAuthRequest(ctx context.Context, stateHash string) (*models.OIDCAuthRequest, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	requests := sortedRows(d.oidcRequests, func(req *models.OIDCAuthRequest) bool { return req.StateHash == stateHash })
+	if len(requests) == 0 {
+		return nil, appErrors.NewNotFoundError("OIDC login request not found", gorm.ErrRecordNotFound)
+	}
+	delete(d.oidcRequests, requests[0].ID)
+	return &requests[0], nil
+}
+
+// CreateHousehold adds a new household
+func (r *MemoryRepository) CreateHousehold(ctx context.Context, h *models.Household) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now()
+	h.ID = d.nextID("households")
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = now
+	}
+	if h.UpdatedAt.IsZero() {
+		h.UpdatedAt = now
+	}
+	d.households[h.ID] = *h
+	return nil
+}
+
+// GetHousehold retrieves a household by its ID
+func (r *MemoryRepository) GetHousehold(ctx context.Context, id uint) (*models.Household, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	h, ok := d.households[id]
+	if !ok {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Household with ID %d not found", id), gorm.ErrRecordNotFound)
+	}
+	return &h, nil
+}
+
+// UpdateHousehold updates the name of an existing household
+func (r *MemoryRepository) UpdateHousehold(ctx context.Context, h *models.Household) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	row, ok := d.households[h.ID]
+	if !ok {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Household with ID %d not found", h.ID), nil)
+	}
+	row.Name = h.Name
+	row.UpdatedAt = time.Now()
+	h.UpdatedAt = row.UpdatedAt
+	d.households[h.ID] = row
+	return nil
+}
+
+// GetUserHouseholds retrieves the memberships of a user with their households
+func (r *MemoryRepository) GetUserHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	members := sortedRows(d.members, func(m *models.HouseholdMember) bool { return m.UserID == userID })
+	sort.SliceStable(members, func(i, j int) bool { return members[i].HouseholdID < members[j].HouseholdID })
+	for i := range members {
+		if h, ok := d.households[members[i].HouseholdID]; ok {
+			members[i].Household = &h
+		}
+	}
+	return members, nil
+}
+
+// GetHouseholdMember retrieves the membership of a user in a household
+func (r *MemoryRepository) GetHouseholdMember(ctx context.Context, householdID, userID uint) (*models.HouseholdMember, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	members := sortedRows(d.members, func(m *models.HouseholdMember) bool {
+		return m.HouseholdID == householdID && m.UserID == userID
+	})
+	if len(members) == 0 {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), gorm.ErrRecordNotFound)
+	}
+	return &members[0], nil
+}
+
+// GetHouseholdMembers retrieves all members of a household with their users
+func (r *MemoryRepository) GetHouseholdMembers(ctx context.Context, householdID uint) ([]models.HouseholdMember, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	members := sortedRows(d.members, func(m *models.HouseholdMember) bool { return m.HouseholdID == householdID })
+	sort.SliceStable(members, func(i, j int) bool { return members[i].CreatedAt.Before(members[j].CreatedAt) })
+	for i := range members {
+		if u, ok := d.users[members[i].UserID]; ok {
+			members[i].User = &u
+		}
+	}
+	return members, nil
+}
+
+// AddHouseholdMember adds a user to a household
+func (r *MemoryRepository) AddHouseholdMember(ctx context.Context, m *models.HouseholdMember) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	for _, other := range d.members {
+		if other.HouseholdID == m.HouseholdID && other.UserID == m.UserID {
+			return appErrors.NewAlreadyExistsError("User is already a member of this household", gorm.ErrDuplicatedKey)
+		}
+	}
+	_, householdExists := d.households[m.HouseholdID]
+	_, userExists := d.users[m.UserID]
+	if !householdExists || !userExists {
+		return appErrors.NewInternalError("Failed to add household member due to database error", gorm.ErrForeignKeyViolated)
+	}
+
+	m.ID = d.nextID("household_members")
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	row := *m
+	row.Household, row.User = nil, nil
+	d.members[m.ID] = row
+	return nil
+}
+
+// UpdateHouseholdMemberRole changes the role of a household member
+func (r *MemoryRepository) UpdateHouseholdMemberRole(ctx context.Context, householdID, userID uint, role models.HouseholdRole) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	for id, m := range d.members {
+		if m.HouseholdID == householdID && m.UserID == userID {
+			m.Role = role
+			d.members[id] = m
+			return nil
+		}
+	}
+	return appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), nil)
+}
+
+// RemoveHouseholdMember removes a user from a household
+func (r *MemoryRepository) RemoveHouseholdMember(ctx context.Context, householdID, userID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	removed := deleteRows(d.members, func(m *models.HouseholdMember) bool {
+		return m.HouseholdID == householdID && m.UserID == userID
+	})
+	if removed == 0 {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User %d is not a member of household %d", userID, householdID), nil)
+	}
+	return nil
+}
+
+// CountHouseholdOwners counts the members of a household with the owner role
+func (r *MemoryRepository) CountHouseholdOwners(ctx context.Context, householdID uint) (int64, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return int64(len(sortedRows(d.members, func(m *models.HouseholdMember) bool {
+		return m.HouseholdID == householdID && m.Role == models.RoleOwner
+	}))), nil
+}
+
+// SetUserDefaultHousehold sets the household used when a request of the user names none
+func (r *MemoryRepository) SetUserDefaultHousehold(ctx context.Context, userID uint, householdID *uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if householdID != nil {
+		if _, ok := d.households[*householdID]; !ok {
+			return appErrors.NewInternalError(fmt.Sprintf("Failed to set default household of user %d", userID), gorm.ErrForeignKeyViolated)
+		}
+	}
+	d.updateUser(userID, func(u *models.User) { u.DefaultHouseholdID = householdID })
+	return nil
+}
+
+// CreateHouseholdInvitation stores a new household invitation
+func (r *MemoryRepository) CreateHouseholdInvitation(ctx context.Context, inv *models.HouseholdInvitation) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.households[inv.HouseholdID]; !ok {
+		return appErrors.NewInternalError("Failed to create household invitation due to database error", gorm.ErrForeignKeyViolated)
+	}
+	inv.ID = d.nextID("household_invitations")
+	if inv.CreatedAt.IsZero() {
+		inv.CreatedAt = time.Now()
+	}
+	row := *inv
+	row.Household = nil
+	d.invitations[inv.ID] = row
+	return nil
+}
+
+// GetHouseholdInvitations retrieves the pending invitations of a household
+func (r *MemoryRepository) GetHouseholdInvitations(ctx context.Context, householdID uint, now time.Time) ([]models.HouseholdInvitation, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	invitations := sortedRows(d.invitations, func(inv *models.HouseholdInvitation) bool {
+		return inv.HouseholdID == householdID && inv.AcceptedAt == nil && inv.RevokedAt == nil && inv.ExpiresAt.After(now)
+	})
+	slices.Reverse(invitations)
+	sort.SliceStable(invitations, func(i, j int) bool { return invitations[i].CreatedAt.After(invitations[j].CreatedAt) })
+	return invitations, nil
+}
+
+// GetHouseholdInvitationByHash retrieves an invitation with its household by the hash of its token
+func (r *MemoryRepository) GetHouseholdInvitationByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvitation, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	invitations := sortedRows(d.invitations, func(inv *models.HouseholdInvitation) bool { return inv.TokenHash == tokenHash })
+	if len(invitations) == 0 {
+		return nil, appErrors.NewNotFoundError("Household invitation not found", gorm.ErrRecordNotFound)
+	}
+	invitation := invitations[0]
+	if h, ok := d.households[invitation.HouseholdID]; ok {
+		invitation.Household = &h
+	}
+	return &invitation, nil
+}
+
+// MarkHouseholdInvitationAccepted marks an invitation as accepted by a user. It reports false if
+// the invitation was already accepted or revoked.
+func (r *MemoryRepository) MarkHouseholdInvitationAccepted(ctx context.Context, id, userID uint, acceptedAt time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	inv, ok := d.invitations[id]
+	if !ok || inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return false, nil
+	}
+	inv.AcceptedAt = timePtr(acceptedAt)
+	inv.AcceptedByID = &userID
+	d.invitations[id] = inv
+	return true, nil
+}
+
+// RevokeHouseholdInvitation withdraws a pending invitation of a household
+func (r *MemoryRepository) RevokeHouseholdInvitation(ctx context.Context, householdID, id uint, revokedAt time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	inv, ok := d.invitations[id]
+	if !ok || inv.HouseholdID != householdID || inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Pending invitation with ID %d not found in household", id), nil)
+	}
+	inv.RevokedAt = timePtr(revokedAt)
+	d.invitations[id] = inv
+	return nil
+}
+
+// AppendAuditEntry links an entry to the latest one in the audit log, computes its hash and stores it
+func (r *MemoryRepository) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	prevHash := ""
+	if len(d.auditEntries) > 0 {
+		prevHash = d.auditEntries[len(d.auditEntries)-1].Hash
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	entry.PrevHash = prevHash
+	entry.ContentHash = entry.ComputeContentHash()
+	entry.Hash = entry.ComputeHash()
+	entry.ID = uint(len(d.auditEntries) + 1)
+
+	row := *entry
+	row.Changes = slices.Clone(entry.Changes)
+	d.auditEntries = append(d.auditEntries, row)
+	return nil
+}
+
+// matchingAuditEntries returns copies of the audit entries matching keep, oldest first
+func (d *memoryData) matchingAuditEntries(keep func(e *models.AuditEntry) bool) []models.AuditEntry {
+	entries := []models.AuditEntry{}
+	for _, e := range d.auditEntries {
+		if keep(&e) {
+			e.Changes = slices.Clone(e.Changes)
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// GetAuditEntries retrieves audit entries matching a filter, newest first
+func (r *MemoryRepository) GetAuditEntries(ctx context.Context, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	entries := d.matchingAuditEntries(func(e *models.AuditEntry) bool {
+		visible := (e.HouseholdID != nil && *e.HouseholdID == filter.VisibleHousehold) ||
+			(e.HouseholdID == nil && e.ActorID != nil && *e.ActorID == filter.VisibleUser)
+		return visible &&
+			(filter.ActorID == nil || (e.ActorID != nil && *e.ActorID == *filter.ActorID)) &&
+			(filter.Action == nil || *filter.Action == "" || e.Action == *filter.Action) &&
+			(filter.EntityType == nil || *filter.EntityType == "" || e.EntityType == *filter.EntityType) &&
+			(filter.EntityID == nil || e.EntityID == *filter.EntityID) &&
+			(filter.From == nil || !e.CreatedAt.Before(*filter.From)) &&
+			(filter.To == nil || !e.CreatedAt.After(*filter.To))
+	})
+	slices.Reverse(entries)
+	return paginate(entries, limit, offset), nil
+}
+
+// GetAuditEntriesAfter retrieves up to limit entries following the entry afterID, oldest first
+func (r *MemoryRepository) GetAuditEntriesAfter(ctx context.Context, afterID uint, limit int) ([]models.AuditEntry, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	entries := d.matchingAuditEntries(func(e *models.AuditEntry) bool { return e.ID > afterID })
+	return paginate(entries, limit, 0), nil
+}
+
+// GetPersonalAuditEntries retrieves the audit entries of changes a user made and of changes to
+// their account by others, oldest first
+func (r *MemoryRepository) GetPersonalAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return d.matchingAuditEntries(func(e *models.AuditEntry) bool {
+		return (e.ActorID != nil && *e.ActorID == userID) ||
+			(e.EntityType == models.AuditEntityUser && e.EntityID == userID)
+	}), nil
+}
+
+// EraseAuditEntryData stores the erased personal data of an audit entry; entries can only be erased once
+func (r *MemoryRepository) EraseAuditEntryData(ctx context.Context, entry *models.AuditEntry) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if entry.ID == 0 || int(entry.ID) > len(d.auditEntries) || d.auditEntries[entry.ID-1].ErasedAt != nil {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Audit entry with ID %d not found or already erased", entry.ID), nil)
+	}
+	row := &d.auditEntries[entry.ID-1]
+	row.Changes = slices.Clone(entry.Changes)
+	row.IPAddress = entry.IPAddress
+	row.UserAgent = entry.UserAgent
+	row.ErasedAt = timePtr(*entry.ErasedAt)
+	return nil
+}
+
+// GetUserCategories retrieves every category a user created in any household, including deleted ones
+func (r *MemoryRepository) GetUserCategories(ctx context.Context, userID uint) ([]models.Category, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return sortedRows(d.categories, func(c *models.Category) bool { return c.UserID == userID }), nil
+}
+
+// GetUserTransactions retrieves every transaction a user recorded in any household, including deleted ones
+func (r *MemoryRepository) GetUserTransactions(ctx context.Context, userID uint) ([]models.Transaction, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	transactions := sortedRows(d.transactions, func(t *models.Transaction) bool { return t.UserID == userID })
+	sort.SliceStable(transactions, func(i, j int) bool { return transactions[i].Date.Before(transactions[j].Date) })
+	return transactions, nil
+}
+
+// GetUserReconciliations retrieves every reconciliation a user started in any household, including deleted ones
+func (r *MemoryRepository) GetUserReconciliations(ctx context.Context, userID uint) ([]models.Reconciliation, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return sortedRows(d.reconciliations, func(rec *models.Reconciliation) bool { return rec.UserID == userID }), nil
+}
+
+// GetUserAuditEntries retrieves the audit entries of changes a user made, oldest first
+func (r *MemoryRepository) GetUserAuditEntries(ctx context.Context, userID uint) ([]models.AuditEntry, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	return d.matchingAuditEntries(func(e *models.AuditEntry) bool {
+		return e.ActorID != nil && *e.ActorID == userID
+	}), nil
+}
+
+// CreateAccountDeletion stores a new account deletion request, replacing any earlier request of the user
+func (r *MemoryRepository) CreateAccountDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.users[deletion.UserID]; !ok {
+		return appErrors.NewInternalError("Failed to create account deletion request due to database error", gorm.ErrForeignKeyViolated)
+	}
+	deleteRows(d.deletions, func(other *models.AccountDeletion) bool { return other.UserID == deletion.UserID })
+	deletion.ID = d.nextID("account_deletions")
+	if deletion.CreatedAt.IsZero() {
+		deletion.CreatedAt = time.Now()
+	}
+	row := *deletion
+	row.User = models.User{}
+	d.deletions[deletion.ID] = row
+	return nil
+}
+
+// accountDeletionID returns the ID of the deletion request of a user, or zero
+func (d *memoryData) accountDeletionID(userID uint) uint {
+	for id, deletion := range d.deletions {
+		if deletion.UserID == userID {
+			return id
+		}
+	}
+	return 0
+}
+
+// GetAccountDeletion retrieves the account deletion request of a user
+func (r *MemoryRepository) GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	id := d.accountDeletionID(userID)
+	if id == 0 {
+		return nil, appErrors.NewNotFoundError("No account deletion has been requested", gorm.ErrRecordNotFound)
+	}
+	deletion := d.deletions[id]
+	return &deletion, nil
+}
+
+// ConfirmAccountDeletion confirms the pending deletion request of a user matching tokenHash and
+// schedules it. It reports false if no unconfirmed, unexpired request matches.
+func (r *MemoryRepository) ConfirmAccountDeletion(ctx context.Context, userID uint, tokenHash string, confirmedAt, scheduledFor time.Time) (bool, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	id := d.accountDeletionID(userID)
+	deletion, ok := d.deletions[id]
+	if !ok || deletion.TokenHash != tokenHash || deletion.ConfirmedAt != nil || !deletion.ExpiresAt.After(confirmedAt) {
+		return false, nil
+	}
+	deletion.ConfirmedAt = timePtr(confirmedAt)
+	deletion.ScheduledFor = timePtr(scheduledFor)
+	d.deletions[id] = deletion
+	return true, nil
+}
+
+// DeleteAccountDeletion removes the account deletion request of a user, cancelling it
+func (r *MemoryRepository) DeleteAccountDeletion(ctx context.Context, userID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if deleteRows(d.deletions, func(deletion *models.AccountDeletion) bool { return deletion.UserID == userID }) == 0 {
+		return appErrors.NewNotFoundError("No account deletion has been requested", nil)
+	}
+	return nil
+}
+
+// GetDueAccountDeletions retrieves the confirmed account deletions whose grace period has passed
+func (r *MemoryRepository) GetDueAccountDeletions(ctx context.Context, now time.Time) ([]models.AccountDeletion, error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	deletions := sortedRows(d.deletions, func(deletion *models.AccountDeletion) bool {
+		return deletion.ConfirmedAt != nil && deletion.ScheduledFor != nil && !deletion.ScheduledFor.After(now)
+	})
+	sort.SliceStable(deletions, func(i, j int) bool { return deletions[i].ScheduledFor.Before(*deletions[j].ScheduledFor) })
+	return deletions, nil
+}
+
+// PurgeHousehold permanently deletes a household with its members, invitations and all of its
+// records, including soft-deleted ones
+func (r *MemoryRepository) PurgeHousehold(ctx context.Context, householdID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	deleteRows(d.transactions, func(t *models.Transaction) bool { return t.HouseholdID == householdID })
+	deleteRows(d.reconciliations, func(rec *models.Reconciliation) bool { return rec.HouseholdID == householdID })
+	deleteRows(d.categories, func(c *models.Category) bool { return c.HouseholdID == householdID })
+	deleteRows(d.invitations, func(inv *models.HouseholdInvitation) bool { return inv.HouseholdID == householdID })
+	deleteRows(d.members, func(m *models.HouseholdMember) bool { return m.HouseholdID == householdID })
+	for id, u := range d.users {
+		if u.DefaultHouseholdID != nil && *u.DefaultHouseholdID == householdID {
+			d.updateUser(id, func(u *models.User) { u.DefaultHouseholdID = nil })
+		}
+	}
+	delete(d.households, householdID)
+	return nil
+}
+
+// ReassignHouseholdRecords attributes the records a user created in a household to another
+// member, including soft-deleted ones
+func (r *MemoryRepository) ReassignHouseholdRecords(ctx context.Context, householdID, fromUserID, toUserID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now()
+	for id, t := range d.transactions {
+		if t.HouseholdID == householdID && t.UserID == fromUserID {
+			t.UserID, t.UpdatedAt = toUserID, now
+			d.transactions[id] = t
+		}
+	}
+	for id, rec := range d.reconciliations {
+		if rec.HouseholdID == householdID && rec.UserID == fromUserID {
+			rec.UserID, rec.UpdatedAt = toUserID, now
+			d.reconciliations[id] = rec
+		}
+	}
+	for id, c := range d.categories {
+		if c.HouseholdID == householdID && c.UserID == fromUserID {
+			c.UserID, c.UpdatedAt = toUserID, now
+			d.categories[id] = c
+		}
+	}
+	return nil
+}
+
+// AnonymizeHouseholdRecords renames a household and clears the free-text descriptions of all of
+// its transactions, including soft-deleted ones
+func (r *MemoryRepository) AnonymizeHouseholdRecords(ctx context.Context, householdID uint, name string) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	now := time.Now()
+	for id, t := range d.transactions {
+		if t.HouseholdID == householdID {
+			t.Description, t.UpdatedAt = "", now
+			d.transactions[id] = t
+		}
+	}
+	if h, ok := d.households[householdID]; ok {
+		h.Name, h.UpdatedAt = name, now
+		d.households[householdID] = h
+	}
+	return nil
+}
+
+// DeleteUserCredentials removes everything a user could sign in with or that was issued to them
+func (r *MemoryRepository) DeleteUserCredentials(ctx context.Context, userID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	d.deleteUserCredentials(userID)
+	return nil
+}
+
+// deleteUserCredentials removes the rows owned by a user that are deleted along with the user
+func (d *memoryData) deleteUserCredentials(userID uint) {
+	deleteRows(d.refreshTokens, func(t *models.RefreshToken) bool { return t.UserID == userID })
+	deleteRows(d.revokedTokens, func(t *models.RevokedToken) bool { return t.UserID == userID })
+	deleteRows(d.apiTokens, func(t *models.APIToken) bool { return t.UserID == userID })
+	deleteRows(d.recoveryCodes, func(c *models.RecoveryCode) bool { return c.UserID == userID })
+	deleteRows(d.loginChallenges, func(c *models.LoginChallenge) bool { return c.UserID == userID })
+	deleteRows(d.passwordResets, func(t *models.PasswordResetToken) bool { return t.UserID == userID })
+	deleteRows(d.identities, func(i *models.UserIdentity) bool { return i.UserID == userID })
+	deleteRows(d.deletions, func(deletion *models.AccountDeletion) bool { return deletion.UserID == userID })
+	deleteRows(d.oidcRequests, func(req *models.OIDCAuthRequest) bool {
+		return req.LinkUserID != nil && *req.LinkUserID == userID
+	})
+	deleteRows(d.invitations, func(inv *models.HouseholdInvitation) bool { return inv.InvitedByID == userID })
+	for id, inv := range d.invitations {
+		if inv.AcceptedByID != nil && *inv.AcceptedByID == userID {
+			inv.AcceptedByID = nil
+			d.invitations[id] = inv
+		}
+	}
+}
+
+// AnonymizeUser replaces the username of a user, clears their email address, password and
+// two-factor settings, and signs out all of their sessions
+func (r *MemoryRepository) AnonymizeUser(ctx context.Context, userID uint, username string, at time.Time) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	found := d.updateUser(userID, func(u *models.User) {
+		u.Username, u.Email, u.PasswordHash = username, nil, ""
+		u.TOTPSecret, u.TOTPEnabled, u.TOTPLastCounter = "", false, 0
+		u.SessionsRevokedAt = timePtr(at)
+	})
+	if !found {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	return nil
+}
+
+// DeleteUser permanently deletes a user. Their records must have been removed or reassigned first.
+func (r *MemoryRepository) DeleteUser(ctx context.Context, userID uint) error {
+	d, unlock := r.lock()
+	defer unlock()
+
+	if _, ok := d.users[userID]; !ok {
+		return appErrors.NewNotFoundError(fmt.Sprintf("User with ID %d not found", userID), nil)
+	}
+	ownsRecords := len(sortedRows(d.categories, func(c *models.Category) bool { return c.UserID == userID })) > 0 ||
+		len(sortedRows(d.transactions, func(t *models.Transaction) bool { return t.UserID == userID })) > 0 ||
+		len(sortedRows(d.reconciliations, func(rec *models.Reconciliation) bool { return rec.UserID == userID })) > 0
+	if ownsRecords {
+		return appErrors.NewConflictError(fmt.Sprintf("User with ID %d still owns records", userID), gorm.ErrForeignKeyViolated)
+	}
+
+	// Rows referencing the user are removed or detached like the foreign keys of the schema do
+	d.deleteUserCredentials(userID)
+	deleteRows(d.members, func(m *models.HouseholdMember) bool { return m.UserID == userID })
+	delete(d.users, userID)
+	return nil
+}
+
+// Transaction executes a function with exclusive access to the repository, discarding all of its
+// changes when it returns an error or panics. Nested transactions roll back on their own.
+func (r *MemoryRepository) Transaction(txFunc func(txRepo Repository) error) (err error) {
+	d, unlock := r.lock()
+	defer unlock()
+
+	snapshot := d.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			r.state.data = snapshot
+			panic(p)
+		}
+		if err != nil {
+			r.state.data = snapshot
+		}
+	}()
+	return txFunc(&MemoryRepository{state: r.state, inTx: true})
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+)
+
+func TestMemoryRepositoryTransactionRollsBackOnPanic(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Transaction did not propagate the panic")
+			}
+		}()
+		repo.Transaction(func(txRepo Repository) error {
+			if err := txRepo.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			panic("boom")
+		})
+	}()
+
+	if _, err := repo.GetUserByUsername(ctx, "alice"); appErrors.GetType(err) != appErrors.TypeNotFound {
+		t.Errorf("user created in a panicking transaction: %v", err)
+	}
+	// The repository must still be usable, i.e. the lock was released
+	if err := repo.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}); err != nil {
+		t.Errorf("CreateUser after the panic: %v", err)
+	}
+}
+
+func TestMemoryRepositoryNestedTransactionRollsBackOnItsOwn(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	err := repo.Transaction(func(txRepo Repository) error {
+		if err := txRepo.CreateUser(ctx, &models.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+			return err
+		}
+		err := txRepo.Transaction(func(nestedRepo Repository) error {
+			if err := nestedRepo.CreateUser(ctx, &models.User{Username: "bob", PasswordHash: "hash"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Errorf("nested Transaction returned %v, want %v", err, errAbort)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+
+	if _, err := repo.GetUserByUsername(ctx, "alice"); err != nil {
+		t.Errorf("user of the committed transaction: %v", err)
+	}
+	if _, err := repo.GetUserByUsername(ctx, "bob"); appErrors.GetType(err) != appErrors.TypeNotFound {
+		t.Errorf("user created in a rolled back nested transaction: %v", err)
+	}
+}
+
+func TestMemoryRepositoryConcurrentWrites(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	user, household, category := seedHousehold(t, repo, "alice")
+
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := repo.Transaction(func(txRepo Repository) error {
+				transaction := &models.Transaction{
+					Description: fmt.Sprintf("purchase %d", i), Amount: 1, Type: models.Expense, Date: time.Now(),
+					CategoryID: category.ID, HouseholdID: household.ID, UserID: user.ID,
+				}
+				if err := txRepo.CreateTransaction(ctx, transaction); err != nil {
+					return err
+				}
+				return txRepo.AppendAuditEntry(ctx, &models.AuditEntry{
+					ActorID: &user.ID, HouseholdID: &household.ID, Action: models.AuditActionCreate,
+					EntityType: models.AuditEntityTransaction, EntityID: transaction.ID,
+				})
+			})
+			if err != nil {
+				t.Errorf("Transaction: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	transactions, err := repo.GetTransactions(ctx, household.ID, 0, 0, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("GetTransactions: %v", err)
+	}
+	if len(transactions) != writers {
+		t.Errorf("got %d transactions, want %d", len(transactions), writers)
+	}
+	entries, err := repo.GetAuditEntriesAfter(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("GetAuditEntriesAfter: %v", err)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].PrevHash != entries[i-1].Hash {
+			t.Fatalf("audit entry %d does not link to its predecessor", entries[i].ID)
+		}
+	}
+}
+
+func TestMemoryRepositoryReturnsCopies(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	_, household, category := seedHousehold(t, repo, "alice")
+
+	category.Name = "Changed without saving"
+	stored, err := repo.GetCategoryByID(ctx, household.ID, category.ID)
+	if err != nil {
+		t.Fatalf("GetCategoryByID: %v", err)
+	}
+	if stored.Name != "Groceries" {
+		t.Errorf("stored category changed through the created value: %q", stored.Name)
+	}
+
+	stored.Name = "Changed again"
+	again, err := repo.GetCategoryByID(ctx, household.ID, category.ID)
+	if err != nil {
+		t.Fatalf("GetCategoryByID: %v", err)
+	}
+	if again.Name != "Groceries" {
+		t.Errorf("stored category changed through a returned value: %q", again.Name)
+	}
+}
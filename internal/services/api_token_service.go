@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lastUsedResolution limits how often a token's last-used timestamp is written
+const lastUsedResolution = time.Minute
+
+// APITokenService defines the interface for personal access token business logic
+type APITokenService interface {
+	CreateToken(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error)
+	GetTokens(ctx context.Context, userID uint) ([]models.APIToken, error)
+	RevokeToken(ctx context.Context, userID, id uint) error
+	AuthenticateToken(ctx context.Context, token string) (*models.APIToken, error)
+}
+
+// apiTokenService implements the APITokenService interface
+type apiTokenService struct {
+	repo repository.Repository
+}
+
+// NewAPITokenService creates a new instance of APITokenService
+func NewAPITokenService(repo repository.Repository) APITokenService {
+	return &apiTokenService{repo: repo}
+}
+
+// CreateToken creates a personal access token and returns it together with its plaintext value,
+// which is not stored and cannot be retrieved again
+func (s *apiTokenService) CreateToken(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", appErrors.NewValidationError("Token name must not be empty", nil)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", appErrors.NewValidationError("Token expiry must be in the future", nil)
+	}
+
+	var granted []string
+	for _, scope := range scopes {
+		if !models.IsValidScope(scope) {
+			return nil, "", appErrors.NewValidationError(fmt.Sprintf("Unknown scope '%s'", scope), nil)
+		}
+		if !containsString(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return nil, "", appErrors.NewValidationError("At least one scope is required", nil)
+	}
+
+	secret, err := generateToken(32)
+	if err != nil {
+		return nil, "", appErrors.NewInternalError("Failed to generate API token", err)
+	}
+	plaintext := models.APITokenPrefix + secret
+
+	token := &models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plaintext[:len(models.APITokenPrefix)+6],
+		TokenHash: hashToken(plaintext),
+		Scopes:    granted,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateAPIToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+
+	return token, plaintext, nil
+}
+
+// GetTokens retrieves all personal access tokens of a user
+func (s *apiTokenService) GetTokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	return s.repo.GetAPITokens(ctx, userID)
+}
+
+// RevokeToken revokes a personal access token; revoked tokens remain listed
+func (s *apiTokenService) RevokeToken(ctx context.Context, userID, id uint) error {
+	return s.repo.RevokeAPIToken(ctx, userID, id)
+}
+
+// AuthenticateToken resolves a plaintext personal access token and records its use
+func (s *apiTokenService) AuthenticateToken(ctx context.Context, plaintext string) (*models.APIToken, error) {
+	token, err := s.repo.GetAPITokenByHash(ctx, hashToken(plaintext))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid API token", nil)
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		return nil, appErrors.NewUnauthorizedError("API token has been revoked", nil)
+	}
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, appErrors.NewUnauthorizedError("API token has expired", nil)
+	}
+	user, err := s.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid API token", nil)
+		}
+		return nil, err
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+
+	// Tracking use is best effort and must not fail the request
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.UpdateAPITokenLastUsed(ctx, token.ID, now); err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":   err.Error(),
+				"tokenID": token.ID,
+			}).Warn("APITokenService: Failed to record API token use")
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+
+	return token, nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	auditVerifyBatchSize = 500          // entries loaded at a time when verifying the hash chain
+	auditRedacted        = "[redacted]" // recorded instead of secrets such as password hashes
+	maxAuditUserAgent    = 255          // length of the user agent column
+)
+
+// auditIgnoredFields are left out of audit diffs because they change with every update
+var auditIgnoredFields = map[string]bool{"UpdatedAt": true, "updatedAt": true}
+
+// requestMetadataKey is the context key under which WithRequestMetadata stores request details
+type requestMetadataKey struct{}
+
+// requestMetadata describes the client of the request that caused a change
+type requestMetadata struct {
+	IPAddress string
+	UserAgent string
+}
+
+// WithRequestMetadata returns a context carrying the client IP address and user agent of a
+// request, which are recorded with every audit entry created while handling it
+func WithRequestMetadata(ctx context.Context, ipAddress, userAgent string) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, requestMetadata{IPAddress: ipAddress, UserAgent: userAgent})
+}
+
+// AuditService defines the interface for reading and verifying the audit log
+type AuditService interface {
+	GetEntries(ctx context.Context, userID, householdID uint, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error)
+	VerifyChain(ctx context.Context) (*models.AuditVerification, error)
+}
+
+// auditService implements the AuditService interface
+type auditService struct {
+	repo   repository.Repository
+	access householdAccess
+}
+
+// NewAuditService creates a new instance of AuditService
+func NewAuditService(repo repository.Repository) AuditService {
+	return &auditService{repo: repo, access: householdAccess{repo: repo}}
+}
+
+// GetEntries retrieves the audit entries of a household the user belongs to, together with the
+// entries of changes the user made to their own account
+func (s *auditService) GetEntries(ctx context.Context, userID, householdID uint, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, appErrors.NewValidationError("The start of the time range must not be after its end", nil)
+	}
+	filter.VisibleHousehold = householdID
+	filter.VisibleUser = userID
+	return s.repo.GetAuditEntries(ctx, filter, limit, offset)
+}
+
+// VerifyChain recomputes the hash of every audit entry and checks that each one links to the
+// entry before it. The first entry that does not match is reported.
+func (s *auditService) VerifyChain(ctx context.Context) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true}
+	var afterID uint
+	prevHash := ""
+	for {
+		entries, err := s.repo.GetAuditEntriesAfter(ctx, afterID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			entry := &entries[i]
+			result.EntriesChecked++
+			if entry.PrevHash != prevHash || !entry.Intact() {
+				result.Valid = false
+				result.FirstInvalidID = &entry.ID
+				return result, nil
+			}
+			prevHash = entry.Hash
+			afterID = entry.ID
+		}
+		if len(entries) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// recordAudit appends an entry for a change to the audit log. It should be called with the
+// repository of the database transaction making the change, so that the change is only kept
+// when it has been recorded. An actorID or householdID of 0 is recorded as none.
+func recordAudit(ctx context.Context, repo repository.Repository, actorID, householdID uint, action, entityType string, entityID uint, changes map[string]models.AuditChange) error {
+	entry := &models.AuditEntry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if actorID != 0 {
+		entry.ActorID = &actorID
+	}
+	if householdID != 0 {
+		entry.HouseholdID = &householdID
+	}
+	if len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			return appErrors.NewInternalError(fmt.Sprintf("Failed to encode audit changes of %s %d", entityType, entityID), err)
+		}
+		entry.Changes = data
+	}
+	if metadata, ok := ctx.Value(requestMetadataKey{}).(requestMetadata); ok {
+		entry.IPAddress = metadata.IPAddress
+		entry.UserAgent = truncateUTF8(metadata.UserAgent, maxAuditUserAgent)
+	}
+	return repo.AppendAuditEntry(ctx, entry)
+}
+
+// eraseAuditData removes a user's personal data from the audit log: the IP address and user
+// agent of their requests, and the values in changes to their account and identities. Which
+// fields changed, when and by whom is kept, and so is the hash chain.
+func eraseAuditData(ctx context.Context, repo repository.Repository, userID uint, erasedAt time.Time) error {
+	entries, err := repo.GetPersonalAuditEntries(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ErasedAt != nil {
+			continue
+		}
+		if entry.ActorID != nil && *entry.ActorID == userID {
+			entry.IPAddress = ""
+			entry.UserAgent = ""
+		}
+		if entry.EntityType == models.AuditEntityUser || entry.EntityType == models.AuditEntityUserIdentity {
+			if entry.Changes, err = redactAuditChanges(entry.Changes); err != nil {
+				return appErrors.NewInternalError(fmt.Sprintf("Failed to redact audit entry %d", entry.ID), err)
+			}
+		}
+		entry.ErasedAt = &erasedAt
+		if err := repo.EraseAuditEntryData(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactAuditChanges replaces every recorded value in changes, keeping the names of the fields
+func redactAuditChanges(changes models.AuditChanges) (models.AuditChanges, error) {
+	if len(changes) == 0 {
+		return changes, nil
+	}
+	var fields map[string]models.AuditChange
+	if err := json.Unmarshal(changes, &fields); err != nil {
+		return nil, err
+	}
+	for name, change := range fields {
+		if change.Before != nil {
+			change.Before = auditRedacted
+		}
+		if change.After != nil {
+			change.After = auditRedacted
+		}
+		fields[name] = change
+	}
+	return json.Marshal(fields)
+}
+
+// auditDiff compares the JSON representations of two versions of an entity and returns the
+// fields that differ. before is nil for created entities and after for deleted ones. Nested
+// objects such as preloaded associations are skipped; their IDs are compared instead.
+func auditDiff(before, after interface{}) map[string]models.AuditChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changes := make(map[string]models.AuditChange)
+	for name, value := range beforeFields {
+		if afterValue, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[name] = models.AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = models.AuditChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditFields flattens an entity into its top-level JSON fields, leaving out nested objects and
+// fields without a value
+func auditFields(entity interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value := reflect.ValueOf(entity); !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return fields
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fields
+	}
+	for name, value := range fields {
+		if _, nested := value.(map[string]interface{}); nested || value == nil || auditIgnoredFields[name] {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// truncateUTF8 shortens s to at most max bytes without splitting a character
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
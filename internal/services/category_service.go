@@ -2,36 +2,66 @@ package services
 
 import (
 	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
 	"personal-finance-tracker-api/internal/models"
 	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/templates"
+	"time"
 )
 
+// maxCategoryDepth bounds ancestor walks so that corrupted data cannot cause an endless loop
+const maxCategoryDepth = 64
+
 // CategoryService defines the interface for category-related business logic
 type CategoryService interface {
-	CreateCategory(ctx context.Context, category *models.Category) (*models.Category, error)
-	GetCategories(ctx context.Context, userID uint, limit, offset int, name *string) ([]models.Category, error)
-	DeleteCategory(ctx context.Context, userID uint, id uint) error
+	CreateCategory(ctx context.Context, userID, householdID uint, category *models.Category) (*models.Category, error)
+	GetCategories(ctx context.Context, userID, householdID uint, limit, offset int, name *string) ([]models.Category, error)
+	GetCategoryTree(ctx context.Context, userID, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]*models.CategoryNode, error)
+	GetCategory(ctx context.Context, userID, householdID uint, id uint) (*models.Category, error)
+	UpdateCategory(ctx context.Context, userID, householdID uint, id uint, name string) (*models.Category, error)
+	MoveCategory(ctx context.Context, userID, householdID uint, id uint, parentID *uint) (*models.Category, error)
+	MergeCategories(ctx context.Context, userID, householdID uint, sourceID, targetID uint) (*models.Category, error)
+	DeleteCategory(ctx context.Context, userID, householdID uint, id uint, reassignTo *uint) error
+	GetCategoryTemplates() []*templates.CategoryTemplate
+	ApplyCategoryTemplate(ctx context.Context, userID, householdID uint, name string) ([]models.Category, error)
 }
 
 // categoryService implements the CategoryService interface
 type categoryService struct {
-	repo repository.Repository
+	repo      repository.Repository
+	access    householdAccess
+	templates *templates.Registry
 }
 
 // NewCategoryService creates a new instance of CategoryService
-func NewCategoryService(repo repository.Repository) CategoryService {
-	return &categoryService{repo: repo}
+func NewCategoryService(repo repository.Repository, templates *templates.Registry) CategoryService {
+	return &categoryService{repo: repo, access: householdAccess{repo: repo}, templates: templates}
 }
 
 // CreateCategory handles the creation of a new category, applying business rules if any
-func (s *categoryService) CreateCategory(ctx context.Context, category *models.Category) (*models.Category, error) {
+func (s *categoryService) CreateCategory(ctx context.Context, userID, householdID uint, category *models.Category) (*models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	category.UserID = userID
+	category.HouseholdID = householdID
+
 	// Execute the creation within a database transaction
-	err := s.repo.Transaction(func(txRepo repository.Repository) error {
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		// The parent must exist and belong to the same household
+		if category.ParentID != nil {
+			if _, err := txRepo.GetCategoryByID(ctx, householdID, *category.ParentID); err != nil {
+				return asInvalidParent(err)
+			}
+		}
+
 		// Use txRepo for operations within this transaction
 		if err := txRepo.CreateCategory(ctx, category); err != nil {
 			return err
 		}
-		return nil
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionCreate, models.AuditEntityCategory, category.ID, auditDiff(nil, category))
 	})
 
 	if err != nil {
@@ -41,8 +71,12 @@ func (s *categoryService) CreateCategory(ctx context.Context, category *models.C
 }
 
 // GetCategories retrieves a list of categories, applying business rules if any
-func (s *categoryService) GetCategories(ctx context.Context, userID uint, limit, offset int, name *string) ([]models.Category, error) {
-	categories, err := s.repo.GetCategories(ctx, userID, limit, offset, name)
+func (s *categoryService) GetCategories(ctx context.Context, userID, householdID uint, limit, offset int, name *string) ([]models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.repo.GetCategories(ctx, householdID, limit, offset, name)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +84,336 @@ func (s *categoryService) GetCategories(ctx context.Context, userID uint, limit,
 	return categories, nil
 }
 
-// DeleteCategory performs a soft delete of a category
-func (s *categoryService) DeleteCategory(ctx context.Context, userID uint, id uint) error {
-	return s.repo.DeleteCategory(ctx, userID, id)
+// GetCategoryTree retrieves the household's categories as a forest of nested nodes.
+// maxDepth limits how many levels are returned; 0 or less returns the whole hierarchy.
+// The statistics cover the calendar dates from startDate to endDate in the user's time zone.
+func (s *categoryService) GetCategoryTree(ctx context.Context, userID, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]*models.CategoryNode, error) {
+	// The query counts depth from 0 at the roots
+	if maxDepth <= 0 || maxDepth > maxCategoryDepth {
+		maxDepth = maxCategoryDepth
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if withStats && (startDate != nil || endDate != nil) {
+		loc, err := userLocation(ctx, s.repo, userID)
+		if err != nil {
+			return nil, err
+		}
+		startDate, endDate = localDateRange(startDate, endDate, loc)
+	}
+
+	rows, err := s.repo.GetCategoryTree(ctx, householdID, maxDepth-1, withStats, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are ordered by depth, so every parent is seen before its children
+	roots := []*models.CategoryNode{}
+	nodes := make(map[uint]*models.CategoryNode, len(rows))
+	for _, row := range rows {
+		node := &models.CategoryNode{
+			ID:       row.ID,
+			Name:     row.Name,
+			ParentID: row.ParentID,
+			Depth:    row.Depth,
+			Children: []*models.CategoryNode{},
+		}
+		if withStats {
+			node.Stats = &models.CategoryStats{
+				TransactionCount: row.TransactionCount,
+				IncomeTotal:      roundCents(row.IncomeTotal),
+				ExpenseTotal:     roundCents(row.ExpenseTotal),
+			}
+		}
+		nodes[row.ID] = node
+
+		if parent, ok := nodes[derefUint(row.ParentID)]; ok && row.Depth > 0 {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	if withStats {
+		for _, root := range roots {
+			sumSubtreeStats(root)
+		}
+	}
+	return roots, nil
+}
+
+// sumSubtreeStats fills TotalStats of a node and its descendants with the aggregates of each subtree
+func sumSubtreeStats(node *models.CategoryNode) *models.CategoryStats {
+	total := *node.Stats
+	for _, child := range node.Children {
+		childTotal := sumSubtreeStats(child)
+		total.TransactionCount += childTotal.TransactionCount
+		total.IncomeTotal = roundCents(total.IncomeTotal + childTotal.IncomeTotal)
+		total.ExpenseTotal = roundCents(total.ExpenseTotal + childTotal.ExpenseTotal)
+	}
+	node.TotalStats = &total
+	return node.TotalStats
+}
+
+// derefUint returns the value of an optional ID, or 0 when it is nil
+func derefUint(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// GetCategory retrieves a single category of a household the user belongs to
+func (s *categoryService) GetCategory(ctx context.Context, userID, householdID uint, id uint) (*models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetCategoryByID(ctx, householdID, id)
+}
+
+// UpdateCategory renames a category
+func (s *categoryService) UpdateCategory(ctx context.Context, userID, householdID uint, id uint, name string) (*models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	var category *models.Category
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		var err error
+		category, err = txRepo.GetCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+
+		before := *category
+		category.Name = name
+		if err := txRepo.UpdateCategory(ctx, category); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionUpdate, models.AuditEntityCategory, id, auditDiff(&before, category))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// MoveCategory changes the parent of a category. A nil parent makes it a top-level category.
+// Moving a category underneath itself or one of its descendants is rejected.
+func (s *categoryService) MoveCategory(ctx context.Context, userID, householdID uint, id uint, parentID *uint) (*models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	var category *models.Category
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		var err error
+		category, err = txRepo.GetCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			descendant, err := isSameOrDescendant(ctx, txRepo, householdID, *parentID, id)
+			if err != nil {
+				return asInvalidParent(err)
+			}
+			if descendant {
+				return appErrors.NewValidationError(fmt.Sprintf("Cannot move category with ID %d underneath itself or one of its descendants", id), nil)
+			}
+		}
+
+		before := *category
+		category.ParentID = parentID
+		if err := txRepo.UpdateCategory(ctx, category); err != nil {
+			return err
+		}
+		category, err = txRepo.GetCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionUpdate, models.AuditEntityCategory, id, auditDiff(&before, category))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// MergeCategories moves all transactions and child categories of the source category to the
+// target category and then deletes the source, all within a single database transaction.
+// Sources with reconciled transactions cannot be merged, since those are locked.
+func (s *categoryService) MergeCategories(ctx context.Context, userID, householdID uint, sourceID, targetID uint) (*models.Category, error) {
+	if sourceID == targetID {
+		return nil, appErrors.NewValidationError("Cannot merge a category into itself", nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.Category
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		source, err := txRepo.GetCategoryByID(ctx, householdID, sourceID)
+		if err != nil {
+			return err
+		}
+
+		// Reparenting the source's children onto one of its own descendants would create a cycle
+		descendant, err := isSameOrDescendant(ctx, txRepo, householdID, targetID, sourceID)
+		if err != nil {
+			return err
+		}
+		if descendant {
+			return appErrors.NewValidationError(fmt.Sprintf("Cannot merge category with ID %d into one of its descendants", sourceID), nil)
+		}
+
+		reassigned, err := txRepo.ReassignTransactions(ctx, householdID, sourceID, targetID)
+		if err != nil {
+			return err
+		}
+		reparented, err := txRepo.ReparentCategories(ctx, householdID, sourceID, &targetID)
+		if err != nil {
+			return err
+		}
+		if err := txRepo.DeleteCategory(ctx, householdID, sourceID); err != nil {
+			return err
+		}
+
+		// The moved transactions and children are summarised rather than recorded one by one
+		changes := auditDiff(source, nil)
+		changes["mergedInto"] = models.AuditChange{After: targetID}
+		changes["transactionsReassigned"] = models.AuditChange{After: reassigned}
+		changes["categoriesReparented"] = models.AuditChange{After: reparented}
+		if err := recordAudit(ctx, txRepo, userID, householdID, models.AuditActionDelete, models.AuditEntityCategory, sourceID, changes); err != nil {
+			return err
+		}
+
+		target, err = txRepo.GetCategoryByID(ctx, householdID, targetID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// DeleteCategory performs a soft delete of a category.
+// A category that still has transactions can only be deleted when a category to reassign them to
+// is given and none of them is reconciled. Child categories are moved up to the deleted category's parent.
+func (s *categoryService) DeleteCategory(ctx context.Context, userID, householdID uint, id uint, reassignTo *uint) error {
+	if reassignTo != nil && *reassignTo == id {
+		return appErrors.NewValidationError("Cannot reassign transactions to the category being deleted", nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		category, err := txRepo.GetCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+
+		var reassigned int64
+		if reassignTo != nil {
+			if _, err := txRepo.GetCategoryByID(ctx, householdID, *reassignTo); err != nil {
+				if appErrors.IsType(err, appErrors.TypeNotFound) {
+					return appErrors.NewValidationError(fmt.Sprintf("Reassignment category with ID %d not found in household", *reassignTo), err)
+				}
+				return err
+			}
+			if reassigned, err = txRepo.ReassignTransactions(ctx, householdID, id, *reassignTo); err != nil {
+				return err
+			}
+		} else {
+			count, err := txRepo.CountTransactionsByCategory(ctx, householdID, id)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return appErrors.NewConflictError(fmt.Sprintf("Category with ID %d has %d transactions; reassign them to another category before deleting", id, count), nil)
+			}
+		}
+
+		reparented, err := txRepo.ReparentCategories(ctx, householdID, id, category.ParentID)
+		if err != nil {
+			return err
+		}
+		if err := txRepo.DeleteCategory(ctx, householdID, id); err != nil {
+			return err
+		}
+
+		changes := auditDiff(category, nil)
+		if reassignTo != nil {
+			changes["reassignedTo"] = models.AuditChange{After: *reassignTo}
+			changes["transactionsReassigned"] = models.AuditChange{After: reassigned}
+		}
+		if reparented > 0 {
+			changes["categoriesReparented"] = models.AuditChange{After: reparented}
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionDelete, models.AuditEntityCategory, id, changes)
+	})
+}
+
+// GetCategoryTemplates lists the category templates that can be applied
+func (s *categoryService) GetCategoryTemplates() []*templates.CategoryTemplate {
+	return s.templates.List()
+}
+
+// ApplyCategoryTemplate adds the categories of a template to the household's existing categories.
+// Categories the household already has are left untouched, so applying a template twice is harmless.
+func (s *categoryService) ApplyCategoryTemplate(ctx context.Context, userID, householdID uint, name string) ([]models.Category, error) {
+	template, ok := s.templates.Get(name)
+	if !ok {
+		return nil, appErrors.NewNotFoundError(fmt.Sprintf("Category template '%s' not found", name), nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []models.Category
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		var err error
+		created, err = applyCategoryTemplate(ctx, txRepo, userID, householdID, template)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// isSameOrDescendant reports whether the category candidateID is ancestorID itself or lies
+// beneath it, by walking up the parent chain from candidateID
+func isSameOrDescendant(ctx context.Context, repo repository.Repository, householdID uint, candidateID, ancestorID uint) (bool, error) {
+	currentID := &candidateID
+	for depth := 0; currentID != nil && depth < maxCategoryDepth; depth++ {
+		if *currentID == ancestorID {
+			return true, nil
+		}
+		current, err := repo.GetCategoryByID(ctx, householdID, *currentID)
+		if err != nil {
+			return false, err
+		}
+		currentID = current.ParentID
+	}
+	if currentID != nil {
+		return false, appErrors.NewInternalError(fmt.Sprintf("Category hierarchy above category with ID %d is too deep or cyclic", candidateID), nil)
+	}
+	return false, nil
+}
+
+// asInvalidParent reports a missing parent category as a validation error
+func asInvalidParent(err error) error {
+	if appErrors.IsType(err, appErrors.TypeNotFound) {
+		return appErrors.NewValidationError("Parent category not found in household", err)
+	}
+	return err
 }
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+func TestCategoryServiceCreateCategory(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewCategoryService(repo, testTemplates(t))
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	bobsCategory := createCategory(t, repo, bob, "Bob's", nil)
+
+	viewer := registerUser(t, repo, "victor")
+	addMember(t, repo, *alice.DefaultHouseholdID, viewer.ID, models.RoleViewer)
+
+	tests := []struct {
+		name        string
+		userID      uint
+		householdID uint
+		category    string
+		parentID    *uint
+		wantErr     appErrors.ErrorType
+	}{
+		{"top level", alice.ID, 0, "Rent", nil, ""},
+		{"with parent", alice.ID, 0, "Produce", &groceries.ID, ""},
+		{"taken name", alice.ID, 0, "Groceries", nil, appErrors.TypeAlreadyExists},
+		{"name taken in another household", bob.ID, 0, "Groceries", nil, ""},
+		{"unknown parent", alice.ID, 0, "Orphan", uintPtr(9999), appErrors.TypeValidation},
+		{"parent in another household", alice.ID, 0, "Stray", &bobsCategory.ID, appErrors.TypeValidation},
+		{"viewer", viewer.ID, *alice.DefaultHouseholdID, "Peek", nil, appErrors.TypeForbidden},
+		{"household of another user", bob.ID, *alice.DefaultHouseholdID, "Intrude", nil, appErrors.TypeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := service.CreateCategory(context.Background(), tt.userID, tt.householdID, &models.Category{Name: tt.category, ParentID: tt.parentID})
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			if created.UserID != tt.userID {
+				t.Errorf("created by user %d, want %d", created.UserID, tt.userID)
+			}
+			if actions := auditActions(t, repo, models.AuditEntityCategory, created.ID); fmt.Sprint(actions) != "[create]" {
+				t.Errorf("audit actions = %v, want [create]", actions)
+			}
+		})
+	}
+}
+
+func TestCategoryServiceMoveCategory(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewCategoryService(repo, testTemplates(t))
+	alice := registerUser(t, repo, "alice")
+	food := createCategory(t, repo, alice, "Food", nil)
+	groceries := createCategory(t, repo, alice, "Groceries", &food.ID)
+	produce := createCategory(t, repo, alice, "Produce", &groceries.ID)
+	transport := createCategory(t, repo, alice, "Transport", nil)
+
+	tests := []struct {
+		name     string
+		id       uint
+		parentID *uint
+		wantErr  appErrors.ErrorType
+	}{
+		{"under itself", food.ID, &food.ID, appErrors.TypeValidation},
+		{"under a descendant", food.ID, &produce.ID, appErrors.TypeValidation},
+		{"unknown parent", food.ID, uintPtr(9999), appErrors.TypeValidation},
+		{"unknown category", 9999, nil, appErrors.TypeNotFound},
+		{"under a sibling tree", produce.ID, &transport.ID, ""},
+		{"to the top level", groceries.ID, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved, err := service.MoveCategory(context.Background(), alice.ID, 0, tt.id, tt.parentID)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			if fmt.Sprint(derefUint(moved.ParentID)) != fmt.Sprint(derefUint(tt.parentID)) {
+				t.Errorf("parent = %v, want %v", moved.ParentID, tt.parentID)
+			}
+			if tt.parentID != nil && (moved.Parent == nil || moved.Parent.ID != *tt.parentID) {
+				t.Errorf("returned parent %+v, want category %d loaded", moved.Parent, *tt.parentID)
+			}
+		})
+	}
+}
+
+func TestCategoryServiceDeleteCategory(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions int
+		status       models.TransactionStatus
+		reassign     string // Name of the category to reassign to; "self" and "unknown" are invalid targets
+		wantErr      appErrors.ErrorType
+	}{
+		{"unused", 0, models.StatusPending, "", ""},
+		{"with transactions", 2, models.StatusPending, "", appErrors.TypeConflict},
+		{"reassigning transactions", 2, models.StatusPending, "Rent", ""},
+		{"reassigning reconciled transactions", 2, models.StatusReconciled, "Rent", appErrors.TypeConflict},
+		{"reassigning to itself", 2, models.StatusPending, "self", appErrors.TypeValidation},
+		{"reassigning to an unknown category", 2, models.StatusPending, "unknown", appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewCategoryService(repo, testTemplates(t))
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+			householdID := *alice.DefaultHouseholdID
+
+			food := createCategory(t, repo, alice, "Food", nil)
+			groceries := createCategory(t, repo, alice, "Groceries", &food.ID)
+			produce := createCategory(t, repo, alice, "Produce", &groceries.ID)
+			rent := createCategory(t, repo, alice, "Rent", nil)
+			for i := 0; i < tt.transactions; i++ {
+				createTransaction(t, repo, alice, groceries.ID, 5, time.Now(), tt.status)
+			}
+
+			var reassignTo *uint
+			switch tt.reassign {
+			case "Rent":
+				reassignTo = &rent.ID
+			case "self":
+				reassignTo = &groceries.ID
+			case "unknown":
+				reassignTo = uintPtr(9999)
+			}
+
+			err := service.DeleteCategory(ctx, alice.ID, 0, groceries.ID, reassignTo)
+			assertErrorType(t, err, tt.wantErr)
+
+			_, getErr := repo.GetCategoryByID(ctx, householdID, groceries.ID)
+			child, childErr := repo.GetCategoryByID(ctx, householdID, produce.ID)
+			if childErr != nil {
+				t.Fatal(childErr)
+			}
+			if tt.wantErr != "" {
+				assertErrorType(t, getErr, "")
+				if derefUint(child.ParentID) != groceries.ID {
+					t.Errorf("child moved by a rejected delete to parent %v", child.ParentID)
+				}
+				if count, _ := repo.CountTransactionsByCategory(ctx, householdID, groceries.ID); count != int64(tt.transactions) {
+					t.Errorf("%d transactions left after a rejected delete, want %d", count, tt.transactions)
+				}
+				return
+			}
+
+			assertErrorType(t, getErr, appErrors.TypeNotFound)
+			if derefUint(child.ParentID) != food.ID {
+				t.Errorf("child parent = %v, want the deleted category's parent %d", child.ParentID, food.ID)
+			}
+			if reassignTo != nil {
+				count, err := repo.CountTransactionsByCategory(ctx, householdID, rent.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != int64(tt.transactions) {
+					t.Errorf("%d transactions reassigned, want %d", count, tt.transactions)
+				}
+			}
+			changes := lastAuditChanges(t, repo, models.AuditEntityCategory, groceries.ID)
+			if reassignTo != nil && changes["transactionsReassigned"].After != float64(tt.transactions) {
+				t.Errorf("audited transactionsReassigned = %v, want %d", changes["transactionsReassigned"].After, tt.transactions)
+			}
+			if changes["categoriesReparented"].After != float64(1) {
+				t.Errorf("audited categoriesReparented = %v, want 1", changes["categoriesReparented"].After)
+			}
+		})
+	}
+}
+
+func TestCategoryServiceMergeCategories(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		target  string
+		status  models.TransactionStatus
+		wantErr appErrors.ErrorType
+	}{
+		{"into a sibling", "Groceries", "Dining", models.StatusPending, ""},
+		{"with reconciled transactions", "Groceries", "Dining", models.StatusReconciled, appErrors.TypeConflict},
+		{"into itself", "Groceries", "Groceries", models.StatusPending, appErrors.TypeValidation},
+		{"into a descendant", "Food", "Produce", models.StatusPending, appErrors.TypeValidation},
+		{"into an unknown category", "Groceries", "unknown", models.StatusPending, appErrors.TypeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewCategoryService(repo, testTemplates(t))
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+			householdID := *alice.DefaultHouseholdID
+
+			food := createCategory(t, repo, alice, "Food", nil)
+			groceries := createCategory(t, repo, alice, "Groceries", &food.ID)
+			produce := createCategory(t, repo, alice, "Produce", &groceries.ID)
+			dining := createCategory(t, repo, alice, "Dining", &food.ID)
+			createTransaction(t, repo, alice, groceries.ID, 5, time.Now(), tt.status)
+			ids := map[string]uint{"Food": food.ID, "Groceries": groceries.ID, "Produce": produce.ID, "Dining": dining.ID, "unknown": 9999}
+
+			target, err := service.MergeCategories(ctx, alice.ID, 0, ids[tt.source], ids[tt.target])
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				if _, err := repo.GetCategoryByID(ctx, householdID, ids[tt.source]); err != nil {
+					t.Errorf("source category gone after a rejected merge: %v", err)
+				}
+				if count, _ := repo.CountTransactionsByCategory(ctx, householdID, groceries.ID); count != 1 {
+					t.Errorf("source has %d transactions after a rejected merge, want 1", count)
+				}
+				return
+			}
+
+			if target.ID != dining.ID {
+				t.Errorf("returned category %d, want the target %d", target.ID, dining.ID)
+			}
+			_, err = repo.GetCategoryByID(ctx, householdID, groceries.ID)
+			assertErrorType(t, err, appErrors.TypeNotFound)
+			if count, _ := repo.CountTransactionsByCategory(ctx, householdID, dining.ID); count != 1 {
+				t.Errorf("target has %d transactions, want 1", count)
+			}
+			child, err := repo.GetCategoryByID(ctx, householdID, produce.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if derefUint(child.ParentID) != dining.ID {
+				t.Errorf("child parent = %v, want the target %d", child.ParentID, dining.ID)
+			}
+			changes := lastAuditChanges(t, repo, models.AuditEntityCategory, groceries.ID)
+			for field, want := range map[string]float64{"mergedInto": float64(dining.ID), "transactionsReassigned": 1, "categoriesReparented": 1} {
+				if changes[field].After != want {
+					t.Errorf("audited %s = %v, want %v", field, changes[field].After, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryServiceGetCategoryTree(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewCategoryService(repo, testTemplates(t))
+	alice := registerUser(t, repo, "alice")
+	food := createCategory(t, repo, alice, "Food", nil)
+	groceries := createCategory(t, repo, alice, "Groceries", &food.ID)
+	produce := createCategory(t, repo, alice, "Produce", &groceries.ID)
+	createCategory(t, repo, alice, "Rent", nil)
+
+	date := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	createTransaction(t, repo, alice, produce.ID, 12.25, date, models.StatusCleared)
+	createTransaction(t, repo, alice, groceries.ID, 7.5, date, models.StatusCleared)
+	createTransaction(t, repo, alice, food.ID, 0.25, date.AddDate(0, 1, 0), models.StatusCleared)
+
+	tests := []struct {
+		name       string
+		maxDepth   int
+		withStats  bool
+		start, end *time.Time
+		want       string // Tree as name(children), with the subtree expense total when withStats
+	}{
+		{"whole tree", 0, false, nil, nil, "[Food(Groceries(Produce)) Rent]"},
+		{"one level", 1, false, nil, nil, "[Food Rent]"},
+		{"two levels", 2, false, nil, nil, "[Food(Groceries) Rent]"},
+		{"with stats", 0, true, nil, nil, "[Food=20(Groceries=19.75(Produce=12.25)) Rent=0]"},
+		{"stats in May", 0, true, &date, &date, "[Food=19.75(Groceries=19.75(Produce=12.25)) Rent=0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, err := service.GetCategoryTree(context.Background(), alice.ID, 0, tt.maxDepth, tt.withStats, tt.start, tt.end)
+			assertErrorType(t, err, "")
+			if got := formatCategoryTree(roots); got != tt.want {
+				t.Errorf("tree = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// formatCategoryTree renders category nodes for comparison in tests
+func formatCategoryTree(nodes []*models.CategoryNode) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = node.Name
+		if node.TotalStats != nil {
+			parts[i] += fmt.Sprintf("=%g", node.TotalStats.ExpenseTotal)
+		}
+		if len(node.Children) > 0 {
+			children := formatCategoryTree(node.Children)
+			parts[i] += "(" + children[1:len(children)-1] + ")"
+		}
+	}
+	return fmt.Sprint(parts)
+}
+
+func TestCategoryServiceApplyCategoryTemplate(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewCategoryService(repo, testTemplates(t))
+	alice := registerUser(t, repo, "alice")
+	createCategory(t, repo, alice, "Groceries", nil)
+
+	tests := []struct {
+		name        string
+		template    string
+		wantErr     appErrors.ErrorType
+		wantCreated int
+	}{
+		{"first time", "basic", "", 8}, // Groceries already exists
+		{"again", "basic", "", 0},
+		{"unknown template", "nonexistent", appErrors.TypeNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, err := service.ApplyCategoryTemplate(context.Background(), alice.ID, 0, tt.template)
+			assertErrorType(t, err, tt.wantErr)
+			if len(created) != tt.wantCreated {
+				t.Errorf("created %d categories, want %d", len(created), tt.wantCreated)
+			}
+		})
+	}
+
+	categories, err := service.GetCategories(context.Background(), alice.ID, 0, 0, 0, nil)
+	assertErrorType(t, err, "")
+	if len(categories) != 9 {
+		t.Errorf("household has %d categories, want 9", len(categories))
+	}
+}
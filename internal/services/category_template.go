@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/templates"
+	"strings"
+)
+
+// applyCategoryTemplate creates the categories of a template in a household on behalf of a user,
+// skipping any whose name the household already has (ignoring case). Existing categories are reused as parents for the
+// template's subcategories. It returns only the newly created categories.
+func applyCategoryTemplate(ctx context.Context, repo repository.Repository, userID, householdID uint, template *templates.CategoryTemplate) ([]models.Category, error) {
+	existing, err := repo.GetCategories(ctx, householdID, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]uint, len(existing))
+	for _, category := range existing {
+		byName[strings.ToLower(category.Name)] = category.ID
+	}
+
+	created := []models.Category{}
+	var apply func(nodes []templates.CategoryTemplateNode, parentID *uint) error
+	apply = func(nodes []templates.CategoryTemplateNode, parentID *uint) error {
+		for _, node := range nodes {
+			name := strings.TrimSpace(node.Name)
+			id, exists := byName[strings.ToLower(name)]
+			if !exists {
+				category := models.Category{
+					Name:        name,
+					ParentID:    parentID,
+					HouseholdID: householdID,
+					UserID:      userID,
+				}
+				if err := repo.CreateCategory(ctx, &category); err != nil {
+					return err
+				}
+				if err := recordAudit(ctx, repo, userID, householdID, models.AuditActionCreate, models.AuditEntityCategory, category.ID, auditDiff(nil, &category)); err != nil {
+					return err
+				}
+				id = category.ID
+				byName[strings.ToLower(name)] = id
+				created = append(created, category)
+			}
+
+			if err := apply(node.Children, &id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := apply(template.Categories, nil); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
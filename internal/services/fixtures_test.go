@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/templates"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword satisfies the password policy of newTestUserService
+const testPassword = "correct horse battery"
+
+// testTemplates loads the built-in category templates
+func testTemplates(t *testing.T) *templates.Registry {
+	t.Helper()
+	registry, err := templates.Builtin()
+	if err != nil {
+		t.Fatalf("load category templates: %v", err)
+	}
+	return registry
+}
+
+// newTestUserService creates a user service on repo that hashes with the cheapest bcrypt cost.
+// A nil notifier logs messages.
+func newTestUserService(t *testing.T, repo repository.Repository, limiter *LoginLimiter, notifier notify.Notifier, opts UserServiceOptions) UserService {
+	t.Helper()
+	bcryptAlgorithm, err := auth.NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.PasswordHasher = auth.NewPasswordHasher(bcryptAlgorithm)
+	opts.PasswordPolicy = auth.PasswordPolicy{MinLength: 12, MaxLength: 128}
+	if opts.PasswordResetTTL == 0 {
+		opts.PasswordResetTTL = time.Hour
+	}
+	if notifier == nil {
+		notifier = notify.NewLogNotifier()
+	}
+	return NewUserService(repo, testTemplates(t), limiter, notifier, opts)
+}
+
+// recordingNotifier keeps the messages sent through it
+type recordingNotifier struct {
+	messages []notify.Message
+}
+
+// Send records msg
+func (n *recordingNotifier) Send(ctx context.Context, msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// registerUser registers a user with testPassword, which also creates their personal household
+func registerUser(t *testing.T, repo repository.Repository, username string) *models.User {
+	t.Helper()
+	user, err := newTestUserService(t, repo, nil, nil, UserServiceOptions{}).RegisterUser(context.Background(), username, testPassword, "")
+	if err != nil {
+		t.Fatalf("RegisterUser(%s): %v", username, err)
+	}
+	return user
+}
+
+// addMember adds a user to a household with a role
+func addMember(t *testing.T, repo repository.Repository, householdID, userID uint, role models.HouseholdRole) {
+	t.Helper()
+	member := &models.HouseholdMember{HouseholdID: householdID, UserID: userID, Role: role}
+	if err := repo.AddHouseholdMember(context.Background(), member); err != nil {
+		t.Fatalf("AddHouseholdMember: %v", err)
+	}
+}
+
+// createCategory adds a category to the default household of user
+func createCategory(t *testing.T, repo repository.Repository, user *models.User, name string, parentID *uint) *models.Category {
+	t.Helper()
+	category := &models.Category{Name: name, ParentID: parentID, HouseholdID: *user.DefaultHouseholdID, UserID: user.ID}
+	if err := repo.CreateCategory(context.Background(), category); err != nil {
+		t.Fatalf("CreateCategory(%s): %v", name, err)
+	}
+	return category
+}
+
+// createTransaction adds a transaction to the default household of user
+func createTransaction(t *testing.T, repo repository.Repository, user *models.User, categoryID uint, amount float64, date time.Time, status models.TransactionStatus) *models.Transaction {
+	t.Helper()
+	transaction := &models.Transaction{
+		Description: "Test transaction", Amount: amount, Type: models.Expense, Date: date, Status: status,
+		CategoryID: categoryID, HouseholdID: *user.DefaultHouseholdID, UserID: user.ID,
+	}
+	if err := repo.CreateTransaction(context.Background(), transaction); err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	return transaction
+}
+
+// assertErrorType fails the test unless err has the wanted type; an empty type expects no error
+func assertErrorType(t *testing.T, err error, want appErrors.ErrorType) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if got := appErrors.GetType(err); got != want {
+		t.Fatalf("error type = %q (%v), want %q", got, err, want)
+	}
+}
+
+// auditActions returns the actions recorded for an entity, oldest first
+func auditActions(t *testing.T, repo repository.Repository, entityType string, entityID uint) []string {
+	t.Helper()
+	entries, err := repo.GetAuditEntriesAfter(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("GetAuditEntriesAfter: %v", err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		if entry.EntityType == entityType && entry.EntityID == entityID {
+			actions = append(actions, entry.Action)
+		}
+	}
+	return actions
+}
+
+// lastAuditChanges returns the changes of the latest audit entry of an entity, with JSON numbers
+// decoded as float64
+func lastAuditChanges(t *testing.T, repo repository.Repository, entityType string, entityID uint) map[string]models.AuditChange {
+	t.Helper()
+	entries, err := repo.GetAuditEntriesAfter(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("GetAuditEntriesAfter: %v", err)
+	}
+	var changes map[string]models.AuditChange
+	for _, entry := range entries {
+		if entry.EntityType == entityType && entry.EntityID == entityID {
+			changes = nil
+			if err := json.Unmarshal(entry.Changes, &changes); err != nil {
+				t.Fatalf("audit entry %d changes: %v", entry.ID, err)
+			}
+		}
+	}
+	return changes
+}
+
+func uintPtr(id uint) *uint {
+	return &id
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+// householdAccess authorises users against the households that own categories, transactions
+// and reconciliations. It is embedded by every service working on household data.
+type householdAccess struct {
+	repo repository.Repository
+}
+
+// authorize checks that the user is a member of the household with at least the required role
+// and returns the household ID to scope queries by. Household 0 stands for the user's default
+// household. Households the user is not a member of are reported as not found, so their
+// existence is not disclosed.
+func (a householdAccess) authorize(ctx context.Context, userID, householdID uint, required models.HouseholdRole) (uint, error) {
+	if householdID == 0 {
+		user, err := a.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		if user.DefaultHouseholdID == nil {
+			return 0, appErrors.NewValidationError("No household selected and the user has no default household", nil)
+		}
+		householdID = *user.DefaultHouseholdID
+	}
+
+	member, err := a.repo.GetHouseholdMember(ctx, householdID, userID)
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return 0, appErrors.NewNotFoundError(fmt.Sprintf("Household with ID %d not found", householdID), nil)
+		}
+		return 0, err
+	}
+	if !member.Role.Includes(required) {
+		return 0, appErrors.NewForbiddenError(fmt.Sprintf("The %s role is required for this action in household %d", required, householdID), nil)
+	}
+	return householdID, nil
+}
+
+// ensureCategoryInHousehold checks that a category referenced by a transaction belongs to the household
+func ensureCategoryInHousehold(ctx context.Context, repo repository.Repository, householdID, categoryID uint) error {
+	if _, err := repo.GetCategoryByID(ctx, householdID, categoryID); err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return appErrors.NewValidationError(fmt.Sprintf("Category with ID %d does not exist in this household", categoryID), nil)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HouseholdService defines the interface for managing households, their members and invitations
+type HouseholdService interface {
+	GetHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error)
+	CreateHousehold(ctx context.Context, userID uint, name string) (*models.Household, error)
+	GetHousehold(ctx context.Context, userID, householdID uint) (*models.Household, error)
+	UpdateHousehold(ctx context.Context, userID, householdID uint, name string) (*models.Household, error)
+	SetDefaultHousehold(ctx context.Context, userID, householdID uint) error
+	GetMembers(ctx context.Context, userID, householdID uint) ([]models.HouseholdMember, error)
+	UpdateMemberRole(ctx context.Context, userID, householdID, memberUserID uint, role models.HouseholdRole) error
+	RemoveMember(ctx context.Context, userID, householdID, memberUserID uint) error
+	CreateInvitation(ctx context.Context, userID, householdID uint, email string, role models.HouseholdRole) (*models.HouseholdInvitation, string, error)
+	GetInvitations(ctx context.Context, userID, householdID uint) ([]models.HouseholdInvitation, error)
+	RevokeInvitation(ctx context.Context, userID, householdID, invitationID uint) error
+	AcceptInvitation(ctx context.Context, userID uint, token string) (*models.HouseholdMember, error)
+}
+
+// HouseholdServiceOptions configures invitations
+type HouseholdServiceOptions struct {
+	InvitationTTL time.Duration // Lifetime of invitation tokens
+	InvitationURL string        // Link sent with invitations; "{token}" is replaced by the token
+}
+
+// householdService implements the HouseholdService interface
+type householdService struct {
+	repo     repository.Repository
+	access   householdAccess
+	notifier notify.Notifier
+	opts     HouseholdServiceOptions
+}
+
+// NewHouseholdService creates a new instance of HouseholdService.
+// Invitations addressed to an email address are delivered through notifier.
+func NewHouseholdService(repo repository.Repository, notifier notify.Notifier, opts HouseholdServiceOptions) HouseholdService {
+	return &householdService{repo: repo, access: householdAccess{repo: repo}, notifier: notifier, opts: opts}
+}
+
+// GetHouseholds lists the households the user is a member of, with the user's role in each
+func (s *householdService) GetHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error) {
+	return s.repo.GetUserHouseholds(ctx, userID)
+}
+
+// CreateHousehold creates a household owned by the user
+func (s *householdService) CreateHousehold(ctx context.Context, userID uint, name string) (*models.Household, error) {
+	household := &models.Household{Name: strings.TrimSpace(name)}
+	err := s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.CreateHousehold(ctx, household); err != nil {
+			return err
+		}
+		return txRepo.AddHouseholdMember(ctx, &models.HouseholdMember{HouseholdID: household.ID, UserID: userID, Role: models.RoleOwner})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+// GetHousehold retrieves a household the user is a member of
+func (s *householdService) GetHousehold(ctx context.Context, userID, householdID uint) (*models.Household, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetHousehold(ctx, householdID)
+}
+
+// UpdateHousehold renames a household
+func (s *householdService) UpdateHousehold(ctx context.Context, userID, householdID uint, name string) (*models.Household, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	household := &models.Household{ID: householdID, Name: strings.TrimSpace(name)}
+	if err := s.repo.UpdateHousehold(ctx, household); err != nil {
+		return nil, err
+	}
+	return s.repo.GetHousehold(ctx, householdID)
+}
+
+// SetDefaultHousehold selects the household used when a request of the user names none
+func (s *householdService) SetDefaultHousehold(ctx context.Context, userID, householdID uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetUserDefaultHousehold(ctx, userID, &householdID)
+}
+
+// GetMembers lists the members of a household
+func (s *householdService) GetMembers(ctx context.Context, userID, householdID uint) ([]models.HouseholdMember, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetHouseholdMembers(ctx, householdID)
+}
+
+// UpdateMemberRole changes the role of a member. A household always keeps at least one owner.
+func (s *householdService) UpdateMemberRole(ctx context.Context, userID, householdID, memberUserID uint, role models.HouseholdRole) error {
+	if !role.IsValid() {
+		return appErrors.NewValidationError(fmt.Sprintf("Invalid role '%s'. Must be 'owner', 'editor' or 'viewer'", role), nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		member, err := txRepo.GetHouseholdMember(ctx, householdID, memberUserID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner && role != models.RoleOwner {
+			if err := ensureAnotherOwner(ctx, txRepo, householdID); err != nil {
+				return err
+			}
+		}
+		return txRepo.UpdateHouseholdMemberRole(ctx, householdID, memberUserID, role)
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":        true,
+		"event":        "household_role_changed",
+		"userID":       userID,
+		"householdID":  householdID,
+		"memberUserID": memberUserID,
+		"role":         role,
+	}).Info("HouseholdService: Member role changed")
+	return nil
+}
+
+// RemoveMember removes a member from a household. Owners may remove anyone and every member
+// may leave; the last owner cannot leave. A removed member whose default household this was
+// falls back to another of their households.
+func (s *householdService) RemoveMember(ctx context.Context, userID, householdID, memberUserID uint) error {
+	required := models.RoleOwner
+	if memberUserID == userID {
+		required = models.RoleViewer
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, required)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		member, err := txRepo.GetHouseholdMember(ctx, householdID, memberUserID)
+		if err != nil {
+			return err
+		}
+		if member.Role == models.RoleOwner {
+			if err := ensureAnotherOwner(ctx, txRepo, householdID); err != nil {
+				return err
+			}
+		}
+		if err := txRepo.RemoveHouseholdMember(ctx, householdID, memberUserID); err != nil {
+			return err
+		}
+
+		user, err := txRepo.GetUserByID(ctx, memberUserID)
+		if err != nil {
+			return err
+		}
+		if user.DefaultHouseholdID == nil || *user.DefaultHouseholdID != householdID {
+			return nil
+		}
+		remaining, err := txRepo.GetUserHouseholds(ctx, memberUserID)
+		if err != nil {
+			return err
+		}
+		var fallback *uint
+		if len(remaining) > 0 {
+			fallback = &remaining[0].HouseholdID
+		}
+		return txRepo.SetUserDefaultHousehold(ctx, memberUserID, fallback)
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":        true,
+		"event":        "household_member_removed",
+		"userID":       userID,
+		"householdID":  householdID,
+		"memberUserID": memberUserID,
+	}).Info("HouseholdService: Member removed")
+	return nil
+}
+
+// CreateInvitation invites someone to a household with the given role. The returned token is
+// shown to the inviter once; when an email address is given the invitation is also sent there
+// and only the user with that address may accept it.
+func (s *householdService) CreateInvitation(ctx context.Context, userID, householdID uint, email string, role models.HouseholdRole) (*models.HouseholdInvitation, string, error) {
+	if !role.IsValid() {
+		return nil, "", appErrors.NewValidationError(fmt.Sprintf("Invalid role '%s'. Must be 'owner', 'editor' or 'viewer'", role), nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleOwner)
+	if err != nil {
+		return nil, "", err
+	}
+	household, err := s.repo.GetHousehold(ctx, householdID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, "", appErrors.NewInternalError("Failed to generate invitation token", err)
+	}
+	invitation := &models.HouseholdInvitation{
+		HouseholdID: householdID,
+		Email:       normalizeEmail(email),
+		Role:        role,
+		TokenHash:   hashToken(token),
+		InvitedByID: userID,
+		ExpiresAt:   time.Now().Add(s.opts.InvitationTTL),
+	}
+	if err := s.repo.CreateHouseholdInvitation(ctx, invitation); err != nil {
+		return nil, "", err
+	}
+
+	if invitation.Email != "" {
+		err = s.notifier.Send(ctx, notify.Message{
+			To:      invitation.Email,
+			Subject: fmt.Sprintf("You have been invited to %s", household.Name),
+			Body:    s.invitationBody(household, invitation, token),
+		})
+		if err != nil {
+			// The inviter still receives the token and can pass it on another way
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":        err.Error(),
+				"householdID":  householdID,
+				"invitationID": invitation.ID,
+			}).Error("HouseholdService: Failed to send invitation message")
+		}
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":        true,
+		"event":        "household_invitation_created",
+		"userID":       userID,
+		"householdID":  householdID,
+		"invitationID": invitation.ID,
+		"role":         role,
+	}).Info("HouseholdService: Invitation created")
+	return invitation, token, nil
+}
+
+// GetInvitations lists the pending invitations of a household
+func (s *householdService) GetInvitations(ctx context.Context, userID, householdID uint) ([]models.HouseholdInvitation, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetHouseholdInvitations(ctx, householdID, time.Now())
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *householdService) RevokeInvitation(ctx context.Context, userID, householdID, invitationID uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	return s.repo.RevokeHouseholdInvitation(ctx, householdID, invitationID, time.Now())
+}
+
+// AcceptInvitation adds the user to the household of an invitation with the invited role
+func (s *householdService) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.HouseholdMember, error) {
+	invitation, err := s.repo.GetHouseholdInvitationByHash(ctx, hashToken(token))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewValidationError("Invalid or expired invitation token", nil)
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || now.After(invitation.ExpiresAt) {
+		return nil, appErrors.NewValidationError("Invalid or expired invitation token", nil)
+	}
+
+	if invitation.Email != "" {
+		user, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Email == nil || normalizeEmail(*user.Email) != invitation.Email {
+			return nil, appErrors.NewForbiddenError("This invitation was sent to a different email address", nil)
+		}
+	}
+
+	member := &models.HouseholdMember{HouseholdID: invitation.HouseholdID, UserID: userID, Role: invitation.Role}
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		accepted, err := txRepo.MarkHouseholdInvitationAccepted(ctx, invitation.ID, userID, now)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return appErrors.NewValidationError("Invalid or expired invitation token", nil)
+		}
+		return txRepo.AddHouseholdMember(ctx, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	member.Household = invitation.Household
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":        true,
+		"event":        "household_member_added",
+		"userID":       userID,
+		"householdID":  invitation.HouseholdID,
+		"invitationID": invitation.ID,
+		"role":         invitation.Role,
+	}).Info("HouseholdService: Invitation accepted")
+	return member, nil
+}
+
+// invitationBody renders the message containing the invitation link or token
+func (s *householdService) invitationBody(household *models.Household, invitation *models.HouseholdInvitation, token string) string {
+	link := token
+	if s.opts.InvitationURL != "" {
+		link = strings.ReplaceAll(s.opts.InvitationURL, "{token}", token)
+	}
+	return fmt.Sprintf("Hello,\n\n"+
+		"You have been invited to join the household %q as %s. Use the following link within %s to accept:\n\n"+
+		"%s\n\n"+
+		"If you were not expecting this invitation, you can ignore this message.\n",
+		household.Name, invitation.Role, s.opts.InvitationTTL, link)
+}
+
+// ensureAnotherOwner rejects changes that would leave a household without an owner
+func ensureAnotherOwner(ctx context.Context, repo repository.Repository, householdID uint) error {
+	owners, err := repo.CountHouseholdOwners(ctx, householdID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return appErrors.NewConflictError("A household must keep at least one owner", nil)
+	}
+	return nil
+}
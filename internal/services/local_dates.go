@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+)
+
+// userLocation returns the time zone of a user, in which dates without a time are interpreted
+func userLocation(ctx context.Context, repo repository.Repository, userID uint) (*time.Location, error) {
+	user, err := repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.Location(), nil
+}
+
+// localDateRange turns the calendar dates of an inclusive date range into the instants it spans
+// in loc: startDate becomes the start of its day and endDate the last microsecond of its day,
+// the precision the database keeps. Either bound may be nil.
+func localDateRange(startDate, endDate *time.Time, loc *time.Location) (*time.Time, *time.Time) {
+	var start, end *time.Time
+	if startDate != nil {
+		dayStart := startOfLocalDay(*startDate, loc)
+		start = &dayStart
+	}
+	if endDate != nil {
+		dayEnd := startOfLocalDay(*endDate, loc).AddDate(0, 0, 1).Add(-time.Microsecond)
+		end = &dayEnd
+	}
+	return start, end
+}
+
+// startOfLocalDay returns midnight in loc of the calendar date of t, taken as written
+func startOfLocalDay(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoginAttemptStore persists failed login counters by key
+type LoginAttemptStore interface {
+	// Get returns the record for key, or nil when no failures are recorded
+	Get(ctx context.Context, key string) (*models.LoginAttempt, error)
+	// RecordFailure atomically counts a failure, restarting the count if the last one was before resetBefore
+	RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginLimiterOptions configures backoff and lockout of failed logins
+type LoginLimiterOptions struct {
+	FreeAttempts             int           // Failures allowed before backoff starts
+	BackoffBase              time.Duration // Delay after the first failure beyond FreeAttempts, doubled for each further failure
+	BackoffMax               time.Duration // Upper bound of the backoff delay
+	UsernameLockoutThreshold int           // Failures for one username that lock it for LockoutDuration
+	IPLockoutThreshold       int           // Failures from one IP that lock it for LockoutDuration
+	LockoutDuration          time.Duration
+	FailureWindow            time.Duration // Failures older than this are forgotten
+}
+
+// LoginLimiter throttles password guessing per username and per client IP
+type LoginLimiter struct {
+	store LoginAttemptStore
+	opts  LoginLimiterOptions
+	now   func() time.Time
+}
+
+// NewLoginLimiter creates a new limiter backed by store
+func NewLoginLimiter(store LoginAttemptStore, opts LoginLimiterOptions) *LoginLimiter {
+	return &LoginLimiter{store: store, opts: opts, now: time.Now}
+}
+
+// Check rejects a login attempt while the username or client IP is backing off or locked out
+func (l *LoginLimiter) Check(ctx context.Context, username, clientIP string) error {
+	now := l.now()
+	var retryAfter time.Duration
+	for _, key := range loginAttemptKeys(username, clientIP) {
+		attempt, err := l.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if attempt != nil && attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			if wait := attempt.LockedUntil.Sub(now); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter > 0 {
+		return appErrors.NewRateLimitedError("Too many failed login attempts; try again later", retryAfter, nil)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the username and the client IP and applies
+// exponential backoff, or a lockout once a threshold is reached
+func (l *LoginLimiter) RecordFailure(ctx context.Context, username, clientIP string) error {
+	now := l.now()
+	for _, key := range loginAttemptKeys(username, clientIP) {
+		attempt, err := l.store.RecordFailure(ctx, key, now, now.Add(-l.opts.FailureWindow))
+		if err != nil {
+			return err
+		}
+
+		threshold := l.opts.UsernameLockoutThreshold
+		if strings.HasPrefix(key, "ip:") {
+			threshold = l.opts.IPLockoutThreshold
+		}
+
+		delay := l.backoff(attempt.Failures)
+		if threshold > 0 && attempt.Failures >= threshold {
+			delay = l.opts.LockoutDuration
+			if attempt.Failures == threshold {
+				logrus.WithContext(ctx).WithFields(logrus.Fields{
+					"audit":       true,
+					"event":       "login_lockout",
+					"key":         key,
+					"failures":    attempt.Failures,
+					"lockedUntil": now.Add(delay),
+				}).Warn("LoginLimiter: Locked out after repeated failed logins")
+			}
+		}
+		if delay <= 0 {
+			continue
+		}
+		if err := l.store.Lock(ctx, key, now.Add(delay)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failures of a username after a successful login. Failures of the
+// client IP are kept, so logging into one account does not reset guessing against others.
+func (l *LoginLimiter) RecordSuccess(ctx context.Context, username string) error {
+	return l.store.Reset(ctx, usernameKey(username))
+}
+
+// Unlock lifts a lockout of a username
+func (l *LoginLimiter) Unlock(ctx context.Context, username string) error {
+	if err := l.store.Reset(ctx, usernameKey(username)); err != nil {
+		return err
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit": true,
+		"event": "login_unlock",
+		"key":   usernameKey(username),
+	}).Info("LoginLimiter: Login lockout lifted")
+	return nil
+}
+
+// PurgeStale removes records that no longer affect any login
+func (l *LoginLimiter) PurgeStale(ctx context.Context) (int64, error) {
+	return l.store.DeleteStale(ctx, l.now().Add(-l.opts.FailureWindow))
+}
+
+// backoff returns the delay imposed after the given number of consecutive failures
+func (l *LoginLimiter) backoff(failures int) time.Duration {
+	excess := failures - l.opts.FreeAttempts
+	if excess <= 0 || l.opts.BackoffBase <= 0 {
+		return 0
+	}
+	delay := l.opts.BackoffBase
+	for i := 1; i < excess && delay < l.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > l.opts.BackoffMax {
+		delay = l.opts.BackoffMax
+	}
+	return delay
+}
+
+// loginAttemptKeys returns the keys a login attempt is counted under
+func loginAttemptKeys(username, clientIP string) []string {
+	keys := []string{usernameKey(username)}
+	if clientIP != "" {
+		keys = append(keys, "ip:"+clientIP)
+	}
+	return keys
+}
+
+// usernameKey returns the key of a username; usernames differing only in case share a counter
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// MemoryLoginAttemptStore keeps failed login counters in process memory.
+// It suits single-instance deployments; counters are lost on restart.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// NewMemoryLoginAttemptStore creates an empty in-memory store
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
+
+// Get returns a copy of the record for key, or nil
+func (s *MemoryLoginAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failure for key
+func (s *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = models.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+// Lock blocks key until the given time
+func (s *MemoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		s.attempts[key] = attempt
+	}
+	return nil
+}
+
+// Reset forgets key
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// DeleteStale removes records whose last failure and lock both ended before the given time
+func (s *MemoryLoginAttemptStore) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// RepositoryLoginAttemptStore keeps failed login counters in the database, shared by all instances
+type RepositoryLoginAttemptStore struct {
+	repo repository.Repository
+}
+
+// NewRepositoryLoginAttemptStore creates a database-backed store
+func NewRepositoryLoginAttemptStore(repo repository.Repository) *RepositoryLoginAttemptStore {
+	return &RepositoryLoginAttemptStore{repo: repo}
+}
+
+// Get returns the record for key, or nil
+func (s *RepositoryLoginAttemptStore) Get(ctx context.Context, key string) (*models.LoginAttempt, error) {
+	attempt, err := s.repo.GetLoginAttempt(ctx, key)
+	if appErrors.IsType(err, appErrors.TypeNotFound) {
+		return nil, nil
+	}
+	return attempt, err
+}
+
+// RecordFailure counts a failure for key
+func (s *RepositoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*models.LoginAttempt, error) {
+	return s.repo.RecordLoginFailure(ctx, key, at, resetBefore)
+}
+
+// Lock blocks key until the given time
+func (s *RepositoryLoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.repo.SetLoginLockedUntil(ctx, key, until)
+}
+
+// Reset forgets key
+func (s *RepositoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.repo.DeleteLoginAttempt(ctx, key)
+}
+
+// DeleteStale removes records whose last failure and lock both ended before the given time
+func (s *RepositoryLoginAttemptStore) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.DeleteStaleLoginAttempts(ctx, before)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+)
+
+// newTestLoginLimiter creates a limiter with an in-memory store and a clock the test advances
+func newTestLoginLimiter(opts LoginLimiterOptions) (*LoginLimiter, *time.Time) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), opts)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+// assertRetryAfter fails the test unless err is a rate limit with the wanted delay; zero expects no error
+func assertRetryAfter(t *testing.T, err error, want time.Duration) {
+	t.Helper()
+	if want == 0 {
+		assertErrorType(t, err, "")
+		return
+	}
+	assertErrorType(t, err, appErrors.TypeRateLimited)
+	if got, _ := appErrors.GetRetryAfter(err); got != want {
+		t.Errorf("retry after %v, want %v", got, want)
+	}
+}
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second}, // Capped at BackoffMax
+		{20, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d failures", tt.failures), func(t *testing.T) {
+			limiter, _ := newTestLoginLimiter(LoginLimiterOptions{
+				FreeAttempts:  2,
+				BackoffBase:   time.Second,
+				BackoffMax:    10 * time.Second,
+				FailureWindow: time.Hour,
+			})
+			ctx := context.Background()
+			for i := 0; i < tt.failures; i++ {
+				assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+			}
+			assertRetryAfter(t, limiter.Check(ctx, "alice", ""), tt.want)
+		})
+	}
+}
+
+func TestLoginLimiterLockout(t *testing.T) {
+	limiter, now := newTestLoginLimiter(LoginLimiterOptions{
+		FreeAttempts:             10,
+		UsernameLockoutThreshold: 3,
+		LockoutDuration:          time.Hour,
+		FailureWindow:            time.Hour,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	}
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), 0)
+	assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), time.Hour)
+	// Usernames differing in case share the lockout
+	assertRetryAfter(t, limiter.Check(ctx, " Alice", ""), time.Hour)
+
+	*now = now.Add(59 * time.Minute)
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), time.Minute)
+	*now = now.Add(time.Minute)
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), 0)
+
+	// Failures keep counting after the lockout, so the next one locks again at once
+	assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), time.Hour)
+	assertErrorType(t, limiter.Unlock(ctx, "alice"), "")
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), 0)
+}
+
+func TestLoginLimiterFailureWindow(t *testing.T) {
+	limiter, now := newTestLoginLimiter(LoginLimiterOptions{
+		FreeAttempts:             10,
+		UsernameLockoutThreshold: 3,
+		LockoutDuration:          time.Hour,
+		FailureWindow:            10 * time.Minute,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	}
+	// The count restarts once the last failure is older than the window
+	*now = now.Add(11 * time.Minute)
+	assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), 0)
+
+	// Failures within the window add up
+	*now = now.Add(9 * time.Minute)
+	assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	assertErrorType(t, limiter.RecordFailure(ctx, "alice", ""), "")
+	assertRetryAfter(t, limiter.Check(ctx, "alice", ""), time.Hour)
+
+	*now = now.Add(2 * time.Hour)
+	deleted, err := limiter.PurgeStale(ctx)
+	assertErrorType(t, err, "")
+	if deleted != 1 {
+		t.Errorf("purged %d records, want 1", deleted)
+	}
+}
+
+func TestLoginLimiterKeys(t *testing.T) {
+	limiter, _ := newTestLoginLimiter(LoginLimiterOptions{
+		FreeAttempts:             10,
+		UsernameLockoutThreshold: 3,
+		IPLockoutThreshold:       5,
+		LockoutDuration:          time.Hour,
+		FailureWindow:            time.Hour,
+	})
+	ctx := context.Background()
+
+	// Guessing one password each for many accounts locks the client IP, not the accounts
+	for _, username := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		assertErrorType(t, limiter.RecordFailure(ctx, username, "192.0.2.1"), "")
+	}
+	tests := []struct {
+		name     string
+		username string
+		clientIP string
+		want     time.Duration
+	}{
+		{"attacked account from another IP", "alice", "198.51.100.1", 0},
+		{"other account from the locked IP", "frank", "192.0.2.1", time.Hour},
+		{"account without IP", "alice", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRetryAfter(t, limiter.Check(ctx, tt.username, tt.clientIP), tt.want)
+		})
+	}
+
+	// A successful login clears the account's failures but not those of the IP
+	for i := 0; i < 2; i++ {
+		assertErrorType(t, limiter.RecordFailure(ctx, "grace", "198.51.100.2"), "")
+	}
+	assertErrorType(t, limiter.RecordSuccess(ctx, "grace"), "")
+	assertErrorType(t, limiter.RecordFailure(ctx, "grace", "198.51.100.2"), "")
+	assertRetryAfter(t, limiter.Check(ctx, "grace", "198.51.100.3"), 0)
+	for i := 0; i < 2; i++ {
+		assertErrorType(t, limiter.RecordFailure(ctx, "heidi", "198.51.100.2"), "")
+	}
+	assertRetryAfter(t, limiter.Check(ctx, "ivan", "198.51.100.2"), time.Hour)
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+)
+
+// ReconciliationService defines the interface for bank reconciliation business logic
+type ReconciliationService interface {
+	StartReconciliation(ctx context.Context, userID, householdID uint, statementEndDate time.Time, closingBalance float64) (*models.Reconciliation, error)
+	GetReconciliations(ctx context.Context, userID, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error)
+	GetReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error)
+	CompleteReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error)
+	CancelReconciliation(ctx context.Context, userID, householdID uint, id uint) error
+}
+
+// reconciliationService implements the ReconciliationService interface
+type reconciliationService struct {
+	repo   repository.Repository
+	access householdAccess
+}
+
+// NewReconciliationService creates a new instance of ReconciliationService
+func NewReconciliationService(repo repository.Repository) ReconciliationService {
+	return &reconciliationService{repo: repo, access: householdAccess{repo: repo}}
+}
+
+// StartReconciliation opens a reconciliation session against a bank statement.
+// Only one session may be open per household at a time; the database enforces this as well, so
+// that concurrent requests cannot both open one.
+func (s *reconciliationService) StartReconciliation(ctx context.Context, userID, householdID uint, statementEndDate time.Time, closingBalance float64) (*models.Reconciliation, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation := &models.Reconciliation{
+		StatementEndDate: statementEndDate,
+		ClosingBalance:   roundCents(closingBalance),
+		Status:           models.ReconciliationOpen,
+		HouseholdID:      householdID,
+		UserID:           userID,
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		openStatus := models.ReconciliationOpen
+		open, err := txRepo.GetReconciliations(ctx, householdID, 1, 0, &openStatus)
+		if err != nil {
+			return err
+		}
+		if len(open) > 0 {
+			return appErrors.NewConflictError(fmt.Sprintf("Reconciliation with ID %d is already open", open[0].ID), nil)
+		}
+
+		if err := s.refreshBalances(ctx, txRepo, reconciliation, loc); err != nil {
+			return err
+		}
+		return txRepo.CreateReconciliation(ctx, reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reconciliation, nil
+}
+
+// GetReconciliations retrieves a list of reconciliation sessions
+func (s *reconciliationService) GetReconciliations(ctx context.Context, userID, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetReconciliations(ctx, householdID, limit, offset, status)
+}
+
+// GetReconciliation retrieves a reconciliation session.
+// Open sessions have their cleared balance and difference recalculated from current data.
+func (s *reconciliationService) GetReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if reconciliation.Status == models.ReconciliationOpen {
+		loc, err := userLocation(ctx, s.repo, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.refreshBalances(ctx, s.repo, reconciliation, loc); err != nil {
+			return nil, err
+		}
+	}
+	return reconciliation, nil
+}
+
+// CompleteReconciliation locks all cleared transactions up to the end of the statement end date
+// in the user's time zone.
+// The session can only be completed once the difference to the closing balance is zero.
+func (s *reconciliationService) CompleteReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	var reconciliation *models.Reconciliation
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		var err error
+		reconciliation, err = txRepo.GetReconciliationByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if reconciliation.Status != models.ReconciliationOpen {
+			return appErrors.NewConflictError(fmt.Sprintf("Reconciliation with ID %d is already completed", id), nil)
+		}
+
+		if err := s.refreshBalances(ctx, txRepo, reconciliation, loc); err != nil {
+			return err
+		}
+		if reconciliation.Difference != 0 {
+			return appErrors.NewValidationError(fmt.Sprintf("Cleared balance differs from the statement closing balance by %.2f", reconciliation.Difference), nil)
+		}
+
+		if _, err := txRepo.MarkTransactionsReconciled(ctx, householdID, reconciliation.ID, statementCutoff(reconciliation.StatementEndDate, loc)); err != nil {
+			return err
+		}
+
+		completedAt := time.Now()
+		reconciliation.Status = models.ReconciliationCompleted
+		reconciliation.CompletedAt = &completedAt
+		return txRepo.UpdateReconciliation(ctx, reconciliation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reconciliation, nil
+}
+
+// CancelReconciliation discards an open reconciliation session
+func (s *reconciliationService) CancelReconciliation(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		reconciliation, err := txRepo.GetReconciliationByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if reconciliation.Status != models.ReconciliationOpen {
+			return appErrors.NewConflictError(fmt.Sprintf("Reconciliation with ID %d is completed and cannot be cancelled", id), nil)
+		}
+		return txRepo.DeleteReconciliation(ctx, householdID, id)
+	})
+}
+
+// refreshBalances recalculates the cleared balance and difference of a reconciliation session,
+// with the statement end date taken in loc
+func (s *reconciliationService) refreshBalances(ctx context.Context, repo repository.Repository, reconciliation *models.Reconciliation, loc *time.Location) error {
+	cleared, err := repo.GetClearedBalance(ctx, reconciliation.HouseholdID, statementCutoff(reconciliation.StatementEndDate, loc))
+	if err != nil {
+		return err
+	}
+	reconciliation.ClearedBalance = roundCents(cleared)
+	reconciliation.Difference = roundCents(reconciliation.ClosingBalance - reconciliation.ClearedBalance)
+	return nil
+}
+
+// statementCutoff returns the first instant after the statement end date in loc, so that
+// transactions dated on the end date itself are included. The end date is stored as midnight UTC
+// of the calendar date.
+func statementCutoff(statementEndDate time.Time, loc *time.Location) time.Time {
+	return startOfLocalDay(statementEndDate.UTC(), loc).AddDate(0, 0, 1)
+}
+
+// roundCents rounds a monetary amount to two decimal places
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+// statementEnd is the end date of the bank statement the tests reconcile against
+var statementEnd = time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+
+// seedStatementTransactions records transactions around the statement end date, of which the
+// cleared ones up to the end date add up to 949.75
+func seedStatementTransactions(t *testing.T, repo repository.Repository, user *models.User) map[string]*models.Transaction {
+	t.Helper()
+	category := createCategory(t, repo, user, "Groceries", nil)
+	salary := &models.Transaction{
+		Description: "Salary", Amount: 1000, Type: models.Income, Date: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		Status: models.StatusCleared, CategoryID: category.ID, HouseholdID: *user.DefaultHouseholdID, UserID: user.ID,
+	}
+	if err := repo.CreateTransaction(context.Background(), salary); err != nil {
+		t.Fatalf("CreateTransaction: %v", err)
+	}
+	return map[string]*models.Transaction{
+		"salary":        salary,
+		"cleared":       createTransaction(t, repo, user, category.ID, 30, time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), models.StatusCleared),
+		"on end date":   createTransaction(t, repo, user, category.ID, 20.25, time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC), models.StatusCleared),
+		"pending":       createTransaction(t, repo, user, category.ID, 100, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), models.StatusPending),
+		"after the end": createTransaction(t, repo, user, category.ID, 5, time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC), models.StatusCleared),
+	}
+}
+
+func TestReconciliationServiceStartReconciliation(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           models.HouseholdRole // Role of the user starting the session; empty for the owner
+		alreadyOpen    bool
+		closingBalance float64
+		wantErr        appErrors.ErrorType
+		wantDifference float64
+	}{
+		{"matching balance", "", false, 949.75, "", 0},
+		{"differing balance", "", false, 1000, "", 50.25},
+		{"session already open", "", true, 949.75, appErrors.TypeConflict, 0},
+		{"editor", models.RoleEditor, false, 949.75, "", 0},
+		{"viewer", models.RoleViewer, false, 949.75, appErrors.TypeForbidden, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewReconciliationService(repo)
+			alice := registerUser(t, repo, "alice")
+			seedStatementTransactions(t, repo, alice)
+			ctx := context.Background()
+
+			if tt.alreadyOpen {
+				_, err := service.StartReconciliation(ctx, alice.ID, 0, statementEnd, 0)
+				assertErrorType(t, err, "")
+			}
+			userID := alice.ID
+			if tt.role != "" {
+				member := registerUser(t, repo, "member")
+				addMember(t, repo, *alice.DefaultHouseholdID, member.ID, tt.role)
+				userID = member.ID
+			}
+
+			reconciliation, err := service.StartReconciliation(ctx, userID, *alice.DefaultHouseholdID, statementEnd, tt.closingBalance)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			if reconciliation.Status != models.ReconciliationOpen || reconciliation.HouseholdID != *alice.DefaultHouseholdID {
+				t.Errorf("reconciliation = %+v, want an open session in household %d", reconciliation, *alice.DefaultHouseholdID)
+			}
+			if reconciliation.ClearedBalance != 949.75 {
+				t.Errorf("cleared balance = %v, want 949.75", reconciliation.ClearedBalance)
+			}
+			if reconciliation.Difference != tt.wantDifference {
+				t.Errorf("difference = %v, want %v", reconciliation.Difference, tt.wantDifference)
+			}
+		})
+	}
+}
+
+func TestReconciliationServiceGetReconciliationRefreshesOpenSessions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewReconciliationService(repo)
+	transactionService := NewTransactionService(repo)
+	alice := registerUser(t, repo, "alice")
+	transactions := seedStatementTransactions(t, repo, alice)
+	ctx := context.Background()
+
+	started, err := service.StartReconciliation(ctx, alice.ID, 0, statementEnd, 849.75)
+	assertErrorType(t, err, "")
+	if started.Difference != -100 {
+		t.Fatalf("difference = %v, want -100", started.Difference)
+	}
+
+	// Clearing the pending transaction closes the gap to the statement
+	_, err = transactionService.UpdateTransactionStatus(ctx, alice.ID, 0, transactions["pending"].ID, models.StatusCleared)
+	assertErrorType(t, err, "")
+	reconciliation, err := service.GetReconciliation(ctx, alice.ID, 0, started.ID)
+	assertErrorType(t, err, "")
+	if reconciliation.ClearedBalance != 849.75 || reconciliation.Difference != 0 {
+		t.Errorf("cleared balance %v and difference %v, want 849.75 and 0", reconciliation.ClearedBalance, reconciliation.Difference)
+	}
+}
+
+func TestReconciliationServiceCompleteReconciliation(t *testing.T) {
+	tests := []struct {
+		name           string
+		closingBalance float64
+		wantErr        appErrors.ErrorType
+	}{
+		{"matching balance", 949.75, ""},
+		{"differing balance", 1000, appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewReconciliationService(repo)
+			alice := registerUser(t, repo, "alice")
+			transactions := seedStatementTransactions(t, repo, alice)
+			ctx := context.Background()
+
+			started, err := service.StartReconciliation(ctx, alice.ID, 0, statementEnd, tt.closingBalance)
+			assertErrorType(t, err, "")
+			completed, err := service.CompleteReconciliation(ctx, alice.ID, 0, started.ID)
+			assertErrorType(t, err, tt.wantErr)
+
+			wantStatus := map[string]models.TransactionStatus{
+				"salary": models.StatusReconciled, "cleared": models.StatusReconciled, "on end date": models.StatusReconciled,
+				"pending": models.StatusPending, "after the end": models.StatusCleared,
+			}
+			if tt.wantErr != "" {
+				wantStatus = map[string]models.TransactionStatus{
+					"salary": models.StatusCleared, "cleared": models.StatusCleared, "on end date": models.StatusCleared,
+					"pending": models.StatusPending, "after the end": models.StatusCleared,
+				}
+			}
+			for name, want := range wantStatus {
+				transaction, err := repo.GetTransactionByID(ctx, *alice.DefaultHouseholdID, transactions[name].ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if transaction.Status != want {
+					t.Errorf("%s transaction is %s, want %s", name, transaction.Status, want)
+				}
+				if want == models.StatusReconciled && derefUint(transaction.ReconciliationID) != started.ID {
+					t.Errorf("%s transaction belongs to reconciliation %v, want %d", name, transaction.ReconciliationID, started.ID)
+				}
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			if completed.Status != models.ReconciliationCompleted || completed.CompletedAt == nil {
+				t.Errorf("reconciliation = %+v, want a completed session", completed)
+			}
+			_, err = service.CompleteReconciliation(ctx, alice.ID, 0, started.ID)
+			assertErrorType(t, err, appErrors.TypeConflict)
+			assertErrorType(t, service.CancelReconciliation(ctx, alice.ID, 0, started.ID), appErrors.TypeConflict)
+
+			// Reconciled transactions are locked
+			transactionService := NewTransactionService(repo)
+			_, err = transactionService.UpdateTransactionStatus(ctx, alice.ID, 0, transactions["cleared"].ID, models.StatusPending)
+			assertErrorType(t, err, appErrors.TypeConflict)
+			assertErrorType(t, transactionService.DeleteTransaction(ctx, alice.ID, 0, transactions["cleared"].ID), appErrors.TypeConflict)
+
+			// The next statement can be reconciled once this one is completed
+			_, err = service.StartReconciliation(ctx, alice.ID, 0, statementEnd.AddDate(0, 1, 0), 944.75)
+			assertErrorType(t, err, "")
+		})
+	}
+}
+
+func TestReconciliationServiceCancelReconciliation(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewReconciliationService(repo)
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	transactions := seedStatementTransactions(t, repo, alice)
+	ctx := context.Background()
+
+	started, err := service.StartReconciliation(ctx, alice.ID, 0, statementEnd, 949.75)
+	assertErrorType(t, err, "")
+	assertErrorType(t, service.CancelReconciliation(ctx, bob.ID, 0, started.ID), appErrors.TypeNotFound)
+	assertErrorType(t, service.CancelReconciliation(ctx, alice.ID, 0, started.ID), "")
+
+	_, err = service.GetReconciliation(ctx, alice.ID, 0, started.ID)
+	assertErrorType(t, err, appErrors.TypeNotFound)
+	transaction, err := repo.GetTransactionByID(ctx, *alice.DefaultHouseholdID, transactions["cleared"].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Status != models.StatusCleared {
+		t.Errorf("transaction is %s after a cancelled reconciliation, want cleared", transaction.Status)
+	}
+
+	// A cancelled session no longer blocks a new one
+	_, err = service.StartReconciliation(ctx, alice.ID, 0, statementEnd, 949.75)
+	assertErrorType(t, err, "")
+}
+
+func TestReconciliationServiceUsesUserTimeZone(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewReconciliationService(repo)
+	alice := registerUser(t, repo, "alice")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	ctx := context.Background()
+
+	// March 31 at 20:00 UTC is already April 1 in Auckland; April 1 at 02:00 UTC is still
+	// March 31 in New York
+	createTransaction(t, repo, alice, groceries.ID, 10, time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC), models.StatusCleared)
+	createTransaction(t, repo, alice, groceries.ID, 20, time.Date(2026, 4, 1, 2, 0, 0, 0, time.UTC), models.StatusCleared)
+
+	tests := []struct {
+		timezone string
+		want     float64
+	}{
+		{"UTC", -10},
+		{"America/New_York", -30},
+		{"Pacific/Auckland", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			user, err := repo.GetUserByID(ctx, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user.Timezone = tt.timezone
+			if err := repo.UpdateUserProfile(ctx, user); err != nil {
+				t.Fatal(err)
+			}
+
+			started, err := service.StartReconciliation(ctx, alice.ID, 0, statementEnd, tt.want)
+			assertErrorType(t, err, "")
+			if started.ClearedBalance != tt.want {
+				t.Errorf("cleared balance = %v, want %v", started.ClearedBalance, tt.want)
+			}
+			assertErrorType(t, service.CancelReconciliation(ctx, alice.ID, 0, started.ID), "")
+		})
+	}
+}
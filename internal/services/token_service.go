@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"personal-finance-tracker-api/internal/auth"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// TokenPair is the set of credentials issued on login and on every refresh
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// AccessClaims holds the validated claims of an access token
+type AccessClaims struct {
+	UserID    uint
+	Username  string
+	TokenID   string // jti, used for revocation
+	ExpiresAt time.Time
+	Raw       jwt.MapClaims
+}
+
+// TokenService defines the interface for issuing, refreshing and revoking authentication tokens
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *models.User) (*TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error)
+	ValidateAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error)
+	Logout(ctx context.Context, claims *AccessClaims, refreshToken string, allSessions bool) error
+	PurgeExpired(ctx context.Context, expiredBefore time.Time) (int64, error)
+}
+
+// tokenService implements the TokenService interface
+type tokenService struct {
+	repo       repository.Repository
+	keys       *auth.KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenService creates a new instance of TokenService.
+// Access tokens are JWTs signed with the active key of keys; refresh tokens are opaque random strings.
+func NewTokenService(repo repository.Repository, keys *auth.KeySet, accessTTL, refreshTTL time.Duration) TokenService {
+	return &tokenService{
+		repo:       repo,
+		keys:       keys,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssueTokens starts a new token family for a freshly authenticated user
+func (s *tokenService) IssueTokens(ctx context.Context, user *models.User) (*TokenPair, error) {
+	familyID, err := generateToken(16)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate token family", err)
+	}
+	return s.issue(ctx, s.repo, user, familyID)
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair.
+// Each refresh token can be used once; presenting a used or revoked token is treated as
+// theft and revokes every token in its family, forcing the user to log in again.
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	stored, err := s.repo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid refresh token", nil)
+		}
+		return nil, err
+	}
+
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		s.revokeFamily(ctx, stored)
+		return nil, appErrors.NewUnauthorizedError("Refresh token has already been used or revoked", nil)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, appErrors.NewUnauthorizedError("Refresh token has expired", nil)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid refresh token", nil)
+		}
+		return nil, err
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		marked, err := txRepo.MarkRefreshTokenUsed(ctx, stored.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !marked {
+			// Lost a race against another refresh with the same token
+			return appErrors.NewUnauthorizedError("Refresh token has already been used or revoked", nil)
+		}
+		pair, err = s.issue(ctx, txRepo, user, stored.FamilyID)
+		return err
+	})
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeUnauthorized) {
+			s.revokeFamily(ctx, stored)
+		}
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// ValidateAccessToken parses an access token and checks it against the revocation denylist
+func (s *tokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	token, err := jwt.Parse(tokenString, s.keys.Keyfunc,
+		jwt.WithValidMethods(s.keys.ValidMethods()), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, appErrors.NewUnauthorizedError("Invalid or expired authentication token", err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, appErrors.NewUnauthorizedError("Invalid authentication token claims", nil)
+	}
+
+	claims := &AccessClaims{Raw: mapClaims}
+	userID, okUserID := mapClaims["userID"].(float64)
+	claims.TokenID, ok = mapClaims["jti"].(string)
+	if !okUserID || !ok || claims.TokenID == "" {
+		return nil, appErrors.NewUnauthorizedError("Invalid authentication token claims", nil)
+	}
+	claims.UserID = uint(userID)
+	claims.Username, _ = mapClaims["username"].(string)
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+
+	revoked, err := s.repo.IsAccessTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, appErrors.NewUnauthorizedError("Authentication token has been revoked", nil)
+	}
+
+	// Tokens issued before the user's sessions were revoked, e.g. by a password change, are rejected
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Authentication token user no longer exists", nil)
+		}
+		return nil, err
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+	if user.SessionsRevokedAt != nil {
+		// Compared in milliseconds, the precision of iat; a token issued in the same millisecond
+		// as the revocation is treated as issued before it
+		issuedAt, ok := issuedAtMilli(mapClaims)
+		if !ok || !issuedAt.After(user.SessionsRevokedAt.Truncate(time.Millisecond)) {
+			return nil, appErrors.NewUnauthorizedError("Authentication token has been revoked", nil)
+		}
+	}
+
+	return claims, nil
+}
+
+// Logout revokes the presented access token and the refresh token family it belongs to.
+// With allSessions every refresh token and every access token issued so far to the user is revoked.
+func (s *tokenService) Logout(ctx context.Context, claims *AccessClaims, refreshToken string, allSessions bool) error {
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		err := txRepo.RevokeAccessToken(ctx, &models.RevokedToken{
+			JTI:       claims.TokenID,
+			UserID:    claims.UserID,
+			ExpiresAt: claims.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		if allSessions {
+			if err := txRepo.RevokeUserSessions(ctx, claims.UserID, time.Now()); err != nil {
+				return err
+			}
+			_, err := txRepo.RevokeUserRefreshTokens(ctx, claims.UserID)
+			return err
+		}
+
+		if refreshToken == "" {
+			return nil
+		}
+		stored, err := txRepo.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+		if err != nil {
+			if appErrors.IsType(err, appErrors.TypeNotFound) {
+				return nil // Nothing to revoke; logging out must not fail on a stale token
+			}
+			return err
+		}
+		if stored.UserID != claims.UserID {
+			return appErrors.NewForbiddenError("Refresh token belongs to a different user", nil)
+		}
+		_, err = txRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+		return err
+	})
+}
+
+// PurgeExpired removes refresh tokens and denylist entries that can no longer be used
+func (s *tokenService) PurgeExpired(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return s.repo.DeleteExpiredTokens(ctx, expiredBefore)
+}
+
+// issuedAtMilli reads the iat claim with the millisecond precision it is issued with. jwt's
+// GetIssuedAt rounds it to whole seconds.
+func issuedAtMilli(claims jwt.MapClaims) (time.Time, bool) {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(math.Round(iat * 1000))), true
+}
+
+// issue signs a new access token and stores a new refresh token in the given family
+func (s *tokenService) issue(ctx context.Context, repo repository.Repository, user *models.User, familyID string) (*TokenPair, error) {
+	now := time.Now()
+	jti, err := generateToken(16)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate token ID", err)
+	}
+
+	pair := &TokenPair{
+		AccessExpiresAt:  now.Add(s.accessTTL),
+		RefreshExpiresAt: now.Add(s.refreshTTL),
+	}
+
+	claims := jwt.MapClaims{
+		"authorized": true,
+		"userID":     user.ID,
+		"username":   user.Username,
+		"jti":        jti,
+		"iat":        float64(now.UnixMilli()) / 1000, // Fractional so that revocations within a second apply
+		"exp":        pair.AccessExpiresAt.Unix(),
+	}
+	pair.AccessToken, err = s.keys.Sign(claims)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to sign access token", err)
+	}
+
+	pair.RefreshToken, err = generateToken(32)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate refresh token", err)
+	}
+	err = repo.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(pair.RefreshToken),
+		ExpiresAt: pair.RefreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// revokeFamily revokes a compromised token family; failures are logged since the caller already rejects the request
+func (s *tokenService) revokeFamily(ctx context.Context, token *models.RefreshToken) {
+	revoked, err := s.repo.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error":    err.Error(),
+			"userID":   token.UserID,
+			"familyID": token.FamilyID,
+		}).Error("TokenService: Failed to revoke refresh token family after reuse")
+		return
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID":   token.UserID,
+		"familyID": token.FamilyID,
+		"revoked":  revoked,
+	}).Warn("TokenService: Refresh token reuse detected, token family revoked")
+}
+
+// generateToken returns a URL-safe random string with n bytes of entropy
+func generateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 digest under which an opaque token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
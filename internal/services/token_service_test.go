@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+func TestTokenServiceSessionRevocation(t *testing.T) {
+	tests := []struct {
+		name      string
+		revokedAt time.Duration // Relative to the token's issue time
+		wantErr   appErrors.ErrorType
+	}{
+		{"revoked before issue", -time.Millisecond, ""},
+		{"revoked in the same millisecond", 0, appErrors.TypeUnauthorized},
+		{"revoked later in the same second", 300 * time.Millisecond, appErrors.TypeUnauthorized},
+		{"revoked a second later", time.Second, appErrors.TypeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			keys, err := auth.LoadKeySet(auth.KeySetConfig{HMACSecret: "token-service-test-secret"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			service := NewTokenService(repo, keys, 15*time.Minute, 24*time.Hour)
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+
+			pair, err := service.IssueTokens(ctx, alice)
+			assertErrorType(t, err, "")
+			claims, err := service.ValidateAccessToken(ctx, pair.AccessToken)
+			assertErrorType(t, err, "")
+			issuedAt, ok := issuedAtMilli(claims.Raw)
+			if !ok {
+				t.Fatalf("iat claim = %v, want a number", claims.Raw["iat"])
+			}
+
+			assertErrorType(t, repo.RevokeUserSessions(ctx, alice.ID, issuedAt.Add(tt.revokedAt)), "")
+			_, err = service.ValidateAccessToken(ctx, pair.AccessToken)
+			assertErrorType(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTokenServiceLogoutAllSessions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	keys, err := auth.LoadKeySet(auth.KeySetConfig{HMACSecret: "token-service-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewTokenService(repo, keys, 15*time.Minute, 24*time.Hour)
+	alice := registerUser(t, repo, "alice")
+	ctx := context.Background()
+
+	// Both tokens are issued and revoked within the same second
+	current, err := service.IssueTokens(ctx, alice)
+	assertErrorType(t, err, "")
+	other, err := service.IssueTokens(ctx, alice)
+	assertErrorType(t, err, "")
+	claims, err := service.ValidateAccessToken(ctx, current.AccessToken)
+	assertErrorType(t, err, "")
+	assertErrorType(t, service.Logout(ctx, claims, current.RefreshToken, true), "")
+
+	_, err = service.ValidateAccessToken(ctx, other.AccessToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	_, err = service.RefreshTokens(ctx, other.RefreshToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+
+	time.Sleep(2 * time.Millisecond)
+	fresh, err := service.IssueTokens(ctx, alice)
+	assertErrorType(t, err, "")
+	_, err = service.ValidateAccessToken(ctx, fresh.AccessToken)
+	assertErrorType(t, err, "")
+}
@@ -0,0 +1,37 @@
+package services
+
+// The traced decorators of the services, which record a span named after the interface and
+// method, e.g. TransactionService.CreateTransaction, for every call that takes a context, are
+// generated from the interfaces. Run go generate after changing a service interface.
+//go:generate go run ../tools/tracegen -output tracing_gen.go
+
+import (
+	"context"
+	appErrors "personal-finance-tracker-api/internal/errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of service calls. It uses the global tracer provider, which is a
+// no-op until tracing is configured.
+var tracer = otel.Tracer("personal-finance-tracker-api/internal/services")
+
+// startSpan starts the span of a service call
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name)
+}
+
+// endSpan ends the span of a service call. Internal errors mark the span as failed; errors
+// caused by the request, such as validation errors, are only recorded as events.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		switch appErrors.GetType(err) {
+		case appErrors.TypeInternal, "":
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,678 @@
+// Code generated by tracegen; DO NOT EDIT.
+
+package services
+
+import (
+	"context"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/templates"
+	"time"
+)
+
+// tracedAPITokenService records a span for every call of an APITokenService
+type tracedAPITokenService struct {
+	next APITokenService
+}
+
+// TraceAPITokenService wraps service so that every call of it is traced
+func TraceAPITokenService(service APITokenService) APITokenService {
+	return &tracedAPITokenService{next: service}
+}
+
+func (s *tracedAPITokenService) CreateToken(ctx context.Context, userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIToken, string, error) {
+	ctx, span := startSpan(ctx, "APITokenService.CreateToken")
+	r0, r1, err := s.next.CreateToken(ctx, userID, name, scopes, expiresAt)
+	endSpan(span, err)
+	return r0, r1, err
+}
+
+func (s *tracedAPITokenService) GetTokens(ctx context.Context, userID uint) ([]models.APIToken, error) {
+	ctx, span := startSpan(ctx, "APITokenService.GetTokens")
+	r0, err := s.next.GetTokens(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedAPITokenService) RevokeToken(ctx context.Context, userID, id uint) error {
+	ctx, span := startSpan(ctx, "APITokenService.RevokeToken")
+	err := s.next.RevokeToken(ctx, userID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedAPITokenService) AuthenticateToken(ctx context.Context, token string) (*models.APIToken, error) {
+	ctx, span := startSpan(ctx, "APITokenService.AuthenticateToken")
+	r0, err := s.next.AuthenticateToken(ctx, token)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedAuditService records a span for every call of an AuditService
+type tracedAuditService struct {
+	next AuditService
+}
+
+// TraceAuditService wraps service so that every call of it is traced
+func TraceAuditService(service AuditService) AuditService {
+	return &tracedAuditService{next: service}
+}
+
+func (s *tracedAuditService) GetEntries(ctx context.Context, userID, householdID uint, filter models.AuditFilter, limit, offset int) ([]models.AuditEntry, error) {
+	ctx, span := startSpan(ctx, "AuditService.GetEntries")
+	r0, err := s.next.GetEntries(ctx, userID, householdID, filter, limit, offset)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedAuditService) VerifyChain(ctx context.Context) (*models.AuditVerification, error) {
+	ctx, span := startSpan(ctx, "AuditService.VerifyChain")
+	r0, err := s.next.VerifyChain(ctx)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedCategoryService records a span for every call of a CategoryService
+type tracedCategoryService struct {
+	next CategoryService
+}
+
+// TraceCategoryService wraps service so that every call of it is traced
+func TraceCategoryService(service CategoryService) CategoryService {
+	return &tracedCategoryService{next: service}
+}
+
+func (s *tracedCategoryService) CreateCategory(ctx context.Context, userID, householdID uint, category *models.Category) (*models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.CreateCategory")
+	r0, err := s.next.CreateCategory(ctx, userID, householdID, category)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) GetCategories(ctx context.Context, userID, householdID uint, limit, offset int, name *string) ([]models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.GetCategories")
+	r0, err := s.next.GetCategories(ctx, userID, householdID, limit, offset, name)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) GetCategoryTree(ctx context.Context, userID, householdID uint, maxDepth int, withStats bool, startDate, endDate *time.Time) ([]*models.CategoryNode, error) {
+	ctx, span := startSpan(ctx, "CategoryService.GetCategoryTree")
+	r0, err := s.next.GetCategoryTree(ctx, userID, householdID, maxDepth, withStats, startDate, endDate)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) GetCategory(ctx context.Context, userID, householdID uint, id uint) (*models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.GetCategory")
+	r0, err := s.next.GetCategory(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) UpdateCategory(ctx context.Context, userID, householdID uint, id uint, name string) (*models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.UpdateCategory")
+	r0, err := s.next.UpdateCategory(ctx, userID, householdID, id, name)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) MoveCategory(ctx context.Context, userID, householdID uint, id uint, parentID *uint) (*models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.MoveCategory")
+	r0, err := s.next.MoveCategory(ctx, userID, householdID, id, parentID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) MergeCategories(ctx context.Context, userID, householdID uint, sourceID, targetID uint) (*models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.MergeCategories")
+	r0, err := s.next.MergeCategories(ctx, userID, householdID, sourceID, targetID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedCategoryService) DeleteCategory(ctx context.Context, userID, householdID uint, id uint, reassignTo *uint) error {
+	ctx, span := startSpan(ctx, "CategoryService.DeleteCategory")
+	err := s.next.DeleteCategory(ctx, userID, householdID, id, reassignTo)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedCategoryService) GetCategoryTemplates() []*templates.CategoryTemplate {
+	return s.next.GetCategoryTemplates()
+}
+
+func (s *tracedCategoryService) ApplyCategoryTemplate(ctx context.Context, userID, householdID uint, name string) ([]models.Category, error) {
+	ctx, span := startSpan(ctx, "CategoryService.ApplyCategoryTemplate")
+	r0, err := s.next.ApplyCategoryTemplate(ctx, userID, householdID, name)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedHouseholdService records a span for every call of a HouseholdService
+type tracedHouseholdService struct {
+	next HouseholdService
+}
+
+// TraceHouseholdService wraps service so that every call of it is traced
+func TraceHouseholdService(service HouseholdService) HouseholdService {
+	return &tracedHouseholdService{next: service}
+}
+
+func (s *tracedHouseholdService) GetHouseholds(ctx context.Context, userID uint) ([]models.HouseholdMember, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.GetHouseholds")
+	r0, err := s.next.GetHouseholds(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) CreateHousehold(ctx context.Context, userID uint, name string) (*models.Household, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.CreateHousehold")
+	r0, err := s.next.CreateHousehold(ctx, userID, name)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) GetHousehold(ctx context.Context, userID, householdID uint) (*models.Household, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.GetHousehold")
+	r0, err := s.next.GetHousehold(ctx, userID, householdID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) UpdateHousehold(ctx context.Context, userID, householdID uint, name string) (*models.Household, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.UpdateHousehold")
+	r0, err := s.next.UpdateHousehold(ctx, userID, householdID, name)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) SetDefaultHousehold(ctx context.Context, userID, householdID uint) error {
+	ctx, span := startSpan(ctx, "HouseholdService.SetDefaultHousehold")
+	err := s.next.SetDefaultHousehold(ctx, userID, householdID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedHouseholdService) GetMembers(ctx context.Context, userID, householdID uint) ([]models.HouseholdMember, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.GetMembers")
+	r0, err := s.next.GetMembers(ctx, userID, householdID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) UpdateMemberRole(ctx context.Context, userID, householdID, memberUserID uint, role models.HouseholdRole) error {
+	ctx, span := startSpan(ctx, "HouseholdService.UpdateMemberRole")
+	err := s.next.UpdateMemberRole(ctx, userID, householdID, memberUserID, role)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedHouseholdService) RemoveMember(ctx context.Context, userID, householdID, memberUserID uint) error {
+	ctx, span := startSpan(ctx, "HouseholdService.RemoveMember")
+	err := s.next.RemoveMember(ctx, userID, householdID, memberUserID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedHouseholdService) CreateInvitation(ctx context.Context, userID, householdID uint, email string, role models.HouseholdRole) (*models.HouseholdInvitation, string, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.CreateInvitation")
+	r0, r1, err := s.next.CreateInvitation(ctx, userID, householdID, email, role)
+	endSpan(span, err)
+	return r0, r1, err
+}
+
+func (s *tracedHouseholdService) GetInvitations(ctx context.Context, userID, householdID uint) ([]models.HouseholdInvitation, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.GetInvitations")
+	r0, err := s.next.GetInvitations(ctx, userID, householdID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedHouseholdService) RevokeInvitation(ctx context.Context, userID, householdID, invitationID uint) error {
+	ctx, span := startSpan(ctx, "HouseholdService.RevokeInvitation")
+	err := s.next.RevokeInvitation(ctx, userID, householdID, invitationID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedHouseholdService) AcceptInvitation(ctx context.Context, userID uint, token string) (*models.HouseholdMember, error) {
+	ctx, span := startSpan(ctx, "HouseholdService.AcceptInvitation")
+	r0, err := s.next.AcceptInvitation(ctx, userID, token)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedReconciliationService records a span for every call of a ReconciliationService
+type tracedReconciliationService struct {
+	next ReconciliationService
+}
+
+// TraceReconciliationService wraps service so that every call of it is traced
+func TraceReconciliationService(service ReconciliationService) ReconciliationService {
+	return &tracedReconciliationService{next: service}
+}
+
+func (s *tracedReconciliationService) StartReconciliation(ctx context.Context, userID, householdID uint, statementEndDate time.Time, closingBalance float64) (*models.Reconciliation, error) {
+	ctx, span := startSpan(ctx, "ReconciliationService.StartReconciliation")
+	r0, err := s.next.StartReconciliation(ctx, userID, householdID, statementEndDate, closingBalance)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedReconciliationService) GetReconciliations(ctx context.Context, userID, householdID uint, limit, offset int, status *models.ReconciliationStatus) ([]models.Reconciliation, error) {
+	ctx, span := startSpan(ctx, "ReconciliationService.GetReconciliations")
+	r0, err := s.next.GetReconciliations(ctx, userID, householdID, limit, offset, status)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedReconciliationService) GetReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error) {
+	ctx, span := startSpan(ctx, "ReconciliationService.GetReconciliation")
+	r0, err := s.next.GetReconciliation(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedReconciliationService) CompleteReconciliation(ctx context.Context, userID, householdID uint, id uint) (*models.Reconciliation, error) {
+	ctx, span := startSpan(ctx, "ReconciliationService.CompleteReconciliation")
+	r0, err := s.next.CompleteReconciliation(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedReconciliationService) CancelReconciliation(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "ReconciliationService.CancelReconciliation")
+	err := s.next.CancelReconciliation(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+// tracedTokenService records a span for every call of a TokenService
+type tracedTokenService struct {
+	next TokenService
+}
+
+// TraceTokenService wraps service so that every call of it is traced
+func TraceTokenService(service TokenService) TokenService {
+	return &tracedTokenService{next: service}
+}
+
+func (s *tracedTokenService) IssueTokens(ctx context.Context, user *models.User) (*TokenPair, error) {
+	ctx, span := startSpan(ctx, "TokenService.IssueTokens")
+	r0, err := s.next.IssueTokens(ctx, user)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTokenService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	ctx, span := startSpan(ctx, "TokenService.RefreshTokens")
+	r0, err := s.next.RefreshTokens(ctx, refreshToken)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*AccessClaims, error) {
+	ctx, span := startSpan(ctx, "TokenService.ValidateAccessToken")
+	r0, err := s.next.ValidateAccessToken(ctx, tokenString)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTokenService) Logout(ctx context.Context, claims *AccessClaims, refreshToken string, allSessions bool) error {
+	ctx, span := startSpan(ctx, "TokenService.Logout")
+	err := s.next.Logout(ctx, claims, refreshToken, allSessions)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedTokenService) PurgeExpired(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "TokenService.PurgeExpired")
+	r0, err := s.next.PurgeExpired(ctx, expiredBefore)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedTransactionService records a span for every call of a TransactionService
+type tracedTransactionService struct {
+	next TransactionService
+}
+
+// TraceTransactionService wraps service so that every call of it is traced
+func TraceTransactionService(service TransactionService) TransactionService {
+	return &tracedTransactionService{next: service}
+}
+
+func (s *tracedTransactionService) CreateTransaction(ctx context.Context, userID, householdID uint, transaction *models.Transaction) (*models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.CreateTransaction")
+	r0, err := s.next.CreateTransaction(ctx, userID, householdID, transaction)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) GetTransactions(ctx context.Context, userID, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.GetTransactions")
+	r0, err := s.next.GetTransactions(ctx, userID, householdID, limit, offset, startDate, endDate, transactionType, description)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) GetTransaction(ctx context.Context, userID, householdID uint, id uint) (*models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.GetTransaction")
+	r0, err := s.next.GetTransaction(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) UpdateTransaction(ctx context.Context, userID, householdID uint, id uint, update *models.Transaction) (*models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.UpdateTransaction")
+	r0, err := s.next.UpdateTransaction(ctx, userID, householdID, id, update)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) UpdateTransactionStatus(ctx context.Context, userID, householdID uint, id uint, status models.TransactionStatus) (*models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.UpdateTransactionStatus")
+	r0, err := s.next.UpdateTransactionStatus(ctx, userID, householdID, id, status)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) ExportTransactionsCSV(ctx context.Context, userID, householdID uint) ([]models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.ExportTransactionsCSV")
+	r0, err := s.next.ExportTransactionsCSV(ctx, userID, householdID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) ImportTransactions(ctx context.Context, userID, householdID uint, transactions []models.Transaction) ([]models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionService.ImportTransactions")
+	r0, err := s.next.ImportTransactions(ctx, userID, householdID, transactions)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTransactionService) DeleteTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "TransactionService.DeleteTransaction")
+	err := s.next.DeleteTransaction(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+// tracedTrashService records a span for every call of a TrashService
+type tracedTrashService struct {
+	next TrashService
+}
+
+// TraceTrashService wraps service so that every call of it is traced
+func TraceTrashService(service TrashService) TrashService {
+	return &tracedTrashService{next: service}
+}
+
+func (s *tracedTrashService) GetDeletedTransactions(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Transaction, error) {
+	ctx, span := startSpan(ctx, "TrashService.GetDeletedTransactions")
+	r0, err := s.next.GetDeletedTransactions(ctx, userID, householdID, limit, offset)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTrashService) RestoreTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "TrashService.RestoreTransaction")
+	err := s.next.RestoreTransaction(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedTrashService) PurgeTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "TrashService.PurgeTransaction")
+	err := s.next.PurgeTransaction(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedTrashService) GetDeletedCategories(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Category, error) {
+	ctx, span := startSpan(ctx, "TrashService.GetDeletedCategories")
+	r0, err := s.next.GetDeletedCategories(ctx, userID, householdID, limit, offset)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedTrashService) RestoreCategory(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "TrashService.RestoreCategory")
+	err := s.next.RestoreCategory(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedTrashService) PurgeCategory(ctx context.Context, userID, householdID uint, id uint) error {
+	ctx, span := startSpan(ctx, "TrashService.PurgeCategory")
+	err := s.next.PurgeCategory(ctx, userID, householdID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedTrashService) PurgeExpired(ctx context.Context, deletedBefore time.Time) (*PurgeResult, error) {
+	ctx, span := startSpan(ctx, "TrashService.PurgeExpired")
+	r0, err := s.next.PurgeExpired(ctx, deletedBefore)
+	endSpan(span, err)
+	return r0, err
+}
+
+// tracedUserService records a span for every call of an UserService
+type tracedUserService struct {
+	next UserService
+}
+
+// TraceUserService wraps service so that every call of it is traced
+func TraceUserService(service UserService) UserService {
+	return &tracedUserService{next: service}
+}
+
+func (s *tracedUserService) RegisterUser(ctx context.Context, username, password, email string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.RegisterUser")
+	r0, err := s.next.RegisterUser(ctx, username, password, email)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) AuthenticateUser(ctx context.Context, username, password string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.AuthenticateUser")
+	r0, err := s.next.AuthenticateUser(ctx, username, password)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) Login(ctx context.Context, username, password, clientIP string) (*LoginResult, error) {
+	ctx, span := startSpan(ctx, "UserService.Login")
+	r0, err := s.next.Login(ctx, username, password, clientIP)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) UnlockAccount(ctx context.Context, userID uint) error {
+	ctx, span := startSpan(ctx, "UserService.UnlockAccount")
+	err := s.next.UnlockAccount(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) CompleteLoginChallenge(ctx context.Context, challengeToken, code, clientIP string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.CompleteLoginChallenge")
+	r0, err := s.next.CompleteLoginChallenge(ctx, challengeToken, code, clientIP)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	ctx, span := startSpan(ctx, "UserService.GetTwoFactorStatus")
+	r0, err := s.next.GetTwoFactorStatus(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	ctx, span := startSpan(ctx, "UserService.BeginTOTPEnrollment")
+	r0, err := s.next.BeginTOTPEnrollment(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	ctx, span := startSpan(ctx, "UserService.ConfirmTOTPEnrollment")
+	r0, err := s.next.ConfirmTOTPEnrollment(ctx, userID, code)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	ctx, span := startSpan(ctx, "UserService.DisableTOTP")
+	err := s.next.DisableTOTP(ctx, userID, code)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	ctx, span := startSpan(ctx, "UserService.RegenerateRecoveryCodes")
+	r0, err := s.next.RegenerateRecoveryCodes(ctx, userID, code)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword, clientIP string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.ChangePassword")
+	r0, err := s.next.ChangePassword(ctx, userID, currentPassword, newPassword, clientIP)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) RequestPasswordReset(ctx context.Context, identifier string) error {
+	ctx, span := startSpan(ctx, "UserService.RequestPasswordReset")
+	err := s.next.RequestPasswordReset(ctx, identifier)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	ctx, span := startSpan(ctx, "UserService.ResetPassword")
+	err := s.next.ResetPassword(ctx, token, newPassword)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) BeginOIDCLogin(ctx context.Context, linkUserID *uint) (*OIDCAuthorization, error) {
+	ctx, span := startSpan(ctx, "UserService.BeginOIDCLogin")
+	r0, err := s.next.BeginOIDCLogin(ctx, linkUserID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) CompleteOIDCLogin(ctx context.Context, state, code string, sessionUserID *uint) (*OIDCLoginResult, error) {
+	ctx, span := startSpan(ctx, "UserService.CompleteOIDCLogin")
+	r0, err := s.next.CompleteOIDCLogin(ctx, state, code, sessionUserID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	ctx, span := startSpan(ctx, "UserService.GetIdentities")
+	r0, err := s.next.GetIdentities(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) UnlinkIdentity(ctx context.Context, userID, id uint) error {
+	ctx, span := startSpan(ctx, "UserService.UnlinkIdentity")
+	err := s.next.UnlinkIdentity(ctx, userID, id)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) GetProfile(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.GetProfile")
+	r0, err := s.next.GetProfile(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) UpdateProfile(ctx context.Context, userID uint, profile UserProfile) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.UpdateProfile")
+	r0, err := s.next.UpdateProfile(ctx, userID, profile)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) ExportUserData(ctx context.Context, userID uint) (*UserDataExport, error) {
+	ctx, span := startSpan(ctx, "UserService.ExportUserData")
+	r0, err := s.next.ExportUserData(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) RequestAccountDeletion(ctx context.Context, userID uint, mode models.AccountDeletionMode, password string) (*models.AccountDeletion, string, error) {
+	ctx, span := startSpan(ctx, "UserService.RequestAccountDeletion")
+	r0, r1, err := s.next.RequestAccountDeletion(ctx, userID, mode, password)
+	endSpan(span, err)
+	return r0, r1, err
+}
+
+func (s *tracedUserService) ConfirmAccountDeletion(ctx context.Context, userID uint, token string) (*models.AccountDeletion, error) {
+	ctx, span := startSpan(ctx, "UserService.ConfirmAccountDeletion")
+	r0, err := s.next.ConfirmAccountDeletion(ctx, userID, token)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error) {
+	ctx, span := startSpan(ctx, "UserService.GetAccountDeletion")
+	r0, err := s.next.GetAccountDeletion(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) CancelAccountDeletion(ctx context.Context, userID uint) error {
+	ctx, span := startSpan(ctx, "UserService.CancelAccountDeletion")
+	err := s.next.CancelAccountDeletion(ctx, userID)
+	endSpan(span, err)
+	return err
+}
+
+func (s *tracedUserService) DeleteDueAccounts(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := startSpan(ctx, "UserService.DeleteDueAccounts")
+	r0, err := s.next.DeleteDueAccounts(ctx, now)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.GetUserByUsername")
+	r0, err := s.next.GetUserByUsername(ctx, username)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) ListUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.ListUsers")
+	r0, err := s.next.ListUsers(ctx, limit, offset)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) DisableUser(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.DisableUser")
+	r0, err := s.next.DisableUser(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) EnableUser(ctx context.Context, userID uint) (*models.User, error) {
+	ctx, span := startSpan(ctx, "UserService.EnableUser")
+	r0, err := s.next.EnableUser(ctx, userID)
+	endSpan(span, err)
+	return r0, err
+}
+
+func (s *tracedUserService) SetPassword(ctx context.Context, userID uint, newPassword string) error {
+	ctx, span := startSpan(ctx, "UserService.SetPassword")
+	err := s.next.SetPassword(ctx, userID, newPassword)
+	endSpan(span, err)
+	return err
+}
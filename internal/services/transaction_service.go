@@ -2,6 +2,9 @@ package services
 
 import (
 	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/metrics"
 	"personal-finance-tracker-api/internal/models"
 	"personal-finance-tracker-api/internal/repository"
 	"time"
@@ -9,36 +12,76 @@ import (
 
 // TransactionService defines the interface for transaction-related business logic
 type TransactionService interface {
-	CreateTransaction(ctx context.Context, transaction *models.Transaction) (*models.Transaction, error)
-	GetTransactions(ctx context.Context, userID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error)
-	ExportTransactionsCSV(ctx context.Context, userID uint) ([]models.Transaction, error)
-	DeleteTransaction(ctx context.Context, userID uint, id uint) error
+	CreateTransaction(ctx context.Context, userID, householdID uint, transaction *models.Transaction) (*models.Transaction, error)
+	GetTransactions(ctx context.Context, userID, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error)
+	GetTransaction(ctx context.Context, userID, householdID uint, id uint) (*models.Transaction, error)
+	UpdateTransaction(ctx context.Context, userID, householdID uint, id uint, update *models.Transaction) (*models.Transaction, error)
+	UpdateTransactionStatus(ctx context.Context, userID, householdID uint, id uint, status models.TransactionStatus) (*models.Transaction, error)
+	ExportTransactionsCSV(ctx context.Context, userID, householdID uint) ([]models.Transaction, error)
+	ImportTransactions(ctx context.Context, userID, householdID uint, transactions []models.Transaction) ([]models.Transaction, error)
+	DeleteTransaction(ctx context.Context, userID, householdID uint, id uint) error
 }
 
 // transactionService implements the TransactionService interface
 type transactionService struct {
-	repo repository.Repository
+	repo   repository.Repository
+	access householdAccess
 }
 
 // NewTransactionService creates a new instance of TransactionService
 func NewTransactionService(repo repository.Repository) TransactionService {
-	return &transactionService{repo: repo}
+	return &transactionService{repo: repo, access: householdAccess{repo: repo}}
 }
 
-// CreateTransaction handles the creation of a new transaction, applying business rules if any
-func (s *transactionService) CreateTransaction(ctx context.Context, transaction *models.Transaction) (*models.Transaction, error) {
-	// Example: Here you could add more complex business logic before saving,
-	// such as checking user balance, applying limits, etc.
-	// For now, it directly calls the repository.
-	if err := s.repo.CreateTransaction(ctx, transaction); err != nil {
+// CreateTransaction records a new transaction in a household the user may edit
+func (s *transactionService) CreateTransaction(ctx context.Context, userID, householdID uint, transaction *models.Transaction) (*models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureCategoryInHousehold(ctx, s.repo, householdID, transaction.CategoryID); err != nil {
+		return nil, err
+	}
+	transaction.UserID = userID
+	transaction.HouseholdID = householdID
+
+	// Transactions only become reconciled through a reconciliation session
+	if transaction.Status == "" {
+		transaction.Status = models.StatusPending
+	}
+	if transaction.Status == models.StatusReconciled {
+		return nil, appErrors.NewValidationError("Transactions can only be reconciled by completing a reconciliation", nil)
+	}
+	transaction.ReconciliationID = nil
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.CreateTransaction(ctx, transaction); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionCreate, models.AuditEntityTransaction, transaction.ID, auditDiff(nil, transaction))
+	})
+	if err != nil {
 		return nil, err
 	}
+	metrics.TransactionsCreated.Inc()
 	return transaction, nil
 }
 
-// GetTransactions retrieves a list of transactions, applying business rules if any
-func (s *transactionService) GetTransactions(ctx context.Context, userID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
-	transactions, err := s.repo.GetTransactions(ctx, userID, limit, offset, startDate, endDate, transactionType, description)
+// GetTransactions retrieves a list of transactions, applying business rules if any.
+// startDate and endDate are calendar dates, both included, in the user's time zone.
+func (s *transactionService) GetTransactions(ctx context.Context, userID, householdID uint, limit, offset int, startDate, endDate *time.Time, transactionType *models.TransactionType, description *string) ([]models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if startDate != nil || endDate != nil {
+		loc, err := userLocation(ctx, s.repo, userID)
+		if err != nil {
+			return nil, err
+		}
+		startDate, endDate = localDateRange(startDate, endDate, loc)
+	}
+	transactions, err := s.repo.GetTransactions(ctx, householdID, limit, offset, startDate, endDate, transactionType, description)
 	if err != nil {
 		return nil, err
 	}
@@ -46,16 +89,217 @@ func (s *transactionService) GetTransactions(ctx context.Context, userID uint, l
 	return transactions, nil
 }
 
-// ExportTransactionsCSV retrieves transactions for CSV export
-func (s *transactionService) ExportTransactionsCSV(ctx context.Context, userID uint) ([]models.Transaction, error) {
-	transactions, err := s.repo.GetTransactions(ctx, userID, 0, 0, nil, nil, nil, nil)
+// GetTransaction retrieves a single transaction of a household the user belongs to
+func (s *transactionService) GetTransaction(ctx context.Context, userID, householdID uint, id uint) (*models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransactionByID(ctx, householdID, id)
+}
+
+// UpdateTransaction replaces the editable fields of a transaction unless it has been reconciled
+func (s *transactionService) UpdateTransaction(ctx context.Context, userID, householdID uint, id uint, update *models.Transaction) (*models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if update.Status == models.StatusReconciled {
+		return nil, appErrors.NewValidationError("Transactions can only be reconciled by completing a reconciliation", nil)
+	}
+
+	var updated *models.Transaction
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		existing, err := txRepo.GetTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if err := ensureNotReconciled(existing); err != nil {
+			return err
+		}
+		if err := ensureCategoryInHousehold(ctx, txRepo, householdID, update.CategoryID); err != nil {
+			return err
+		}
+
+		before := *existing
+		existing.Description = update.Description
+		existing.Amount = update.Amount
+		existing.Type = update.Type
+		existing.Date = update.Date
+		existing.CategoryID = update.CategoryID
+		if update.Status != "" {
+			existing.Status = update.Status
+		}
+
+		if err := txRepo.UpdateTransaction(ctx, existing); err != nil {
+			return err
+		}
+		updated, err = txRepo.GetTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionUpdate, models.AuditEntityTransaction, id, auditDiff(&before, updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// UpdateTransactionStatus marks a transaction as pending or cleared
+func (s *transactionService) UpdateTransactionStatus(ctx context.Context, userID, householdID uint, id uint, status models.TransactionStatus) (*models.Transaction, error) {
+	if status != models.StatusPending && status != models.StatusCleared {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Invalid status '%s'. Must be 'pending' or 'cleared'", status), nil)
+	}
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.Transaction
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		existing, err := txRepo.GetTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if err := ensureNotReconciled(existing); err != nil {
+			return err
+		}
+
+		before := *existing
+		existing.Status = status
+		if err := txRepo.UpdateTransaction(ctx, existing); err != nil {
+			return err
+		}
+		updated = existing
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionUpdate, models.AuditEntityTransaction, id, auditDiff(&before, updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ExportTransactionsCSV retrieves transactions for CSV export, with their dates in the user's
+// time zone so that each falls on the day the user sees it on
+func (s *transactionService) ExportTransactionsCSV(ctx context.Context, userID, householdID uint) ([]models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetTransactions(ctx, householdID, 0, 0, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range transactions {
+		transactions[i].Date = transactions[i].Date.In(loc)
+	}
+	return transactions, nil
+}
+
+// ImportTransactions records a batch of transactions, e.g. read from a bank statement, in a
+// household the user may edit. Their dates are calendar days in the user's time zone. Either
+// every transaction is recorded or, when one is invalid, none is.
+func (s *transactionService) ImportTransactions(ctx context.Context, userID, householdID uint, transactions []models.Transaction) ([]models.Transaction, error) {
+	imported, err := s.importTransactions(ctx, userID, householdID, transactions)
+	if err != nil {
+		metrics.ImportsProcessed.WithLabelValues(metrics.ImportFailed).Inc()
+		return nil, err
+	}
+	metrics.ImportsProcessed.WithLabelValues(metrics.ImportSucceeded).Inc()
+	metrics.TransactionsCreated.Add(float64(len(imported)))
+	return imported, nil
+}
+
+// importTransactions validates and records the transactions of an import
+func (s *transactionService) importTransactions(ctx context.Context, userID, householdID uint, transactions []models.Transaction) ([]models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := userLocation(ctx, s.repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range transactions {
+		transaction := &transactions[i]
+		if transaction.Amount <= 0 {
+			return nil, appErrors.NewValidationError(fmt.Sprintf("Transaction %d: amount must be greater than zero", i+1), nil)
+		}
+		if transaction.Type != models.Income && transaction.Type != models.Expense {
+			return nil, appErrors.NewValidationError(fmt.Sprintf("Transaction %d: invalid type '%s'. Must be 'income' or 'expense'", i+1, transaction.Type), nil)
+		}
+		if transaction.Date.IsZero() {
+			return nil, appErrors.NewValidationError(fmt.Sprintf("Transaction %d: date is required", i+1), nil)
+		}
+		switch transaction.Status {
+		case "":
+			transaction.Status = models.StatusPending
+		case models.StatusPending, models.StatusCleared:
+		default:
+			return nil, appErrors.NewValidationError(fmt.Sprintf("Transaction %d: invalid status '%s'. Must be 'pending' or 'cleared'", i+1, transaction.Status), nil)
+		}
+		transaction.Date = startOfLocalDay(transaction.Date, loc)
+		transaction.UserID = userID
+		transaction.HouseholdID = householdID
+		transaction.ReconciliationID = nil
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		checked := make(map[uint]bool)
+		for i := range transactions {
+			transaction := &transactions[i]
+			if !checked[transaction.CategoryID] {
+				if err := ensureCategoryInHousehold(ctx, txRepo, householdID, transaction.CategoryID); err != nil {
+					return err
+				}
+				checked[transaction.CategoryID] = true
+			}
+			if err := txRepo.CreateTransaction(ctx, transaction); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, txRepo, userID, householdID, models.AuditActionCreate, models.AuditEntityTransaction, transaction.ID, auditDiff(nil, transaction)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	return transactions, nil
 }
 
-// DeleteTransaction performs a soft delete of a transaction
-func (s *transactionService) DeleteTransaction(ctx context.Context, userID uint, id uint) error {
-	return s.repo.DeleteTransaction(ctx, userID, id)
+// DeleteTransaction performs a soft delete of a transaction unless it has been reconciled
+func (s *transactionService) DeleteTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		existing, err := txRepo.GetTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if err := ensureNotReconciled(existing); err != nil {
+			return err
+		}
+		if err := txRepo.DeleteTransaction(ctx, householdID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionDelete, models.AuditEntityTransaction, id, auditDiff(existing, nil))
+	})
+}
+
+// ensureNotReconciled rejects changes to transactions locked by a completed reconciliation
+func ensureNotReconciled(transaction *models.Transaction) error {
+	if transaction.Status == models.StatusReconciled {
+		return appErrors.NewConflictError(fmt.Sprintf("Transaction with ID %d is reconciled and cannot be modified", transaction.ID), nil)
+	}
+	return nil
 }
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/metrics"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestTransactionServiceCreateTransaction(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewTransactionService(repo)
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	bobsCategory := createCategory(t, repo, bob, "Bob's", nil)
+
+	viewer := registerUser(t, repo, "victor")
+	addMember(t, repo, *alice.DefaultHouseholdID, viewer.ID, models.RoleViewer)
+
+	date := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		userID      uint
+		householdID uint
+		categoryID  uint
+		status      models.TransactionStatus
+		wantErr     appErrors.ErrorType
+		wantStatus  models.TransactionStatus
+	}{
+		{"default household", alice.ID, 0, groceries.ID, "", "", models.StatusPending},
+		{"selected household", alice.ID, *alice.DefaultHouseholdID, groceries.ID, models.StatusCleared, "", models.StatusCleared},
+		{"category of another household", alice.ID, 0, bobsCategory.ID, "", appErrors.TypeValidation, ""},
+		{"unknown category", alice.ID, 0, 9999, "", appErrors.TypeValidation, ""},
+		{"reconciled status", alice.ID, 0, groceries.ID, models.StatusReconciled, appErrors.TypeValidation, ""},
+		{"user without access to the category", bob.ID, *alice.DefaultHouseholdID, groceries.ID, "", appErrors.TypeNotFound, ""},
+		{"viewer", viewer.ID, *alice.DefaultHouseholdID, groceries.ID, "", appErrors.TypeForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &models.Transaction{
+				Description: tt.name, Amount: 42.5, Type: models.Expense, Date: date,
+				Status: tt.status, CategoryID: tt.categoryID, ReconciliationID: uintPtr(7),
+			}
+			created, err := service.CreateTransaction(context.Background(), tt.userID, tt.householdID, input)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			if created.UserID != tt.userID || created.HouseholdID != *alice.DefaultHouseholdID {
+				t.Errorf("created by user %d in household %d, want user %d in household %d",
+					created.UserID, created.HouseholdID, tt.userID, *alice.DefaultHouseholdID)
+			}
+			if created.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", created.Status, tt.wantStatus)
+			}
+			if created.ReconciliationID != nil {
+				t.Errorf("reconciliation ID %d taken from the input", *created.ReconciliationID)
+			}
+			if actions := auditActions(t, repo, models.AuditEntityTransaction, created.ID); fmt.Sprint(actions) != "[create]" {
+				t.Errorf("audit actions = %v, want [create]", actions)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceGetTransactionsUsesUserTimeZone(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewTransactionService(repo)
+	alice := registerUser(t, repo, "alice")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+
+	// March 1 at 20:00 UTC is already March 2 in Auckland
+	instant := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	createTransaction(t, repo, alice, groceries.ID, 10, instant, models.StatusPending)
+
+	march1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	march2 := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		timezone   string
+		start, end time.Time
+		want       int
+	}{
+		{"UTC same day", "UTC", march1, march1, 1},
+		{"UTC next day", "UTC", march2, march2, 0},
+		{"Auckland same day", "Pacific/Auckland", march1, march1, 0},
+		{"Auckland next day", "Pacific/Auckland", march2, march2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := repo.GetUserByID(context.Background(), alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user.Timezone = tt.timezone
+			if err := repo.UpdateUserProfile(context.Background(), user); err != nil {
+				t.Fatal(err)
+			}
+
+			transactions, err := service.GetTransactions(context.Background(), alice.ID, 0, 0, 0, &tt.start, &tt.end, nil, nil)
+			assertErrorType(t, err, "")
+			if len(transactions) != tt.want {
+				t.Errorf("got %d transactions, want %d", len(transactions), tt.want)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceUpdateTransaction(t *testing.T) {
+	date := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		status       models.TransactionStatus // Status of the existing transaction
+		updateStatus models.TransactionStatus
+		otherID      bool // Update a transaction that does not exist
+		foreignCat   bool // Move to a category of another household
+		wantErr      appErrors.ErrorType
+	}{
+		{"pending", models.StatusPending, "", false, false, ""},
+		{"clear while editing", models.StatusPending, models.StatusCleared, false, false, ""},
+		{"reconciled", models.StatusReconciled, "", false, false, appErrors.TypeConflict},
+		{"set reconciled", models.StatusPending, models.StatusReconciled, false, false, appErrors.TypeValidation},
+		{"unknown transaction", models.StatusPending, "", true, false, appErrors.TypeNotFound},
+		{"category of another household", models.StatusPending, "", false, true, appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewTransactionService(repo)
+			alice := registerUser(t, repo, "alice")
+			bob := registerUser(t, repo, "bob")
+			groceries := createCategory(t, repo, alice, "Groceries", nil)
+			rent := createCategory(t, repo, alice, "Rent", nil)
+			existing := createTransaction(t, repo, alice, groceries.ID, 10, date, tt.status)
+
+			id, categoryID := existing.ID, rent.ID
+			if tt.otherID {
+				id = 9999
+			}
+			if tt.foreignCat {
+				categoryID = createCategory(t, repo, bob, "Bob's", nil).ID
+			}
+			update := &models.Transaction{
+				Description: "Rent for April", Amount: 950, Type: models.Expense, Date: date.AddDate(0, 0, 1),
+				Status: tt.updateStatus, CategoryID: categoryID,
+			}
+			updated, err := service.UpdateTransaction(context.Background(), alice.ID, 0, id, update)
+			assertErrorType(t, err, tt.wantErr)
+
+			stored, getErr := repo.GetTransactionByID(context.Background(), *alice.DefaultHouseholdID, existing.ID)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			if tt.wantErr != "" {
+				if stored.Description != existing.Description || stored.CategoryID != existing.CategoryID {
+					t.Errorf("transaction changed by a rejected update: %+v", stored)
+				}
+				return
+			}
+
+			wantStatus := tt.status
+			if tt.updateStatus != "" {
+				wantStatus = tt.updateStatus
+			}
+			if stored.Description != "Rent for April" || stored.Amount != 950 || stored.CategoryID != rent.ID || stored.Status != wantStatus {
+				t.Errorf("stored %+v, want the update applied with status %q", stored, wantStatus)
+			}
+			if updated.Category.Name != "Rent" {
+				t.Errorf("returned category %q, want the new category loaded", updated.Category.Name)
+			}
+			if actions := auditActions(t, repo, models.AuditEntityTransaction, existing.ID); fmt.Sprint(actions) != "[update]" {
+				t.Errorf("audit actions = %v, want [update]", actions)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceUpdateTransactionStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		current models.TransactionStatus
+		status  models.TransactionStatus
+		wantErr appErrors.ErrorType
+	}{
+		{"clear", models.StatusPending, models.StatusCleared, ""},
+		{"back to pending", models.StatusCleared, models.StatusPending, ""},
+		{"reconcile", models.StatusCleared, models.StatusReconciled, appErrors.TypeValidation},
+		{"unknown status", models.StatusPending, "void", appErrors.TypeValidation},
+		{"already reconciled", models.StatusReconciled, models.StatusPending, appErrors.TypeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewTransactionService(repo)
+			alice := registerUser(t, repo, "alice")
+			groceries := createCategory(t, repo, alice, "Groceries", nil)
+			existing := createTransaction(t, repo, alice, groceries.ID, 10, time.Now(), tt.current)
+
+			_, err := service.UpdateTransactionStatus(context.Background(), alice.ID, 0, existing.ID, tt.status)
+			assertErrorType(t, err, tt.wantErr)
+
+			stored, getErr := repo.GetTransactionByID(context.Background(), *alice.DefaultHouseholdID, existing.ID)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			want := tt.status
+			if tt.wantErr != "" {
+				want = tt.current
+			}
+			if stored.Status != want {
+				t.Errorf("stored status = %q, want %q", stored.Status, want)
+			}
+		})
+	}
+}
+
+func TestTransactionServiceDeleteTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  models.TransactionStatus
+		role    models.HouseholdRole // Role of the deleting member; empty deletes as the owner
+		wantErr appErrors.ErrorType
+	}{
+		{"pending", models.StatusPending, "", ""},
+		{"by an editor", models.StatusCleared, models.RoleEditor, ""},
+		{"by a viewer", models.StatusPending, models.RoleViewer, appErrors.TypeForbidden},
+		{"reconciled", models.StatusReconciled, "", appErrors.TypeConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := NewTransactionService(repo)
+			alice := registerUser(t, repo, "alice")
+			groceries := createCategory(t, repo, alice, "Groceries", nil)
+			existing := createTransaction(t, repo, alice, groceries.ID, 10, time.Now(), tt.status)
+
+			userID := alice.ID
+			if tt.role != "" {
+				member := registerUser(t, repo, "member")
+				addMember(t, repo, *alice.DefaultHouseholdID, member.ID, tt.role)
+				userID = member.ID
+			}
+
+			err := service.DeleteTransaction(context.Background(), userID, *alice.DefaultHouseholdID, existing.ID)
+			assertErrorType(t, err, tt.wantErr)
+
+			_, getErr := service.GetTransaction(context.Background(), alice.ID, 0, existing.ID)
+			if tt.wantErr == "" {
+				assertErrorType(t, getErr, appErrors.TypeNotFound)
+				if _, err := repo.GetDeletedTransactionByID(context.Background(), *alice.DefaultHouseholdID, existing.ID); err != nil {
+					t.Errorf("deleted transaction not in the trash: %v", err)
+				}
+			} else {
+				assertErrorType(t, getErr, "")
+			}
+		})
+	}
+}
+
+func TestTransactionServiceExportTransactionsCSVUsesUserTimeZone(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewTransactionService(repo)
+	alice := registerUser(t, repo, "alice")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	instant := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	createTransaction(t, repo, alice, groceries.ID, 10, instant, models.StatusPending)
+
+	user, err := repo.GetUserByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Timezone = "Pacific/Auckland"
+	if err := repo.UpdateUserProfile(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err := service.ExportTransactionsCSV(context.Background(), alice.ID, 0)
+	assertErrorType(t, err, "")
+	if len(transactions) != 1 {
+		t.Fatalf("got %d transactions, want 1", len(transactions))
+	}
+	if got := transactions[0].Date.Format("2006-01-02"); got != "2026-03-02" {
+		t.Errorf("exported date %s, want 2026-03-02", got)
+	}
+}
+
+func TestTransactionServiceImportTransactions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewTransactionService(repo)
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	bobsCategory := createCategory(t, repo, bob, "Bob's", nil)
+
+	user, err := repo.GetUserByID(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Timezone = "Pacific/Auckland"
+	if err := repo.UpdateUserProfile(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	valid := func() models.Transaction {
+		return models.Transaction{Description: "Imported", Amount: 12.5, Type: models.Expense, Date: day, CategoryID: groceries.ID}
+	}
+	tests := []struct {
+		name    string
+		userID  uint
+		change  func(transaction *models.Transaction)
+		wantErr appErrors.ErrorType
+	}{
+		{"valid", alice.ID, func(transaction *models.Transaction) {}, ""},
+		{"cleared", alice.ID, func(transaction *models.Transaction) { transaction.Status = models.StatusCleared }, ""},
+		{"zero amount", alice.ID, func(transaction *models.Transaction) { transaction.Amount = 0 }, appErrors.TypeValidation},
+		{"invalid type", alice.ID, func(transaction *models.Transaction) { transaction.Type = "transfer" }, appErrors.TypeValidation},
+		{"missing date", alice.ID, func(transaction *models.Transaction) { transaction.Date = time.Time{} }, appErrors.TypeValidation},
+		{"reconciled", alice.ID, func(transaction *models.Transaction) { transaction.Status = models.StatusReconciled }, appErrors.TypeValidation},
+		{"category of another household", alice.ID, func(transaction *models.Transaction) { transaction.CategoryID = bobsCategory.ID }, appErrors.TypeValidation},
+		{"user without access to the category", bob.ID, func(transaction *models.Transaction) {}, appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := repo.GetTransactions(context.Background(), *alice.DefaultHouseholdID, 0, 0, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The second transaction is the one under test, so a failure must roll back the first
+			second := valid()
+			tt.change(&second)
+			succeeded := testutil.ToFloat64(metrics.ImportsProcessed.WithLabelValues(metrics.ImportSucceeded))
+			failed := testutil.ToFloat64(metrics.ImportsProcessed.WithLabelValues(metrics.ImportFailed))
+			created := testutil.ToFloat64(metrics.TransactionsCreated)
+			imported, err := service.ImportTransactions(context.Background(), tt.userID, 0, []models.Transaction{valid(), second})
+			assertErrorType(t, err, tt.wantErr)
+
+			wantSucceeded, wantFailed, wantCreated := succeeded+1, failed, created+2
+			if tt.wantErr != "" {
+				wantSucceeded, wantFailed, wantCreated = succeeded, failed+1, created
+			}
+			if got := testutil.ToFloat64(metrics.ImportsProcessed.WithLabelValues(metrics.ImportSucceeded)); got != wantSucceeded {
+				t.Errorf("successful imports = %v, want %v", got, wantSucceeded)
+			}
+			if got := testutil.ToFloat64(metrics.ImportsProcessed.WithLabelValues(metrics.ImportFailed)); got != wantFailed {
+				t.Errorf("failed imports = %v, want %v", got, wantFailed)
+			}
+			if got := testutil.ToFloat64(metrics.TransactionsCreated); got != wantCreated {
+				t.Errorf("transactions created = %v, want %v", got, wantCreated)
+			}
+
+			after, err := repo.GetTransactions(context.Background(), *alice.DefaultHouseholdID, 0, 0, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if len(after) != len(before) {
+					t.Errorf("%d transactions recorded by a failed import", len(after)-len(before))
+				}
+				return
+			}
+
+			if len(imported) != 2 || len(after) != len(before)+2 {
+				t.Fatalf("imported %d transactions, %d recorded, want 2", len(imported), len(after)-len(before))
+			}
+			for _, transaction := range imported {
+				if got := transaction.Date.In(user.Location()).Format("2006-01-02 15:04"); got != "2026-03-02 00:00" {
+					t.Errorf("date %s, want the start of March 2 in the user's time zone", got)
+				}
+				if transaction.Status == "" {
+					t.Error("status not set")
+				}
+				if actions := auditActions(t, repo, models.AuditEntityTransaction, transaction.ID); fmt.Sprint(actions) != "[create]" {
+					t.Errorf("audit actions = %v, want [create]", actions)
+				}
+			}
+		})
+	}
+}
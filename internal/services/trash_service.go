@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+)
+
+// TrashService defines the interface for listing, restoring and purging soft-deleted records
+type TrashService interface {
+	GetDeletedTransactions(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Transaction, error)
+	RestoreTransaction(ctx context.Context, userID, householdID uint, id uint) error
+	PurgeTransaction(ctx context.Context, userID, householdID uint, id uint) error
+	GetDeletedCategories(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Category, error)
+	RestoreCategory(ctx context.Context, userID, householdID uint, id uint) error
+	PurgeCategory(ctx context.Context, userID, householdID uint, id uint) error
+	PurgeExpired(ctx context.Context, deletedBefore time.Time) (*PurgeResult, error)
+}
+
+// PurgeResult reports how many rows a retention purge permanently deleted
+type PurgeResult struct {
+	Transactions int64 `json:"transactions"`
+	Categories   int64 `json:"categories"`
+}
+
+// trashService implements the TrashService interface
+type trashService struct {
+	repo   repository.Repository
+	access householdAccess
+}
+
+// NewTrashService creates a new instance of TrashService
+func NewTrashService(repo repository.Repository) TrashService {
+	return &trashService{repo: repo, access: householdAccess{repo: repo}}
+}
+
+// GetDeletedTransactions retrieves the household's soft-deleted transactions
+func (s *trashService) GetDeletedTransactions(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Transaction, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeletedTransactions(ctx, householdID, limit, offset)
+}
+
+// RestoreTransaction restores a soft-deleted transaction.
+// Its category must not be in the trash, otherwise the restored transaction would be orphaned.
+func (s *trashService) RestoreTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		transaction, err := txRepo.GetDeletedTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if transaction.Category.DeletedAt.Valid {
+			return appErrors.NewConflictError(fmt.Sprintf("Category with ID %d is deleted; restore it before restoring this transaction", transaction.CategoryID), nil)
+		}
+		if err := txRepo.RestoreTransaction(ctx, householdID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionRestore, models.AuditEntityTransaction, id, map[string]models.AuditChange{
+			"deletedAt": {Before: transaction.DeletedAt.Time},
+		})
+	})
+}
+
+// PurgeTransaction permanently deletes a soft-deleted transaction
+func (s *trashService) PurgeTransaction(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		transaction, err := txRepo.GetDeletedTransactionByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if err := txRepo.PurgeTransaction(ctx, householdID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionPurge, models.AuditEntityTransaction, id, auditDiff(transaction, nil))
+	})
+}
+
+// GetDeletedCategories retrieves the household's soft-deleted categories
+func (s *trashService) GetDeletedCategories(ctx context.Context, userID, householdID uint, limit, offset int) ([]models.Category, error) {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeletedCategories(ctx, householdID, limit, offset)
+}
+
+// RestoreCategory restores a soft-deleted category.
+// Its parent must not be in the trash, otherwise the restored category would be orphaned.
+func (s *trashService) RestoreCategory(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		category, err := txRepo.GetDeletedCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if category.Parent != nil && category.Parent.DeletedAt.Valid {
+			return appErrors.NewConflictError(fmt.Sprintf("Parent category with ID %d is deleted; restore it before restoring this category", category.Parent.ID), nil)
+		}
+		if err := txRepo.RestoreCategory(ctx, householdID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionRestore, models.AuditEntityCategory, id, map[string]models.AuditChange{
+			"deletedAt": {Before: category.DeletedAt.Time},
+		})
+	})
+}
+
+// PurgeCategory permanently deletes a soft-deleted category
+func (s *trashService) PurgeCategory(ctx context.Context, userID, householdID uint, id uint) error {
+	householdID, err := s.access.authorize(ctx, userID, householdID, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		category, err := txRepo.GetDeletedCategoryByID(ctx, householdID, id)
+		if err != nil {
+			return err
+		}
+		if err := txRepo.PurgeCategory(ctx, householdID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, householdID, models.AuditActionPurge, models.AuditEntityCategory, id, auditDiff(category, nil))
+	})
+}
+
+// PurgeExpired permanently deletes all records soft-deleted before the given time.
+// Transactions are purged first so that categories they referenced become eligible.
+func (s *trashService) PurgeExpired(ctx context.Context, deletedBefore time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	err := s.repo.Transaction(func(txRepo repository.Repository) error {
+		var err error
+		if result.Transactions, err = txRepo.PurgeDeletedTransactions(ctx, deletedBefore); err != nil {
+			return err
+		}
+		result.Categories, err = txRepo.PurgeDeletedCategories(ctx, deletedBefore)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+func TestTrashServiceRestoreAndPurge(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewTrashService(repo)
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	addMember(t, repo, *alice.DefaultHouseholdID, bob.ID, models.RoleViewer)
+	ctx := context.Background()
+	householdID := *alice.DefaultHouseholdID
+
+	groceries := createCategory(t, repo, alice, "Groceries", nil)
+	rent := createCategory(t, repo, alice, "Rent", nil)
+	transaction := createTransaction(t, repo, alice, groceries.ID, 5, time.Now(), models.StatusPending)
+	for _, err := range []error{
+		repo.DeleteTransaction(ctx, householdID, transaction.ID),
+		repo.DeleteCategory(ctx, householdID, rent.ID),
+	} {
+		assertErrorType(t, err, "")
+	}
+
+	// Viewers cannot change the trash, and failed attempts leave no audit entry
+	assertErrorType(t, service.RestoreTransaction(ctx, bob.ID, householdID, transaction.ID), appErrors.TypeForbidden)
+	assertErrorType(t, service.PurgeCategory(ctx, bob.ID, householdID, rent.ID), appErrors.TypeForbidden)
+	assertErrorType(t, service.PurgeTransaction(ctx, alice.ID, householdID, 9999), appErrors.TypeNotFound)
+
+	assertErrorType(t, service.RestoreTransaction(ctx, alice.ID, householdID, transaction.ID), "")
+	assertErrorType(t, repo.DeleteTransaction(ctx, householdID, transaction.ID), "")
+	assertErrorType(t, service.PurgeTransaction(ctx, alice.ID, householdID, transaction.ID), "")
+	assertErrorType(t, service.RestoreCategory(ctx, alice.ID, householdID, rent.ID), "")
+	assertErrorType(t, repo.DeleteCategory(ctx, householdID, rent.ID), "")
+	assertErrorType(t, service.PurgeCategory(ctx, alice.ID, householdID, rent.ID), "")
+
+	if actions := auditActions(t, repo, models.AuditEntityTransaction, transaction.ID); fmt.Sprint(actions) != "[restore purge]" {
+		t.Errorf("transaction audit actions = %v, want [restore purge]", actions)
+	}
+	if actions := auditActions(t, repo, models.AuditEntityCategory, rent.ID); fmt.Sprint(actions) != "[restore purge]" {
+		t.Errorf("category audit actions = %v, want [restore purge]", actions)
+	}
+	// A purge keeps what was removed for good
+	if changes := lastAuditChanges(t, repo, models.AuditEntityCategory, rent.ID); changes["name"].Before != "Rent" {
+		t.Errorf("purge audit changes = %v, want the category's name", changes)
+	}
+}
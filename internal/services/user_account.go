@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// anonymizedHouseholdName replaces the name of households kept when their only member is anonymised
+const anonymizedHouseholdName = "Deleted household"
+
+// UserDataExport holds all personal data of a user, including soft-deleted records
+type UserDataExport struct {
+	ExportedAt      time.Time
+	User            *models.User
+	Identities      []models.UserIdentity
+	APITokens       []models.APIToken
+	Households      []models.HouseholdMember
+	Categories      []models.Category
+	Transactions    []models.Transaction
+	Reconciliations []models.Reconciliation
+	AuditEntries    []models.AuditEntry
+}
+
+// ExportUserData collects everything stored about a user: their account, linked identities,
+// personal access tokens, household memberships, the records they created in any household and
+// the audit entries of their changes
+func (s *userService) ExportUserData(ctx context.Context, userID uint) (*UserDataExport, error) {
+	export := &UserDataExport{ExportedAt: time.Now().UTC()}
+	var err error
+	if export.User, err = s.repo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Identities, err = s.repo.GetUserIdentities(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.APITokens, err = s.repo.GetAPITokens(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Households, err = s.repo.GetUserHouseholds(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Categories, err = s.repo.GetUserCategories(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Transactions, err = s.repo.GetUserTransactions(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Reconciliations, err = s.repo.GetUserReconciliations(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.AuditEntries, err = s.repo.GetUserAuditEntries(ctx, userID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// RequestAccountDeletion starts the deletion of a user's account and returns the request with
+// the token that confirms it. Users with a password must enter it again. A new request replaces
+// an earlier one, including one that was already confirmed.
+func (s *userService) RequestAccountDeletion(ctx context.Context, userID uint, mode models.AccountDeletionMode, password string) (*models.AccountDeletion, string, error) {
+	if mode == "" {
+		mode = models.DeletionModeDelete
+	}
+	if mode != models.DeletionModeDelete && mode != models.DeletionModeAnonymize {
+		return nil, "", appErrors.NewValidationError(fmt.Sprintf("Invalid mode '%s'. Must be 'delete' or 'anonymize'", mode), nil)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.PasswordHash != "" {
+		match, _, err := s.opts.PasswordHasher.Verify(password, user.PasswordHash)
+		if err != nil {
+			return nil, "", appErrors.NewInternalError("Failed to verify password", err)
+		}
+		if !match {
+			return nil, "", appErrors.NewValidationError("Current password is incorrect", nil)
+		}
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, "", appErrors.NewInternalError("Failed to generate account deletion token", err)
+	}
+	deletion := &models.AccountDeletion{
+		UserID:    userID,
+		Mode:      mode,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.opts.AccountDeletionConfirmTTL),
+	}
+	if err := s.repo.CreateAccountDeletion(ctx, deletion); err != nil {
+		return nil, "", err
+	}
+	return deletion, token, nil
+}
+
+// ConfirmAccountDeletion confirms a deletion request with its token and schedules the deletion
+// for the end of the grace period. The user is notified by email when they have an address.
+func (s *userService) ConfirmAccountDeletion(ctx context.Context, userID uint, token string) (*models.AccountDeletion, error) {
+	now := time.Now()
+	confirmed, err := s.repo.ConfirmAccountDeletion(ctx, userID, hashToken(token), now, now.Add(s.opts.AccountDeletionGracePeriod))
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, appErrors.NewValidationError("Invalid or expired account deletion token", nil)
+	}
+
+	deletion, err := s.repo.GetAccountDeletion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":        true,
+		"event":        "account_deletion_scheduled",
+		"userID":       userID,
+		"mode":         deletion.Mode,
+		"scheduledFor": deletion.ScheduledFor,
+	}).Info("UserService: Account deletion scheduled")
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != nil {
+		err = s.notifier.Send(ctx, notify.Message{
+			To:      *user.Email,
+			Subject: "Your account will be deleted",
+			Body: fmt.Sprintf("Hello %s,\n\n"+
+				"Your account and its data will be deleted on %s.\n\n"+
+				"If you did not request this or have changed your mind, sign in and cancel the deletion before then.\n",
+				user.Username, deletion.ScheduledFor.UTC().Format(time.RFC1123)),
+		})
+		if err != nil {
+			// The deletion is scheduled either way; the message is only a courtesy
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":  err.Error(),
+				"userID": userID,
+			}).Error("UserService: Failed to send account deletion notice")
+		}
+	}
+	return deletion, nil
+}
+
+// GetAccountDeletion retrieves the pending or scheduled deletion of a user's account
+func (s *userService) GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error) {
+	return s.repo.GetAccountDeletion(ctx, userID)
+}
+
+// CancelAccountDeletion withdraws a deletion request of a user before it has been carried out
+func (s *userService) CancelAccountDeletion(ctx context.Context, userID uint) error {
+	if err := s.repo.DeleteAccountDeletion(ctx, userID); err != nil {
+		return err
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":  true,
+		"event":  "account_deletion_cancelled",
+		"userID": userID,
+	}).Info("UserService: Account deletion cancelled")
+	return nil
+}
+
+// DeleteDueAccounts carries out every confirmed account deletion whose grace period has passed
+// and returns how many accounts were deleted. A failing deletion does not stop the others.
+func (s *userService) DeleteDueAccounts(ctx context.Context, now time.Time) (int, error) {
+	deletions, err := s.repo.GetDueAccountDeletions(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i := range deletions {
+		if err := s.deleteAccount(ctx, &deletions[i]); err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":  err.Error(),
+				"userID": deletions[i].UserID,
+			}).Error("UserService: Failed to delete account")
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// deleteAccount removes or anonymises a user and their data in a single database transaction.
+// Households the user was the only member of are purged, or anonymised and kept. The user leaves
+// every shared household, handing ownership to the longest-standing member if they were the
+// last owner; when deleting, their records there are attributed to an owner. Their personal data
+// is erased from the audit log.
+func (s *userService) deleteAccount(ctx context.Context, deletion *models.AccountDeletion) error {
+	user, err := s.repo.GetUserByID(ctx, deletion.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		memberships, err := txRepo.GetUserHouseholds(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, membership := range memberships {
+			if err := leaveHousehold(ctx, txRepo, user.ID, membership.HouseholdID, deletion.Mode); err != nil {
+				return err
+			}
+		}
+
+		if err := txRepo.DeleteUserCredentials(ctx, user.ID); err != nil {
+			return err
+		}
+		// The log keeps what happened, but not who the user was or where they connected from
+		if err := eraseAuditData(ctx, txRepo, user.ID, time.Now()); err != nil {
+			return err
+		}
+		// Recorded without the client of the request, which would identify the user again
+		err = recordAudit(WithRequestMetadata(ctx, "", ""), txRepo, user.ID, 0, models.AuditActionDelete, models.AuditEntityUser, user.ID, map[string]models.AuditChange{
+			"mode": {After: deletion.Mode},
+		})
+		if err != nil {
+			return err
+		}
+		if deletion.Mode == models.DeletionModeAnonymize {
+			return txRepo.AnonymizeUser(ctx, user.ID, fmt.Sprintf("deleted-user-%d", user.ID), time.Now())
+		}
+		return txRepo.DeleteUser(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.limiter != nil {
+		if err := s.limiter.Unlock(ctx, user.Username); err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"error":  err.Error(),
+				"userID": user.ID,
+			}).Warn("UserService: Failed to clear login attempts of deleted account")
+		}
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":  true,
+		"event":  "account_deleted",
+		"userID": user.ID,
+		"mode":   deletion.Mode,
+	}).Info("UserService: Account deleted")
+	return nil
+}
+
+// leaveHousehold removes a user who is being deleted from a household, as described for deleteAccount
+func leaveHousehold(ctx context.Context, repo repository.Repository, userID, householdID uint, mode models.AccountDeletionMode) error {
+	members, err := repo.GetHouseholdMembers(ctx, householdID)
+	if err != nil {
+		return err
+	}
+
+	var successor *models.HouseholdMember
+	for i := range members {
+		if members[i].UserID == userID {
+			continue
+		}
+		if successor == nil || (members[i].Role == models.RoleOwner && successor.Role != models.RoleOwner) {
+			successor = &members[i]
+		}
+	}
+
+	if successor == nil {
+		if mode == models.DeletionModeAnonymize {
+			return repo.AnonymizeHouseholdRecords(ctx, householdID, anonymizedHouseholdName)
+		}
+		return repo.PurgeHousehold(ctx, householdID)
+	}
+
+	if successor.Role != models.RoleOwner {
+		if err := repo.UpdateHouseholdMemberRole(ctx, householdID, successor.UserID, models.RoleOwner); err != nil {
+			return err
+		}
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"audit":       true,
+			"event":       "household_owner_promoted",
+			"householdID": householdID,
+			"userID":      successor.UserID,
+		}).Info("UserService: Household ownership handed over from deleted account")
+	}
+	if mode == models.DeletionModeDelete {
+		if err := repo.ReassignHouseholdRecords(ctx, householdID, userID, successor.UserID); err != nil {
+			return err
+		}
+	}
+	return repo.RemoveHouseholdMember(ctx, householdID, userID)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GetUserByUsername retrieves a user by their username, e.g. to resolve the target of an
+// administrative command
+func (s *userService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.repo.GetUserByUsername(ctx, username)
+}
+
+// ListUsers retrieves a page of all users, ordered by ID
+func (s *userService) ListUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
+	return s.repo.GetUsers(ctx, limit, offset)
+}
+
+// DisableUser prevents a user from signing in and signs out every existing session. Their data
+// is kept, and EnableUser lets them sign in again.
+func (s *userService) DisableUser(ctx context.Context, userID uint) (*models.User, error) {
+	return s.setDisabled(ctx, userID, true)
+}
+
+// EnableUser lets a disabled user sign in again
+func (s *userService) EnableUser(ctx context.Context, userID uint) (*models.User, error) {
+	return s.setDisabled(ctx, userID, false)
+}
+
+// setDisabled changes the disabled state of a user; the change is recorded without an actor,
+// since it is made by an administrator rather than a user
+func (s *userService) setDisabled(ctx context.Context, userID uint, disabled bool) (*models.User, error) {
+	var user *models.User
+	err := s.repo.Transaction(func(txRepo repository.Repository) error {
+		existing, err := txRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user = existing
+		if (existing.DisabledAt != nil) == disabled {
+			return nil
+		}
+
+		before := *existing
+		now := time.Now()
+		user.DisabledAt = nil
+		if disabled {
+			user.DisabledAt = &now
+		}
+		if err := txRepo.SetUserDisabled(ctx, userID, user.DisabledAt); err != nil {
+			return err
+		}
+		if disabled {
+			if err := txRepo.RevokeUserSessions(ctx, userID, now); err != nil {
+				return err
+			}
+			if _, err := txRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+				return err
+			}
+		}
+		return recordAudit(ctx, txRepo, 0, 0, models.AuditActionUpdate, models.AuditEntityUser, userID, auditDiff(&before, user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":    true,
+		"event":    "user_disabled_changed",
+		"userID":   userID,
+		"disabled": disabled,
+	}).Info("UserService: User disabled state changed")
+	return user, nil
+}
+
+// SetPassword replaces the password of a user without the current one or a reset token, e.g.
+// when an administrator resets it. Like a reset, it signs out every session and lifts any
+// login lockout.
+func (s *userService) SetPassword(ctx context.Context, userID uint, newPassword string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	if s.limiter != nil {
+		return s.limiter.Unlock(ctx, user.Username)
+	}
+	return nil
+}
+
+// ensureEnabled rejects users whose account has been disabled. It is only checked once the
+// credentials are known to be valid, so it does not disclose the state of other accounts.
+func ensureEnabled(user *models.User) error {
+	if user.DisabledAt != nil {
+		return appErrors.NewUnauthorizedError("This account has been disabled", nil)
+	}
+	return nil
+}
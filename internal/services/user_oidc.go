@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/oidc"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxUsernameAttempts bounds the search for a free username when provisioning a user
+const maxUsernameAttempts = 20
+
+// OIDCProvider is the OpenID Connect identity provider used for single sign-on
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(state, nonce, codeChallenge string) string
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*oidc.Claims, error)
+}
+
+// OIDCAuthorization is a started OIDC flow. The state must be kept by the browser that is sent
+// to the URL, e.g. in a cookie, and checked against the state of the callback, so that a flow
+// cannot be completed in another browser.
+type OIDCAuthorization struct {
+	URL       string
+	State     string
+	ExpiresAt time.Time
+}
+
+// OIDCLoginResult is the outcome of an OIDC callback: a login, which like a password login may
+// still need a second factor, or an identity linked to the user who started the flow
+type OIDCLoginResult struct {
+	LoginResult
+	Identity *models.UserIdentity
+	Linked   bool // The flow linked an identity to an existing session instead of logging in
+}
+
+// BeginOIDCLogin starts a login at the identity provider. With linkUserID the resulting
+// identity is linked to that user instead of logging in.
+func (s *userService) BeginOIDCLogin(ctx context.Context, linkUserID *uint) (*OIDCAuthorization, error) {
+	if s.opts.OIDCProvider == nil {
+		return nil, appErrors.NewNotFoundError("OIDC login is not enabled", nil)
+	}
+
+	state, err := generateToken(32)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate OIDC state", err)
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate OIDC nonce", err)
+	}
+	verifier, err := oidc.NewPKCEVerifier()
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate PKCE verifier", err)
+	}
+
+	req := &models.OIDCAuthRequest{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(s.opts.OIDCLoginTTL),
+	}
+	if err := s.repo.CreateOIDCAuthRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return &OIDCAuthorization{
+		URL:       s.opts.OIDCProvider.AuthCodeURL(state, nonce, oidc.PKCEChallenge(verifier)),
+		State:     state,
+		ExpiresAt: req.ExpiresAt,
+	}, nil
+}
+
+// CompleteOIDCLogin handles the provider's callback. It redeems the code, validates the ID token
+// and resolves the identity to a user, linking or provisioning one as configured. A flow that
+// links an identity must be completed by the user who started it, signed in as sessionUserID.
+func (s *userService) CompleteOIDCLogin(ctx context.Context, state, code string, sessionUserID *uint) (*OIDCLoginResult, error) {
+	if s.opts.OIDCProvider == nil {
+		return nil, appErrors.NewNotFoundError("OIDC login is not enabled", nil)
+	}
+
+	req, err := s.repo.ConsumeOIDCAuthRequest(ctx, hashToken(state))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid or expired OIDC login; start again", nil)
+		}
+		return nil, err
+	}
+	if time.Now().After(req.ExpiresAt) {
+		return nil, appErrors.NewUnauthorizedError("Invalid or expired OIDC login; start again", nil)
+	}
+	if req.LinkUserID != nil && (sessionUserID == nil || *sessionUserID != *req.LinkUserID) {
+		return nil, appErrors.NewUnauthorizedError("Complete linking the identity signed in as the user who started it", nil)
+	}
+
+	rawIDToken, err := s.opts.OIDCProvider.Exchange(ctx, code, req.CodeVerifier)
+	if err != nil {
+		return nil, appErrors.NewUnauthorizedError("Failed to redeem the authorization code", err)
+	}
+	claims, err := s.opts.OIDCProvider.VerifyIDToken(ctx, rawIDToken, req.Nonce)
+	if err != nil {
+		return nil, appErrors.NewUnauthorizedError("Invalid ID token", err)
+	}
+
+	identity, err := s.repo.GetUserIdentity(ctx, claims.Issuer, claims.Subject)
+	if err != nil && !appErrors.IsType(err, appErrors.TypeNotFound) {
+		return nil, err
+	}
+	if identity == nil {
+		identity = &models.UserIdentity{Issuer: claims.Issuer, Subject: claims.Subject}
+	}
+
+	if req.LinkUserID != nil {
+		user, err := s.linkIdentity(ctx, *req.LinkUserID, identity, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCLoginResult{LoginResult: LoginResult{User: user}, Identity: identity, Linked: true}, nil
+	}
+
+	user, err := s.resolveIdentity(ctx, identity, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+	// The identity provider is the first factor; users with two-factor authentication still
+	// need their code
+	login, err := s.completeFirstFactor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCLoginResult{LoginResult: *login, Identity: identity}, nil
+}
+
+// resolveIdentity finds the user an identity logs in as: the user it is linked to, the user
+// with the same verified email when linking by email is allowed, or a newly provisioned user
+func (s *userService) resolveIdentity(ctx context.Context, identity *models.UserIdentity, claims *oidc.Claims) (*models.User, error) {
+	if identity.ID != 0 {
+		user, err := s.repo.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.RecordUserIdentityLogin(ctx, identity.ID, claims.Email, time.Now()); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// Both sides must have verified the address: otherwise whoever registers an account, or an
+	// identity, with someone else's address would take over that person's account
+	if s.opts.OIDCLinkByEmail && claims.EmailVerified && claims.Email != "" {
+		user, err := s.repo.GetUserByEmail(ctx, normalizeEmail(claims.Email))
+		if err == nil {
+			if user.EmailVerifiedAt == nil {
+				return nil, appErrors.NewConflictError("An account with this email address exists; sign in to it and link the identity from the account settings", nil)
+			}
+			if err := ensureEnabled(user); err != nil {
+				return nil, err
+			}
+			return s.linkIdentity(ctx, user.ID, identity, claims)
+		}
+		if !appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, err
+		}
+	}
+	if !s.opts.OIDCAutoProvision {
+		return nil, appErrors.NewUnauthorizedError("No account is linked to this identity", nil)
+	}
+	return s.provisionUser(ctx, identity, claims)
+}
+
+// GetIdentities retrieves the external identities linked to a user
+func (s *userService) GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error) {
+	return s.repo.GetUserIdentities(ctx, userID)
+}
+
+// UnlinkIdentity removes an external identity from a user. The last identity of a user without
+// a password cannot be removed, as the user could no longer log in.
+func (s *userService) UnlinkIdentity(ctx context.Context, userID, id uint) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.repo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var identity *models.UserIdentity
+	for i := range identities {
+		if identities[i].ID == id {
+			identity = &identities[i]
+		}
+	}
+	if identity == nil {
+		return appErrors.NewNotFoundError(fmt.Sprintf("Identity with ID %d not found", id), nil)
+	}
+	if user.PasswordHash == "" && len(identities) <= 1 {
+		return appErrors.NewConflictError("Set a password before unlinking the last identity", nil)
+	}
+
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.DeleteUserIdentity(ctx, userID, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, 0, models.AuditActionDelete, models.AuditEntityUserIdentity, id, auditDiff(identity, nil))
+	})
+}
+
+// linkIdentity links identity to the user, unless it already belongs to someone else
+func (s *userService) linkIdentity(ctx context.Context, userID uint, identity *models.UserIdentity, claims *oidc.Claims) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if identity.ID != 0 && identity.UserID != userID {
+		return nil, appErrors.NewConflictError("This identity is already linked to another user", nil)
+	}
+
+	now := time.Now()
+	if identity.ID == 0 {
+		identity.UserID = userID
+		identity.Email = claims.Email
+		identity.LastLoginAt = &now
+		err := s.repo.Transaction(func(txRepo repository.Repository) error {
+			if err := txRepo.CreateUserIdentity(ctx, identity); err != nil {
+				return err
+			}
+			return recordAudit(ctx, txRepo, userID, 0, models.AuditActionCreate, models.AuditEntityUserIdentity, identity.ID, auditDiff(nil, identity))
+		})
+		if err != nil {
+			return nil, err
+		}
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"audit":   true,
+			"event":   "identity_linked",
+			"userID":  userID,
+			"issuer":  identity.Issuer,
+			"subject": identity.Subject,
+		}).Info("UserService: External identity linked")
+	}
+	return user, nil
+}
+
+// provisionUser creates a user for a new identity, deriving the username from the ID token claims
+func (s *userService) provisionUser(ctx context.Context, identity *models.UserIdentity, claims *oidc.Claims) (*models.User, error) {
+	username, err := s.freeUsername(ctx, usernameCandidate(claims))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Username: username}
+	if email := normalizeEmail(claims.Email); email != "" && claims.EmailVerified {
+		_, err := s.repo.GetUserByEmail(ctx, email)
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			now := time.Now()
+			user.Email = &email
+			user.EmailVerifiedAt = &now
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionCreate, models.AuditEntityUser, user.ID, auditDiff(nil, user)); err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		identity.Email = claims.Email
+		identity.LastLoginAt = &now
+		if err := txRepo.CreateUserIdentity(ctx, identity); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionCreate, models.AuditEntityUserIdentity, identity.ID, auditDiff(nil, identity)); err != nil {
+			return err
+		}
+		return s.createPersonalHousehold(ctx, txRepo, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":    true,
+		"event":    "user_provisioned",
+		"userID":   user.ID,
+		"username": user.Username,
+		"issuer":   identity.Issuer,
+	}).Info("UserService: User provisioned from external identity")
+	return user, nil
+}
+
+// freeUsername returns base, or base with a numeric suffix, whichever is not taken yet
+func (s *userService) freeUsername(ctx context.Context, base string) (string, error) {
+	for i := 1; i <= maxUsernameAttempts; i++ {
+		candidate := base
+		if i > 1 {
+			suffix := fmt.Sprintf("-%d", i)
+			if len(candidate)+len(suffix) > 50 {
+				candidate = candidate[:50-len(suffix)]
+			}
+			candidate += suffix
+		}
+		_, err := s.repo.GetUserByUsername(ctx, candidate)
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	suffix, err := generateToken(6)
+	if err != nil {
+		return "", appErrors.NewInternalError("Failed to generate username", err)
+	}
+	return fmt.Sprintf("%.40s-%s", base, suffix), nil
+}
+
+// usernameCandidate derives a username from the preferred username or email of an identity,
+// keeping letters, digits, dots, dashes and underscores
+func usernameCandidate(claims *oidc.Claims) string {
+	source := claims.PreferredUsername
+	if source == "" {
+		source, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	var b strings.Builder
+	for _, r := range source {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	if len(username) > 50 {
+		username = username[:50]
+	}
+	if len(username) < 3 {
+		username = "user"
+	}
+	return username
+}
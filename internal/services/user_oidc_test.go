@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/oidc"
+	"personal-finance-tracker-api/internal/oidc/oidctest"
+	"personal-finance-tracker-api/internal/repository"
+)
+
+// newTestOIDCService starts a test identity provider and a user service that logs in through it
+func newTestOIDCService(t *testing.T, repo repository.Repository, opts UserServiceOptions) (UserService, *oidctest.Server) {
+	t.Helper()
+	server := oidctest.NewServer(t, "finance-tracker")
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:   server.Issuer(),
+		ClientID:    server.ClientID,
+		RedirectURL: "https://finance.example.com/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	opts.OIDCProvider = provider
+	opts.OIDCLoginTTL = time.Minute
+	opts.LoginChallengeTTL = time.Minute
+	return newTestUserService(t, repo, nil, nil, opts), server
+}
+
+// authorizeOIDC starts a flow, linking to linkUserID when set, and authenticates identity at
+// the provider; it returns the state and code of the callback
+func authorizeOIDC(t *testing.T, service UserService, server *oidctest.Server, linkUserID *uint, identity oidctest.Identity) (string, string) {
+	t.Helper()
+	authorization, err := service.BeginOIDCLogin(context.Background(), linkUserID)
+	assertErrorType(t, err, "")
+	state, code := server.Authorize(t, authorization.URL, identity)
+	if state != authorization.State {
+		t.Fatalf("provider returned state %q, want %q", state, authorization.State)
+	}
+	return state, code
+}
+
+func TestUserServiceOIDCLogin(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service, server := newTestOIDCService(t, repo, UserServiceOptions{OIDCAutoProvision: true})
+	ctx := context.Background()
+	alice := oidctest.Identity{Subject: "alice-sub", Email: "Alice@Example.com", EmailVerified: true, PreferredUsername: "alice"}
+
+	// The first login provisions a user with the verified email address
+	state, code := authorizeOIDC(t, service, server, nil, alice)
+	result, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if result.Linked || result.TwoFactorRequired() || result.User == nil {
+		t.Fatalf("result = %+v, want a completed login", result)
+	}
+	provisioned := result.User
+	if provisioned.Username != "alice" || derefString(provisioned.Email) != "alice@example.com" || provisioned.EmailVerifiedAt == nil {
+		t.Errorf("provisioned user = %+v, want alice with the verified email alice@example.com", provisioned)
+	}
+	if result.Identity.Issuer != server.Issuer() || result.Identity.Subject != "alice-sub" || result.Identity.UserID != provisioned.ID {
+		t.Errorf("identity = %+v, want alice-sub of %s linked to user %d", result.Identity, server.Issuer(), provisioned.ID)
+	}
+
+	// Later logins find the linked user
+	state, code = authorizeOIDC(t, service, server, nil, alice)
+	result, err = service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if result.User.ID != provisioned.ID {
+		t.Errorf("logged in as user %d, want %d", result.User.ID, provisioned.ID)
+	}
+
+	tests := []struct {
+		name     string
+		complete func(t *testing.T) error
+	}{
+		{"unknown state", func(t *testing.T) error {
+			_, code := authorizeOIDC(t, service, server, nil, alice)
+			_, err := service.CompleteOIDCLogin(ctx, "not-a-state", code, nil)
+			return err
+		}},
+		{"state used twice", func(t *testing.T) error {
+			state, code := authorizeOIDC(t, service, server, nil, alice)
+			if _, err := service.CompleteOIDCLogin(ctx, state, code, nil); err != nil {
+				t.Fatal(err)
+			}
+			_, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+			return err
+		}},
+		{"ID token for another nonce", func(t *testing.T) error {
+			replayed := alice
+			replayed.Nonce = "nonce-of-another-login"
+			state, code := authorizeOIDC(t, service, server, nil, replayed)
+			_, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+			return err
+		}},
+		{"code redeemed with the verifier of another flow", func(t *testing.T) error {
+			_, code := authorizeOIDC(t, service, server, nil, alice)
+			otherState, _ := authorizeOIDC(t, service, server, nil, alice)
+			_, err := service.CompleteOIDCLogin(ctx, otherState, code, nil)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertErrorType(t, tt.complete(t), appErrors.TypeUnauthorized)
+		})
+	}
+}
+
+func TestUserServiceOIDCLoginWithoutProvisioning(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service, server := newTestOIDCService(t, repo, UserServiceOptions{})
+
+	state, code := authorizeOIDC(t, service, server, nil, oidctest.Identity{Subject: "stranger", PreferredUsername: "stranger"})
+	_, err := service.CompleteOIDCLogin(context.Background(), state, code, nil)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	if _, err := repo.GetUserByUsername(context.Background(), "stranger"); !appErrors.IsType(err, appErrors.TypeNotFound) {
+		t.Errorf("user provisioned although provisioning is disabled: %v", err)
+	}
+}
+
+func TestUserServiceOIDCLink(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service, server := newTestOIDCService(t, repo, UserServiceOptions{})
+	alice := registerUser(t, repo, "alice")
+	bob := registerUser(t, repo, "bob")
+	ctx := context.Background()
+	identity := oidctest.Identity{Subject: "alice-sub"}
+
+	tests := []struct {
+		name          string
+		sessionUserID *uint
+		wantErr       appErrors.ErrorType
+	}{
+		{"without a session", nil, appErrors.TypeUnauthorized},
+		{"completed by another user", &bob.ID, appErrors.TypeUnauthorized},
+		{"completed by the user who started it", &alice.ID, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, code := authorizeOIDC(t, service, server, &alice.ID, identity)
+			result, err := service.CompleteOIDCLogin(ctx, state, code, tt.sessionUserID)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr == "" && (!result.Linked || result.Identity.UserID != alice.ID) {
+				t.Errorf("result = %+v, want the identity linked to alice", result)
+			}
+		})
+	}
+
+	// The linked identity logs in as alice, and cannot be linked to bob as well
+	state, code := authorizeOIDC(t, service, server, nil, identity)
+	result, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if result.User.ID != alice.ID {
+		t.Errorf("logged in as user %d, want alice (%d)", result.User.ID, alice.ID)
+	}
+	state, code = authorizeOIDC(t, service, server, &bob.ID, identity)
+	_, err = service.CompleteOIDCLogin(ctx, state, code, &bob.ID)
+	assertErrorType(t, err, appErrors.TypeConflict)
+}
+
+func TestUserServiceOIDCLinkByEmail(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	notifier := &recordingNotifier{}
+	service, server := newTestOIDCService(t, repo, UserServiceOptions{OIDCLinkByEmail: true, OIDCAutoProvision: true})
+	ctx := context.Background()
+	alice, err := service.RegisterUser(ctx, "alice", testPassword, "alice@example.com")
+	assertErrorType(t, err, "")
+	identity := oidctest.Identity{Subject: "alice-sub", Email: "alice@example.com", EmailVerified: true}
+
+	// Whoever registered the address locally has not proved they own it
+	state, code := authorizeOIDC(t, service, server, nil, identity)
+	_, err = service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, appErrors.TypeConflict)
+
+	// Completing a password reset sent to the address verifies it
+	resetting := newTestUserService(t, repo, nil, notifier, UserServiceOptions{PasswordResetURL: "https://example.com/reset?token={token}"})
+	assertErrorType(t, resetting.RequestPasswordReset(ctx, "alice"), "")
+	if len(notifier.messages) != 1 {
+		t.Fatalf("messages = %+v, want one reset link", notifier.messages)
+	}
+	match := resetTokenPattern.FindStringSubmatch(notifier.messages[0].Body)
+	if match == nil {
+		t.Fatalf("no reset link in %q", notifier.messages[0].Body)
+	}
+	assertErrorType(t, resetting.ResetPassword(ctx, match[1], "a brand new passphrase"), "")
+
+	// An address the provider has not verified is never linked
+	unverified := oidctest.Identity{Subject: "other-sub", Email: "alice@example.com", PreferredUsername: "mallory"}
+	state, code = authorizeOIDC(t, service, server, nil, unverified)
+	result, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if result.User.ID == alice.ID || result.User.Email != nil {
+		t.Errorf("unverified identity logged in as %+v, want a new user without email", result.User)
+	}
+
+	state, code = authorizeOIDC(t, service, server, nil, identity)
+	result, err = service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if result.User.ID != alice.ID || result.Identity.UserID != alice.ID {
+		t.Errorf("result = %+v, want a login as alice with the identity linked", result)
+	}
+}
+
+func TestUserServiceOIDCLoginWithTwoFactor(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service, server := newTestOIDCService(t, repo, UserServiceOptions{OIDCAutoProvision: true, TOTPIssuer: "Test"})
+	ctx := context.Background()
+	identity := oidctest.Identity{Subject: "alice-sub", PreferredUsername: "alice"}
+
+	state, code := authorizeOIDC(t, service, server, nil, identity)
+	result, err := service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	alice := result.User
+
+	enrollment, err := service.BeginTOTPEnrollment(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	totpCode, err := auth.TOTPCode(enrollment.Secret, auth.TOTPCounter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := service.ConfirmTOTPEnrollment(ctx, alice.ID, totpCode)
+	assertErrorType(t, err, "")
+
+	// The identity provider replaces the password, not the second factor
+	state, code = authorizeOIDC(t, service, server, nil, identity)
+	result, err = service.CompleteOIDCLogin(ctx, state, code, nil)
+	assertErrorType(t, err, "")
+	if !result.TwoFactorRequired() || result.User != nil {
+		t.Fatalf("result = %+v, want a two-factor challenge", result)
+	}
+	user, err := service.CompleteLoginChallenge(ctx, result.ChallengeToken, recoveryCodes[0], "192.0.2.1")
+	assertErrorType(t, err, "")
+	if user.ID != alice.ID {
+		t.Errorf("logged in as user %d, want %d", user.ID, alice.ID)
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ChangePassword replaces the password of a signed-in user after checking the current one.
+// Every existing session of the user is signed out. Wrong current passwords count as failed
+// logins, so a stolen session cannot be used to guess the password.
+func (s *userService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword, clientIP string) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" {
+		return nil, appErrors.NewValidationError("No password is set; use the password reset to set one", nil)
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Check(ctx, user.Username, clientIP); err != nil {
+			return nil, err
+		}
+	}
+	match, _, err := s.opts.PasswordHasher.Verify(currentPassword, user.PasswordHash)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to verify password", err)
+	}
+	if !match {
+		if s.limiter != nil {
+			if err := s.limiter.RecordFailure(ctx, user.Username, clientIP); err != nil {
+				return nil, err
+			}
+		}
+		return nil, appErrors.NewValidationError("Current password is incorrect", nil)
+	}
+	if s.limiter != nil {
+		if err := s.limiter.RecordSuccess(ctx, user.Username); err != nil {
+			return nil, err
+		}
+	}
+	if currentPassword == newPassword {
+		return nil, appErrors.NewValidationError("New password must differ from the current password", nil)
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RequestPasswordReset sends a reset link to the email address of the user identified by
+// username or email. It succeeds whether or not such a user exists, so it cannot be used to
+// probe for accounts; the notifier should deliver in the background, e.g. notify.AsyncNotifier,
+// so that the response time does not reveal it either.
+func (s *userService) RequestPasswordReset(ctx context.Context, identifier string) error {
+	identifier = strings.TrimSpace(identifier)
+
+	var user *models.User
+	var err error
+	if strings.Contains(identifier, "@") {
+		user, err = s.repo.GetUserByEmail(ctx, normalizeEmail(identifier))
+	} else {
+		user, err = s.repo.GetUserByUsername(ctx, identifier)
+	}
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.Email == nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"userID": user.ID,
+		}).Warn("UserService: Password reset requested for a user without an email address")
+		return nil
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to generate password reset token", err)
+	}
+	reset := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.opts.PasswordResetTTL),
+	}
+
+	// Only the most recently sent link works
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		return txRepo.CreatePasswordResetToken(ctx, reset)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.notifier.Send(ctx, notify.Message{
+		To:      *user.Email,
+		Subject: "Reset your password",
+		Body:    s.passwordResetBody(user, token),
+	})
+	if err != nil {
+		// Failing the request would reveal that the account exists
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": user.ID,
+		}).Error("UserService: Failed to send password reset message")
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a token from RequestPasswordReset. It also lifts any
+// login lockout, since proving access to the user's email is the way out of one.
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	reset, err := s.repo.GetPasswordResetTokenByHash(ctx, hashToken(token))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return appErrors.NewValidationError("Invalid or expired password reset token", nil)
+		}
+		return err
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return appErrors.NewValidationError("Invalid or expired password reset token", nil)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPasswordPolicy(newPassword, user.Username); err != nil {
+		return err
+	}
+
+	used, err := s.repo.MarkPasswordResetTokenUsed(ctx, reset.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return appErrors.NewValidationError("Invalid or expired password reset token", nil)
+	}
+
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	// The reset link was sent to the user's address, so following it proves control of it
+	if user.Email != nil && user.EmailVerifiedAt == nil {
+		if _, err := s.repo.SetUserEmailVerified(ctx, user.ID, *user.Email, time.Now()); err != nil {
+			return err
+		}
+	}
+	if s.limiter != nil {
+		return s.limiter.Unlock(ctx, user.Username)
+	}
+	return nil
+}
+
+// setPassword validates, hashes and stores a new password, then signs out every session of the user
+// and revokes their personal access tokens, which may have been created with the old password
+func (s *userService) setPassword(ctx context.Context, user *models.User, password string) error {
+	if err := s.checkPasswordPolicy(password, user.Username); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.opts.PasswordHasher.Hash(password)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to hash password", err)
+	}
+
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+			return err
+		}
+		if err := txRepo.RevokeUserSessions(ctx, user.ID, time.Now()); err != nil {
+			return err
+		}
+		if _, err := txRepo.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+			return err
+		}
+		if _, err := txRepo.RevokeUserAPITokens(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := txRepo.InvalidatePasswordResetTokens(ctx, user.ID); err != nil {
+			return err
+		}
+
+		change := models.AuditChange{After: auditRedacted}
+		if user.PasswordHash != "" {
+			change.Before = auditRedacted
+		}
+		return recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionUpdate, models.AuditEntityUser, user.ID, map[string]models.AuditChange{
+			"password": change,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hashedPassword
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"audit":  true,
+		"event":  "password_changed",
+		"userID": user.ID,
+	}).Info("UserService: Password changed and sessions and API tokens revoked")
+	return nil
+}
+
+// checkPasswordPolicy rejects passwords that do not meet the configured policy
+func (s *userService) checkPasswordPolicy(password, username string) error {
+	problems := s.opts.PasswordPolicy.Validate(password, username)
+	if len(problems) == 0 {
+		return nil
+	}
+	return appErrors.NewValidationError("Password "+strings.Join(problems, ", "), nil)
+}
+
+// passwordResetBody renders the message containing the reset link or token
+func (s *userService) passwordResetBody(user *models.User, token string) string {
+	link := token
+	if s.opts.PasswordResetURL != "" {
+		link = strings.ReplaceAll(s.opts.PasswordResetURL, "{token}", token)
+	}
+	return fmt.Sprintf("Hello %s,\n\n"+
+		"We received a request to reset your password. Use the following link within %s to choose a new one:\n\n"+
+		"%s\n\n"+
+		"If you did not request this, you can ignore this message; your password has not been changed.\n",
+		user.Username, s.opts.PasswordResetTTL, link)
+}
+
+// normalizeEmail trims and lower-cases an email address so lookups are case-insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
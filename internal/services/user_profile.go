@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/repository"
+	"strings"
+	"time"
+)
+
+// weekdayNames are the accepted values of a user's first day of the week
+var weekdayNames = map[string]bool{
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true,
+	"friday": true, "saturday": true, "sunday": true,
+}
+
+// UserProfile holds the editable profile and preferences of a user. Every field is replaced;
+// an empty DisplayName or Email removes it.
+type UserProfile struct {
+	DisplayName     string
+	Email           string
+	Locale          string // BCP 47 language tag, e.g. en-GB
+	Timezone        string // IANA time zone name, e.g. Europe/London
+	BaseCurrency    string // ISO 4217 currency code
+	FirstDayOfWeek  string // English weekday name
+	FiscalYearStart string // Month and day as MM-DD
+	CurrentPassword string // Required to change the email address of users with a password
+}
+
+// GetProfile retrieves the profile and preferences of a user
+func (s *userService) GetProfile(ctx context.Context, userID uint) (*models.User, error) {
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// UpdateProfile replaces the profile and preferences of a user. Since the email address receives
+// password reset links, users with a password must enter it again to change the address.
+func (s *userService) UpdateProfile(ctx context.Context, userID uint, profile UserProfile) (*models.User, error) {
+	if _, err := time.LoadLocation(profile.Timezone); err != nil || profile.Timezone == "" || profile.Timezone == "Local" {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Unknown time zone '%s'", profile.Timezone), nil)
+	}
+	firstDayOfWeek := strings.ToLower(profile.FirstDayOfWeek)
+	if !weekdayNames[firstDayOfWeek] {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Invalid first day of week '%s'", profile.FirstDayOfWeek), nil)
+	}
+	// Parsing with a common year rejects February 29, which most years lack
+	if _, err := time.Parse("2006-01-02", "2023-"+profile.FiscalYearStart); err != nil || len(profile.FiscalYearStart) != 5 {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Invalid fiscal year start '%s'. Expected MM-DD", profile.FiscalYearStart), nil)
+	}
+
+	var updated *models.User
+	err := s.repo.Transaction(func(txRepo repository.Repository) error {
+		existing, err := txRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		user := *existing
+		user.DisplayName = strings.TrimSpace(profile.DisplayName)
+		user.Locale = profile.Locale
+		user.Timezone = profile.Timezone
+		user.BaseCurrency = strings.ToUpper(profile.BaseCurrency)
+		user.FirstDayOfWeek = firstDayOfWeek
+		user.FiscalYearStart = profile.FiscalYearStart
+		user.Email = nil
+		if email := normalizeEmail(profile.Email); email != "" {
+			user.Email = &email
+		}
+
+		emailChanged := derefString(user.Email) != derefString(existing.Email)
+		if emailChanged && existing.PasswordHash != "" {
+			match, _, err := s.opts.PasswordHasher.Verify(profile.CurrentPassword, existing.PasswordHash)
+			if err != nil {
+				return appErrors.NewInternalError("Failed to verify password", err)
+			}
+			if !match {
+				return appErrors.NewValidationError("Current password is required to change the email address", nil)
+			}
+		}
+		if emailChanged {
+			// A new address is unverified, and reset links sent to the old one must not verify it
+			user.EmailVerifiedAt = nil
+			if err := txRepo.InvalidatePasswordResetTokens(ctx, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := txRepo.UpdateUserProfile(ctx, &user); err != nil {
+			return err
+		}
+		if changes := auditDiff(existing, &user); len(changes) > 0 {
+			if err := recordAudit(ctx, txRepo, userID, 0, models.AuditActionUpdate, models.AuditEntityUser, userID, changes); err != nil {
+				return err
+			}
+		}
+		updated = &user
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// derefString returns the value of s, or "" when it is nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -2,62 +2,578 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"personal-finance-tracker-api/internal/auth"
 	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/metrics"
 	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
 	"personal-finance-tracker-api/internal/repository"
+	"personal-finance-tracker-api/internal/templates"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	recoveryCodeCount    = 10 // recovery codes issued per enrolment or regeneration
+	maxChallengeAttempts = 5  // wrong codes accepted before a login challenge is void
+)
+
 // UserService defines the interface for user-related business logic
 type UserService interface {
-	RegisterUser(ctx context.Context, username, password string) (*models.User, error)
+	RegisterUser(ctx context.Context, username, password, email string) (*models.User, error)
 	AuthenticateUser(ctx context.Context, username, password string) (*models.User, error)
+	Login(ctx context.Context, username, password, clientIP string) (*LoginResult, error)
+	UnlockAccount(ctx context.Context, userID uint) error
+	CompleteLoginChallenge(ctx context.Context, challengeToken, code, clientIP string) (*models.User, error)
+	GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uint, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error)
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword, clientIP string) (*models.User, error)
+	RequestPasswordReset(ctx context.Context, identifier string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	BeginOIDCLogin(ctx context.Context, linkUserID *uint) (*OIDCAuthorization, error)
+	CompleteOIDCLogin(ctx context.Context, state, code string, sessionUserID *uint) (*OIDCLoginResult, error)
+	GetIdentities(ctx context.Context, userID uint) ([]models.UserIdentity, error)
+	UnlinkIdentity(ctx context.Context, userID, id uint) error
+	GetProfile(ctx context.Context, userID uint) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID uint, profile UserProfile) (*models.User, error)
+	ExportUserData(ctx context.Context, userID uint) (*UserDataExport, error)
+	RequestAccountDeletion(ctx context.Context, userID uint, mode models.AccountDeletionMode, password string) (*models.AccountDeletion, string, error)
+	ConfirmAccountDeletion(ctx context.Context, userID uint, token string) (*models.AccountDeletion, error)
+	GetAccountDeletion(ctx context.Context, userID uint) (*models.AccountDeletion, error)
+	CancelAccountDeletion(ctx context.Context, userID uint) error
+	DeleteDueAccounts(ctx context.Context, now time.Time) (int, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	ListUsers(ctx context.Context, limit, offset int) ([]models.User, error)
+	DisableUser(ctx context.Context, userID uint) (*models.User, error)
+	EnableUser(ctx context.Context, userID uint) (*models.User, error)
+	SetPassword(ctx context.Context, userID uint, newPassword string) error
+}
+
+// UserServiceOptions configures a UserService
+type UserServiceOptions struct {
+	DefaultCategoryTemplate    string        // Template applied to new users; empty skips seeding
+	TOTPIssuer                 string        // Issuer shown in authenticator apps
+	LoginChallengeTTL          time.Duration // Time allowed for the second login step
+	PasswordPolicy             auth.PasswordPolicy
+	PasswordHasher             *auth.PasswordHasher // Defaults to bcrypt at bcrypt.DefaultCost
+	PasswordResetTTL           time.Duration        // Lifetime of password reset tokens
+	PasswordResetURL           string               // Link sent for password resets; "{token}" is replaced by the token
+	OIDCProvider               OIDCProvider         // Identity provider for single sign-on; nil disables OIDC login
+	OIDCLoginTTL               time.Duration        // Time allowed to complete a login at the provider
+	OIDCAutoProvision          bool                 // Create users for unknown identities on their first login
+	OIDCLinkByEmail            bool                 // Link unknown identities to the user with the same verified email
+	AccountDeletionConfirmTTL  time.Duration        // Time allowed to confirm an account deletion request
+	AccountDeletionGracePeriod time.Duration        // Time between confirming and carrying out an account deletion
+}
+
+// LoginResult is the outcome of a password login. Users with two-factor authentication
+// receive a challenge token instead of being logged in directly.
+type LoginResult struct {
+	User               *models.User // Set when the login is complete
+	ChallengeToken     string       // Set when a TOTP or recovery code is still required
+	ChallengeExpiresAt time.Time
+}
+
+// TwoFactorRequired reports whether the login must be completed with a second factor
+func (r *LoginResult) TwoFactorRequired() bool {
+	return r.ChallengeToken != ""
+}
+
+// TOTPEnrollment holds the secret of a pending TOTP enrolment
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"` // otpauth:// URI, usually rendered as a QR code
+}
+
+// TwoFactorStatus describes the two-factor authentication state of a user
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
 }
 
 // userService implements the UserService interface
 type userService struct {
-	repo repository.Repository
+	repo      repository.Repository
+	templates *templates.Registry
+	limiter   *LoginLimiter
+	notifier  notify.Notifier
+	opts      UserServiceOptions
 }
 
-// NewUserService creates a new instance of UserService
-func NewUserService(repo repository.Repository) UserService {
-	return &userService{repo: repo}
+// NewUserService creates a new instance of UserService.
+// Failed logins are throttled by limiter; a nil limiter disables throttling.
+// Password reset links are delivered through notifier.
+func NewUserService(repo repository.Repository, templates *templates.Registry, limiter *LoginLimiter, notifier notify.Notifier, opts UserServiceOptions) UserService {
+	if opts.PasswordHasher == nil {
+		algorithm, _ := auth.NewBcrypt(bcrypt.DefaultCost)
+		opts.PasswordHasher = auth.NewPasswordHasher(algorithm)
+	}
+	return &userService{repo: repo, templates: templates, limiter: limiter, notifier: notifier, opts: opts}
 }
 
 // RegisterUser handles new user registration, including password hashing
-func (s *userService) RegisterUser(ctx context.Context, username, password string) (*models.User, error) {
+func (s *userService) RegisterUser(ctx context.Context, username, password, email string) (*models.User, error) {
+	if err := s.checkPasswordPolicy(password, username); err != nil {
+		return nil, err
+	}
+
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.opts.PasswordHasher.Hash(password)
 	if err != nil {
 		return nil, appErrors.NewInternalError("Failed to hash password", err)
 	}
 
 	user := &models.User{
 		Username:     username,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+	}
+	if email = normalizeEmail(email); email != "" {
+		user.Email = &email
 	}
 
-	if err := s.repo.CreateUser(ctx, user); err != nil {
+	// Create the user with their personal household and seed its categories atomically
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionCreate, models.AuditEntityUser, user.ID, auditDiff(nil, user)); err != nil {
+			return err
+		}
+		return s.createPersonalHousehold(ctx, txRepo, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-// AuthenticateUser authenticates a user by username and password
+// AuthenticateUser authenticates a user by username and password. Users without a password hash
+// to check, unknown ones included, take as long to reject as a wrong password.
 func (s *userService) AuthenticateUser(ctx context.Context, username, password string) (*models.User, error) {
 	user, err := s.repo.GetUserByUsername(ctx, username)
 	if err != nil {
 		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			s.opts.PasswordHasher.VerifyDummy(password)
 			return nil, appErrors.NewUnauthorizedError("Invalid credentials", nil)
 		}
 		return nil, appErrors.NewInternalError("Failed to authenticate user due to internal error", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	// Users provisioned through single sign-on have no password until they set one
+	if user.PasswordHash == "" {
+		s.opts.PasswordHasher.VerifyDummy(password)
 		return nil, appErrors.NewUnauthorizedError("Invalid credentials", nil)
 	}
 
+	match, needsRehash, err := s.opts.PasswordHasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to verify password", err)
+	}
+	if !match {
+		return nil, appErrors.NewUnauthorizedError("Invalid credentials", nil)
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+	if needsRehash {
+		s.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
+// rehashPassword upgrades an outdated password hash after a successful login.
+// Failures are logged only; the old hash keeps working and is upgraded on a later login.
+func (s *userService) rehashPassword(ctx context.Context, user *models.User, password string) {
+	hashedPassword, err := s.opts.PasswordHasher.Hash(password)
+	if err == nil {
+		err = s.repo.Transaction(func(txRepo repository.Repository) error {
+			replaced, err := txRepo.ReplacePasswordHash(ctx, user.ID, user.PasswordHash, hashedPassword)
+			if err != nil || !replaced {
+				return err
+			}
+			user.PasswordHash = hashedPassword
+			return recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionUpdate, models.AuditEntityUser, user.ID, map[string]models.AuditChange{
+				"password": {Before: auditRedacted, After: auditRedacted},
+			})
+		})
+	}
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"error":  err.Error(),
+			"userID": user.ID,
+		}).Warn("UserService: Failed to upgrade password hash")
+		return
+	}
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"userID":    user.ID,
+		"algorithm": s.opts.PasswordHasher.Algorithm(),
+	}).Info("UserService: Password hash upgraded")
+}
+
+// Login checks a username and password. When the user has two-factor authentication
+// enabled, a short-lived challenge is created that CompleteLoginChallenge exchanges for the user.
+// Repeated failures for the username or from clientIP are throttled and eventually locked out.
+func (s *userService) Login(ctx context.Context, username, password, clientIP string) (*LoginResult, error) {
+	if s.limiter != nil {
+		if err := s.limiter.Check(ctx, username, clientIP); err != nil {
+			recordLoginFailure(err, metrics.LoginFailureRateLimited)
+			return nil, err
+		}
+	}
+
+	user, err := s.AuthenticateUser(ctx, username, password)
+	if err != nil {
+		recordLoginFailure(err, metrics.LoginFailureInvalidCredentials)
+		if s.limiter != nil && appErrors.IsType(err, appErrors.TypeUnauthorized) {
+			if limitErr := s.limiter.RecordFailure(ctx, username, clientIP); limitErr != nil {
+				return nil, limitErr
+			}
+		}
+		return nil, err
+	}
+
+	result, err := s.completeFirstFactor(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	// Failures are only cleared once the login is complete, so that logging in with the password
+	// again does not reset the count of wrong second factor codes
+	if s.limiter != nil && !result.TwoFactorRequired() {
+		if err := s.limiter.RecordSuccess(ctx, username); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// completeFirstFactor finishes a login whose first factor, a password or an identity provider,
+// has been accepted: users with two-factor authentication get a challenge, the others are logged in
+func (s *userService) completeFirstFactor(ctx context.Context, user *models.User) (*LoginResult, error) {
+	if !user.TOTPEnabled {
+		return &LoginResult{User: user}, nil
+	}
+
+	token, err := generateToken(32)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate login challenge", err)
+	}
+	challenge := &models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.opts.LoginChallengeTTL),
+	}
+	if err := s.repo.CreateLoginChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{ChallengeToken: token, ChallengeExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// recordLoginFailure counts a rejected login under reason, unless err is not a rejection but
+// e.g. a database failure
+func recordLoginFailure(err error, reason string) {
+	if appErrors.IsType(err, appErrors.TypeUnauthorized) || appErrors.IsType(err, appErrors.TypeRateLimited) {
+		metrics.LoginFailures.WithLabelValues(reason).Inc()
+	}
+}
+
+// UnlockAccount lifts a login lockout of the user, e.g. from a session that is still signed in
+func (s *userService) UnlockAccount(ctx context.Context, userID uint) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.Unlock(ctx, user.Username)
+}
+
+// CompleteLoginChallenge finishes a two-factor login with a TOTP or recovery code.
+// Wrong codes count as failed logins, so requesting new challenges does not allow unlimited guessing.
+func (s *userService) CompleteLoginChallenge(ctx context.Context, challengeToken, code, clientIP string) (*models.User, error) {
+	challenge, err := s.repo.GetLoginChallengeByHash(ctx, hashToken(challengeToken))
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid login challenge", nil)
+		}
+		return nil, err
+	}
+	if challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxChallengeAttempts {
+		return nil, appErrors.NewUnauthorizedError("Login challenge has expired; log in again", nil)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		if appErrors.IsType(err, appErrors.TypeNotFound) {
+			return nil, appErrors.NewUnauthorizedError("Invalid login challenge", nil)
+		}
+		return nil, err
+	}
+	if s.limiter != nil {
+		if err := s.limiter.Check(ctx, user.Username, clientIP); err != nil {
+			recordLoginFailure(err, metrics.LoginFailureRateLimited)
+			return nil, err
+		}
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginFailureInvalidCode).Inc()
+		if err := s.repo.RecordLoginChallengeAttempt(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+		if s.limiter != nil {
+			if err := s.limiter.RecordFailure(ctx, user.Username, clientIP); err != nil {
+				return nil, err
+			}
+		}
+		return nil, appErrors.NewUnauthorizedError("Invalid authentication code", nil)
+	}
+
+	completed, err := s.repo.MarkLoginChallengeUsed(ctx, challenge.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, appErrors.NewUnauthorizedError("Login challenge has already been used", nil)
+	}
+	if err := ensureEnabled(user); err != nil {
+		return nil, err
+	}
+	if s.limiter != nil {
+		if err := s.limiter.RecordSuccess(ctx, user.Username); err != nil {
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
+
+// GetTwoFactorStatus reports whether a user has two-factor authentication enabled
+func (s *userService) GetTwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: user.TOTPEnabled}
+	if user.TOTPEnabled {
+		status.RecoveryCodesRemaining, err = s.repo.CountUnusedRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret. It takes effect once confirmed with a valid code;
+// starting again before confirming replaces the pending secret.
+func (s *userService) BeginTOTPEnrollment(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, appErrors.NewConflictError("Two-factor authentication is already enabled", nil)
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to generate TOTP secret", err)
+	}
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.UpdateUserTOTP(ctx, userID, secret, false, 0); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, 0, models.AuditActionUpdate, models.AuditEntityUser, userID, map[string]models.AuditChange{
+			"totpSecret": {After: auditRedacted},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.opts.TOTPIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables two-factor authentication after checking a code from the
+// authenticator app, and returns a fresh set of recovery codes
+func (s *userService) ConfirmTOTPEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, appErrors.NewConflictError("Two-factor authentication is already enabled", nil)
+	}
+	if user.TOTPSecret == "" {
+		return nil, appErrors.NewValidationError("No two-factor enrolment in progress", nil)
+	}
+
+	counter, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, appErrors.NewValidationError("Invalid authentication code", nil)
+	}
+
+	var codes []string
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.UpdateUserTOTP(ctx, userID, user.TOTPSecret, true, counter); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(ctx, txRepo, userID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, userID, 0, models.AuditActionUpdate, models.AuditEntityUser, userID, map[string]models.AuditChange{
+			"totpEnabled":   {Before: false, After: true},
+			"recoveryCodes": {After: auditRedacted},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication; it requires a current TOTP or recovery code
+func (s *userService) DisableTOTP(ctx context.Context, userID uint, code string) error {
+	user, err := s.enabledTwoFactorUser(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Transaction(func(txRepo repository.Repository) error {
+		if err := txRepo.UpdateUserTOTP(ctx, user.ID, "", false, 0); err != nil {
+			return err
+		}
+		if err := txRepo.ReplaceRecoveryCodes(ctx, user.ID, nil); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionUpdate, models.AuditEntityUser, user.ID, map[string]models.AuditChange{
+			"totpEnabled":   {Before: true, After: false},
+			"totpSecret":    {Before: auditRedacted},
+			"recoveryCodes": {Before: auditRedacted},
+		})
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; it requires a current TOTP or recovery code
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.enabledTwoFactorUser(ctx, userID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.repo.Transaction(func(txRepo repository.Repository) error {
+		codes, err = replaceRecoveryCodes(ctx, txRepo, user.ID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txRepo, user.ID, 0, models.AuditActionUpdate, models.AuditEntityUser, user.ID, map[string]models.AuditChange{
+			"recoveryCodes": {Before: auditRedacted, After: auditRedacted},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// enabledTwoFactorUser loads a user with two-factor authentication enabled and checks their code
+func (s *userService) enabledTwoFactorUser(ctx context.Context, userID uint, code string) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, appErrors.NewConflictError("Two-factor authentication is not enabled", nil)
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, appErrors.NewValidationError("Invalid authentication code", nil)
+	}
+	return user, nil
+}
+
+// verifySecondFactor checks a TOTP code, or failing that a recovery code, and consumes it
+func (s *userService) verifySecondFactor(ctx context.Context, user *models.User, code string) (bool, error) {
+	if counter, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now()); ok {
+		// A code is only valid once, even within its time step
+		return s.repo.AdvanceTOTPCounter(ctx, user.ID, counter)
+	}
+
+	normalized := auth.NormalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+	return s.repo.UseRecoveryCode(ctx, user.ID, hashToken(normalized))
+}
+
+// replaceRecoveryCodes generates and stores a new set of recovery codes, returning them in plaintext
+func replaceRecoveryCodes(ctx context.Context, repo repository.Repository, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := auth.GenerateRecoveryCode()
+		if err != nil {
+			return nil, appErrors.NewInternalError("Failed to generate recovery codes", err)
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(auth.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := repo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// createPersonalHousehold creates a household owned by a newly created user, makes it their
+// default household and applies the default category template to it
+func (s *userService) createPersonalHousehold(ctx context.Context, repo repository.Repository, user *models.User) error {
+	household := &models.Household{Name: user.Username}
+	if err := repo.CreateHousehold(ctx, household); err != nil {
+		return err
+	}
+	member := &models.HouseholdMember{HouseholdID: household.ID, UserID: user.ID, Role: models.RoleOwner}
+	if err := repo.AddHouseholdMember(ctx, member); err != nil {
+		return err
+	}
+	if err := repo.SetUserDefaultHousehold(ctx, user.ID, &household.ID); err != nil {
+		return err
+	}
+	user.DefaultHouseholdID = &household.ID
+
+	if s.opts.DefaultCategoryTemplate == "" {
+		return nil
+	}
+	template, ok := s.templates.Get(s.opts.DefaultCategoryTemplate)
+	if !ok {
+		return appErrors.NewInternalError(fmt.Sprintf("Default category template '%s' not found", s.opts.DefaultCategoryTemplate), nil)
+	}
+	_, err := applyCategoryTemplate(ctx, repo, user.ID, household.ID, template)
+	return err
+}
@@ -0,0 +1,618 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"personal-finance-tracker-api/internal/auth"
+	appErrors "personal-finance-tracker-api/internal/errors"
+	"personal-finance-tracker-api/internal/metrics"
+	"personal-finance-tracker-api/internal/models"
+	"personal-finance-tracker-api/internal/notify"
+	"personal-finance-tracker-api/internal/repository"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserServiceRegisterUser(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := newTestUserService(t, repo, nil, nil, UserServiceOptions{DefaultCategoryTemplate: "basic"})
+	if _, err := service.RegisterUser(context.Background(), "alice", testPassword, "alice@example.com"); err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		email     string
+		wantErr   appErrors.ErrorType
+		wantEmail string
+	}{
+		{"without email", "bob", testPassword, "", "", ""},
+		{"email is normalised", "carol", testPassword, "  Carol@Example.COM ", "", "carol@example.com"},
+		{"short password", "dave", "short", "", appErrors.TypeValidation, ""},
+		{"taken username", "alice", testPassword, "", appErrors.TypeAlreadyExists, ""},
+		{"taken email in other case", "erin", testPassword, "ALICE@example.com", appErrors.TypeAlreadyExists, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			user, err := service.RegisterUser(ctx, tt.username, tt.password, tt.email)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				if tt.username != "alice" {
+					if _, err := repo.GetUserByUsername(ctx, tt.username); !appErrors.IsType(err, appErrors.TypeNotFound) {
+						t.Errorf("user stored despite the error: %v", err)
+					}
+				}
+				return
+			}
+
+			if derefString(user.Email) != tt.wantEmail {
+				t.Errorf("email = %q, want %q", derefString(user.Email), tt.wantEmail)
+			}
+			if user.PasswordHash == "" || user.PasswordHash == tt.password {
+				t.Error("password not hashed")
+			}
+			if user.DefaultHouseholdID == nil {
+				t.Fatal("no default household")
+			}
+			member, err := repo.GetHouseholdMember(ctx, *user.DefaultHouseholdID, user.ID)
+			if err != nil {
+				t.Fatalf("GetHouseholdMember: %v", err)
+			}
+			if member.Role != models.RoleOwner {
+				t.Errorf("role in the personal household = %q, want %q", member.Role, models.RoleOwner)
+			}
+			categories, err := repo.GetCategories(ctx, *user.DefaultHouseholdID, 0, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(categories) != 9 {
+				t.Errorf("personal household has %d categories, want the 9 of the basic template", len(categories))
+			}
+		})
+	}
+}
+
+func TestUserServiceLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int // Failed logins before the attempt
+		username   string
+		password   string
+		wantErr    appErrors.ErrorType
+		wantMetric string // Reason the attempt is counted under in the login failure metric
+	}{
+		{"correct password", 0, "alice", testPassword, "", ""},
+		{"wrong password", 0, "alice", "wrong password!", appErrors.TypeUnauthorized, metrics.LoginFailureInvalidCredentials},
+		{"unknown user", 0, "mallory", testPassword, appErrors.TypeUnauthorized, metrics.LoginFailureInvalidCredentials},
+		{"below the lockout threshold", 2, "alice", testPassword, "", ""},
+		{"locked out", 3, "alice", testPassword, appErrors.TypeRateLimited, metrics.LoginFailureRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			limiter := NewLoginLimiter(NewRepositoryLoginAttemptStore(repo), LoginLimiterOptions{
+				FreeAttempts:             10,
+				UsernameLockoutThreshold: 3,
+				LockoutDuration:          time.Hour,
+				FailureWindow:            time.Hour,
+			})
+			service := newTestUserService(t, repo, limiter, nil, UserServiceOptions{})
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+
+			for i := 0; i < tt.failures; i++ {
+				_, err := service.Login(ctx, "alice", "wrong password!", "192.0.2.1")
+				assertErrorType(t, err, appErrors.TypeUnauthorized)
+			}
+
+			failures := loginFailureCounts()
+			result, err := service.Login(ctx, tt.username, tt.password, "192.0.2.1")
+			assertErrorType(t, err, tt.wantErr)
+			for reason, before := range failures {
+				want := before
+				if reason == tt.wantMetric {
+					want++
+				}
+				if got := testutil.ToFloat64(metrics.LoginFailures.WithLabelValues(reason)); got != want {
+					t.Errorf("login failures for %s = %v, want %v", reason, got, want)
+				}
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			if result.TwoFactorRequired() || result.User == nil || result.User.ID != alice.ID {
+				t.Errorf("result = %+v, want a completed login of user %d", result, alice.ID)
+			}
+			if _, err := repo.GetLoginAttempt(ctx, "user:alice"); !appErrors.IsType(err, appErrors.TypeNotFound) {
+				t.Errorf("failed logins not reset after a successful login: %v", err)
+			}
+		})
+	}
+}
+
+// loginFailureCounts returns the login failure metric of every reason
+func loginFailureCounts() map[string]float64 {
+	counts := make(map[string]float64)
+	for _, reason := range []string{metrics.LoginFailureInvalidCredentials, metrics.LoginFailureInvalidCode, metrics.LoginFailureRateLimited} {
+		counts[reason] = testutil.ToFloat64(metrics.LoginFailures.WithLabelValues(reason))
+	}
+	return counts
+}
+
+// countingAlgorithm counts the passwords verified by the algorithm it wraps
+type countingAlgorithm struct {
+	auth.PasswordAlgorithm
+	verified int
+}
+
+func (a *countingAlgorithm) Verify(password, encoded string) (bool, error) {
+	a.verified++
+	return a.PasswordAlgorithm.Verify(password, encoded)
+}
+
+func TestUserServiceAuthenticateUserVerifiesAHash(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	bcryptAlgorithm, err := auth.NewBcrypt(bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := &countingAlgorithm{PasswordAlgorithm: bcryptAlgorithm}
+	service := NewUserService(repo, testTemplates(t), nil, notify.NewLogNotifier(), UserServiceOptions{
+		PasswordHasher: auth.NewPasswordHasher(algorithm),
+		PasswordPolicy: auth.PasswordPolicy{MinLength: 12, MaxLength: 128},
+	})
+	alice := registerUser(t, repo, "alice")
+	sso := registerUser(t, repo, "sso")
+	if err := repo.UpdateUserPassword(context.Background(), sso.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every rejection costs one verification, so response times do not tell the cases apart
+	for _, username := range []string{"alice", "mallory", "sso"} {
+		t.Run(username, func(t *testing.T) {
+			before := algorithm.verified
+			_, err := service.AuthenticateUser(context.Background(), username, "wrong password!")
+			assertErrorType(t, err, appErrors.TypeUnauthorized)
+			if got := algorithm.verified - before; got != 1 {
+				t.Errorf("%d passwords verified, want 1", got)
+			}
+		})
+	}
+	_, err = service.AuthenticateUser(context.Background(), alice.Username, testPassword)
+	assertErrorType(t, err, "")
+}
+
+func TestUserServiceLoginWithTwoFactor(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := newTestUserService(t, repo, nil, nil, UserServiceOptions{LoginChallengeTTL: time.Minute, TOTPIssuer: "Test"})
+	alice := registerUser(t, repo, "alice")
+	ctx := context.Background()
+
+	enrollment, err := service.BeginTOTPEnrollment(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	code, err := auth.TOTPCode(enrollment.Secret, auth.TOTPCounter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := service.ConfirmTOTPEnrollment(ctx, alice.ID, code)
+	assertErrorType(t, err, "")
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recoveryCodes), recoveryCodeCount)
+	}
+
+	result, err := service.Login(ctx, "alice", testPassword, "192.0.2.1")
+	assertErrorType(t, err, "")
+	if !result.TwoFactorRequired() || result.User != nil {
+		t.Fatalf("result = %+v, want a two-factor challenge", result)
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr appErrors.ErrorType
+	}{
+		{"wrong code", "000000", appErrors.TypeUnauthorized},
+		{"code used for the enrolment", code, appErrors.TypeUnauthorized},
+		{"recovery code", recoveryCodes[0], ""},
+		{"challenge already completed", recoveryCodes[1], appErrors.TypeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := service.CompleteLoginChallenge(ctx, result.ChallengeToken, tt.code, "192.0.2.1")
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr == "" && user.ID != alice.ID {
+				t.Errorf("logged in as user %d, want %d", user.ID, alice.ID)
+			}
+		})
+	}
+
+	status, err := service.GetTwoFactorStatus(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	if !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("status = %+v, want enabled with %d recovery codes left", status, recoveryCodeCount-1)
+	}
+}
+
+func TestUserServiceLoginWithTwoFactorLocksOut(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	limiter := NewLoginLimiter(NewRepositoryLoginAttemptStore(repo), LoginLimiterOptions{
+		FreeAttempts:             10,
+		UsernameLockoutThreshold: 3,
+		LockoutDuration:          time.Hour,
+		FailureWindow:            time.Hour,
+	})
+	service := newTestUserService(t, repo, limiter, nil, UserServiceOptions{LoginChallengeTTL: time.Minute, TOTPIssuer: "Test"})
+	alice := registerUser(t, repo, "alice")
+	ctx := context.Background()
+
+	enrollment, err := service.BeginTOTPEnrollment(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	code, err := auth.TOTPCode(enrollment.Secret, auth.TOTPCounter(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := service.ConfirmTOTPEnrollment(ctx, alice.ID, code)
+	assertErrorType(t, err, "")
+
+	// Logging in with the password again between wrong codes, each time from another address,
+	// does not reset the count of failures towards the lockout
+	for i, clientIP := range []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"} {
+		result, err := service.Login(ctx, "alice", testPassword, clientIP)
+		assertErrorType(t, err, "")
+		if !result.TwoFactorRequired() {
+			t.Fatalf("login %d: result = %+v, want a two-factor challenge", i, result)
+		}
+		_, err = service.CompleteLoginChallenge(ctx, result.ChallengeToken, "000000", clientIP)
+		assertErrorType(t, err, appErrors.TypeUnauthorized)
+	}
+	_, err = service.Login(ctx, "alice", testPassword, "192.0.2.4")
+	assertErrorType(t, err, appErrors.TypeRateLimited)
+
+	// A completed two-factor login clears the failures
+	assertErrorType(t, service.UnlockAccount(ctx, alice.ID), "")
+	_, err = service.Login(ctx, "alice", "wrong password!", "192.0.2.5")
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	result, err := service.Login(ctx, "alice", testPassword, "192.0.2.5")
+	assertErrorType(t, err, "")
+	if _, err := repo.GetLoginAttempt(ctx, "user:alice"); err != nil {
+		t.Errorf("failed logins reset before the second factor: %v", err)
+	}
+	_, err = service.CompleteLoginChallenge(ctx, result.ChallengeToken, recoveryCodes[0], "192.0.2.5")
+	assertErrorType(t, err, "")
+	if _, err := repo.GetLoginAttempt(ctx, "user:alice"); !appErrors.IsType(err, appErrors.TypeNotFound) {
+		t.Errorf("failed logins not reset after a completed two-factor login: %v", err)
+	}
+}
+
+func TestUserServiceChangePassword(t *testing.T) {
+	const newPassword = "a different passphrase"
+	tests := []struct {
+		name            string
+		currentPassword string
+		newPassword     string
+		wantErr         appErrors.ErrorType
+	}{
+		{"valid", testPassword, newPassword, ""},
+		{"wrong current password", "wrong password!", newPassword, appErrors.TypeValidation},
+		{"unchanged", testPassword, testPassword, appErrors.TypeValidation},
+		{"too short", testPassword, "short", appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := newTestUserService(t, repo, nil, nil, UserServiceOptions{})
+			apiTokens := NewAPITokenService(repo)
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+			_, apiToken, err := apiTokens.CreateToken(ctx, alice.ID, "script", []string{"transactions:read"}, nil)
+			assertErrorType(t, err, "")
+
+			_, err = service.ChangePassword(ctx, alice.ID, tt.currentPassword, tt.newPassword, "192.0.2.1")
+			assertErrorType(t, err, tt.wantErr)
+
+			// Personal access tokens may have been created by whoever knew the old password
+			_, err = apiTokens.AuthenticateToken(ctx, apiToken)
+			if tt.wantErr == "" {
+				assertErrorType(t, err, appErrors.TypeUnauthorized)
+			} else {
+				assertErrorType(t, err, "")
+			}
+
+			wantWorking := testPassword
+			if tt.wantErr == "" {
+				wantWorking = tt.newPassword
+				_, err := service.AuthenticateUser(ctx, "alice", testPassword)
+				assertErrorType(t, err, appErrors.TypeUnauthorized)
+
+				stored, err := repo.GetUserByID(ctx, alice.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stored.SessionsRevokedAt == nil {
+					t.Error("sessions not revoked after a password change")
+				}
+			}
+			_, err = service.AuthenticateUser(ctx, "alice", wantWorking)
+			assertErrorType(t, err, "")
+		})
+	}
+}
+
+// resetTokenPattern extracts the token from a password reset link
+var resetTokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+func TestUserServiceChangePasswordThrottled(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	limiter := NewLoginLimiter(NewRepositoryLoginAttemptStore(repo), LoginLimiterOptions{
+		FreeAttempts:             10,
+		UsernameLockoutThreshold: 3,
+		LockoutDuration:          time.Hour,
+		FailureWindow:            time.Hour,
+	})
+	service := newTestUserService(t, repo, limiter, nil, UserServiceOptions{})
+	alice := registerUser(t, repo, "alice")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := service.ChangePassword(ctx, alice.ID, "wrong password!", "a different passphrase", "192.0.2.1")
+		assertErrorType(t, err, appErrors.TypeValidation)
+	}
+
+	// Guessing through a session locks the account for password logins as well
+	_, err := service.ChangePassword(ctx, alice.ID, testPassword, "a different passphrase", "192.0.2.1")
+	assertErrorType(t, err, appErrors.TypeRateLimited)
+	_, err = service.Login(ctx, "alice", testPassword, "198.51.100.1")
+	assertErrorType(t, err, appErrors.TypeRateLimited)
+
+	assertErrorType(t, service.UnlockAccount(ctx, alice.ID), "")
+	_, err = service.ChangePassword(ctx, alice.ID, testPassword, "a different passphrase", "192.0.2.1")
+	assertErrorType(t, err, "")
+}
+func TestUserServiceResetPassword(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	notifier := &recordingNotifier{}
+	service := newTestUserService(t, repo, nil, notifier, UserServiceOptions{PasswordResetURL: "https://example.com/reset?token={token}"})
+	ctx := context.Background()
+	alice, err := service.RegisterUser(ctx, "alice", testPassword, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiTokens := NewAPITokenService(repo)
+	_, apiToken, err := apiTokens.CreateToken(ctx, alice.ID, "script", []string{"transactions:read"}, nil)
+	assertErrorType(t, err, "")
+
+	// Unknown accounts are not revealed, and receive nothing
+	assertErrorType(t, service.RequestPasswordReset(ctx, "nobody@example.com"), "")
+	if len(notifier.messages) != 0 {
+		t.Fatalf("sent %d messages for an unknown account", len(notifier.messages))
+	}
+
+	assertErrorType(t, service.RequestPasswordReset(ctx, "Alice@Example.com"), "")
+	if len(notifier.messages) != 1 || notifier.messages[0].To != "alice@example.com" {
+		t.Fatalf("messages = %+v, want one to alice@example.com", notifier.messages)
+	}
+	match := resetTokenPattern.FindStringSubmatch(notifier.messages[0].Body)
+	if match == nil {
+		t.Fatalf("no reset link in %q", notifier.messages[0].Body)
+	}
+	token := match[1]
+
+	const newPassword = "a brand new passphrase"
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		wantErr  appErrors.ErrorType
+	}{
+		{"unknown token", "not-a-token", newPassword, appErrors.TypeValidation},
+		{"password against the policy", token, "short", appErrors.TypeValidation},
+		{"valid", token, newPassword, ""},
+		{"token already used", token, "yet another passphrase", appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertErrorType(t, service.ResetPassword(ctx, tt.token, tt.password), tt.wantErr)
+		})
+	}
+
+	_, err = service.AuthenticateUser(ctx, "alice", newPassword)
+	assertErrorType(t, err, "")
+	_, err = apiTokens.AuthenticateToken(ctx, apiToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+}
+
+func TestUserServiceUpdateProfile(t *testing.T) {
+	valid := UserProfile{
+		DisplayName: " Alice ", Email: "alice@example.com", Locale: "en-GB", Timezone: "Europe/London",
+		BaseCurrency: "gbp", FirstDayOfWeek: "Sunday", FiscalYearStart: "04-06",
+	}
+	tests := []struct {
+		name    string
+		change  func(p *UserProfile)
+		wantErr appErrors.ErrorType
+	}{
+		{"unchanged email", func(p *UserProfile) {}, ""},
+		{"unknown time zone", func(p *UserProfile) { p.Timezone = "Mars/Olympus_Mons" }, appErrors.TypeValidation},
+		{"local time zone", func(p *UserProfile) { p.Timezone = "Local" }, appErrors.TypeValidation},
+		{"invalid weekday", func(p *UserProfile) { p.FirstDayOfWeek = "someday" }, appErrors.TypeValidation},
+		{"leap day fiscal year start", func(p *UserProfile) { p.FiscalYearStart = "02-29" }, appErrors.TypeValidation},
+		{"new email without password", func(p *UserProfile) { p.Email = "new@example.com" }, appErrors.TypeValidation},
+		{"new email with password", func(p *UserProfile) { p.Email = "new@example.com"; p.CurrentPassword = testPassword }, ""},
+		{"email of another user", func(p *UserProfile) { p.Email = "bob@example.com"; p.CurrentPassword = testPassword }, appErrors.TypeAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			service := newTestUserService(t, repo, nil, nil, UserServiceOptions{})
+			ctx := context.Background()
+			alice, err := service.RegisterUser(ctx, "alice", testPassword, "alice@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := service.RegisterUser(ctx, "bob", testPassword, "bob@example.com"); err != nil {
+				t.Fatal(err)
+			}
+
+			profile := valid
+			tt.change(&profile)
+			_, err = service.UpdateProfile(ctx, alice.ID, profile)
+			assertErrorType(t, err, tt.wantErr)
+
+			stored, getErr := service.GetProfile(ctx, alice.ID)
+			if getErr != nil {
+				t.Fatal(getErr)
+			}
+			if tt.wantErr != "" {
+				if stored.Timezone != "UTC" || derefString(stored.Email) != "alice@example.com" {
+					t.Errorf("profile changed by a rejected update: %+v", stored)
+				}
+				return
+			}
+			if stored.DisplayName != "Alice" || stored.BaseCurrency != "GBP" || stored.FirstDayOfWeek != "sunday" ||
+				stored.Timezone != "Europe/London" || derefString(stored.Email) != profile.Email {
+				t.Errorf("stored profile %+v does not match %+v", stored, profile)
+			}
+		})
+	}
+}
+
+func TestUserServiceDisableUser(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := newTestUserService(t, repo, nil, nil, UserServiceOptions{})
+	keys, err := auth.LoadKeySet(auth.KeySetConfig{HMACSecret: "user-service-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := NewTokenService(repo, keys, 15*time.Minute, 24*time.Hour)
+	apiTokens := NewAPITokenService(repo)
+	alice := registerUser(t, repo, "alice")
+	ctx := context.Background()
+
+	pair, err := tokens.IssueTokens(ctx, alice)
+	assertErrorType(t, err, "")
+	_, apiToken, err := apiTokens.CreateToken(ctx, alice.ID, "script", []string{"transactions:read"}, nil)
+	assertErrorType(t, err, "")
+
+	disabled, err := service.DisableUser(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	if disabled.DisabledAt == nil {
+		t.Fatal("DisabledAt not set")
+	}
+
+	_, err = service.Login(ctx, "alice", testPassword, "192.0.2.1")
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	_, err = tokens.ValidateAccessToken(ctx, pair.AccessToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	_, err = tokens.RefreshTokens(ctx, pair.RefreshToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+	_, err = apiTokens.AuthenticateToken(ctx, apiToken)
+	assertErrorType(t, err, appErrors.TypeUnauthorized)
+
+	_, err = service.EnableUser(ctx, alice.ID)
+	assertErrorType(t, err, "")
+	_, err = service.Login(ctx, "alice", testPassword, "192.0.2.1")
+	assertErrorType(t, err, "")
+	_, err = apiTokens.AuthenticateToken(ctx, apiToken)
+	assertErrorType(t, err, "")
+
+	if actions := auditActions(t, repo, models.AuditEntityUser, alice.ID); len(actions) != 3 {
+		t.Errorf("audit actions = %v, want create and two updates", actions)
+	}
+	_, err = service.DisableUser(ctx, 9999)
+	assertErrorType(t, err, appErrors.TypeNotFound)
+}
+
+func TestUserServiceSetPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  appErrors.ErrorType
+	}{
+		{"valid", "a password set by an admin", ""},
+		{"too short", "short", appErrors.TypeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repository.NewMemoryRepository()
+			limiter := NewLoginLimiter(NewRepositoryLoginAttemptStore(repo), LoginLimiterOptions{
+				FreeAttempts: 10, UsernameLockoutThreshold: 1, LockoutDuration: time.Hour, FailureWindow: time.Hour,
+			})
+			service := newTestUserService(t, repo, limiter, nil, UserServiceOptions{})
+			alice := registerUser(t, repo, "alice")
+			ctx := context.Background()
+
+			_, err := service.Login(ctx, "alice", "wrong password!", "192.0.2.1")
+			assertErrorType(t, err, appErrors.TypeUnauthorized)
+			_, err = service.Login(ctx, "alice", testPassword, "192.0.2.1")
+			assertErrorType(t, err, appErrors.TypeRateLimited)
+
+			err = service.SetPassword(ctx, alice.ID, tt.password)
+			assertErrorType(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			_, err = service.Login(ctx, "alice", tt.password, "192.0.2.1")
+			assertErrorType(t, err, "")
+		})
+	}
+}
+
+func TestUserServiceDeleteAccountErasesAuditData(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := newTestUserService(t, repo, nil, nil, UserServiceOptions{AccountDeletionConfirmTTL: time.Hour})
+	ctx := WithRequestMetadata(context.Background(), "192.0.2.1", "alice-browser")
+
+	alice, err := service.RegisterUser(ctx, "alice", testPassword, "alice@example.com")
+	assertErrorType(t, err, "")
+	bob, err := service.RegisterUser(WithRequestMetadata(context.Background(), "192.0.2.2", "bob-browser"), "bob", testPassword, "bob@example.com")
+	assertErrorType(t, err, "")
+	err = recordAudit(ctx, repo, alice.ID, *bob.DefaultHouseholdID, models.AuditActionCreate, models.AuditEntityTransaction, 1,
+		map[string]models.AuditChange{"amount": {After: 12.5}})
+	assertErrorType(t, err, "")
+
+	_, token, err := service.RequestAccountDeletion(ctx, alice.ID, models.DeletionModeDelete, testPassword)
+	assertErrorType(t, err, "")
+	_, err = service.ConfirmAccountDeletion(ctx, alice.ID, token)
+	assertErrorType(t, err, "")
+	deleted, err := service.DeleteDueAccounts(ctx, time.Now().Add(time.Second))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteDueAccounts = %d, %v; want 1 account deleted", deleted, err)
+	}
+
+	entries, err := repo.GetAuditEntriesAfter(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, personal := range []string{"alice@example.com", `"alice"`, "192.0.2.1", "alice-browser"} {
+			if strings.Contains(string(data), personal) {
+				t.Errorf("entry %d still holds %s: %s", entry.ID, personal, data)
+			}
+		}
+		switch {
+		case entry.EntityType == models.AuditEntityTransaction && !strings.Contains(string(entry.Changes), "12.5"):
+			t.Errorf("changes to the household's records were erased: %s", entry.Changes)
+		case entry.ActorID != nil && *entry.ActorID == bob.ID && entry.IPAddress != "192.0.2.2":
+			t.Errorf("entry %d of bob lost its IP address", entry.ID)
+		}
+	}
+
+	verification, err := NewAuditService(repo).VerifyChain(context.Background())
+	assertErrorType(t, err, "")
+	if !verification.Valid || verification.EntriesChecked != int64(len(entries)) {
+		t.Errorf("verification = %+v, want all %d entries valid", verification, len(entries))
+	}
+}
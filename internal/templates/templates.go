@@ -0,0 +1,104 @@
+package templates
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed categories/*.json
+var builtinCategoryTemplates embed.FS
+
+// CategoryTemplate is a named, predefined category hierarchy that can be applied to a user
+type CategoryTemplate struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Categories  []CategoryTemplateNode `json:"categories"`
+}
+
+// CategoryTemplateNode is a category within a template, with optional subcategories
+type CategoryTemplateNode struct {
+	Name     string                 `json:"name"`
+	Children []CategoryTemplateNode `json:"children,omitempty"`
+}
+
+// Registry holds the category templates available to the application, keyed by name
+type Registry struct {
+	templates map[string]*CategoryTemplate
+}
+
+// Builtin loads the category templates embedded in the binary
+func Builtin() (*Registry, error) {
+	sub, err := fs.Sub(builtinCategoryTemplates, "categories")
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(sub)
+}
+
+// NewRegistry loads every *.json category template at the root of the given file system
+func NewRegistry(fsys fs.FS) (*Registry, error) {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{templates: make(map[string]*CategoryTemplate, len(files))}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read category template %s: %w", file, err)
+		}
+
+		var template CategoryTemplate
+		if err := json.Unmarshal(data, &template); err != nil {
+			return nil, fmt.Errorf("failed to parse category template %s: %w", file, err)
+		}
+		if template.Name == "" {
+			template.Name = strings.TrimSuffix(path.Base(file), ".json")
+		}
+		if err := validateNodes(template.Categories); err != nil {
+			return nil, fmt.Errorf("invalid category template %s: %w", file, err)
+		}
+
+		key := strings.ToLower(template.Name)
+		if _, exists := registry.templates[key]; exists {
+			return nil, fmt.Errorf("duplicate category template name '%s' in %s", template.Name, file)
+		}
+		registry.templates[key] = &template
+	}
+	return registry, nil
+}
+
+// Get returns the template with the given name, ignoring case
+func (r *Registry) Get(name string) (*CategoryTemplate, bool) {
+	template, ok := r.templates[strings.ToLower(name)]
+	return template, ok
+}
+
+// List returns all templates ordered by name
+func (r *Registry) List() []*CategoryTemplate {
+	list := make([]*CategoryTemplate, 0, len(r.templates))
+	for _, template := range r.templates {
+		list = append(list, template)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// validateNodes checks that every category in a template has a usable name
+func validateNodes(nodes []CategoryTemplateNode) error {
+	for _, node := range nodes {
+		if length := len(strings.TrimSpace(node.Name)); length < 2 || length > 100 {
+			return fmt.Errorf("category name '%s' must be between 2 and 100 characters", node.Name)
+		}
+		if err := validateNodes(node.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
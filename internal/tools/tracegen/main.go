@@ -0,0 +1,268 @@
+// Command tracegen generates the traced decorators of the service interfaces in the package it
+// runs in. Every exported interface named *Service gets a wrapper that records a span, named
+// after the interface and method, around each method taking a context.Context first; other
+// methods are delegated as they are.
+//
+// It is run by go generate in internal/services:
+//
+//	//go:generate go run ../tools/tracegen -output tracing_gen.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	output := flag.String("output", "tracing_gen.go", "file to write, in the current directory")
+	flag.Parse()
+
+	src, err := generate(".", *output)
+	if err != nil {
+		log.Fatalf("tracegen: %v", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatalf("tracegen: %v", err)
+	}
+}
+
+// service is a service interface and the file it is declared in
+type service struct {
+	name  string
+	iface *ast.InterfaceType
+	file  *ast.File
+}
+
+// generate returns the formatted source of the decorators of the services declared in dir,
+// ignoring tests and the output file itself
+func generate(dir, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != output
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkgName string
+	var services []service
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					iface, ok := typeSpec.Type.(*ast.InterfaceType)
+					if ok && typeSpec.Name.IsExported() && strings.HasSuffix(typeSpec.Name.Name, "Service") {
+						services = append(services, service{name: typeSpec.Name.Name, iface: iface, file: file})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].name < services[j].name })
+
+	g := &generator{fset: fset, imports: map[string]string{}}
+	var body bytes.Buffer
+	for _, s := range services {
+		if err := g.service(&body, s); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tracegen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkgName)
+	paths := make([]string, 0, len(g.imports))
+	for importPath := range g.imports {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	for _, importPath := range paths {
+		if name := g.imports[importPath]; name != path.Base(importPath) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, importPath)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", importPath)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+// generator writes decorators and collects the imports their signatures need
+type generator struct {
+	fset    *token.FileSet
+	imports map[string]string // Import path to the name it is referred to by
+}
+
+// service writes the decorator of one service interface
+func (g *generator) service(w *bytes.Buffer, s service) error {
+	traced := "traced" + s.name
+	article := "a"
+	if strings.ContainsRune("AEIOU", rune(s.name[0])) {
+		article = "an"
+	}
+	fmt.Fprintf(w, "\n// %s records a span for every call of %s %s\n", traced, article, s.name)
+	fmt.Fprintf(w, "type %s struct {\n\tnext %s\n}\n", traced, s.name)
+	fmt.Fprintf(w, "\n// Trace%s wraps service so that every call of it is traced\n", s.name)
+	fmt.Fprintf(w, "func Trace%s(service %s) %s {\n\treturn &%s{next: service}\n}\n", s.name, s.name, s.name, traced)
+
+	for _, field := range s.iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			return fmt.Errorf("%s embeds %s; only methods are supported", s.name, g.expr(field.Type))
+		}
+		if err := g.useImports(fn, s.file); err != nil {
+			return err
+		}
+		for _, name := range field.Names {
+			g.method(w, s.name, traced, name.Name, fn)
+		}
+	}
+	return nil
+}
+
+// method writes one method of a decorator
+func (g *generator) method(w *bytes.Buffer, serviceName, traced, name string, fn *ast.FuncType) {
+	var params, args []string
+	traceable := false
+	for i, field := range fn.Params.List {
+		typ := g.expr(field.Type)
+		if i == 0 && typ == "context.Context" {
+			traceable = true
+		}
+		var names []string
+		for _, ident := range field.Names {
+			names = append(names, ident.Name)
+		}
+		if len(names) == 0 {
+			names = []string{fmt.Sprintf("arg%d", len(args))}
+		}
+		if traceable && len(args) == 0 {
+			names[0] = "ctx"
+		}
+		params = append(params, strings.Join(names, ", ")+" "+typ)
+		for _, arg := range names {
+			if _, variadic := field.Type.(*ast.Ellipsis); variadic {
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+	}
+
+	var results []string
+	returnsError := false
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			n := len(field.Names)
+			if n == 0 {
+				n = 1
+			}
+			for range n {
+				results = append(results, g.expr(field.Type))
+			}
+		}
+		returnsError = len(results) > 0 && results[len(results)-1] == "error"
+	}
+
+	signature := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	switch len(results) {
+	case 0:
+	case 1:
+		signature += " " + results[0]
+	default:
+		signature += " (" + strings.Join(results, ", ") + ")"
+	}
+	call := fmt.Sprintf("s.next.%s(%s)", name, strings.Join(args, ", "))
+
+	fmt.Fprintf(w, "\nfunc (s *%s) %s {\n", traced, signature)
+	if !traceable {
+		if len(results) > 0 {
+			fmt.Fprintf(w, "\treturn %s\n}\n", call)
+		} else {
+			fmt.Fprintf(w, "\t%s\n}\n", call)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "\tctx, span := startSpan(ctx, %s)\n", strconv.Quote(serviceName+"."+name))
+	if len(results) == 0 {
+		fmt.Fprintf(w, "\t%s\n\tendSpan(span, nil)\n}\n", call)
+		return
+	}
+	vars := make([]string, len(results))
+	for i := range vars {
+		vars[i] = fmt.Sprintf("r%d", i)
+	}
+	spanErr := "nil"
+	if returnsError {
+		vars[len(vars)-1] = "err"
+		spanErr = "err"
+	}
+	fmt.Fprintf(w, "\t%s := %s\n", strings.Join(vars, ", "), call)
+	fmt.Fprintf(w, "\tendSpan(span, %s)\n", spanErr)
+	fmt.Fprintf(w, "\treturn %s\n}\n", strings.Join(vars, ", "))
+}
+
+// useImports records the imports of file that the signature fn refers to
+func (g *generator) useImports(fn *ast.FuncType, file *ast.File) error {
+	var err error
+	ast.Inspect(fn, func(node ast.Node) bool {
+		sel, ok := node.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		importPath, found := lookupImport(file, pkg.Name)
+		if !found {
+			err = fmt.Errorf("no import for %s in %s", pkg.Name, g.fset.Position(file.Pos()).Filename)
+			return false
+		}
+		g.imports[importPath] = pkg.Name
+		return false
+	})
+	return err
+}
+
+// lookupImport finds the path of the import referred to as name in file
+func lookupImport(file *ast.File, name string) (string, bool) {
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil && spec.Name.Name == name || spec.Name == nil && path.Base(importPath) == name {
+			return importPath, true
+		}
+	}
+	return "", false
+}
+
+// expr prints a type expression as source
+func (g *generator) expr(node ast.Expr) string {
+	var b bytes.Buffer
+	if err := printer.Fprint(&b, g.fset, node); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestServicesUpToDate fails when a service interface changed without running go generate, which
+// would leave the new or changed methods untraced or the package not building
+func TestServicesUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "services")
+	want, err := generate(dir, "tracing_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "tracing_gen.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("internal/services/tracing_gen.go is out of date; run go generate ./internal/services")
+	}
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"personal-finance-tracker-api/internal/repository"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestGormPluginRecordsQuerySpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	db, err := repository.OpenDB(repository.DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+	if err := db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if err := db.WithContext(ctx).Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var found widget
+	if err := db.WithContext(ctx).First(&found, 42).Error; err == nil {
+		t.Fatal("expected no widget 42")
+	}
+	if err := db.WithContext(ctx).Exec("SELECT * FROM missing").Error; err == nil {
+		t.Fatal("expected an error querying a missing table")
+	}
+	parent.End()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for name, wantStatus := range map[string]codes.Code{"create widgets": codes.Unset, "query widgets": codes.Unset, "raw": codes.Error} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("no span %q, got %v", name, spans)
+			continue
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the request span", name)
+		}
+		if span.Status().Code != wantStatus {
+			t.Errorf("span %q status = %v, want %v", name, span.Status().Code, wantStatus)
+		}
+	}
+
+	var text string
+	for _, attr := range spans["create widgets"].Attributes() {
+		if attr.Key == "db.query.text" {
+			text = attr.Value.AsString()
+		}
+	}
+	if text == "" {
+		t.Error("create span has no db.query.text")
+	}
+}
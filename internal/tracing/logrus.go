@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LogHook adds the trace and span ID to log entries made with a context that carries a span,
+// as in logrus.WithContext(ctx), so that logs can be looked up by trace
+type LogHook struct{}
+
+// Levels returns every level, as any entry may belong to a trace
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire adds the traceID and spanID fields
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	spanContext := trace.SpanContextFromContext(entry.Context)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	entry.Data["traceID"] = spanContext.TraceID().String()
+	entry.Data["spanID"] = spanContext.SpanID().String()
+	return nil
+}
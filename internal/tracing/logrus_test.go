@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestLogHookAddsTraceFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(&out)
+	logger.AddHook(LogHook{})
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	logger.WithContext(ctx).Info("traced")
+	logger.WithContext(context.Background()).Info("untraced")
+
+	decoder := json.NewDecoder(&out)
+	var traced, untraced map[string]interface{}
+	if err := decoder.Decode(&traced); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.Decode(&untraced); err != nil {
+		t.Fatal(err)
+	}
+
+	if traced["traceID"] != span.SpanContext().TraceID().String() || traced["spanID"] != span.SpanContext().SpanID().String() {
+		t.Errorf("traced entry = %v, want trace %s and span %s", traced, span.SpanContext().TraceID(), span.SpanContext().SpanID())
+	}
+	if _, ok := untraced["traceID"]; ok {
+		t.Errorf("untraced entry has a traceID: %v", untraced)
+	}
+}
@@ -0,0 +1,95 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider and its exporter, W3C
+// trace context propagation, and the GORM plugin and logrus hook that connect queries and log
+// entries to the trace of the request they belong to
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"personal-finance-tracker-api/internal/buildinfo"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// Exporters that spans can be sent to
+const (
+	ExporterNone   = "none"   // Spans are not recorded; trace context is still propagated
+	ExporterOTLP   = "otlp"   // OTLP over HTTP, configured by the standard OTEL_EXPORTER_OTLP_* variables
+	ExporterStdout = "stdout" // One JSON object per span on standard output, for local testing
+)
+
+// Options configures tracing
+type Options struct {
+	Exporter    string  // One of the Exporter constants
+	ServiceName string  // Reported as service.name unless OTEL_SERVICE_NAME is set
+	SampleRatio float64 // Fraction of traces started here that are recorded; callers' decisions are kept
+}
+
+// Provider is the configured tracer provider. As a lifecycle component it flushes the spans
+// still buffered when it stops.
+type Provider struct {
+	provider *sdktrace.TracerProvider // nil when spans are not exported
+}
+
+// Setup installs the global tracer provider and the W3C trace context and baggage propagators
+func Setup(ctx context.Context, opts Options) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return &Provider{}, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q, expected %q, %q or %q", opts.Exporter, ExporterNone, ExporterOTLP, ExporterStdout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(opts.ServiceName),
+			semconv.ServiceVersion(buildinfo.Version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describing the trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return &Provider{provider: provider}, nil
+}
+
+// Start does nothing; the provider records spans from Setup on
+func (p *Provider) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop exports the buffered spans and shuts the exporter down
+func (p *Provider) Stop(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}